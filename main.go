@@ -1,20 +1,45 @@
 package main
 
 import (
+	"context"
+	"log"
+	"os"
 	"strconv"
+	"time"
 
 	"github.com/sajalmia381/store-api/src/api"
+	"github.com/sajalmia381/store-api/src/command"
 	"github.com/sajalmia381/store-api/src/config"
 	"github.com/sajalmia381/store-api/src/dependency"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/fixture"
 	"github.com/sajalmia381/store-api/src/v1/db"
 	"github.com/sajalmia381/store-api/src/v1/dtos"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if err := command.Run(os.Args[1:]); err != nil {
+			log.Fatal("[ERROR] ", err.Error())
+		}
+		return
+	}
 	server := config.New()
-	db.GetDmManager()
+	if config.Database == string(enums.MONGO) {
+		db.GetDmManager()
+	}
 	go intSuperAdmin()
-	go initDefaultUser()
+	// before the server starts, the cart handlers read DefaultUserId
+	initDefaultUser()
+	if config.SeedOnStartup {
+		go initSeed()
+	}
+	if config.TrashRetention > 0 {
+		go purgeTrash()
+	}
+	if config.CartReservationTTL > 0 {
+		go releaseReservations()
+	}
 
 	api.Routes(server)
 
@@ -23,12 +48,12 @@ func main() {
 
 func initDefaultUser() {
 	userService := dependency.GetUserService()
-	user, err := userService.FindByEmail(config.DefaultUserEmail)
+	user, err := userService.FindByEmail(context.Background(), config.DefaultUserEmail)
 	if err == nil {
 		config.DefaultUserId = &user.ID
 		return
 	}
-	num, err := strconv.Atoi(config.SuperAdminNumber)
+	num, err := strconv.Atoi(config.DefaultUserNumber)
 	if err != nil {
 		num = 1234567891
 	}
@@ -39,14 +64,14 @@ func initDefaultUser() {
 		Password: config.DefaultUserPassword,
 		Number:   &menNum,
 	}
-	user, _ = userService.Store(payload)
+	user, _ = userService.Store(context.Background(), payload)
 	config.DefaultUserId = &user.ID
 }
 
 func intSuperAdmin() {
 	if config.SuperAdminEmail != "" {
 		userService := dependency.GetUserService()
-		_, err := userService.FindByEmail(config.SuperAdminEmail)
+		_, err := userService.FindByEmail(context.Background(), config.SuperAdminEmail)
 		if err == nil {
 			return
 		}
@@ -61,6 +86,48 @@ func intSuperAdmin() {
 			Password: config.SuperAdminPassword,
 			Number:   &menNum,
 		}
-		userService.StoreSuperAdmin(payload)
+		userService.StoreSuperAdmin(context.Background(), payload)
+	}
+}
+
+func initSeed() {
+	catalog, err := fixture.Load(config.SeedPath)
+	if err != nil {
+		log.Println("[ERROR] failed to load seed fixtures:", err.Error())
+		return
+	}
+	report, err := dependency.GetSeedService().Seed(context.Background(), catalog)
+	if err != nil {
+		log.Println("[ERROR] failed to seed:", err.Error())
+		return
+	}
+	log.Printf("[INFO] Seeded categories: %d, products: %d, users: %d, carts: %d\n",
+		report.Categories.Created, report.Products.Created, report.Users.Created, report.Carts.Created)
+}
+
+// purgeTrash removes the trash older than TRASH_RETENTION every hour.
+func purgeTrash() {
+	for {
+		report, err := dependency.GetTrashService().Purge(context.Background(), config.TrashRetention)
+		if err != nil {
+			log.Println("[ERROR] failed to purge the trash:", err.Error())
+		} else if report.Products+report.Categories+report.Users > 0 {
+			log.Printf("[INFO] Purged trash products: %d, categories: %d, users: %d\n", report.Products, report.Categories, report.Users)
+		}
+		time.Sleep(time.Hour)
+	}
+}
+
+// releaseReservations gives the stock of cart lines not touched for
+// CART_RESERVATION_TTL back every minute.
+func releaseReservations() {
+	for {
+		count, err := dependency.GetCartService().ReleaseExpiredReservations(context.Background())
+		if err != nil {
+			log.Println("[ERROR] failed to release the cart reservations:", err.Error())
+		} else if count > 0 {
+			log.Printf("[INFO] Released cart reservations: %d\n", count)
+		}
+		time.Sleep(time.Minute)
 	}
 }
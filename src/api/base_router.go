@@ -5,18 +5,15 @@ import (
 
 	"github.com/labstack/echo/v4"
 	v1 "github.com/sajalmia381/store-api/src/api/v1"
+	"github.com/sajalmia381/store-api/src/custom_middleware"
 )
 
 func Routes(e *echo.Echo) {
+	e.Use(custom_middleware.RequestTimeoutMiddleware)
 	e.GET("/v1/", index)
-	e.GET("/v1/health", health)
 	v1.Routes(e.Group("/v1"))
 }
 
 func index(c echo.Context) error {
 	return c.String(http.StatusOK, "Welcome to Store-Api")
 }
-
-func health(c echo.Context) error {
-	return c.String(http.StatusOK, "I am alive...")
-}
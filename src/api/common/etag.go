@@ -0,0 +1,70 @@
+package common
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sajalmia381/store-api/src/domain_error"
+)
+
+const (
+	headerETag        = "ETag"
+	headerIfMatch     = "If-Match"
+	headerIfNoneMatch = "If-None-Match"
+)
+
+// ETag is the strong tag "<version>" of a versioned entity.
+func ETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// SetETag tags the response with the version of the entity it carries.
+func SetETag(c echo.Context, version int64) {
+	c.Response().Header().Set(headerETag, ETag(version))
+}
+
+// IsNotModified tags the response and reports whether If-None-Match already
+// lists the version, the caller answers with NotModified then. The comparison
+// is weak, as RFC 7232 asks for.
+func IsNotModified(c echo.Context, version int64) bool {
+	SetETag(c, version)
+	header := c.Request().Header.Get(headerIfNoneMatch)
+	if header == "" {
+		return false
+	}
+	etag := ETag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func NotModified(c echo.Context) error {
+	return c.NoContent(http.StatusNotModified)
+}
+
+// IfMatchVersion reads the version a conditional write expects from If-Match.
+// It is nil without the header or for "*", which any existing entity matches.
+// A weak or foreign tag never matches a version.
+func IfMatchVersion(c echo.Context) (*int64, error) {
+	header := strings.TrimSpace(c.Request().Header.Get(headerIfMatch))
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+	if strings.Contains(header, ",") {
+		return nil, domain_error.Validation("If-Match takes a single ETag")
+	}
+	if len(header) < 2 || header[0] != '"' || header[len(header)-1] != '"' {
+		return nil, domain_error.PreconditionFailed("If-Match does not match the current version")
+	}
+	version, err := strconv.ParseInt(header[1:len(header)-1], 10, 64)
+	if err != nil {
+		return nil, domain_error.PreconditionFailed("If-Match does not match the current version")
+	}
+	return &version, nil
+}
@@ -1,9 +1,12 @@
 package common
 
 import (
+	"errors"
+	"log"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
+	"github.com/sajalmia381/store-api/src/domain_error"
 )
 
 type MetaData struct {
@@ -13,6 +16,10 @@ type MetaData struct {
 	TotalElements uint64  `json:"totalElements"`
 	NextPage      *uint64 `json:"nextPage"`
 	PrevPage      *uint64 `json:"prevPage"`
+	// NextCursor and PrevCursor continue the list with after and before,
+	// they are nil at its ends.
+	NextCursor *string `json:"nextCursor"`
+	PrevCursor *string `json:"prevCursor"`
 }
 
 type ResponseDTO struct {
@@ -98,3 +105,29 @@ func GenerateErrorResponse(c echo.Context, data interface{}, message string, opt
 		Status:  _statusText,
 	})
 }
+
+var domainErrorHttpCodes = map[domain_error.Kind]int{
+	domain_error.NOT_FOUND:           http.StatusNotFound,
+	domain_error.CONFLICT:            http.StatusConflict,
+	domain_error.VALIDATION:          http.StatusUnprocessableEntity,
+	domain_error.FORBIDDEN:           http.StatusForbidden,
+	domain_error.UNAVAILABLE:         http.StatusServiceUnavailable,
+	domain_error.PRECONDITION_FAILED: http.StatusPreconditionFailed,
+}
+
+// GenerateDomainErrorResponse answers with the status of the domain error kind.
+// Any other error is unexpected and answered with 500.
+func GenerateDomainErrorResponse(c echo.Context, data interface{}, err error, options ...*ResponseOption) error {
+	httpCode, ok := domainErrorHttpCodes[domain_error.KindOf(err)]
+	if !ok {
+		httpCode = http.StatusInternalServerError
+	}
+	if httpCode >= http.StatusInternalServerError {
+		cause := errors.Unwrap(err)
+		if cause == nil {
+			cause = err
+		}
+		log.Println("[ERROR]", c.Request().Method, c.Request().RequestURI+":", cause.Error())
+	}
+	return GenerateErrorResponse(c, data, err.Error(), append([]*ResponseOption{{HttpCode: httpCode}}, options...)...)
+}
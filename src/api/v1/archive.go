@@ -0,0 +1,68 @@
+package v1
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/utils"
+	"github.com/sajalmia381/store-api/src/v1/api"
+	"github.com/sajalmia381/store-api/src/v1/service"
+)
+
+type archiveApi struct {
+	archiveService service.ArchiveService
+}
+
+func (a archiveApi) Export(c echo.Context) error {
+	if !utils.IsSuperAdmin(c) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Forbidden("Only super admin can export the store"))
+	}
+	// Buffered, so a failed export still answers with an error response
+	var buffer bytes.Buffer
+	if _, err := a.archiveService.Export(c.Request().Context(), &buffer); err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	fileName := "store-api-" + time.Now().UTC().Format("20060102-150405") + ".tar"
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+fileName+`"`)
+	return c.Blob(http.StatusOK, "application/x-tar", buffer.Bytes())
+}
+
+// Import reads the archive from the "archive" file of a multipart form or
+// from the raw request body.
+func (a archiveApi) Import(c echo.Context) error {
+	if !utils.IsSuperAdmin(c) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Forbidden("Only super admin can import the store"))
+	}
+	var reader io.Reader = c.Request().Body
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		fileHeader, err := c.FormFile("archive")
+		if err != nil {
+			return common.GenerateErrorResponse(c, nil, "Failed to read archive file")
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return common.GenerateErrorResponse(c, nil, "Failed to read archive file")
+		}
+		defer file.Close()
+		reader = file
+	}
+	mode := enums.ArchiveMode(c.QueryParam("mode"))
+	manifest, err := a.archiveService.Import(c.Request().Context(), reader, mode)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	return common.GenerateSuccessResponse(c, manifest, "Success! Archive imported")
+}
+
+func NewArchiveApi(archiveService service.ArchiveService) api.ArchiveApi {
+	return &archiveApi{
+		archiveService: archiveService,
+	}
+}
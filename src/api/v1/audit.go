@@ -0,0 +1,54 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/utils"
+	"github.com/sajalmia381/store-api/src/v1/api"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"github.com/sajalmia381/store-api/src/v1/service"
+)
+
+type auditApi struct {
+	auditService service.AuditService
+}
+
+// FindAll is the audit feed of every entity, filtered by the query parameters
+// of dtos.AuditQueryParams.
+func (a auditApi) FindAll(c echo.Context) error {
+	if !utils.IsSuperAdmin(c) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Forbidden("Only super admin can see the audit log"))
+	}
+	queryParams, err := bindAuditQuery(c)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	entries, metaData, err := a.auditService.FindAll(c.Request().Context(), queryParams)
+	return auditResponse(c, entries, metaData, err, "Success! Audit log")
+}
+
+func bindAuditQuery(c echo.Context) (dtos.AuditQueryParams, error) {
+	var queryParams dtos.AuditQueryParams
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, &queryParams); err != nil {
+		return queryParams, domain_error.Validation("query parameters are not valid, since and until must be RFC 3339 times")
+	}
+	return queryParams, nil
+}
+
+// auditResponse answers the audit feed and the history endpoints.
+func auditResponse(c echo.Context, entries []model.AuditLog, metaData common.MetaData, err error, message string) error {
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	return common.GenerateSuccessResponse(c, entries, message, &common.ResponseOption{
+		MetaData: &metaData,
+	})
+}
+
+func NewAuditApi(auditService service.AuditService) api.AuditApi {
+	return &auditApi{
+		auditService: auditService,
+	}
+}
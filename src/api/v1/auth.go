@@ -31,11 +31,11 @@ func (a authApi) Login(c echo.Context) error {
 		return common.GenerateErrorResponse(c, err.Error(), "Failed to bind data")
 	}
 	if err := payload.Validate(); err != nil {
-		return common.GenerateErrorResponse(c, nil, err.Error())
+		return common.GenerateDomainErrorResponse(c, nil, err)
 	}
-	user, err := a.authService.Login(payload)
+	user, err := a.authService.Login(c.Request().Context(), payload)
 	if err != nil {
-		return common.GenerateErrorResponse(c, "[ERROR]: User is not matched!", err.Error())
+		return common.GenerateDomainErrorResponse(c, "[ERROR]: User is not matched!", err)
 	}
 	if !user.Status {
 		return common.GenerateErrorResponse(c, "[ERROR]: You were disabled!", "Please contact to admin for active you account!")
@@ -63,7 +63,7 @@ func (a authApi) Login(c echo.Context) error {
 		})
 	}
 	if user.Role == enums.ROLE_SUPER_ADMIN {
-		_, err = a.authService.UpdateUserLoginTime(user.ID)
+		_, err = a.authService.UpdateUserLoginTime(c.Request().Context(), user.ID)
 		if err != nil {
 			log.Println("Failed to update user login time", err.Error())
 		}
@@ -73,7 +73,7 @@ func (a authApi) Login(c echo.Context) error {
 			Type:   string(enums.REFRESH_TOKEN),
 			Token:  jwtRes.RefreshToken,
 		}
-		if _, err := a.tokenService.Store(token); err != nil {
+		if _, err := a.tokenService.Store(c.Request().Context(), token); err != nil {
 			log.Println("Failed to store super admin refresh token:", err.Error())
 		}
 	}
@@ -86,11 +86,11 @@ func (a authApi) Register(c echo.Context) error {
 		return common.GenerateErrorResponse(c, err.Error(), "Failed to bind data")
 	}
 	if err := payload.Validate(); err != nil {
-		return common.GenerateErrorResponse(c, nil, err.Error())
+		return common.GenerateDomainErrorResponse(c, nil, err)
 	}
-	user, err := a.authService.Register(payload)
+	user, err := a.authService.Register(c.Request().Context(), payload)
 	if err != nil {
-		return common.GenerateErrorResponse(c, "[ERROR]: failed to register user!", err.Error())
+		return common.GenerateDomainErrorResponse(c, "[ERROR]: failed to register user!", err)
 	}
 	jwtPayload := dtos.JwtPayload{
 		ID:    user.ID,
@@ -119,7 +119,7 @@ func (a authApi) RefreshToken(c echo.Context) error {
 		return common.GenerateErrorResponse(c, err.Error(), "Failed to bind data")
 	}
 	if err := payload.Validate(); err != nil {
-		return common.GenerateErrorResponse(c, nil, err.Error())
+		return common.GenerateDomainErrorResponse(c, nil, err)
 	}
 	isValid := a.jwtService.VerifyToken(payload.RefreshToken)
 	if !isValid {
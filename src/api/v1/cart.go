@@ -6,17 +6,19 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/sajalmia381/store-api/src/api/common"
 	"github.com/sajalmia381/store-api/src/config"
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/money"
 	"github.com/sajalmia381/store-api/src/utils"
 	"github.com/sajalmia381/store-api/src/v1/api"
 	"github.com/sajalmia381/store-api/src/v1/dtos"
 	"github.com/sajalmia381/store-api/src/v1/model"
 	"github.com/sajalmia381/store-api/src/v1/service"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type cartApi struct {
-	cartService service.CartService
+	cartService     service.CartService
+	currencyService service.CurrencyService
 }
 
 // Requester Cart
@@ -26,14 +28,18 @@ func (a cartApi) ViewCart(c echo.Context) error {
 	if err == nil {
 		requesterId = jwtPayload.ID
 	}
-	cart, err := a.cartService.FindByUserId(requesterId)
+	converter, err := requestConverter(c, a.currencyService)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	cart, err := a.cartService.FindByUserId(c.Request().Context(), requesterId)
+	if err != nil {
+		if domain_error.Is(err, domain_error.NOT_FOUND) {
 			return common.GenerateSuccessResponse(c, nil, "User cart empty")
 		}
-		return common.GenerateErrorResponse(c, nil, "User cart")
+		return common.GenerateDomainErrorResponse(c, nil, err)
 	}
-	return common.GenerateSuccessResponse(c, cart, "User cart")
+	return a.cartResponse(c, cart, converter, "User cart")
 }
 
 func (a cartApi) UpdateCartByProducts(c echo.Context) error {
@@ -42,16 +48,20 @@ func (a cartApi) UpdateCartByProducts(c echo.Context) error {
 	if err == nil {
 		requesterId = jwtPayload.ID
 	}
+	converter, err := requestConverter(c, a.currencyService)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
 	var productSpec []model.CartProductSpec
 	if err := c.Bind(&productSpec); err != nil {
 		log.Println("[ERROR] Cart update data bind:", err)
 		return common.GenerateSuccessResponse(c, nil, "Failed to bind data")
 	}
-	cart, err := a.cartService.UpdateCartByProducts(requesterId, productSpec)
+	cart, err := a.cartService.UpdateCartByProducts(c.Request().Context(), requesterId, productSpec)
 	if err != nil {
-		return common.GenerateErrorResponse(c, nil, err.Error())
+		return common.GenerateDomainErrorResponse(c, nil, err)
 	}
-	return common.GenerateSuccessResponse(c, cart, "Success! Cart update")
+	return a.cartResponse(c, cart, converter, "Success! Cart update")
 }
 
 func (a cartApi) UpdateCartByProduct(c echo.Context) error {
@@ -60,16 +70,20 @@ func (a cartApi) UpdateCartByProduct(c echo.Context) error {
 	if err == nil {
 		requesterId = jwtPayload.ID
 	}
+	converter, err := requestConverter(c, a.currencyService)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
 	var productSpec model.CartProductSpec
 	if err := c.Bind(&productSpec); err != nil {
 		log.Println("[ERROR] Cart update data bind:", err)
 		return common.GenerateSuccessResponse(c, nil, "Failed to bind data")
 	}
-	cart, err := a.cartService.UpdateCartByProduct(requesterId, productSpec)
+	cart, err := a.cartService.UpdateCartByProduct(c.Request().Context(), requesterId, productSpec)
 	if err != nil {
-		return common.GenerateErrorResponse(c, nil, err.Error())
+		return common.GenerateDomainErrorResponse(c, nil, err)
 	}
-	return common.GenerateSuccessResponse(c, cart, "Success! Cart update")
+	return a.cartResponse(c, cart, converter, "Success! Cart update")
 }
 func (a cartApi) RemoveProductFromCart(c echo.Context) error {
 	requesterId := *config.DefaultUserId
@@ -78,42 +92,81 @@ func (a cartApi) RemoveProductFromCart(c echo.Context) error {
 		log.Println("[ERROR]", err)
 		requesterId = jwtPayload.ID
 	}
+	converter, err := requestConverter(c, a.currencyService)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
 	var productIdSpec dtos.CartProductId
 	if err := c.Bind(&productIdSpec); err != nil {
 		log.Println("[ERROR] Cart update data bind:", err)
 		return common.GenerateSuccessResponse(c, nil, "Failed to bind data")
 	}
-	cart, err := a.cartService.RemoveProductFromCart(requesterId, productIdSpec.ProductId)
+	cart, err := a.cartService.RemoveProductFromCart(c.Request().Context(), requesterId, productIdSpec.ProductId, productIdSpec.VariantId)
 	if err != nil {
-		return common.GenerateErrorResponse(c, nil, err.Error())
+		return common.GenerateDomainErrorResponse(c, nil, err)
 	}
-	return common.GenerateSuccessResponse(c, cart, "Success! Cart update")
+	return a.cartResponse(c, cart, converter, "Success! Cart update")
 }
 
 // Cart CRUD
 func (a cartApi) FindAll(c echo.Context) error {
-	carts, err := a.cartService.FindAll()
+	var queryParams dtos.CartQueryParams
+	if err := bindListQuery(c, &queryParams); err != nil {
+		return common.GenerateDomainErrorResponse(c, "", err)
+	}
+	queryParams.PageByDefault()
+	carts, metaData, err := a.cartService.FindAll(c.Request().Context(), queryParams)
 	if err != nil {
-		return common.GenerateErrorResponse(c, "", err.Error())
+		return common.GenerateDomainErrorResponse(c, "", err)
 	}
-	return common.GenerateSuccessResponse(c, carts, "Success! All carts list")
+	return listResponse(c, carts, metaData, "Success! All carts list")
 }
 
 func (a cartApi) FindByUserId(c echo.Context) error {
 	userId := c.Param("userId")
 	_id, err := primitive.ObjectIDFromHex(userId)
 	if err != nil {
-		return common.GenerateErrorResponse(c, nil, "User is not valid")
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Validation("User is not valid"))
+	}
+	converter, err := requestConverter(c, a.currencyService)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
 	}
-	cart, err := a.cartService.FindByUserId(_id)
+	cart, err := a.cartService.FindByUserId(c.Request().Context(), _id)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	return a.cartResponse(c, cart, converter, "User Cart")
+}
+
+func (a cartApi) History(c echo.Context) error {
+	if !utils.IsSuperAdmin(c) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Forbidden("Only super admin can see the history"))
+	}
+	_id, err := primitive.ObjectIDFromHex(c.Param("userId"))
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Validation("User is not valid"))
+	}
+	queryParams, err := bindAuditQuery(c)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	entries, metaData, err := a.cartService.History(c.Request().Context(), _id, queryParams)
+	return auditResponse(c, entries, metaData, err, "Success! Cart history")
+}
+
+// cartResponse answers the cart with the prices of its lines.
+func (a cartApi) cartResponse(c echo.Context, cart model.Cart, converter money.Converter, message string) error {
+	response, err := a.cartService.Price(c.Request().Context(), cart, converter)
 	if err != nil {
-		return common.GenerateErrorResponse(c, nil, err.Error())
+		return common.GenerateDomainErrorResponse(c, nil, err)
 	}
-	return common.GenerateSuccessResponse(c, cart, "User Cart")
+	return common.GenerateSuccessResponse(c, response, message)
 }
 
-func NewCartApi(service service.CartService) api.CartApi {
+func NewCartApi(service service.CartService, currencyService service.CurrencyService) api.CartApi {
 	return &cartApi{
-		cartService: service,
+		cartService:     service,
+		currencyService: currencyService,
 	}
 }
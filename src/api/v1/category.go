@@ -1,8 +1,12 @@
 package v1
 
 import (
+	"strconv"
+
 	"github.com/labstack/echo/v4"
 	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/spreadsheet"
 	"github.com/sajalmia381/store-api/src/utils"
 	"github.com/sajalmia381/store-api/src/v1/api"
 	"github.com/sajalmia381/store-api/src/v1/dtos"
@@ -12,6 +16,7 @@ import (
 
 type categoryApi struct {
 	categoryService service.CategoryService
+	exportService   service.ExportService
 }
 
 func (cat categoryApi) Store(c echo.Context) error {
@@ -20,7 +25,7 @@ func (cat categoryApi) Store(c echo.Context) error {
 		return common.GenerateErrorResponse(c, err, "Failed to bind data")
 	}
 	if err := formData.Validate(); err != nil {
-		return common.GenerateErrorResponse(c, nil, err.Error())
+		return common.GenerateDomainErrorResponse(c, nil, err)
 	}
 	isSuperAdmin := utils.IsSuperAdmin(c)
 	var (
@@ -28,74 +33,173 @@ func (cat categoryApi) Store(c echo.Context) error {
 		err      error
 	)
 	if !isSuperAdmin {
-		category = cat.categoryService.FakeStore(formData)
+		category = cat.categoryService.FakeStore(c.Request().Context(), formData)
 	} else {
-		category, err = cat.categoryService.Store(formData)
+		category, err = cat.categoryService.Store(c.Request().Context(), formData)
 	}
 	if err != nil {
-		return common.GenerateErrorResponse(c, nil, err.Error())
+		return common.GenerateDomainErrorResponse(c, nil, err)
 	}
+	common.SetETag(c, category.Version)
 	return common.GenerateSuccessResponse(c, category, "Success! Category created")
 }
 
 func (cat categoryApi) FindAll(c echo.Context) error {
-	categories, err := cat.categoryService.FindAll()
+	var queryParams dtos.CategoryQueryParams
+	if err := bindListQuery(c, &queryParams); err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	queryParams.PageByDefault()
+	categories, metaData, err := cat.categoryService.FindAll(c.Request().Context(), queryParams)
 	if err != nil {
-		return common.GenerateErrorResponse(c, nil, err.Error())
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	return listResponse(c, categories, metaData, "Success! Category list")
+}
+
+// Export answers the categories of the list filters, unpaged, as a csv or
+// xlsx file.
+func (cat categoryApi) Export(c echo.Context) error {
+	var queryParams dtos.CategoryQueryParams
+	if err := bindListQuery(c, &queryParams); err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
 	}
-	return common.GenerateSuccessResponse(c, categories, "Success! Category list")
+	return exportResponse(c, "categories", func(writer spreadsheet.Writer) error {
+		return cat.exportService.Categories(c.Request().Context(), queryParams, writer)
+	})
 }
 
 func (cat categoryApi) FindBySlug(c echo.Context) error {
 	slug := c.Param("slug")
-	category, err := cat.categoryService.FindBySlug(slug)
+	category, err := cat.categoryService.FindBySlug(c.Request().Context(), slug)
 	if err != nil {
-		return common.GenerateErrorResponse(c, nil, err.Error())
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	if common.IsNotModified(c, category.Version) {
+		return common.NotModified(c)
 	}
 	return common.GenerateSuccessResponse(c, category, "Success! Category description")
 }
 
+// FindSchema answers the attribute schema of the products of the category,
+// with the attributes it inherits from its parents.
+func (cat categoryApi) FindSchema(c echo.Context) error {
+	schema, err := cat.categoryService.FindSchema(c.Request().Context(), c.Param("slug"))
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	return common.GenerateSuccessResponse(c, schema, "Success! Category attributes")
+}
+
 func (cat categoryApi) UpdateBySlug(c echo.Context) error {
 	slug := c.Param("slug")
 	var formData dtos.CategoryUpdateDto
 	if err := c.Bind(&formData); err != nil {
 		return common.GenerateErrorResponse(c, formData, "Failed to bind data")
 	}
+	if err := formData.Validate(); err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	version, err := common.IfMatchVersion(c)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
 	isSuperAdmin := utils.IsSuperAdmin(c)
-	var (
-		category model.Category
-		err      error
-	)
+	var category model.Category
 	if !isSuperAdmin {
-		category, err = cat.categoryService.FakeUpdateBySlug(slug, formData)
+		category, err = cat.categoryService.FakeUpdateBySlug(c.Request().Context(), slug, formData, version)
 	} else {
-		category, err = cat.categoryService.UpdateBySlug(slug, formData)
+		category, err = cat.categoryService.UpdateBySlug(c.Request().Context(), slug, formData, version)
 	}
 	if err != nil {
-		return common.GenerateErrorResponse(c, nil, err.Error())
+		return common.GenerateDomainErrorResponse(c, nil, err)
 	}
+	common.SetETag(c, category.Version)
 	return common.GenerateSuccessResponse(c, category, "Success! category updated")
 }
 
 func (cat categoryApi) DeleteBySlug(c echo.Context) error {
 	slug := c.Param("slug")
+	version, err := common.IfMatchVersion(c)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
 	isSuperAdmin := utils.IsSuperAdmin(c)
-	var (
-		err error
-	)
 	if !isSuperAdmin {
-		_, err = cat.categoryService.FindBySlug(slug)
+		_, err = cat.categoryService.FakeDeleteBySlug(c.Request().Context(), slug, version)
 	} else {
-		_, err = cat.categoryService.DeleteBySlug(slug)
+		_, err = cat.categoryService.DeleteBySlug(c.Request().Context(), slug, version)
 	}
 	if err != nil {
-		return common.GenerateErrorResponse(c, nil, err.Error())
+		return common.GenerateDomainErrorResponse(c, nil, err)
 	}
 	return common.GenerateSuccessResponse(c, nil, "Success! Category deleted")
 }
 
-func NewCategoryApi(categoryService service.CategoryService) api.CategoryApi {
+func (cat categoryApi) ReconcileProducts(c echo.Context) error {
+	if !utils.IsSuperAdmin(c) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Forbidden("Only super admin can reconcile categories"))
+	}
+	dryRun, _ := strconv.ParseBool(c.QueryParam("dryRun"))
+	drifts, err := cat.categoryService.ReconcileProducts(c.Request().Context(), dryRun)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	if dryRun {
+		return common.GenerateSuccessResponse(c, drifts, "Success! Category drift report")
+	}
+	return common.GenerateSuccessResponse(c, drifts, "Success! Categories reconciled")
+}
+
+func (cat categoryApi) FindTrash(c echo.Context) error {
+	if !utils.IsSuperAdmin(c) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Forbidden("Only super admin can see the trash"))
+	}
+	categories, err := cat.categoryService.FindTrash(c.Request().Context())
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	return common.GenerateSuccessResponse(c, categories, "Success! Category trash")
+}
+
+func (cat categoryApi) RestoreBySlug(c echo.Context) error {
+	if !utils.IsSuperAdmin(c) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Forbidden("Only super admin can restore categories"))
+	}
+	category, err := cat.categoryService.RestoreBySlug(c.Request().Context(), c.Param("slug"))
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	common.SetETag(c, category.Version)
+	return common.GenerateSuccessResponse(c, category, "Success! Category restored")
+}
+
+func (cat categoryApi) PurgeBySlug(c echo.Context) error {
+	if !utils.IsSuperAdmin(c) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Forbidden("Only super admin can purge categories"))
+	}
+	_, err := cat.categoryService.PurgeBySlug(c.Request().Context(), c.Param("slug"))
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	return common.GenerateSuccessResponse(c, nil, "Success! Category purged")
+}
+
+func (cat categoryApi) History(c echo.Context) error {
+	if !utils.IsSuperAdmin(c) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Forbidden("Only super admin can see the history"))
+	}
+	queryParams, err := bindAuditQuery(c)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	entries, metaData, err := cat.categoryService.History(c.Request().Context(), c.Param("slug"), queryParams)
+	return auditResponse(c, entries, metaData, err, "Success! Category history")
+}
+
+func NewCategoryApi(categoryService service.CategoryService, exportService service.ExportService) api.CategoryApi {
 	return &categoryApi{
 		categoryService: categoryService,
+		exportService:   exportService,
 	}
 }
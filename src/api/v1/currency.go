@@ -0,0 +1,72 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/money"
+	"github.com/sajalmia381/store-api/src/utils"
+	"github.com/sajalmia381/store-api/src/v1/api"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/service"
+)
+
+const headerAcceptCurrency = "Accept-Currency"
+
+type currencyApi struct {
+	currencyService service.CurrencyService
+}
+
+func (a currencyApi) FindAll(c echo.Context) error {
+	currencies, err := a.currencyService.FindAll(c.Request().Context())
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	return common.GenerateSuccessResponse(c, currencies, "Success! Currency list")
+}
+
+func (a currencyApi) StoreRate(c echo.Context) error {
+	if !utils.IsSuperAdmin(c) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Forbidden("Only super admin can set exchange rates"))
+	}
+	var formData dtos.ExchangeRateDto
+	if err := c.Bind(&formData); err != nil {
+		return common.GenerateErrorResponse(c, nil, "Failed to bind data")
+	}
+	if err := formData.Validate(); err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	rate, err := a.currencyService.StoreRate(c.Request().Context(), c.Param("code"), formData)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	return common.GenerateSuccessResponse(c, rate, "Success! Exchange rate set")
+}
+
+func (a currencyApi) DeleteRate(c echo.Context) error {
+	if !utils.IsSuperAdmin(c) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Forbidden("Only super admin can delete exchange rates"))
+	}
+	if err := a.currencyService.DeleteRate(c.Request().Context(), c.Param("code")); err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	return common.GenerateSuccessResponse(c, nil, "Success! Exchange rate deleted")
+}
+
+// requestConverter converts the prices of a read to the currency of the
+// currency parameter, else of the Accept-Currency header, the base currency
+// without either.
+func requestConverter(c echo.Context, currencyService service.CurrencyService) (money.Converter, error) {
+	c.Response().Header().Add(echo.HeaderVary, headerAcceptCurrency)
+	code := c.QueryParam("currency")
+	if code == "" {
+		code = c.Request().Header.Get(headerAcceptCurrency)
+	}
+	return currencyService.Converter(c.Request().Context(), code)
+}
+
+func NewCurrencyApi(currencyService service.CurrencyService) api.CurrencyApi {
+	return &currencyApi{
+		currencyService: currencyService,
+	}
+}
@@ -0,0 +1,46 @@
+package v1
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/spreadsheet"
+)
+
+// exportResponse answers an export as a csv or xlsx attachment, by the format
+// parameter, named after the list. The rows are sent while export writes
+// them. An export failing before the first few kilobytes are sent answers
+// with an error response, a later failure aborts the response so the client
+// does not take the file for complete.
+func exportResponse(c echo.Context, name string, export func(writer spreadsheet.Writer) error) error {
+	format := c.QueryParam("format")
+	if format == "" {
+		format = "csv"
+	}
+	response := c.Response()
+	writer, err := spreadsheet.New(format, response, name)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Validation(err.Error()))
+	}
+	fileName := name + "-" + time.Now().UTC().Format("20060102-150405") + "." + format
+	response.Header().Set(echo.HeaderContentType, spreadsheet.ContentType(format))
+	response.Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+fileName+`"`)
+	err = export(writer)
+	if err == nil {
+		err = writer.Close()
+	}
+	if err == nil {
+		return nil
+	}
+	if !response.Committed {
+		response.Header().Del(echo.HeaderContentType)
+		response.Header().Del(echo.HeaderContentDisposition)
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	log.Println("[ERROR] Export "+name+" err: ", err)
+	panic(http.ErrAbortHandler)
+}
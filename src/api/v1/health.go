@@ -0,0 +1,30 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/v1/api"
+	"github.com/sajalmia381/store-api/src/v1/service"
+)
+
+type healthApi struct {
+	healthService service.HealthService
+}
+
+// Check answers 200 while the database is reachable and 503 otherwise, so it
+// can back a readiness probe. The body is not wrapped, probes read it as is.
+func (h healthApi) Check(c echo.Context) error {
+	health := h.healthService.Check(c.Request().Context())
+	if health.Status != enums.HEALTH_UP {
+		return c.JSON(http.StatusServiceUnavailable, health)
+	}
+	return c.JSON(http.StatusOK, health)
+}
+
+func NewHealthApi(healthService service.HealthService) api.HealthApi {
+	return &healthApi{
+		healthService: healthService,
+	}
+}
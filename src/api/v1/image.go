@@ -0,0 +1,32 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/v1/api"
+	"github.com/sajalmia381/store-api/src/v1/service"
+)
+
+type imageApi struct {
+	imageService service.ImageService
+}
+
+// Serve sends an image file. A file never changes, a new upload gets a new
+// url, so it can be cached for good.
+func (i imageApi) Serve(c echo.Context) error {
+	file, contentType, err := i.imageService.Open(c.Request().Context(), c.Param("id")+"/"+c.Param("name"))
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	defer file.Close()
+	c.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=31536000, immutable")
+	return c.Stream(http.StatusOK, contentType, file)
+}
+
+func NewImageApi(imageService service.ImageService) api.ImageApi {
+	return &imageApi{
+		imageService: imageService,
+	}
+}
@@ -0,0 +1,58 @@
+package v1
+
+import (
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/domain_error"
+)
+
+// listQuery is the query parameters of a list endpoint, they embed
+// dtos.ListQueryParams.
+type listQuery interface {
+	Validate() error
+}
+
+func bindListQuery(c echo.Context, queryParams listQuery) error {
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, queryParams); err != nil {
+		return domain_error.Validation("query parameters are not valid, limit and page must be numbers and times RFC 3339")
+	}
+	return queryParams.Validate()
+}
+
+// queryInt reads an optional number parameter, nil when it is missing.
+func queryInt(c echo.Context, name string) (*int, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return nil, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, domain_error.Validation(name + " must be a number")
+	}
+	return &i, nil
+}
+
+// queryBool reads an optional true or false parameter, nil when it is missing.
+func queryBool(c echo.Context, name string) (*bool, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, domain_error.Validation(name + " must be true or false")
+	}
+	return &b, nil
+}
+
+// listResponse answers a list, paged ones in the pagination envelope.
+func listResponse(c echo.Context, data interface{}, metaData common.MetaData, message string) error {
+	if metaData.PerPage == 0 {
+		return common.GenerateSuccessResponse(c, data, message)
+	}
+	return common.GenerateSuccessResponse(c, data, message, &common.ResponseOption{
+		MetaData: &metaData,
+	})
+}
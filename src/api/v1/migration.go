@@ -0,0 +1,42 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/utils"
+	"github.com/sajalmia381/store-api/src/v1/api"
+	"github.com/sajalmia381/store-api/src/v1/service"
+)
+
+type migrationApi struct {
+	migrationService service.MigrationService
+}
+
+func (m migrationApi) FindAll(c echo.Context) error {
+	if !utils.IsSuperAdmin(c) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Forbidden("Only super admin can see migrations"))
+	}
+	migrations, err := m.migrationService.FindAll(c.Request().Context())
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	return common.GenerateSuccessResponse(c, migrations, "Success! Migration list")
+}
+
+func (m migrationApi) Migrate(c echo.Context) error {
+	if !utils.IsSuperAdmin(c) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Forbidden("Only super admin can run migrations"))
+	}
+	migrations, err := m.migrationService.Migrate(c.Request().Context())
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	return common.GenerateSuccessResponse(c, migrations, "Success! Migrations applied")
+}
+
+func NewMigrationApi(migrationService service.MigrationService) api.MigrationApi {
+	return &migrationApi{
+		migrationService: migrationService,
+	}
+}
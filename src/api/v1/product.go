@@ -1,10 +1,19 @@
 package v1
 
 import (
-	"log"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/config"
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/money"
+	"github.com/sajalmia381/store-api/src/spreadsheet"
 	"github.com/sajalmia381/store-api/src/utils"
 	"github.com/sajalmia381/store-api/src/v1/api"
 	"github.com/sajalmia381/store-api/src/v1/dtos"
@@ -13,16 +22,23 @@ import (
 )
 
 type productApi struct {
-	productService service.ProductService
+	productService  service.ProductService
+	importService   service.ProductImportService
+	exportService   service.ExportService
+	currencyService service.CurrencyService
 }
 
+// asyncImportRows is the size from which an import runs in the background
+// unless async is given.
+const asyncImportRows = 200
+
 func (p productApi) Store(c echo.Context) error {
 	var formData dtos.ProductStoreDto
 	if err := c.Bind(&formData); err != nil {
 		return common.GenerateErrorResponse(c, nil, "Failed to bind data")
 	}
 	if err := formData.Validate(); err != nil {
-		return common.GenerateErrorResponse(c, nil, err.Error())
+		return common.GenerateDomainErrorResponse(c, nil, err)
 	}
 	isSuperAdmin := utils.IsSuperAdmin(c)
 	var (
@@ -30,25 +46,109 @@ func (p productApi) Store(c echo.Context) error {
 		err     error
 	)
 	if !isSuperAdmin {
-		product, err = p.productService.FakeStore(formData)
+		product, err = p.productService.FakeStore(c.Request().Context(), formData)
 	} else {
-		product, err = p.productService.Store(formData)
+		product, err = p.productService.Store(c.Request().Context(), formData)
 	}
 	if err != nil {
-		return common.GenerateErrorResponse(c, nil, err.Error())
+		return common.GenerateDomainErrorResponse(c, nil, err)
 	}
+	common.SetETag(c, product.Version)
 	return common.GenerateSuccessResponse(c, product, "Success! Product description")
 }
 
+// Import reads the rows from the "file" of a multipart form or from the raw
+// request body, as csv or json by the format parameter, the file extension
+// or the content type. The import of a requester that is not super admin is
+// a dry run, like the fake writes.
+func (p productApi) Import(c echo.Context) error {
+	var reader io.Reader = c.Request().Body
+	name, contentType := "", c.Request().Header.Get(echo.HeaderContentType)
+	if strings.HasPrefix(contentType, echo.MIMEMultipartForm) {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return common.GenerateErrorResponse(c, nil, "Failed to read import file")
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return common.GenerateErrorResponse(c, nil, "Failed to read import file")
+		}
+		defer file.Close()
+		reader, name, contentType = file, fileHeader.Filename, fileHeader.Header.Get(echo.HeaderContentType)
+	}
+	format := c.QueryParam("format")
+	if format == "" {
+		format = importFormat(name, contentType)
+	}
+	rows, err := p.importService.Parse(reader, format)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	dryRun, _ := strconv.ParseBool(c.QueryParam("dryRun"))
+	if !utils.IsSuperAdmin(c) {
+		dryRun = true
+	}
+	async, err := strconv.ParseBool(c.QueryParam("async"))
+	if err != nil {
+		// only signed in requesters can follow a job
+		_, signedIn := utils.GetRequester(c.Request().Context())
+		async = signedIn && len(rows) >= asyncImportRows
+	}
+	if async {
+		job, err := p.importService.Start(c.Request().Context(), rows, dryRun)
+		if err != nil {
+			return common.GenerateDomainErrorResponse(c, nil, err)
+		}
+		return common.GenerateSuccessResponse(c, job, "Success! Product import started", &common.ResponseOption{HttpCode: http.StatusAccepted})
+	}
+	report, err := p.importService.Import(c.Request().Context(), rows, dryRun)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, report, err)
+	}
+	if dryRun {
+		return common.GenerateSuccessResponse(c, report, "Success! Product import checked")
+	}
+	return common.GenerateSuccessResponse(c, report, "Success! Products imported")
+}
+
+// importFormat tells csv from json by the file extension, then by the content
+// type.
+func importFormat(name string, contentType string) string {
+	switch {
+	case strings.HasSuffix(strings.ToLower(name), ".csv"):
+		return "csv"
+	case strings.HasSuffix(strings.ToLower(name), ".json"):
+		return "json"
+	case strings.HasPrefix(contentType, "text/csv"):
+		return "csv"
+	case strings.HasPrefix(contentType, echo.MIMEApplicationJSON):
+		return "json"
+	}
+	return ""
+}
+
+func (p productApi) FindImportJob(c echo.Context) error {
+	job, err := p.importService.FindJob(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	return common.GenerateSuccessResponse(c, job, "Success! Product import job")
+}
+
 func (p productApi) FindAll(c echo.Context) error {
 	var queryParams dtos.ProductQueryParams
-	if err := (&echo.DefaultBinder{}).BindQueryParams(c, &queryParams); err != nil {
-		log.Println("err", err)
+	if err := bindProductQuery(c, &queryParams); err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	converter, err := requestConverter(c, p.currencyService)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
 	}
-	products, metaData, err := p.productService.FindAll(queryParams)
+	products, metaData, err := p.productService.FindAll(c.Request().Context(), queryParams)
 	if err != nil {
-		return common.GenerateErrorResponse(c, nil, err.Error())
+		return common.GenerateDomainErrorResponse(c, nil, err)
 	}
+	service.PriceProducts(converter, products)
 	if metaData.PerPage != 0 {
 		return common.GenerateSuccessResponse(c, products, "Success! Product list", &common.ResponseOption{
 			MetaData: &metaData,
@@ -60,56 +160,435 @@ func (p productApi) FindAll(c echo.Context) error {
 	return common.GenerateSuccessResponse(c, products, "Success! No more products found")
 }
 
+// Export answers the products of the list filters, unpaged, as a csv or xlsx
+// file.
+func (p productApi) Export(c echo.Context) error {
+	var queryParams dtos.ProductQueryParams
+	if err := bindProductQuery(c, &queryParams); err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	return exportResponse(c, "products", func(writer spreadsheet.Writer) error {
+		return p.exportService.Products(c.Request().Context(), queryParams, writer)
+	})
+}
+
+// bindProductQuery reads the parameters of the product list.
+func bindProductQuery(c echo.Context, queryParams *dtos.ProductQueryParams) error {
+	var err error
+	if queryParams.MinPrice, err = queryInt(c, "minPrice"); err != nil {
+		return err
+	}
+	if queryParams.MaxPrice, err = queryInt(c, "maxPrice"); err != nil {
+		return err
+	}
+	if queryParams.Active, err = queryBool(c, "active"); err != nil {
+		return err
+	}
+	if queryParams.Attributes, err = queryAttributeFilters(c); err != nil {
+		return err
+	}
+	return bindListQuery(c, queryParams)
+}
+
+// queryAttributeFilters reads the attr.<name> parameters, by name. A parameter
+// is a value or a min..max range of numbers with either end left open, like
+// attr.ram=16 or attr.ram=8..32. The values of a name match any of them.
+func queryAttributeFilters(c echo.Context) ([]dtos.ProductAttributeFilter, error) {
+	filters := []dtos.ProductAttributeFilter{}
+	for key, values := range c.QueryParams() {
+		if !strings.HasPrefix(key, "attr.") {
+			continue
+		}
+		filter := dtos.ProductAttributeFilter{Name: strings.TrimPrefix(key, "attr.")}
+		for _, value := range values {
+			bounds := strings.SplitN(value, "..", 2)
+			if len(bounds) == 1 {
+				filter.Values = append(filter.Values, value)
+				continue
+			}
+			if filter.Min != nil || filter.Max != nil {
+				return filters, domain_error.Validation(key + " takes one range")
+			}
+			for i, bound := range bounds {
+				if bound == "" {
+					continue
+				}
+				number, err := strconv.ParseFloat(bound, 64)
+				if err != nil {
+					return filters, domain_error.Validation(key + " range must be numbers")
+				}
+				if i == 0 {
+					filter.Min = &number
+				} else {
+					filter.Max = &number
+				}
+			}
+		}
+		filters = append(filters, filter)
+	}
+	sort.Slice(filters, func(i, j int) bool {
+		return filters[i].Name < filters[j].Name
+	})
+	return filters, nil
+}
+
 func (p productApi) FindBySlug(c echo.Context) error {
 	slug := c.Param("slug")
-	product, err := p.productService.FindBySlug(slug)
+	converter, err := requestConverter(c, p.currencyService)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	product, err := p.productService.FindBySlug(c.Request().Context(), slug)
 	if err != nil {
-		return common.GenerateErrorResponse(c, nil, err.Error())
+		return common.GenerateDomainErrorResponse(c, nil, err)
 	}
+	if isNotModified(c, product.Version, converter) {
+		return common.NotModified(c)
+	}
+	service.PriceProduct(converter, &product)
 	return common.GenerateSuccessResponse(c, product, "Success! Product description")
 }
 
+// isNotModified is common.IsNotModified for prices in the base currency. The
+// version does not change with the exchange rates, converted prices are always
+// answered.
+func isNotModified(c echo.Context, version int64, converter money.Converter) bool {
+	if !converter.IsIdentity() {
+		common.SetETag(c, version)
+		return false
+	}
+	return common.IsNotModified(c, version)
+}
+
 func (p productApi) UpdateBySlug(c echo.Context) error {
 	slug := c.Param("slug")
 	var formData dtos.ProductUpdateDto
 	if err := c.Bind(&formData); err != nil {
 		return common.GenerateErrorResponse(c, nil, "Failed to bind data")
 	}
+	version, err := common.IfMatchVersion(c)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
 	isSuperAdmin := utils.IsSuperAdmin(c)
-	var (
-		product model.Product
-		err     error
-	)
+	var product model.Product
 	if !isSuperAdmin {
-		product, err = p.productService.FakeUpdateBySlug(slug, formData)
+		product, err = p.productService.FakeUpdateBySlug(c.Request().Context(), slug, formData, version)
 	} else {
-		product, err = p.productService.UpdateBySlug(slug, formData)
+		product, err = p.productService.UpdateBySlug(c.Request().Context(), slug, formData, version)
 	}
 	if err != nil {
-		return common.GenerateErrorResponse(c, nil, err.Error())
+		return common.GenerateDomainErrorResponse(c, nil, err)
 	}
+	common.SetETag(c, product.Version)
 	return common.GenerateSuccessResponse(c, product, "Success! Product description")
 }
 
 func (p productApi) DeleteBySlug(c echo.Context) error {
 	slug := c.Param("slug")
+	version, err := common.IfMatchVersion(c)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
 	isSuperAdmin := utils.IsSuperAdmin(c)
-	var (
-		err error
-	)
 	if !isSuperAdmin {
-		_, err = p.productService.FindBySlug(slug)
+		_, err = p.productService.FakeDeleteBySlug(c.Request().Context(), slug, version)
 	} else {
-		_, err = p.productService.DeleteBySlug(slug)
+		_, err = p.productService.DeleteBySlug(c.Request().Context(), slug, version)
 	}
 	if err != nil {
-		return common.GenerateErrorResponse(c, nil, err.Error())
+		return common.GenerateDomainErrorResponse(c, nil, err)
 	}
 	return common.GenerateSuccessResponse(c, nil, "Success! Product deleted")
 }
 
-func NewProductApi(productService service.ProductService, categoryService service.CategoryService) api.ProductApi {
+func (p productApi) FindTrash(c echo.Context) error {
+	if !utils.IsSuperAdmin(c) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Forbidden("Only super admin can see the trash"))
+	}
+	products, err := p.productService.FindTrash(c.Request().Context())
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	return common.GenerateSuccessResponse(c, products, "Success! Product trash")
+}
+
+func (p productApi) RestoreBySlug(c echo.Context) error {
+	if !utils.IsSuperAdmin(c) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Forbidden("Only super admin can restore products"))
+	}
+	product, err := p.productService.RestoreBySlug(c.Request().Context(), c.Param("slug"))
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	common.SetETag(c, product.Version)
+	return common.GenerateSuccessResponse(c, product, "Success! Product restored")
+}
+
+func (p productApi) PurgeBySlug(c echo.Context) error {
+	if !utils.IsSuperAdmin(c) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Forbidden("Only super admin can purge products"))
+	}
+	_, err := p.productService.PurgeBySlug(c.Request().Context(), c.Param("slug"))
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	return common.GenerateSuccessResponse(c, nil, "Success! Product purged")
+}
+
+func (p productApi) History(c echo.Context) error {
+	if !utils.IsSuperAdmin(c) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Forbidden("Only super admin can see the history"))
+	}
+	queryParams, err := bindAuditQuery(c)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	entries, metaData, err := p.productService.History(c.Request().Context(), c.Param("slug"), queryParams)
+	return auditResponse(c, entries, metaData, err, "Success! Product history")
+}
+
+func (p productApi) FindVariants(c echo.Context) error {
+	converter, err := requestConverter(c, p.currencyService)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	product, err := p.productService.FindBySlug(c.Request().Context(), c.Param("slug"))
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	if isNotModified(c, product.Version, converter) {
+		return common.NotModified(c)
+	}
+	service.PriceProduct(converter, &product)
+	return common.GenerateSuccessResponse(c, product.Variants, "Success! Product variants")
+}
+
+func (p productApi) StoreVariant(c echo.Context) error {
+	var formData dtos.ProductVariantDto
+	if err := c.Bind(&formData); err != nil {
+		return common.GenerateErrorResponse(c, nil, "Failed to bind data")
+	}
+	if err := formData.Validate(); err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	version, err := common.IfMatchVersion(c)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	var (
+		product model.Product
+		variant model.ProductVariant
+	)
+	if !utils.IsSuperAdmin(c) {
+		product, variant, err = p.productService.FakeStoreVariant(c.Request().Context(), c.Param("slug"), formData, version)
+	} else {
+		product, variant, err = p.productService.StoreVariant(c.Request().Context(), c.Param("slug"), formData, version)
+	}
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	common.SetETag(c, product.Version)
+	return common.GenerateSuccessResponse(c, variant, "Success! Product variant created")
+}
+
+func (p productApi) UpdateVariant(c echo.Context) error {
+	var formData dtos.ProductVariantDto
+	if err := c.Bind(&formData); err != nil {
+		return common.GenerateErrorResponse(c, nil, "Failed to bind data")
+	}
+	if err := formData.Validate(); err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	version, err := common.IfMatchVersion(c)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	var (
+		product model.Product
+		variant model.ProductVariant
+	)
+	if !utils.IsSuperAdmin(c) {
+		product, variant, err = p.productService.FakeUpdateVariant(c.Request().Context(), c.Param("slug"), c.Param("id"), formData, version)
+	} else {
+		product, variant, err = p.productService.UpdateVariant(c.Request().Context(), c.Param("slug"), c.Param("id"), formData, version)
+	}
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	common.SetETag(c, product.Version)
+	return common.GenerateSuccessResponse(c, variant, "Success! Product variant updated")
+}
+
+func (p productApi) DeleteVariant(c echo.Context) error {
+	version, err := common.IfMatchVersion(c)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	var product model.Product
+	if !utils.IsSuperAdmin(c) {
+		product, _, err = p.productService.FakeDeleteVariant(c.Request().Context(), c.Param("slug"), c.Param("id"), version)
+	} else {
+		product, _, err = p.productService.DeleteVariant(c.Request().Context(), c.Param("slug"), c.Param("id"), version)
+	}
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	common.SetETag(c, product.Version)
+	return common.GenerateSuccessResponse(c, nil, "Success! Product variant deleted")
+}
+
+func (p productApi) AdjustStock(c echo.Context) error {
+	var formData dtos.StockAdjustmentDto
+	if err := c.Bind(&formData); err != nil {
+		return common.GenerateErrorResponse(c, nil, "Failed to bind data")
+	}
+	if err := formData.Validate(); err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	version, err := common.IfMatchVersion(c)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	var (
+		product    model.Product
+		adjustment model.StockAdjustment
+	)
+	if !utils.IsSuperAdmin(c) {
+		product, adjustment, err = p.productService.FakeAdjustStock(c.Request().Context(), c.Param("slug"), formData, version)
+	} else {
+		product, adjustment, err = p.productService.AdjustStock(c.Request().Context(), c.Param("slug"), formData, version)
+	}
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	common.SetETag(c, product.Version)
+	return common.GenerateSuccessResponse(c, adjustment, "Success! Product stock adjusted")
+}
+
+func (p productApi) StockHistory(c echo.Context) error {
+	if !utils.IsSuperAdmin(c) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Forbidden("Only super admin can see the stock history"))
+	}
+	var queryParams dtos.StockQueryParams
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, &queryParams); err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Validation("query parameters are not valid"))
+	}
+	adjustments, metaData, err := p.productService.StockHistory(c.Request().Context(), c.Param("slug"), queryParams)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	return common.GenerateSuccessResponse(c, adjustments, "Success! Product stock history", &common.ResponseOption{
+		MetaData: &metaData,
+	})
+}
+
+func (p productApi) FindImages(c echo.Context) error {
+	product, err := p.productService.FindBySlug(c.Request().Context(), c.Param("slug"))
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	if common.IsNotModified(c, product.Version) {
+		return common.NotModified(c)
+	}
+	return common.GenerateSuccessResponse(c, product.Images, "Success! Product images")
+}
+
+// StoreImages reads the "images" files of a multipart form.
+func (p productApi) StoreImages(c echo.Context) error {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return common.GenerateErrorResponse(c, nil, "Failed to read images")
+	}
+	uploads := []dtos.ProductImageUpload{}
+	for _, fileHeader := range form.File["images"] {
+		upload, err := readImageUpload(fileHeader)
+		if err != nil {
+			return common.GenerateErrorResponse(c, nil, "Failed to read image "+fileHeader.Filename)
+		}
+		uploads = append(uploads, upload)
+	}
+	version, err := common.IfMatchVersion(c)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	var (
+		product model.Product
+		images  []model.ProductImage
+	)
+	if !utils.IsSuperAdmin(c) {
+		product, images, err = p.productService.FakeStoreImages(c.Request().Context(), c.Param("slug"), uploads, version)
+	} else {
+		product, images, err = p.productService.StoreImages(c.Request().Context(), c.Param("slug"), uploads, version)
+	}
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	common.SetETag(c, product.Version)
+	return common.GenerateSuccessResponse(c, images, "Success! Product images uploaded")
+}
+
+// readImageUpload reads at most one byte more than an image may have, the
+// service refuses the larger ones.
+func readImageUpload(fileHeader *multipart.FileHeader) (dtos.ProductImageUpload, error) {
+	upload := dtos.ProductImageUpload{Name: fileHeader.Filename}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return upload, err
+	}
+	defer file.Close()
+	upload.Content, err = io.ReadAll(io.LimitReader(file, int64(config.ImageMaxSize)+1))
+	return upload, err
+}
+
+func (p productApi) OrderImages(c echo.Context) error {
+	var formData dtos.ProductImageOrderDto
+	if err := c.Bind(&formData); err != nil {
+		return common.GenerateErrorResponse(c, nil, "Failed to bind data")
+	}
+	if err := formData.Validate(); err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	version, err := common.IfMatchVersion(c)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	var product model.Product
+	if !utils.IsSuperAdmin(c) {
+		product, err = p.productService.FakeOrderImages(c.Request().Context(), c.Param("slug"), formData, version)
+	} else {
+		product, err = p.productService.OrderImages(c.Request().Context(), c.Param("slug"), formData, version)
+	}
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	common.SetETag(c, product.Version)
+	return common.GenerateSuccessResponse(c, product.Images, "Success! Product images ordered")
+}
+
+func (p productApi) DeleteImage(c echo.Context) error {
+	version, err := common.IfMatchVersion(c)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	var product model.Product
+	if !utils.IsSuperAdmin(c) {
+		product, _, err = p.productService.FakeDeleteImage(c.Request().Context(), c.Param("slug"), c.Param("id"), version)
+	} else {
+		product, _, err = p.productService.DeleteImage(c.Request().Context(), c.Param("slug"), c.Param("id"), version)
+	}
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	common.SetETag(c, product.Version)
+	return common.GenerateSuccessResponse(c, nil, "Success! Product image deleted")
+}
+
+func NewProductApi(productService service.ProductService, categoryService service.CategoryService, importService service.ProductImportService, exportService service.ExportService, currencyService service.CurrencyService) api.ProductApi {
 	return &productApi{
-		productService: productService,
+		productService:  productService,
+		importService:   importService,
+		exportService:   exportService,
+		currencyService: currencyService,
 	}
 }
@@ -0,0 +1,175 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/utils"
+	"github.com/sajalmia381/store-api/src/v1/api"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"github.com/sajalmia381/store-api/src/v1/service"
+)
+
+// Reviews are written by their authors for real, whatever their role. The
+// moderation and the deletion of the reviews of others follow the catalog:
+// the super admin writes, an admin gets fake writes.
+type reviewApi struct {
+	reviewService service.ReviewService
+}
+
+func (r reviewApi) Store(c echo.Context) error {
+	var formData dtos.ReviewStoreDto
+	if err := c.Bind(&formData); err != nil {
+		return common.GenerateErrorResponse(c, nil, "Failed to bind data")
+	}
+	if err := formData.Validate(); err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	review, err := r.reviewService.Store(c.Request().Context(), c.Param("slug"), formData)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	common.SetETag(c, review.Version)
+	return common.GenerateSuccessResponse(c, review, "Success! Review created")
+}
+
+// FindByProduct lists the approved reviews of a product, admins may filter by
+// any status.
+func (r reviewApi) FindByProduct(c echo.Context) error {
+	var queryParams dtos.ReviewQueryParams
+	if err := bindListQuery(c, &queryParams); err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	if !utils.IsAdmin(c) {
+		queryParams.Status = enums.REVIEW_APPROVED
+	}
+	reviews, metaData, err := r.reviewService.FindByProduct(c.Request().Context(), c.Param("slug"), queryParams)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	return listResponse(c, reviews, metaData, "Success! Review list")
+}
+
+// FindAll is the moderation queue, the reviews of every product.
+func (r reviewApi) FindAll(c echo.Context) error {
+	if !utils.IsAdmin(c) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Forbidden("Only admin can see the reviews of every product"))
+	}
+	var queryParams dtos.ReviewQueryParams
+	if err := bindListQuery(c, &queryParams); err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	reviews, metaData, err := r.reviewService.FindAll(c.Request().Context(), queryParams)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	return listResponse(c, reviews, metaData, "Success! Review list")
+}
+
+// FindById answers an approved review, the author and admins see it in any
+// status.
+func (r reviewApi) FindById(c echo.Context) error {
+	review, err := r.reviewService.FindById(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	if review.Status != enums.REVIEW_APPROVED && !isAuthor(c, review) && !utils.IsAdmin(c) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.NotFound("review is not found"))
+	}
+	if common.IsNotModified(c, review.Version) {
+		return common.NotModified(c)
+	}
+	return common.GenerateSuccessResponse(c, review, "Success! Review description")
+}
+
+func (r reviewApi) UpdateById(c echo.Context) error {
+	var formData dtos.ReviewUpdateDto
+	if err := c.Bind(&formData); err != nil {
+		return common.GenerateErrorResponse(c, nil, "Failed to bind data")
+	}
+	if err := formData.Validate(); err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	version, err := common.IfMatchVersion(c)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	review, err := r.reviewService.FindById(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	if !isAuthor(c, review) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Forbidden("Only the author can edit the review"))
+	}
+	review, err = r.reviewService.UpdateById(c.Request().Context(), c.Param("id"), formData, version)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	common.SetETag(c, review.Version)
+	return common.GenerateSuccessResponse(c, review, "Success! Review updated")
+}
+
+func (r reviewApi) Moderate(c echo.Context) error {
+	if !utils.IsAdmin(c) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Forbidden("Only admin can moderate the reviews"))
+	}
+	var formData dtos.ReviewModerationDto
+	if err := c.Bind(&formData); err != nil {
+		return common.GenerateErrorResponse(c, nil, "Failed to bind data")
+	}
+	if err := formData.Validate(); err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	version, err := common.IfMatchVersion(c)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	var review model.Review
+	if !utils.IsSuperAdmin(c) {
+		review, err = r.reviewService.FakeModerate(c.Request().Context(), c.Param("id"), formData, version)
+	} else {
+		review, err = r.reviewService.Moderate(c.Request().Context(), c.Param("id"), formData, version)
+	}
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	common.SetETag(c, review.Version)
+	return common.GenerateSuccessResponse(c, review, "Success! Review moderated")
+}
+
+func (r reviewApi) DeleteById(c echo.Context) error {
+	version, err := common.IfMatchVersion(c)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	review, err := r.reviewService.FindById(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	switch {
+	case isAuthor(c, review) || utils.IsSuperAdmin(c):
+		_, err = r.reviewService.DeleteById(c.Request().Context(), c.Param("id"), version)
+	case utils.IsAdmin(c):
+		_, err = r.reviewService.FakeDeleteById(c.Request().Context(), c.Param("id"), version)
+	default:
+		err = domain_error.Forbidden("Only the author or admin can delete the review")
+	}
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	return common.GenerateSuccessResponse(c, nil, "Success! Review deleted")
+}
+
+// isAuthor reports whether the requester wrote the review.
+func isAuthor(c echo.Context, review model.Review) bool {
+	requester, err := utils.GetRequestData(c)
+	return err == nil && requester.ID == review.AuthorId
+}
+
+func NewReviewApi(reviewService service.ReviewService) api.ReviewApi {
+	return &reviewApi{
+		reviewService: reviewService,
+	}
+}
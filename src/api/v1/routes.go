@@ -8,12 +8,26 @@ import (
 )
 
 func Routes(g *echo.Group) {
+	healthRoutes(g.Group("/health"))
 	authRoutes(g.Group("/auth"))
 	userRoutes(g.Group("/users"))
 	categoryRoutes(g.Group("/categories"))
 	productRoutes(g.Group("/products"))
+	reviewRoutes(g.Group("/reviews"))
+	imageRoutes(g.Group("/images"))
 	cartCrudRoutes(g.Group("/carts"))
 	cartRequesterRoutes(g.Group("/cart"))
+	migrationRoutes(g.Group("/migrations"))
+	seedRoutes(g.Group("/seed"))
+	archiveRoutes(g.Group("/archive"))
+	trashRoutes(g.Group("/trash"))
+	auditRoutes(g.Group("/audit"))
+	currencyRoutes(g.Group("/currencies"))
+}
+
+func healthRoutes(g *echo.Group) {
+	newHealthApi := NewHealthApi(dependency.GetHealthService())
+	g.GET("", newHealthApi.Check)
 }
 
 func authRoutes(g *echo.Group) {
@@ -24,36 +38,84 @@ func authRoutes(g *echo.Group) {
 }
 
 func userRoutes(g *echo.Group) {
-	newUserApi := NewUserApi(dependency.GetUserService())
+	newUserApi := NewUserApi(dependency.GetUserService(), dependency.GetExportService())
 	g.Use(middleware.JWTWithConfig(custom_middleware.AttachUserMiddlewareConfig()))
 	g.GET("", newUserApi.FindAll)
 	g.POST("", newUserApi.Store)
+	g.GET("/export", newUserApi.Export)
 	g.GET("/:id", newUserApi.FindById)
 	g.PUT("/:id", newUserApi.UpdateById)
 	g.DELETE("/:id", newUserApi.DeleteById)
+	g.GET("/trash", newUserApi.FindTrash)
+	g.POST("/trash/:id/restore", newUserApi.RestoreById)
+	g.DELETE("/trash/:id", newUserApi.PurgeById)
+	g.GET("/:id/history", newUserApi.History)
 }
 
 func categoryRoutes(g *echo.Group) {
-	newCategoryApi := NewCategoryApi(dependency.GetCategoryService())
+	newCategoryApi := NewCategoryApi(dependency.GetCategoryService(), dependency.GetExportService())
 	g.Use(middleware.JWTWithConfig(custom_middleware.AttachUserMiddlewareConfig()))
 	g.GET("", newCategoryApi.FindAll)
+	g.GET("/export", newCategoryApi.Export)
 	g.GET("/:slug", newCategoryApi.FindBySlug)
 	g.PUT("/:slug", newCategoryApi.UpdateBySlug)
 	g.DELETE("/:slug", newCategoryApi.DeleteBySlug)
+	g.POST("/reconcile", newCategoryApi.ReconcileProducts)
+	g.GET("/trash", newCategoryApi.FindTrash)
+	g.POST("/trash/:slug/restore", newCategoryApi.RestoreBySlug)
+	g.DELETE("/trash/:slug", newCategoryApi.PurgeBySlug)
+	g.GET("/:slug/history", newCategoryApi.History)
+	g.GET("/:slug/attributes", newCategoryApi.FindSchema)
 }
 
 func productRoutes(g *echo.Group) {
-	newProductApi := NewProductApi(dependency.GetProductService(), dependency.GetCategoryService())
+	newProductApi := NewProductApi(dependency.GetProductService(), dependency.GetCategoryService(), dependency.GetProductImportService(), dependency.GetExportService(), dependency.GetCurrencyService())
+	newReviewApi := NewReviewApi(dependency.GetReviewService())
 	g.Use(middleware.JWTWithConfig(custom_middleware.AttachUserMiddlewareConfig()))
 	g.GET("", newProductApi.FindAll)
 	g.POST("", newProductApi.Store)
+	g.POST("/import", newProductApi.Import)
+	g.GET("/import/:id", newProductApi.FindImportJob)
+	g.GET("/export", newProductApi.Export)
 	g.GET("/:slug", newProductApi.FindBySlug)
 	g.PUT("/:slug", newProductApi.UpdateBySlug)
 	g.DELETE("/:slug", newProductApi.DeleteBySlug)
+	g.GET("/trash", newProductApi.FindTrash)
+	g.POST("/trash/:slug/restore", newProductApi.RestoreBySlug)
+	g.DELETE("/trash/:slug", newProductApi.PurgeBySlug)
+	g.GET("/:slug/history", newProductApi.History)
+	g.GET("/:slug/variants", newProductApi.FindVariants)
+	g.POST("/:slug/variants", newProductApi.StoreVariant)
+	g.PUT("/:slug/variants/:id", newProductApi.UpdateVariant)
+	g.DELETE("/:slug/variants/:id", newProductApi.DeleteVariant)
+	g.GET("/:slug/stock", newProductApi.StockHistory)
+	g.POST("/:slug/stock", newProductApi.AdjustStock)
+	g.GET("/:slug/images", newProductApi.FindImages)
+	g.POST("/:slug/images", newProductApi.StoreImages)
+	g.PUT("/:slug/images", newProductApi.OrderImages)
+	g.DELETE("/:slug/images/:id", newProductApi.DeleteImage)
+	g.GET("/:slug/reviews", newReviewApi.FindByProduct)
+	g.POST("/:slug/reviews", newReviewApi.Store, middleware.JWTWithConfig(custom_middleware.AuthMiddlewareConfig()))
+}
+
+func reviewRoutes(g *echo.Group) {
+	newReviewApi := NewReviewApi(dependency.GetReviewService())
+	g.Use(middleware.JWTWithConfig(custom_middleware.AttachUserMiddlewareConfig()))
+	g.GET("", newReviewApi.FindAll)
+	g.GET("/:id", newReviewApi.FindById)
+	g.PUT("/:id", newReviewApi.UpdateById, middleware.JWTWithConfig(custom_middleware.AuthMiddlewareConfig()))
+	g.DELETE("/:id", newReviewApi.DeleteById, middleware.JWTWithConfig(custom_middleware.AuthMiddlewareConfig()))
+	g.PUT("/:id/status", newReviewApi.Moderate)
+}
+
+// imageRoutes are public, so the image urls work in an img tag.
+func imageRoutes(g *echo.Group) {
+	newImageApi := NewImageApi(dependency.GetImageService())
+	g.GET("/:id/:name", newImageApi.Serve)
 }
 
 func cartRequesterRoutes(g *echo.Group) {
-	newCartApi := NewCartApi(dependency.GetCartService())
+	newCartApi := NewCartApi(dependency.GetCartService(), dependency.GetCurrencyService())
 	g.Use(middleware.JWTWithConfig(custom_middleware.AttachUserMiddlewareConfig()))
 	g.GET("", newCartApi.ViewCart)                     // Request User cart
 	g.PUT("", newCartApi.UpdateCartByProducts)         // Request User update Cart with bulk product
@@ -62,7 +124,48 @@ func cartRequesterRoutes(g *echo.Group) {
 }
 
 func cartCrudRoutes(g *echo.Group) {
-	newCartApi := NewCartApi(dependency.GetCartService())
+	newCartApi := NewCartApi(dependency.GetCartService(), dependency.GetCurrencyService())
 	g.GET("", newCartApi.FindAll) // All Carts
 	g.GET("/:userId", newCartApi.FindByUserId)
+	g.GET("/:userId/history", newCartApi.History, middleware.JWTWithConfig(custom_middleware.AuthMiddlewareConfig()))
+}
+
+func migrationRoutes(g *echo.Group) {
+	newMigrationApi := NewMigrationApi(dependency.GetMigrationService())
+	g.Use(middleware.JWTWithConfig(custom_middleware.AuthMiddlewareConfig()))
+	g.GET("", newMigrationApi.FindAll)
+	g.POST("", newMigrationApi.Migrate)
+}
+
+func seedRoutes(g *echo.Group) {
+	newSeedApi := NewSeedApi(dependency.GetSeedService())
+	g.Use(middleware.JWTWithConfig(custom_middleware.AuthMiddlewareConfig()))
+	g.POST("", newSeedApi.Seed)
+}
+
+func archiveRoutes(g *echo.Group) {
+	newArchiveApi := NewArchiveApi(dependency.GetArchiveService())
+	g.Use(middleware.JWTWithConfig(custom_middleware.AuthMiddlewareConfig()))
+	g.GET("", newArchiveApi.Export)
+	g.POST("", newArchiveApi.Import)
+}
+
+func trashRoutes(g *echo.Group) {
+	newTrashApi := NewTrashApi(dependency.GetTrashService())
+	g.Use(middleware.JWTWithConfig(custom_middleware.AuthMiddlewareConfig()))
+	g.POST("/purge", newTrashApi.Purge)
+}
+
+func auditRoutes(g *echo.Group) {
+	newAuditApi := NewAuditApi(dependency.GetAuditService())
+	g.Use(middleware.JWTWithConfig(custom_middleware.AuthMiddlewareConfig()))
+	g.GET("", newAuditApi.FindAll)
+}
+
+func currencyRoutes(g *echo.Group) {
+	newCurrencyApi := NewCurrencyApi(dependency.GetCurrencyService())
+	g.Use(middleware.JWTWithConfig(custom_middleware.AttachUserMiddlewareConfig()))
+	g.GET("", newCurrencyApi.FindAll)
+	g.PUT("/:code", newCurrencyApi.StoreRate)
+	g.DELETE("/:code", newCurrencyApi.DeleteRate)
 }
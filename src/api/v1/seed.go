@@ -0,0 +1,38 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/config"
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/fixture"
+	"github.com/sajalmia381/store-api/src/utils"
+	"github.com/sajalmia381/store-api/src/v1/api"
+	"github.com/sajalmia381/store-api/src/v1/service"
+)
+
+type seedApi struct {
+	seedService service.SeedService
+}
+
+// Seed loads the fixtures of SEED_PATH, the bundled demo catalog when unset.
+func (s seedApi) Seed(c echo.Context) error {
+	if !utils.IsSuperAdmin(c) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Forbidden("Only super admin can seed the database"))
+	}
+	catalog, err := fixture.Load(config.SeedPath)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	report, err := s.seedService.Seed(c.Request().Context(), catalog)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, report, err)
+	}
+	return common.GenerateSuccessResponse(c, report, "Success! Database seeded")
+}
+
+func NewSeedApi(seedService service.SeedService) api.SeedApi {
+	return &seedApi{
+		seedService: seedService,
+	}
+}
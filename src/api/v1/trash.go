@@ -0,0 +1,44 @@
+package v1
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/config"
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/utils"
+	"github.com/sajalmia381/store-api/src/v1/api"
+	"github.com/sajalmia381/store-api/src/v1/service"
+)
+
+type trashApi struct {
+	trashService service.TrashService
+}
+
+// Purge removes the trash older than the olderThan duration, TRASH_RETENTION
+// when it is not given.
+func (t trashApi) Purge(c echo.Context) error {
+	if !utils.IsSuperAdmin(c) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Forbidden("Only super admin can purge the trash"))
+	}
+	olderThan := config.TrashRetention
+	if value := c.QueryParam("olderThan"); value != "" {
+		var err error
+		olderThan, err = time.ParseDuration(value)
+		if err != nil {
+			return common.GenerateDomainErrorResponse(c, nil, domain_error.Validation("olderThan is not a valid duration"))
+		}
+	}
+	report, err := t.trashService.Purge(c.Request().Context(), olderThan)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, report, err)
+	}
+	return common.GenerateSuccessResponse(c, report, "Success! Trash purged")
+}
+
+func NewTrashApi(trashService service.TrashService) api.TrashApi {
+	return &trashApi{
+		trashService: trashService,
+	}
+}
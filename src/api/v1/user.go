@@ -5,7 +5,9 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/domain_error"
 	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/spreadsheet"
 	"github.com/sajalmia381/store-api/src/utils"
 	"github.com/sajalmia381/store-api/src/v1/api"
 	"github.com/sajalmia381/store-api/src/v1/dtos"
@@ -14,13 +16,14 @@ import (
 )
 
 type userApi struct {
-	userService service.UserService
+	userService   service.UserService
+	exportService service.ExportService
 }
 
 func (u userApi) Store(c echo.Context) error {
 	var formData dtos.UserRegisterDTO
 	if err := formData.Validate(); err != nil {
-		return common.GenerateErrorResponse(c, nil, err.Error())
+		return common.GenerateDomainErrorResponse(c, nil, err)
 	}
 	if err := c.Bind(&formData); err != nil {
 		return common.GenerateErrorResponse(c, err.Error(), "Failed to bind data!")
@@ -32,45 +35,71 @@ func (u userApi) Store(c echo.Context) error {
 		err  error
 	)
 	if !isSuperAdmin {
-		user, err = u.userService.FakeStore(formData)
+		user, err = u.userService.FakeStore(c.Request().Context(), formData)
 	} else {
-		user, err = u.userService.Store(formData)
+		user, err = u.userService.Store(c.Request().Context(), formData)
 	}
 
 	// To super admin
 	if err != nil {
-		return common.GenerateErrorResponse(c, nil, err.Error())
+		return common.GenerateDomainErrorResponse(c, nil, err)
 	}
 	return common.GenerateSuccessResponse(c, user, "Success! User created", &common.ResponseOption{
 		HttpCode: http.StatusCreated,
 	})
 }
 
+// FindAll lists the users by dtos.UserQuery, a status of true or false keeps
+// the active or inactive ones. Others than the super admin only see customers.
 func (u userApi) FindAll(c echo.Context) error {
-	// log.Println("req", c.Request())
-	isSuperAdmin := utils.IsSuperAdmin(c)
-	var (
-		objects []model.User
-		err     error
-	)
-	query := dtos.UserQuery{}
-	if isSuperAdmin {
-		objects, err = u.userService.FindAll(query)
-	} else {
-		query.Role = string(enums.ROLE_CUSTOMER)
-		objects, err = u.userService.FindAll(query)
+	var query dtos.UserQuery
+	if err := bindUserQuery(c, &query); err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
 	}
+	query.PageByDefault()
+	objects, metaData, err := u.userService.FindAll(c.Request().Context(), query)
 	if err != nil {
-		return common.GenerateErrorResponse(c, nil, err.Error())
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	return listResponse(c, objects, metaData, "Success! User list")
+}
+
+// Export answers the users of the list filters, unpaged, as a csv or xlsx
+// file. Like the list it only has customers for others than the super admin.
+func (u userApi) Export(c echo.Context) error {
+	var query dtos.UserQuery
+	if err := bindUserQuery(c, &query); err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
 	}
-	return common.GenerateSuccessResponse(c, objects, "Success! User list")
+	return exportResponse(c, "users", func(writer spreadsheet.Writer) error {
+		return u.exportService.Users(c.Request().Context(), query, writer)
+	})
+}
+
+// bindUserQuery reads the parameters of the user list, the role of others
+// than the super admin is ROLE_CUSTOMER.
+func bindUserQuery(c echo.Context, query *dtos.UserQuery) error {
+	if err := bindListQuery(c, query); err != nil {
+		return err
+	}
+	var err error
+	if query.Status, err = queryBool(c, "status"); err != nil {
+		return err
+	}
+	if !utils.IsSuperAdmin(c) {
+		query.Role = string(enums.ROLE_CUSTOMER)
+	}
+	return nil
 }
 
 func (u userApi) FindById(c echo.Context) error {
 	id := c.Param("id")
-	user, err := u.userService.FindById(id)
+	user, err := u.userService.FindById(c.Request().Context(), id)
 	if err != nil {
-		return common.GenerateErrorResponse(c, nil, err.Error())
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	if common.IsNotModified(c, user.Version) {
+		return common.NotModified(c)
 	}
 	return common.GenerateSuccessResponse(c, user, "Success! User description")
 }
@@ -79,45 +108,97 @@ func (u userApi) UpdateById(c echo.Context) error {
 	id := c.Param("id")
 	var formData dtos.UserUpdateDto
 	if err := formData.Validate(); err != nil {
-		return common.GenerateErrorResponse(c, nil, err.Error())
+		return common.GenerateDomainErrorResponse(c, nil, err)
 	}
 	if err := c.Bind(&formData); err != nil {
 		return common.GenerateErrorResponse(c, err.Error(), "Failed to bind data!")
 	}
+	version, err := common.IfMatchVersion(c)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
 	isSuperAdmin := utils.IsSuperAdmin(c)
-	var (
-		user model.User
-		err  error
-	)
+	var user model.User
 	if !isSuperAdmin {
-		user, err = u.userService.FakeUpdateById(id, formData)
+		user, err = u.userService.FakeUpdateById(c.Request().Context(), id, formData, version)
 	} else {
-		user, err = u.userService.UpdateById(id, formData)
+		user, err = u.userService.UpdateById(c.Request().Context(), id, formData, version)
 	}
 	if err != nil {
-		return common.GenerateErrorResponse(c, nil, err.Error())
+		return common.GenerateDomainErrorResponse(c, nil, err)
 	}
+	common.SetETag(c, user.Version)
 
 	return common.GenerateSuccessResponse(c, user, "Success! User updated")
 }
 
 func (u userApi) DeleteById(c echo.Context) error {
 	id := c.Param("id")
+	version, err := common.IfMatchVersion(c)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
 	isSuperAdmin := utils.IsSuperAdmin(c)
-	var err error
 	if !isSuperAdmin {
-		_, err = u.userService.FindById(id)
+		err = u.userService.FakeDeleteById(c.Request().Context(), id, version)
 	} else {
-		err = u.userService.DeleteById(id)
+		err = u.userService.DeleteById(c.Request().Context(), id, version)
 	}
 	if err != nil {
-		return common.GenerateErrorResponse(c, "Failed to delete user", err.Error())
+		return common.GenerateDomainErrorResponse(c, "Failed to delete user", err)
 	}
 	return common.GenerateSuccessResponse(c, nil, "Success! User deleted")
 }
 
-func NewUserApi(userService service.UserService) api.User {
+func (u userApi) FindTrash(c echo.Context) error {
+	if !utils.IsSuperAdmin(c) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Forbidden("Only super admin can see the trash"))
+	}
+	users, err := u.userService.FindTrash(c.Request().Context())
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	return common.GenerateSuccessResponse(c, users, "Success! User trash")
+}
+
+func (u userApi) RestoreById(c echo.Context) error {
+	if !utils.IsSuperAdmin(c) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Forbidden("Only super admin can restore users"))
+	}
+	user, err := u.userService.RestoreById(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	common.SetETag(c, user.Version)
+	return common.GenerateSuccessResponse(c, user, "Success! User restored")
+}
+
+func (u userApi) PurgeById(c echo.Context) error {
+	if !utils.IsSuperAdmin(c) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Forbidden("Only super admin can purge users"))
+	}
+	_, err := u.userService.PurgeById(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	return common.GenerateSuccessResponse(c, nil, "Success! User purged")
+}
+
+func (u userApi) History(c echo.Context) error {
+	if !utils.IsSuperAdmin(c) {
+		return common.GenerateDomainErrorResponse(c, nil, domain_error.Forbidden("Only super admin can see the history"))
+	}
+	queryParams, err := bindAuditQuery(c)
+	if err != nil {
+		return common.GenerateDomainErrorResponse(c, nil, err)
+	}
+	entries, metaData, err := u.userService.History(c.Request().Context(), c.Param("id"), queryParams)
+	return auditResponse(c, entries, metaData, err, "Success! User history")
+}
+
+func NewUserApi(userService service.UserService, exportService service.ExportService) api.User {
 	return &userApi{
-		userService: userService,
+		userService:   userService,
+		exportService: exportService,
 	}
 }
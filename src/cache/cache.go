@@ -0,0 +1,27 @@
+package cache
+
+// Cache keeps encoded values by key. Implementations are safe for concurrent
+// use, so a cache shared by several instances, like redis, can take the place
+// of the in-process LRU.
+type Cache interface {
+	// Get returns the value of key, false once it is missing or expired.
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+	// Clear drops every entry.
+	Clear()
+}
+
+type noopCache struct{}
+
+func (noopCache) Get(key string) ([]byte, bool) {
+	return nil, false
+}
+
+func (noopCache) Set(key string, value []byte) {}
+
+func (noopCache) Clear() {}
+
+// NewNoop returns a cache that keeps nothing, for when caching is disabled.
+func NewNoop() Cache {
+	return noopCache{}
+}
@@ -0,0 +1,225 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/config"
+	"github.com/sajalmia381/store-api/src/dependency"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/fixture"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const usage = `Usage: store-api [command]
+
+Without a command the api server is started.
+
+Commands:
+  migrate          apply pending database migrations
+  migrate status   list database migrations
+  seed [path]      load the fixtures of the directory, the bundled demo catalog without a path
+  export <file>    write every collection into a tar archive
+  import [--replace] <file>
+                   import a tar archive, merging into the existing documents unless --replace
+  categories reconcile [--dry-run]
+                   rebuild the product lists of the categories and report drift
+  trash purge [--older-than DURATION]
+                   permanently remove the trash older than TRASH_RETENTION or the given duration`
+
+// Run executes a command line command instead of starting the server.
+func Run(args []string) error {
+	config.IntVariables()
+	// Commands decide themselves what to migrate
+	config.MigrateOnStartup = false
+	switch args[0] {
+	case "migrate":
+		return migrate(args[1:])
+	case "seed":
+		return seed(args[1:])
+	case "export":
+		return exportArchive(args[1:])
+	case "import":
+		return importArchive(args[1:])
+	case "categories":
+		if len(args) > 1 && args[1] == "reconcile" {
+			return reconcileCategories(args[2:])
+		}
+	case "trash":
+		if len(args) > 1 && args[1] == "purge" {
+			return purgeTrash(args[2:])
+		}
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+		return nil
+	}
+	fmt.Println(usage)
+	return errors.New("unknown command: " + args[0])
+}
+
+func migrate(args []string) error {
+	migrationService := dependency.GetMigrationService()
+	var (
+		migrations []dtos.MigrationStatusDto
+		err        error
+	)
+	if len(args) > 0 && args[0] == "status" {
+		migrations, err = migrationService.FindAll(context.Background())
+	} else {
+		migrations, err = migrationService.Migrate(context.Background())
+	}
+	if err != nil {
+		return err
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "VERSION\tAPPLIED AT\tDESCRIPTION")
+	for _, migration := range migrations {
+		appliedAt := "pending"
+		if migration.AppliedAt != nil {
+			appliedAt = migration.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(writer, "%d\t%s\t%s\n", migration.Version, appliedAt, migration.Description)
+	}
+	return writer.Flush()
+}
+
+func seed(args []string) error {
+	path := config.SeedPath
+	if len(args) > 0 {
+		path = args[0]
+	}
+	catalog, err := fixture.Load(path)
+	if err != nil {
+		return err
+	}
+	report, err := dependency.GetSeedService().Seed(context.Background(), catalog)
+	if err != nil {
+		return err
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "ENTITY\tCREATED\tSKIPPED")
+	fmt.Fprintf(writer, "categories\t%d\t%d\n", report.Categories.Created, report.Categories.Skipped)
+	fmt.Fprintf(writer, "products\t%d\t%d\n", report.Products.Created, report.Products.Skipped)
+	fmt.Fprintf(writer, "users\t%d\t%d\n", report.Users.Created, report.Users.Skipped)
+	fmt.Fprintf(writer, "carts\t%d\t%d\n", report.Carts.Created, report.Carts.Skipped)
+	return writer.Flush()
+}
+
+func exportArchive(args []string) error {
+	if len(args) == 0 {
+		return errors.New("export needs an archive file")
+	}
+	file, err := os.Create(args[0])
+	if err != nil {
+		return err
+	}
+	manifest, err := dependency.GetArchiveService().Export(context.Background(), file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	printArchiveManifest(manifest)
+	return nil
+}
+
+func importArchive(args []string) error {
+	mode := enums.ARCHIVE_MERGE
+	if len(args) > 0 && args[0] == "--replace" {
+		mode = enums.ARCHIVE_REPLACE
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return errors.New("import needs an archive file")
+	}
+	file, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	manifest, err := dependency.GetArchiveService().Import(context.Background(), file, mode)
+	if err != nil {
+		return err
+	}
+	printArchiveManifest(manifest)
+	return nil
+}
+
+func printArchiveManifest(manifest dtos.ArchiveManifestDto) {
+	fmt.Printf("Archive of %s created at %s\n", manifest.Database, manifest.CreatedAt.Format("2006-01-02 15:04:05"))
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "COLLECTION\tDOCUMENTS")
+	for _, collection := range manifest.Collections {
+		fmt.Fprintf(writer, "%s\t%d\n", collection.Name, collection.Count)
+	}
+	writer.Flush()
+	fmt.Printf("%d image files\n", manifest.Images)
+}
+
+func reconcileCategories(args []string) error {
+	dryRun := len(args) > 0 && args[0] == "--dry-run"
+	drifts, err := dependency.GetCategoryService().ReconcileProducts(context.Background(), dryRun)
+	if err != nil {
+		return err
+	}
+	if len(drifts) == 0 {
+		fmt.Println("No drift found")
+		return nil
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "CATEGORY\tMISSING\tPHANTOM")
+	for _, drift := range drifts {
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", drift.Category, joinObjectIds(drift.Missing), joinObjectIds(drift.Phantom))
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if dryRun {
+		fmt.Println("Dry run, nothing changed")
+	}
+	return nil
+}
+
+func purgeTrash(args []string) error {
+	olderThan := config.TrashRetention
+	if len(args) > 0 {
+		if args[0] != "--older-than" || len(args) < 2 {
+			return errors.New("trash purge takes --older-than DURATION")
+		}
+		var err error
+		olderThan, err = time.ParseDuration(args[1])
+		if err != nil {
+			return err
+		}
+	}
+	report, err := dependency.GetTrashService().Purge(context.Background(), olderThan)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Purged the trash older than %s\n", report.Before.Format("2006-01-02 15:04:05"))
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "ENTITY\tPURGED")
+	fmt.Fprintf(writer, "products\t%d\n", report.Products)
+	fmt.Fprintf(writer, "categories\t%d\n", report.Categories)
+	fmt.Fprintf(writer, "users\t%d\n", report.Users)
+	return writer.Flush()
+}
+
+func joinObjectIds(ids []primitive.ObjectID) string {
+	if len(ids) == 0 {
+		return "-"
+	}
+	hexes := make([]string, 0, len(ids))
+	for _, id := range ids {
+		hexes = append(hexes, id.Hex())
+	}
+	return strings.Join(hexes, ",")
+}
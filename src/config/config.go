@@ -4,14 +4,21 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/money"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 var RunMode string
 var ServerPort string
+var RequestTimeout time.Duration
+var QueryTimeout time.Duration
+var HealthCheckInterval time.Duration
 
 var Database string
 var DatabaseName string
@@ -20,6 +27,18 @@ var MongoUsername string
 var MongoPassword string
 var MongoServer string
 var MongoPort string
+var SqlDSN string
+var MigrateOnStartup bool
+var SeedOnStartup bool
+var SeedPath string
+var TrashRetention time.Duration
+var CacheSize int
+var CacheTTL time.Duration
+var CartReservationTTL time.Duration
+var ImagePath string
+var ImageMaxSize int
+var ReviewModeration bool
+var BaseCurrency string
 
 var JwtRegularSecretKey string
 var JwtRefreshSecretKey string
@@ -52,20 +71,51 @@ func IntVariables() {
 		}
 	}
 	ServerPort = os.Getenv("SERVER_PORT")
+	RequestTimeout = durationVariable("REQUEST_TIMEOUT", 30*time.Second)
+	QueryTimeout = durationVariable("QUERY_TIMEOUT", 10*time.Second)
+	HealthCheckInterval = durationVariable("HEALTH_CHECK_INTERVAL", 5*time.Second)
 	// Database
 	Database = os.Getenv("DATABASE")
+	if Database == "" {
+		Database = string(enums.MONGO)
+	}
 	DatabaseName = os.Getenv("DATABASE_NAME")
 	MongoServer = os.Getenv("MONGO_SERVER")
 	MongoPort = os.Getenv("MONGO_PORT")
 	MongoUsername = os.Getenv("MONGO_USERNAME")
 	MongoPassword = os.Getenv("MONGO_PASSWORD")
+	SqlDSN = os.Getenv("SQL_DSN")
+	MigrateOnStartup = os.Getenv("MIGRATE_ON_STARTUP") != "false"
+	SeedOnStartup = os.Getenv("SEED_ON_STARTUP") == "true"
+	SeedPath = os.Getenv("SEED_PATH")
+	TrashRetention = durationVariable("TRASH_RETENTION", 30*24*time.Hour)
+	CacheSize = intVariable("CACHE_SIZE", 1000)
+	CacheTTL = durationVariable("CACHE_TTL", time.Minute)
+	CartReservationTTL = durationVariable("CART_RESERVATION_TTL", 15*time.Minute)
+	ImagePath = os.Getenv("IMAGE_PATH")
+	if ImagePath == "" {
+		ImagePath = "images"
+	}
+	ImageMaxSize = intVariable("IMAGE_MAX_SIZE", 5<<20)
+	ReviewModeration = os.Getenv("REVIEW_MODERATION") == "true"
+	BaseCurrency = strings.ToUpper(os.Getenv("BASE_CURRENCY"))
+	if BaseCurrency == "" {
+		BaseCurrency = "USD"
+	} else if !money.IsCurrency(BaseCurrency) {
+		log.Println("[ERROR] unsupported currency in BASE_CURRENCY:", BaseCurrency)
+		BaseCurrency = "USD"
+	}
 
-	if Database == "MONGO" {
+	if Database == string(enums.MONGO) {
 		DBConnectionString = "mongodb://" + MongoUsername + ":" + MongoPassword + "@" + MongoServer + ":" + MongoPort
+		fmt.Printf("DB Username: %s, DB Server: %s, DB Port: %s\n", MongoUsername, MongoServer, MongoPort)
+	} else {
+		if Database == string(enums.SQLITE) && SqlDSN == "" {
+			SqlDSN = "file:store-api.db"
+		}
+		fmt.Printf("Database: %s\n", Database)
 	}
 
-	fmt.Printf("DB Username: %s, DB Server: %s, DB Port: %s", MongoUsername, MongoServer, MongoPort)
-
 	// JWT
 	JwtRegularSecretKey = os.Getenv("JWT_SECRET_KEY")
 	JwtRefreshSecretKey = os.Getenv("JWT_REFRESH_KEY")
@@ -83,3 +133,31 @@ func IntVariables() {
 	DefaultUserNumber = "1234567891"
 	DefaultUserPassword = "simple_password"
 }
+
+// durationVariable reads a duration like "1500ms" or "30s", "0" disables it.
+func durationVariable(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Println("[ERROR] invalid duration in", key+":", err.Error())
+		return defaultValue
+	}
+	return duration
+}
+
+// intVariable reads a whole number, like "1000".
+func intVariable(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		log.Println("[ERROR] invalid number in", key+":", err.Error())
+		return defaultValue
+	}
+	return number
+}
@@ -1,7 +1,6 @@
 package config
 
 import (
-	"log"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
@@ -10,11 +9,6 @@ import (
 
 func New() *echo.Echo {
 	IntVariables()
-	isConnected := InitDBConnection()
-	if isConnected {
-		log.Println("[INFO] Database connected...")
-	}
-	go DBHealthChecker()
 
 	echoInstance := echo.New()
 
@@ -22,7 +16,7 @@ func New() *echo.Echo {
 	echoInstance.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
 		// Skipping logging for health checking api
 		Skipper: func(c echo.Context) bool {
-			return c.Request().RequestURI == "/health"
+			return c.Request().URL.Path == "/v1/health"
 		},
 		Format: "[${time_rfc3339}] method=${method}, uri=${uri}, status=${status}, latency=${latency_human} remote_ip=${remote_ip}\n",
 	}))
@@ -30,8 +24,9 @@ func New() *echo.Echo {
 	echoInstance.Use(middleware.Recover())
 
 	echoInstance.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: []string{"*"},
-		AllowMethods: []string{http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete},
+		AllowOrigins:  []string{"*"},
+		AllowMethods:  []string{http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete},
+		ExposeHeaders: []string{"ETag", echo.HeaderContentDisposition},
 	}))
 	return echoInstance
 }
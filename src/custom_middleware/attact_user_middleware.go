@@ -37,6 +37,7 @@ func AttachUserMiddlewareConfig() middleware.JWTConfig {
 			}
 			return token, nil
 		},
+		SuccessHandler: attachRequester,
 		ErrorHandler: func(err error) error {
 			return &echo.HTTPError{
 				Code:    http.StatusUnauthorized,
@@ -33,6 +33,7 @@ func AuthMiddlewareConfig() middleware.JWTConfig {
 			}
 			return token, nil
 		},
+		SuccessHandler: attachRequester,
 		ErrorHandler: func(err error) error {
 			return &echo.HTTPError{
 				Code:    http.StatusUnauthorized,
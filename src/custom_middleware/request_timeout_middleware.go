@@ -0,0 +1,40 @@
+package custom_middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sajalmia381/store-api/src/config"
+)
+
+// RequestTimeoutMiddleware puts a REQUEST_TIMEOUT deadline on the request
+// context. Services and repositories receive that context, so a client that
+// disconnects or a request running past the deadline cancels its queries.
+// Exports stream their rows for as long as the client reads them, only a
+// disconnect stops them.
+func RequestTimeoutMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if config.RequestTimeout <= 0 || isStream(c) {
+			return next(c)
+		}
+		ctx, cancel := context.WithTimeout(c.Request().Context(), config.RequestTimeout)
+		defer cancel()
+		c.SetRequest(c.Request().WithContext(ctx))
+		err := next(c)
+		if ctx.Err() == context.DeadlineExceeded && !c.Response().Committed {
+			return &echo.HTTPError{
+				Code:    http.StatusServiceUnavailable,
+				Message: "Request timeout",
+			}
+		}
+		return err
+	}
+}
+
+// isStream reports whether the route streams its response, the export of a
+// list.
+func isStream(c echo.Context) bool {
+	return strings.HasSuffix(c.Path(), "/export")
+}
@@ -0,0 +1,16 @@
+package custom_middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/sajalmia381/store-api/src/utils"
+)
+
+// attachRequester copies the payload of a valid token to the request context,
+// so the services can tell who made a change.
+func attachRequester(c echo.Context) {
+	requester, err := utils.GetRequestData(c)
+	if err != nil {
+		return
+	}
+	c.SetRequest(c.Request().WithContext(utils.WithRequester(c.Request().Context(), requester)))
+}
@@ -1,16 +1,23 @@
 package dependency
 
 import (
+	"sync"
+
+	"github.com/sajalmia381/store-api/src/cache"
+	"github.com/sajalmia381/store-api/src/config"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/storage"
+	"github.com/sajalmia381/store-api/src/v1/db"
 	"github.com/sajalmia381/store-api/src/v1/repository"
 	"github.com/sajalmia381/store-api/src/v1/service"
 )
 
 func GetTokenService() service.TokenService {
-	return service.NewTokenService(repository.NewTokenRepository())
+	return service.NewTokenService(getTokenRepository())
 }
 
 func GetAuthService() service.AuthService {
-	return service.NewAuthService(repository.NewUserRepository(), repository.NewTokenRepository())
+	return service.NewAuthService(getUserRepository(), getTokenRepository(), GetAuditService())
 }
 
 func GetJwtService() service.JwtService {
@@ -18,17 +25,227 @@ func GetJwtService() service.JwtService {
 }
 
 func GetUserService() service.UserService {
-	return service.NewUserService(repository.NewUserRepository())
+	return service.NewUserService(getUserRepository(), GetAuditService(), getCatalogCache())
 }
 
 func GetCategoryService() service.CategoryService {
-	return service.NewCategoryService(repository.NewCategoryRepository())
+	return service.NewCategoryService(getCategoryRepository(), GetAuditService(), getCatalogCache())
 }
 
 func GetProductService() service.ProductService {
-	return service.NewProductService(repository.NewProductRepository(), repository.NewCategoryRepository())
+	return service.NewProductService(getProductRepository(), getCategoryRepository(), getStockRepository(), GetImageService(), GetAuditService(), getCatalogCache())
+}
+
+func GetReviewService() service.ReviewService {
+	return service.NewReviewService(getReviewRepository(), getProductRepository(), GetAuditService(), getCatalogCache())
+}
+
+func GetProductImportService() service.ProductImportService {
+	return service.NewProductImportService(getProductRepository(), getCategoryRepository(), GetAuditService(), getCatalogCache(), getImportJobs())
+}
+
+func GetExportService() service.ExportService {
+	return service.NewExportService(getProductRepository(), getCategoryRepository(), getUserRepository())
 }
 
 func GetCartService() service.CartService {
-	return service.NewCartService(repository.NewCartRepository())
+	return service.NewCartService(getCartRepository(), getProductRepository(), GetAuditService(), getCatalogCache())
+}
+
+func GetSeedService() service.SeedService {
+	return service.NewSeedService(getCategoryRepository(), getProductRepository(), getUserRepository(), getCartRepository(), GetImageService(), getCatalogCache())
+}
+
+func GetArchiveService() service.ArchiveService {
+	return service.NewArchiveService(getArchiveRepository(), getImageStorage(), getCatalogCache())
+}
+
+func GetMigrationService() service.MigrationService {
+	return service.NewMigrationService(getMigrator(), getCatalogCache())
+}
+
+func GetTrashService() service.TrashService {
+	return service.NewTrashService(getProductRepository(), getCategoryRepository(), getUserRepository(), GetImageService(), getCatalogCache())
+}
+
+func GetHealthService() service.HealthService {
+	return service.NewHealthService(getHealthChecker())
+}
+
+func GetAuditService() service.AuditService {
+	return service.NewAuditService(getAuditRepository())
+}
+
+func GetCurrencyService() service.CurrencyService {
+	return service.NewCurrencyService(getExchangeRateRepository(), getCatalogCache())
+}
+
+func GetImageService() service.ImageService {
+	return service.NewImageService(getImageStorage(), config.ImageMaxSize)
+}
+
+var catalogCache *service.CatalogCache
+var onceCatalogCache sync.Once
+
+// getCatalogCache is shared by every service, so a write invalidates the reads
+// of all of them. CACHE_SIZE or CACHE_TTL of 0 disables it.
+func getCatalogCache() *service.CatalogCache {
+	onceCatalogCache.Do(func() {
+		if config.CacheSize > 0 && config.CacheTTL > 0 {
+			catalogCache = service.NewCatalogCache(cache.NewLRU(config.CacheSize, config.CacheTTL))
+		} else {
+			catalogCache = service.NewCatalogCache(cache.NewNoop())
+		}
+	})
+	return catalogCache
+}
+
+var importJobs *service.ImportJobs
+var onceImportJobs sync.Once
+
+// getImportJobs is shared by every import service, so a job started by one
+// request can be followed by the next.
+func getImportJobs() *service.ImportJobs {
+	onceImportJobs.Do(func() {
+		importJobs = service.NewImportJobs()
+	})
+	return importJobs
+}
+
+var imageStorage storage.Storage
+var onceImageStorage sync.Once
+
+// getImageStorage keeps the images under IMAGE_PATH, or in process along with
+// the memory database.
+func getImageStorage() storage.Storage {
+	onceImageStorage.Do(func() {
+		if enums.DatabaseType(config.Database) == enums.MEMORY {
+			imageStorage = storage.NewMemory()
+		} else {
+			imageStorage = storage.NewLocal(config.ImagePath)
+		}
+	})
+	return imageStorage
+}
+
+// Repositories are picked by the DATABASE variable, MONGO is the default.
+
+func getTokenRepository() repository.TokenRepository {
+	switch enums.DatabaseType(config.Database) {
+	case enums.MEMORY:
+		return repository.NewTokenMemoryRepository()
+	case enums.SQLITE, enums.POSTGRES:
+		return repository.NewTokenSqlRepository()
+	}
+	return repository.NewTokenRepository()
+}
+
+func getUserRepository() repository.UserRepository {
+	switch enums.DatabaseType(config.Database) {
+	case enums.MEMORY:
+		return repository.NewUserMemoryRepository()
+	case enums.SQLITE, enums.POSTGRES:
+		return repository.NewUserSqlRepository()
+	}
+	return repository.NewUserRepository()
+}
+
+func getCategoryRepository() repository.CategoryRepository {
+	switch enums.DatabaseType(config.Database) {
+	case enums.MEMORY:
+		return repository.NewCategoryMemoryRepository()
+	case enums.SQLITE, enums.POSTGRES:
+		return repository.NewCategorySqlRepository()
+	}
+	return repository.NewCategoryRepository()
+}
+
+func getProductRepository() repository.ProductRepository {
+	switch enums.DatabaseType(config.Database) {
+	case enums.MEMORY:
+		return repository.NewProductMemoryRepository()
+	case enums.SQLITE, enums.POSTGRES:
+		return repository.NewProductSqlRepository()
+	}
+	return repository.NewProductRepository()
+}
+
+func getCartRepository() repository.CartRepository {
+	switch enums.DatabaseType(config.Database) {
+	case enums.MEMORY:
+		return repository.NewCartMemoryRepository()
+	case enums.SQLITE, enums.POSTGRES:
+		return repository.NewCartSqlRepository()
+	}
+	return repository.NewCartRepository()
+}
+
+func getArchiveRepository() repository.ArchiveRepository {
+	switch enums.DatabaseType(config.Database) {
+	case enums.MEMORY:
+		return repository.NewArchiveMemoryRepository()
+	case enums.SQLITE, enums.POSTGRES:
+		return repository.NewArchiveSqlRepository()
+	}
+	return repository.NewArchiveRepository()
+}
+
+func getAuditRepository() repository.AuditRepository {
+	switch enums.DatabaseType(config.Database) {
+	case enums.MEMORY:
+		return repository.NewAuditMemoryRepository()
+	case enums.SQLITE, enums.POSTGRES:
+		return repository.NewAuditSqlRepository()
+	}
+	return repository.NewAuditRepository()
+}
+
+func getStockRepository() repository.StockRepository {
+	switch enums.DatabaseType(config.Database) {
+	case enums.MEMORY:
+		return repository.NewStockMemoryRepository()
+	case enums.SQLITE, enums.POSTGRES:
+		return repository.NewStockSqlRepository()
+	}
+	return repository.NewStockRepository()
+}
+
+func getReviewRepository() repository.ReviewRepository {
+	switch enums.DatabaseType(config.Database) {
+	case enums.MEMORY:
+		return repository.NewReviewMemoryRepository()
+	case enums.SQLITE, enums.POSTGRES:
+		return repository.NewReviewSqlRepository()
+	}
+	return repository.NewReviewRepository()
+}
+
+func getExchangeRateRepository() repository.ExchangeRateRepository {
+	switch enums.DatabaseType(config.Database) {
+	case enums.MEMORY:
+		return repository.NewExchangeRateMemoryRepository()
+	case enums.SQLITE, enums.POSTGRES:
+		return repository.NewExchangeRateSqlRepository()
+	}
+	return repository.NewExchangeRateRepository()
+}
+
+func getMigrator() db.Migrator {
+	switch enums.DatabaseType(config.Database) {
+	case enums.MEMORY:
+		return db.GetMemoryManager()
+	case enums.SQLITE, enums.POSTGRES:
+		return db.GetSqlManager()
+	}
+	return db.GetDmManager()
+}
+
+func getHealthChecker() db.HealthChecker {
+	switch enums.DatabaseType(config.Database) {
+	case enums.MEMORY:
+		return db.GetMemoryManager()
+	case enums.SQLITE, enums.POSTGRES:
+		return db.GetSqlManager()
+	}
+	return db.GetDmManager()
 }
@@ -0,0 +1,79 @@
+package domain_error
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kind classifies a domain error. Repositories and services return them, the
+// api layer picks the http status from the kind.
+type Kind string
+
+const (
+	NOT_FOUND           = Kind("NOT_FOUND")
+	CONFLICT            = Kind("CONFLICT")
+	VALIDATION          = Kind("VALIDATION")
+	FORBIDDEN           = Kind("FORBIDDEN")
+	UNAVAILABLE         = Kind("UNAVAILABLE")
+	PRECONDITION_FAILED = Kind("PRECONDITION_FAILED")
+)
+
+type Error struct {
+	Kind    Kind
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func NotFound(message string) error {
+	return &Error{Kind: NOT_FOUND, Message: message}
+}
+
+func Conflict(message string) error {
+	return &Error{Kind: CONFLICT, Message: message}
+}
+
+func Validation(message string) error {
+	return &Error{Kind: VALIDATION, Message: message}
+}
+
+func Forbidden(message string) error {
+	return &Error{Kind: FORBIDDEN, Message: message}
+}
+
+func PreconditionFailed(message string) error {
+	return &Error{Kind: PRECONDITION_FAILED, Message: message}
+}
+
+// StaleVersion fails a conditional write on an entity that is at the current
+// version by now.
+func StaleVersion(entity string, current int64) error {
+	return PreconditionFailed(fmt.Sprintf("%s was modified meanwhile, its version is %d", entity, current))
+}
+
+// Unavailable wraps a database or network failure, the cause is kept for logs
+// and never sent to the client.
+func Unavailable(err error) error {
+	return &Error{Kind: UNAVAILABLE, Message: "service is temporarily unavailable", Err: err}
+}
+
+// KindOf returns the kind of the first domain error in the err chain, empty
+// when err is not a domain error.
+func KindOf(err error) Kind {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Kind
+	}
+	return ""
+}
+
+func Is(err error, kind Kind) bool {
+	return err != nil && KindOf(err) == kind
+}
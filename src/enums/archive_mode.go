@@ -0,0 +1,10 @@
+package enums
+
+type ArchiveMode string
+
+const (
+	// ARCHIVE_MERGE overwrites the archived documents and keeps the others
+	ARCHIVE_MERGE = ArchiveMode("merge")
+	// ARCHIVE_REPLACE empties every collection before importing
+	ARCHIVE_REPLACE = ArchiveMode("replace")
+)
@@ -0,0 +1,14 @@
+package enums
+
+// AttributeType is the type of the values of a category attribute. ENUM
+// values are strings out of the options of the attribute.
+type AttributeType string
+
+const (
+	ATTRIBUTE_STRING = AttributeType("STRING")
+	ATTRIBUTE_NUMBER = AttributeType("NUMBER")
+	ATTRIBUTE_ENUM   = AttributeType("ENUM")
+	ATTRIBUTE_BOOL   = AttributeType("BOOL")
+)
+
+var ATTRIBUTE_TYPES = []AttributeType{ATTRIBUTE_STRING, ATTRIBUTE_NUMBER, ATTRIBUTE_ENUM, ATTRIBUTE_BOOL}
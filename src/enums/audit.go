@@ -0,0 +1,25 @@
+package enums
+
+type AuditAction string
+
+const (
+	AUDIT_CREATE  = AuditAction("CREATE")
+	AUDIT_UPDATE  = AuditAction("UPDATE")
+	AUDIT_DELETE  = AuditAction("DELETE")
+	AUDIT_RESTORE = AuditAction("RESTORE")
+	AUDIT_PURGE   = AuditAction("PURGE")
+)
+
+var AUDIT_ACTIONS = []AuditAction{AUDIT_CREATE, AUDIT_UPDATE, AUDIT_DELETE, AUDIT_RESTORE, AUDIT_PURGE}
+
+type AuditEntity string
+
+const (
+	AUDIT_PRODUCT  = AuditEntity("PRODUCT")
+	AUDIT_CATEGORY = AuditEntity("CATEGORY")
+	AUDIT_USER     = AuditEntity("USER")
+	AUDIT_CART     = AuditEntity("CART")
+	AUDIT_REVIEW   = AuditEntity("REVIEW")
+)
+
+var AUDIT_ENTITIES = []AuditEntity{AUDIT_PRODUCT, AUDIT_CATEGORY, AUDIT_USER, AUDIT_CART, AUDIT_REVIEW}
@@ -7,8 +7,18 @@ const (
 	USER_COLLECTION_NAME     = CollectionName("users")
 	CATEGORY_COLLECTION_NAME = CollectionName("categories")
 	PRODUCT_COLLECTION_NAME  = CollectionName("products")
+	REVIEW_COLLECTION_NAME   = CollectionName("reviews")
 	CART_COLLECTION_NAME     = CollectionName("carts")
 	ORDER_COLLECTION_NAME    = CollectionName("orders")
+
+	// Bookkeeping of applied schema migrations, not part of COLLECTION_NAMES
+	MIGRATION_COLLECTION_NAME = CollectionName("migrations")
+	// Change history of the other collections, not part of COLLECTION_NAMES
+	AUDIT_COLLECTION_NAME = CollectionName("audit_logs")
+	// Stock history of the products, not part of COLLECTION_NAMES
+	STOCK_ADJUSTMENT_COLLECTION_NAME = CollectionName("stock_adjustments")
+	// Exchange rates of the currencies, not part of COLLECTION_NAMES
+	EXCHANGE_RATE_COLLECTION_NAME = CollectionName("exchange_rates")
 )
 
 var COLLECTION_NAMES = []string{
@@ -16,6 +26,7 @@ var COLLECTION_NAMES = []string{
 	string(USER_COLLECTION_NAME),
 	string(CATEGORY_COLLECTION_NAME),
 	string(PRODUCT_COLLECTION_NAME),
+	string(REVIEW_COLLECTION_NAME),
 	string(CART_COLLECTION_NAME),
 	string(ORDER_COLLECTION_NAME),
 }
@@ -0,0 +1,10 @@
+package enums
+
+type ConnectionState string
+
+const (
+	CONNECTING   = ConnectionState("CONNECTING")
+	CONNECTED    = ConnectionState("CONNECTED")
+	RECONNECTING = ConnectionState("RECONNECTING")
+	DISCONNECTED = ConnectionState("DISCONNECTED")
+)
@@ -0,0 +1,10 @@
+package enums
+
+type DatabaseType string
+
+const (
+	MONGO    = DatabaseType("MONGO")
+	MEMORY   = DatabaseType("MEMORY")
+	SQLITE   = DatabaseType("SQLITE")
+	POSTGRES = DatabaseType("POSTGRES")
+)
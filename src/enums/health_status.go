@@ -0,0 +1,8 @@
+package enums
+
+type HealthStatus string
+
+const (
+	HEALTH_UP   = HealthStatus("UP")
+	HEALTH_DOWN = HealthStatus("DOWN")
+)
@@ -0,0 +1,19 @@
+package enums
+
+// ImportAction is what importing a row does to the product of its slug.
+type ImportAction string
+
+const (
+	IMPORT_CREATE    = ImportAction("CREATE")
+	IMPORT_UPDATE    = ImportAction("UPDATE")
+	IMPORT_UNCHANGED = ImportAction("UNCHANGED")
+)
+
+// JobStatus is the state of a background job.
+type JobStatus string
+
+const (
+	JOB_RUNNING = JobStatus("RUNNING")
+	JOB_DONE    = JobStatus("DONE")
+	JOB_FAILED  = JobStatus("FAILED")
+)
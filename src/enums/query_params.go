@@ -6,3 +6,12 @@ const (
 	ASCENDING  = Sort("asc")
 	DESCENDING = Sort("desc")
 )
+
+type SearchMode string
+
+const (
+	// SEARCH_REGEX matches the search as a case-insensitive substring
+	SEARCH_REGEX = SearchMode("regex")
+	// SEARCH_TEXT matches the words of the search and ranks by relevance
+	SEARCH_TEXT = SearchMode("text")
+)
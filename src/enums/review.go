@@ -0,0 +1,13 @@
+package enums
+
+// ReviewStatus is the moderation state of a review, only APPROVED reviews are
+// shown and rated.
+type ReviewStatus string
+
+const (
+	REVIEW_PENDING  = ReviewStatus("PENDING")
+	REVIEW_APPROVED = ReviewStatus("APPROVED")
+	REVIEW_REJECTED = ReviewStatus("REJECTED")
+)
+
+var REVIEW_STATUSES = []ReviewStatus{REVIEW_PENDING, REVIEW_APPROVED, REVIEW_REJECTED}
@@ -0,0 +1,14 @@
+package enums
+
+// StockReason tells why the stock of a product was adjusted.
+type StockReason string
+
+const (
+	STOCK_RESTOCK    = StockReason("RESTOCK")
+	STOCK_SALE       = StockReason("SALE")
+	STOCK_RETURN     = StockReason("RETURN")
+	STOCK_DAMAGE     = StockReason("DAMAGE")
+	STOCK_CORRECTION = StockReason("CORRECTION")
+)
+
+var STOCK_REASONS = []StockReason{STOCK_RESTOCK, STOCK_SALE, STOCK_RETURN, STOCK_DAMAGE, STOCK_CORRECTION}
@@ -0,0 +1,288 @@
+// Package fixture reads the seed catalog from a directory of JSON or CSV files.
+//
+// Every entity has its own file, categories, products, users and carts, with a
+// .json or .csv extension. Missing files are skipped. Entities refer to each
+// other by slug (categories, products) or email (users). Product images are
+// files of the directory, referred to by their path.
+package fixture
+
+import (
+	"embed"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+)
+
+//go:embed demo
+var demo embed.FS
+
+type Category struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Parent is the slug of the parent category
+	Parent string `json:"parent"`
+	// Attributes can only be given in JSON
+	Attributes []Attribute `json:"attributes"`
+}
+
+// Attribute defines an attribute of the products of a category, Type is one
+// of STRING, NUMBER, ENUM and BOOL.
+type Attribute struct {
+	Name     string   `json:"name"`
+	Label    string   `json:"label"`
+	Type     string   `json:"type"`
+	Required bool     `json:"required"`
+	Unit     string   `json:"unit"`
+	Options  []string `json:"options"`
+}
+
+// Product prices are in minor units of the base currency, like cents.
+type Product struct {
+	Title       string `json:"title"`
+	Price       int    `json:"price"`
+	Description string `json:"description"`
+	Image       string `json:"image"`
+	// Category is the slug of the product category
+	Category string `json:"category"`
+	// CreatedBy is the email of the creator, the default user when empty
+	CreatedBy string `json:"createdBy"`
+	// Stock starts tracking the stock of the product when set
+	Stock *int `json:"stock"`
+	// Variants and Attributes can only be given in JSON
+	Variants   []Variant              `json:"variants"`
+	Attributes map[string]interface{} `json:"attributes"`
+	// Images are paths of image files in the catalog directory, the first is
+	// the primary image. They can only be given in JSON.
+	Images []string `json:"images"`
+}
+
+type Variant struct {
+	SKU string `json:"sku"`
+	// Price overrides the product price when set
+	Price      *int              `json:"price"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+type User struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Number   *uint  `json:"number"`
+	Role     string `json:"role"`
+}
+
+type CartProduct struct {
+	// Product is the product slug
+	Product string `json:"product"`
+	// Variant is the SKU of a variant of the product, optional
+	Variant  string `json:"variant"`
+	Quantity uint16 `json:"quantity"`
+}
+
+type Cart struct {
+	// User is the email of the cart owner
+	User     string        `json:"user"`
+	Products []CartProduct `json:"products"`
+}
+
+type Catalog struct {
+	Categories []Category
+	Products   []Product
+	Users      []User
+	Carts      []Cart
+	// files is the catalog directory
+	files fs.FS
+}
+
+// ReadFile reads a file of the catalog directory, like a product image.
+func (c Catalog) ReadFile(name string) ([]byte, error) {
+	if c.files == nil {
+		return nil, fmt.Errorf("%s: catalog has no directory", name)
+	}
+	return fs.ReadFile(c.files, name)
+}
+
+// Load reads the catalog from the directory at path, the bundled demo catalog
+// when path is empty.
+func Load(path string) (Catalog, error) {
+	if path == "" {
+		fsys, err := fs.Sub(demo, "demo")
+		if err != nil {
+			return Catalog{}, err
+		}
+		return LoadFS(fsys)
+	}
+	return LoadFS(os.DirFS(path))
+}
+
+func LoadFS(fsys fs.FS) (Catalog, error) {
+	catalog := Catalog{files: fsys}
+	err := load(fsys, "categories", &catalog.Categories, func(records []map[string]string) (err error) {
+		catalog.Categories, err = categoriesFromCsv(records)
+		return err
+	})
+	if err != nil {
+		return catalog, err
+	}
+	err = load(fsys, "products", &catalog.Products, func(records []map[string]string) (err error) {
+		catalog.Products, err = productsFromCsv(records)
+		return err
+	})
+	if err != nil {
+		return catalog, err
+	}
+	err = load(fsys, "users", &catalog.Users, func(records []map[string]string) (err error) {
+		catalog.Users, err = usersFromCsv(records)
+		return err
+	})
+	if err != nil {
+		return catalog, err
+	}
+	err = load(fsys, "carts", &catalog.Carts, func(records []map[string]string) (err error) {
+		catalog.Carts, err = cartsFromCsv(records)
+		return err
+	})
+	return catalog, err
+}
+
+// load decodes name.json into objects, or hands the rows of name.csv to fromCsv.
+func load(fsys fs.FS, name string, objects interface{}, fromCsv func(records []map[string]string) error) error {
+	data, err := fs.ReadFile(fsys, name+".json")
+	if err == nil {
+		if err := json.Unmarshal(data, objects); err != nil {
+			return fmt.Errorf("%s.json: %w", name, err)
+		}
+		return nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	file, err := fsys.Open(name + ".csv")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	records, err := readCsv(file)
+	if err != nil {
+		return fmt.Errorf("%s.csv: %w", name, err)
+	}
+	if err := fromCsv(records); err != nil {
+		return fmt.Errorf("%s.csv: %w", name, err)
+	}
+	return nil
+}
+
+// readCsv maps every row to the column names of the header row.
+func readCsv(reader io.Reader) ([]map[string]string, error) {
+	rows, err := csv.NewReader(reader).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	records := []map[string]string{}
+	if len(rows) == 0 {
+		return records, nil
+	}
+	header := rows[0]
+	for _, row := range rows[1:] {
+		record := map[string]string{}
+		for i, column := range header {
+			if i < len(row) {
+				record[strings.TrimSpace(column)] = strings.TrimSpace(row[i])
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func categoriesFromCsv(records []map[string]string) ([]Category, error) {
+	categories := []Category{}
+	for _, record := range records {
+		categories = append(categories, Category{
+			Name:        record["name"],
+			Description: record["description"],
+			Parent:      record["parent"],
+		})
+	}
+	return categories, nil
+}
+
+func productsFromCsv(records []map[string]string) ([]Product, error) {
+	products := []Product{}
+	for i, record := range records {
+		price, err := strconv.Atoi(record["price"])
+		if err != nil {
+			return nil, fmt.Errorf("row %d: invalid price %q", i+2, record["price"])
+		}
+		product := Product{
+			Title:       record["title"],
+			Price:       price,
+			Description: record["description"],
+			Image:       record["image"],
+			Category:    record["category"],
+			CreatedBy:   record["createdBy"],
+		}
+		// the stock column is optional, an empty one leaves it untracked
+		if record["stock"] != "" {
+			stock, err := strconv.Atoi(record["stock"])
+			if err != nil {
+				return nil, fmt.Errorf("row %d: invalid stock %q", i+2, record["stock"])
+			}
+			product.Stock = &stock
+		}
+		products = append(products, product)
+	}
+	return products, nil
+}
+
+func usersFromCsv(records []map[string]string) ([]User, error) {
+	users := []User{}
+	for i, record := range records {
+		user := User{
+			Name:     record["name"],
+			Email:    record["email"],
+			Password: record["password"],
+			Role:     record["role"],
+		}
+		if record["number"] != "" {
+			number, err := strconv.ParseUint(record["number"], 10, 0)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: invalid number %q", i+2, record["number"])
+			}
+			_number := uint(number)
+			user.Number = &_number
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// cartsFromCsv reads one product per row and groups the rows by user, the
+// variant column is optional.
+func cartsFromCsv(records []map[string]string) ([]Cart, error) {
+	carts := []Cart{}
+	index := map[string]int{}
+	for i, record := range records {
+		quantity, err := strconv.ParseUint(record["quantity"], 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: invalid quantity %q", i+2, record["quantity"])
+		}
+		product := CartProduct{Product: record["product"], Variant: record["variant"], Quantity: uint16(quantity)}
+		if j, ok := index[record["user"]]; ok {
+			carts[j].Products = append(carts[j].Products, product)
+			continue
+		}
+		index[record["user"]] = len(carts)
+		carts = append(carts, Cart{User: record["user"], Products: []CartProduct{product}})
+	}
+	return carts, nil
+}
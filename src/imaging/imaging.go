@@ -0,0 +1,132 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// Formats of the images accepted by their sniffed content type, gif is
+// decoded at its first frame.
+var formats = map[string]string{
+	"image/jpeg": "jpeg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+}
+
+var (
+	ErrFormat     = errors.New("image is not a jpeg, png or gif")
+	ErrDimensions = errors.New("image has too many pixels")
+)
+
+// ContentType returns the content type of the image format.
+func ContentType(format string) string {
+	for contentType, name := range formats {
+		if name == format {
+			return contentType
+		}
+	}
+	return "application/octet-stream"
+}
+
+// Decode reads a jpeg, png or gif image and returns it with its format. The
+// header is checked first, so an image of more than maxPixels is refused
+// before it is decoded.
+func Decode(content []byte, maxPixels int) (image.Image, string, error) {
+	format, ok := formats[http.DetectContentType(content)]
+	if !ok {
+		return nil, "", ErrFormat
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, "", ErrFormat
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxPixels {
+		return nil, "", ErrDimensions
+	}
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, "", ErrFormat
+	}
+	return img, format, nil
+}
+
+// Fit scales img down to fit in a size by size square, keeping its aspect
+// ratio. Every pixel of the result averages the pixels it covers. Images that
+// already fit are returned as they are.
+func Fit(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return img
+	}
+	fitWidth, fitHeight := size, height*size/width
+	if height > width {
+		fitWidth, fitHeight = width*size/height, size
+	}
+	if fitWidth < 1 {
+		fitWidth = 1
+	}
+	if fitHeight < 1 {
+		fitHeight = 1
+	}
+	src := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+	dst := image.NewRGBA(image.Rect(0, 0, fitWidth, fitHeight))
+	for y := 0; y < fitHeight; y++ {
+		y0, y1 := y*height/fitHeight, (y+1)*height/fitHeight
+		for x := 0; x < fitWidth; x++ {
+			x0, x1 := x*width/fitWidth, (x+1)*width/fitWidth
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride+x0*4 : sy*src.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+			count := (y1 - y0) * (x1 - x0)
+			offset := y*dst.Stride + x*4
+			for i := range sum {
+				dst.Pix[offset+i] = uint8(sum[i] / count)
+			}
+		}
+	}
+	return dst
+}
+
+// ThumbnailFormat is the format the thumbnails of an image are encoded in, a
+// png for the formats other than jpeg keeps their transparency.
+func ThumbnailFormat(format string) string {
+	if format == "jpeg" {
+		return format
+	}
+	return "png"
+}
+
+// Encode writes img in a thumbnail format.
+func Encode(img image.Image, format string) ([]byte, error) {
+	var buffer bytes.Buffer
+	var err error
+	if format == "jpeg" {
+		err = jpeg.Encode(&buffer, img, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(&buffer, img)
+	}
+	return buffer.Bytes(), err
+}
+
+// Extension is the file extension of the format.
+func Extension(format string) string {
+	if format == "jpeg" {
+		return "jpg"
+	}
+	return format
+}
@@ -0,0 +1,77 @@
+package money
+
+import (
+	"math"
+	"sort"
+)
+
+// digits are the minor unit digits of the supported ISO 4217 currencies, 2
+// for the cents of USD, 0 for JPY.
+var digits = map[string]int{
+	"AED": 2, "ARS": 2, "AUD": 2, "BDT": 2, "BGN": 2, "BHD": 3, "BRL": 2, "CAD": 2,
+	"CHF": 2, "CLP": 0, "CNY": 2, "COP": 2, "CZK": 2, "DKK": 2, "EGP": 2, "EUR": 2,
+	"GBP": 2, "HKD": 2, "HUF": 2, "IDR": 2, "ILS": 2, "INR": 2, "ISK": 0, "JOD": 3,
+	"JPY": 0, "KES": 2, "KRW": 0, "KWD": 3, "LKR": 2, "MXN": 2, "MYR": 2, "NGN": 2,
+	"NOK": 2, "NPR": 2, "NZD": 2, "OMR": 3, "PHP": 2, "PKR": 2, "PLN": 2, "QAR": 2,
+	"RON": 2, "SAR": 2, "SEK": 2, "SGD": 2, "THB": 2, "TND": 3, "TRY": 2, "TWD": 2,
+	"UAH": 2, "USD": 2, "VND": 0, "ZAR": 2,
+}
+
+// IsCurrency reports whether the code is a supported currency, codes are upper
+// case.
+func IsCurrency(code string) bool {
+	_, ok := digits[code]
+	return ok
+}
+
+// Currencies are the codes of the supported currencies in order.
+func Currencies() []string {
+	codes := []string{}
+	for code := range digits {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Digits is the number of minor unit digits of the currency.
+func Digits(code string) int {
+	return digits[code]
+}
+
+// Scale is the number of minor units in a unit of the currency, 100 for USD.
+func Scale(code string) int64 {
+	scale := int64(1)
+	for i := 0; i < digits[code]; i++ {
+		scale *= 10
+	}
+	return scale
+}
+
+// Converter converts amounts of the From currency to the To currency, a unit
+// of From is Rate units of To.
+type Converter struct {
+	From string
+	To   string
+	Rate float64
+}
+
+// Identity leaves the amounts of the currency as they are.
+func Identity(code string) Converter {
+	return Converter{From: code, To: code, Rate: 1}
+}
+
+// IsIdentity reports whether the converter leaves the amounts unchanged.
+func (c Converter) IsIdentity() bool {
+	return c.From == c.To
+}
+
+// Convert converts an amount in minor units of From to minor units of To,
+// rounding half away from zero.
+func (c Converter) Convert(amount int64) int64 {
+	if c.IsIdentity() {
+		return amount
+	}
+	value := float64(amount) * c.Rate * float64(Scale(c.To)) / float64(Scale(c.From))
+	return int64(math.Round(value))
+}
@@ -0,0 +1,34 @@
+package money
+
+import "testing"
+
+func TestScale(t *testing.T) {
+	for code, scale := range map[string]int64{"USD": 100, "JPY": 1, "KWD": 1000} {
+		if got := Scale(code); got != scale {
+			t.Errorf("scale of %s is %d, want %d", code, got, scale)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	conversions := []struct {
+		converter Converter
+		amount    int64
+		want      int64
+	}{
+		{Identity("USD"), 1999, 1999},
+		{Converter{From: "USD", To: "EUR", Rate: 0.9}, 1000, 900},
+		// 19.99 USD at 150.5 is 3008.495 JPY, which has no minor units
+		{Converter{From: "USD", To: "JPY", Rate: 150.5}, 1999, 3008},
+		{Converter{From: "JPY", To: "USD", Rate: 0.0067}, 1000, 670},
+		{Converter{From: "USD", To: "KWD", Rate: 0.3075}, 1000, 3075},
+		// halves round away from zero
+		{Converter{From: "USD", To: "EUR", Rate: 0.5}, 1, 1},
+		{Converter{From: "USD", To: "EUR", Rate: 0.5}, -1, -1},
+	}
+	for _, c := range conversions {
+		if got := c.converter.Convert(c.amount); got != c.want {
+			t.Errorf("%+v converts %d to %d, want %d", c.converter, c.amount, got, c.want)
+		}
+	}
+}
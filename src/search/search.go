@@ -0,0 +1,142 @@
+package search
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Weights of the product fields in the text search, the mongo text index is
+// created with the same.
+const (
+	TitleWeight       = 10
+	DescriptionWeight = 2
+)
+
+// Terms splits a text search into its lower case words.
+func Terms(search string) []string {
+	terms := []string{}
+	seen := map[string]bool{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(search), isSeparator) {
+		if !seen[word] {
+			seen[word] = true
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// Score ranks a product for the terms the way the weighted mongo text index
+// does: every word of a field matching a term adds the weight of the field,
+// the more of the field matches the more it adds. 0 means no word matches.
+func Score(terms []string, title string, description string) float64 {
+	return fieldScore(terms, title, TitleWeight) + fieldScore(terms, description, DescriptionWeight)
+}
+
+func fieldScore(terms []string, text string, weight float64) float64 {
+	words := wordBounds(text)
+	matches := len(Words(text, terms))
+	if matches == 0 {
+		return 0
+	}
+	return weight * float64(matches) * (0.5 + 0.5*float64(matches)/float64(len(words)))
+}
+
+// Words finds the words of text matching any of the terms, case-insensitive
+// and ignoring a plural s. Mongo stems words its own way, so results differ
+// slightly between databases.
+func Words(text string, terms []string) [][]int {
+	matches := [][]int{}
+	for _, bounds := range wordBounds(text) {
+		word := strings.ToLower(text[bounds[0]:bounds[1]])
+		for _, term := range terms {
+			if word == term || word == term+"s" || word+"s" == term {
+				matches = append(matches, bounds)
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// Substrings finds the case-insensitive occurrences of search in text.
+func Substrings(text string, search string) [][]int {
+	if search == "" {
+		return nil
+	}
+	return regexp.MustCompile("(?i)"+regexp.QuoteMeta(search)).FindAllStringIndex(text, -1)
+}
+
+// Mark wraps the matches of text in <em>, the rest is HTML escaped.
+func Mark(text string, matches [][]int) string {
+	var marked strings.Builder
+	last := 0
+	for _, match := range matches {
+		marked.WriteString(html.EscapeString(text[last:match[0]]))
+		marked.WriteString("<em>" + html.EscapeString(text[match[0]:match[1]]) + "</em>")
+		last = match[1]
+	}
+	marked.WriteString(html.EscapeString(text[last:]))
+	return marked.String()
+}
+
+// Snippet is Mark of about size characters of text around the first match,
+// cut text ends with an ellipsis.
+func Snippet(text string, matches [][]int, size int) string {
+	if utf8.RuneCountInString(text) <= size {
+		return Mark(text, matches)
+	}
+	start := 0
+	if len(matches) > 0 {
+		// a third of the snippet leads up to the match
+		start = matches[0][0]
+		for lead := 0; start > 0 && lead < size/3; lead++ {
+			_, width := utf8.DecodeLastRuneInString(text[:start])
+			start -= width
+		}
+	}
+	end := start
+	for n := 0; end < len(text) && n < size; n++ {
+		_, width := utf8.DecodeRuneInString(text[end:])
+		end += width
+	}
+	inside := [][]int{}
+	for _, match := range matches {
+		if match[0] >= start && match[1] <= end {
+			inside = append(inside, []int{match[0] - start, match[1] - start})
+		}
+	}
+	snippet := Mark(text[start:end], inside)
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(text) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+func wordBounds(text string) [][]int {
+	bounds := [][]int{}
+	start := -1
+	for i, r := range text {
+		if isSeparator(r) {
+			if start >= 0 {
+				bounds = append(bounds, []int{start, i})
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		bounds = append(bounds, []int{start, len(text)})
+	}
+	return bounds
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
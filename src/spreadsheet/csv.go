@@ -0,0 +1,46 @@
+package spreadsheet
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+type csvWriter struct {
+	writer *csv.Writer
+	record []string
+}
+
+// NewCSV writes RFC 4180 csv. Text starting like a formula is prefixed with
+// a quote, so spreadsheet applications show it instead of running it.
+func NewCSV(w io.Writer) Writer {
+	return &csvWriter{writer: csv.NewWriter(w)}
+}
+
+func (w *csvWriter) WriteRow(cells ...interface{}) error {
+	w.record = w.record[:0]
+	for _, value := range cells {
+		text := ""
+		switch value := cell(value).(type) {
+		case string:
+			text = value
+			if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+				text = "'" + value
+			}
+		case float64:
+			text = formatNumber(value)
+		case bool:
+			text = "false"
+			if value {
+				text = "true"
+			}
+		}
+		w.record = append(w.record, text)
+	}
+	return w.writer.Write(w.record)
+}
+
+func (w *csvWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
@@ -0,0 +1,101 @@
+package spreadsheet
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Writer writes a table row by row, the first row is the header. Rows go out
+// as they are written, a Writer holds no more than a small buffer of them.
+type Writer interface {
+	// WriteRow writes a row of cells. A cell is a string, a number, a bool,
+	// a time or nil for an empty cell, pointers to them are followed. Other
+	// values are printed as text.
+	WriteRow(cells ...interface{}) error
+	// Close ends the table, nothing is complete before it.
+	Close() error
+}
+
+var ErrFormat = errors.New("format must be csv or xlsx")
+
+// Formats are the formats New writes.
+var Formats = []string{"csv", "xlsx"}
+
+var contentTypes = map[string]string{
+	"csv":  "text/csv; charset=utf-8",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// New returns a Writer of the format to w, sheet names the table in formats
+// that name it.
+func New(format string, w io.Writer, sheet string) (Writer, error) {
+	switch format {
+	case "csv":
+		return NewCSV(w), nil
+	case "xlsx":
+		return NewXLSX(w, sheet), nil
+	}
+	return nil, ErrFormat
+}
+
+// ContentType returns the content type of the format.
+func ContentType(format string) string {
+	if contentType, ok := contentTypes[format]; ok {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// cell is a cell value reduced to a string, a float64, a bool or nil.
+func cell(value interface{}) interface{} {
+	switch value := value.(type) {
+	case nil, string, float64, bool:
+		return value
+	case int:
+		return float64(value)
+	case int64:
+		return float64(value)
+	case uint:
+		return float64(value)
+	case uint64:
+		return float64(value)
+	case time.Time:
+		if value.IsZero() {
+			return nil
+		}
+		return value.UTC().Format(time.RFC3339)
+	case *string:
+		if value == nil {
+			return nil
+		}
+		return *value
+	case *int:
+		if value == nil {
+			return nil
+		}
+		return float64(*value)
+	case *uint:
+		if value == nil {
+			return nil
+		}
+		return float64(*value)
+	case *bool:
+		if value == nil {
+			return nil
+		}
+		return *value
+	case *time.Time:
+		if value == nil {
+			return nil
+		}
+		return cell(*value)
+	}
+	return fmt.Sprint(value)
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,180 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// The parts of a workbook with a single sheet. The sheet is the only part
+// that depends on the rows, its cells hold their text inline, so there is no
+// shared strings table to collect before the end.
+const (
+	xlsxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`</Types>`
+	xlsxRelationships = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	xlsxWorkbookRelationships = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`</Relationships>`
+	// the second cell format makes the header bold
+	xlsxStyles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+		`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+		`</styleSheet>`
+	// the header row stays in view while scrolling
+	xlsxSheetStart = xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>` +
+		`<sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+// xlsxMaxText bounds the text of a cell, a cell holds 32767 characters.
+const xlsxMaxText = 32767
+
+type xlsxWriter struct {
+	archive *zip.Writer
+	sheet   io.Writer
+	name    string
+	rows    int
+	// text is the buffer a row is built in.
+	text strings.Builder
+}
+
+// NewXLSX writes an Office Open XML workbook of one sheet with the given
+// name, which is at most 31 characters and has none of []:*?/\. The workbook
+// is a zip file, it can not be read before Close.
+func NewXLSX(w io.Writer, sheet string) Writer {
+	return &xlsxWriter{archive: zip.NewWriter(w), name: sheet}
+}
+
+func (w *xlsxWriter) WriteRow(cells ...interface{}) error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	w.rows++
+	w.text.Reset()
+	row := strconv.Itoa(w.rows)
+	w.text.WriteString(`<row r="` + row + `">`)
+	for i, value := range cells {
+		reference := columnName(i) + row
+		style := ""
+		if w.rows == 1 {
+			style = ` s="1"`
+		}
+		switch value := cell(value).(type) {
+		case string:
+			value = truncate(value, xlsxMaxText)
+			w.text.WriteString(`<c r="` + reference + `"` + style + ` t="inlineStr"><is><t xml:space="preserve">`)
+			xml.EscapeText(&w.text, []byte(value))
+			w.text.WriteString(`</t></is></c>`)
+		case float64:
+			w.text.WriteString(`<c r="` + reference + `"` + style + `><v>` + formatNumber(value) + `</v></c>`)
+		case bool:
+			v := "0"
+			if value {
+				v = "1"
+			}
+			w.text.WriteString(`<c r="` + reference + `"` + style + ` t="b"><v>` + v + `</v></c>`)
+		}
+	}
+	w.text.WriteString(`</row>`)
+	_, err := io.WriteString(w.sheet, w.text.String())
+	return err
+}
+
+// start writes the parts around the sheet and opens it, at the first row.
+func (w *xlsxWriter) start() error {
+	if w.sheet != nil {
+		return nil
+	}
+	var workbook strings.Builder
+	workbook.WriteString(xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="`)
+	xml.EscapeText(&workbook, []byte(w.name))
+	workbook.WriteString(`" sheetId="1" r:id="rId1"/></sheets></workbook>`)
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRelationships},
+		{"xl/workbook.xml", workbook.String()},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRelationships},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, part := range parts {
+		if err := w.writePart(part.name, part.content); err != nil {
+			return err
+		}
+	}
+	sheet, err := w.create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	w.sheet = sheet
+	_, err = io.WriteString(w.sheet, xlsxSheetStart)
+	return err
+}
+
+func (w *xlsxWriter) writePart(name string, content string) error {
+	part, err := w.create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(part, content)
+	return err
+}
+
+func (w *xlsxWriter) create(name string) (io.Writer, error) {
+	return w.archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+}
+
+func (w *xlsxWriter) Close() error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w.sheet, xlsxSheetEnd); err != nil {
+		return err
+	}
+	return w.archive.Close()
+}
+
+// truncate cuts text longer than max bytes at a character boundary.
+func truncate(text string, max int) string {
+	if len(text) <= max {
+		return text
+	}
+	for max > 0 && !utf8.RuneStart(text[max]) {
+		max--
+	}
+	return text[:max]
+}
+
+// columnName is the letter name of the column at index i, A to Z, AA and on.
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+)
+
+// memoryStorage keeps the files in process, they are gone with it like the
+// rest of the memory database.
+type memoryStorage struct {
+	mu    sync.RWMutex
+	files map[string][]byte
+}
+
+func (s *memoryStorage) Put(ctx context.Context, key string, content []byte) error {
+	if !ValidKey(key) {
+		return ErrInvalidKey
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[key] = append([]byte{}, content...)
+	return nil
+}
+
+func (s *memoryStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	content, ok := s.files[key]
+	if !ok {
+		return nil, ErrNotExist
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (s *memoryStorage) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.files, key)
+	return nil
+}
+
+func NewMemory() Storage {
+	return &memoryStorage{files: map[string][]byte{}}
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// Storage keeps files by key, a slash separated path like "id/small.jpg".
+// Implementations are safe for concurrent use, so an object store like s3 can
+// take the place of the local filesystem.
+type Storage interface {
+	Put(ctx context.Context, key string, content []byte) error
+	// Open returns the content of key, ErrNotExist once it is missing.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes key, a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+var (
+	ErrNotExist   = fs.ErrNotExist
+	ErrInvalidKey = errors.New("storage key is not valid")
+)
+
+// ValidKey reports whether every part of key is a plain file name, so a key
+// never leaves the storage.
+func ValidKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+		for _, r := range part {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -1,38 +1,36 @@
 package utils
 
 import (
+	"context"
 	"math/rand"
 	"time"
 
 	"github.com/gosimple/slug"
-	"github.com/sajalmia381/store-api/src/v1/db"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 var smallChars = "abcdefghijklmnopqrstuvwxyz"
 
-func GenerateUniqueSlug(title string, collectionName string, skip_slugs ...string) string {
-	newSlug := slug.MakeLang(title, "en")
-	collection := db.GetDmManager().DB.Collection(collectionName)
-	for {
-		result := collection.FindOne(db.GetDmManager().Ctx, bson.M{"slug": newSlug})
-		if err := result.Err(); err != nil {
-			if err == mongo.ErrNoDocuments {
-				break
-			}
-		} else {
-			for _, s := range skip_slugs {
-				if s == newSlug {
-					return newSlug
-				}
+// GenerateUniqueSlug makes a slug from title and keeps adding a random suffix
+// while isExists reports it as taken. isExists is backed by the repository of
+// the collection, so the same logic works for every database.
+func GenerateUniqueSlug(ctx context.Context, title string, isExists func(ctx context.Context, slug string) bool, skip_slugs ...string) string {
+	newSlug := GenerateSlug(title)
+	for isExists(ctx, newSlug) {
+		for _, s := range skip_slugs {
+			if s == newSlug {
+				return newSlug
 			}
-			newSlug = newSlug + "-" + GenerateRandomString(5, &smallChars)
 		}
+		newSlug = newSlug + "-" + GenerateRandomString(5, &smallChars)
 	}
 	return newSlug
 }
 
+// GenerateSlug makes the slug of title without checking if it is taken.
+func GenerateSlug(title string) string {
+	return slug.MakeLang(title, "en")
+}
+
 func GenerateFakeUniqueSlug(title string, withPrefix bool, skip_slugs ...string) string {
 	// For Fake generator
 	newSlug := slug.MakeLang(title, "en")
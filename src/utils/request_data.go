@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
@@ -33,3 +34,24 @@ func IsSuperAdmin(c echo.Context) bool {
 	// log.Println("Utils Requester file! Is Super admin:", err == nil && requesterData.Role == string(enums.ROLE_SUPER_ADMIN))
 	return err == nil && requesterData.Role == string(enums.ROLE_SUPER_ADMIN)
 }
+
+// IsAdmin reports whether the requester is an admin or the super admin.
+func IsAdmin(c echo.Context) bool {
+	requesterData, err := GetRequestData(c)
+	return err == nil && (requesterData.Role == string(enums.ROLE_ADMIN) || requesterData.Role == string(enums.ROLE_SUPER_ADMIN))
+}
+
+type requesterKey struct{}
+
+// WithRequester keeps the jwt payload in ctx, services only receive the
+// request context.
+func WithRequester(ctx context.Context, requester dtos.JwtPayload) context.Context {
+	return context.WithValue(ctx, requesterKey{}, requester)
+}
+
+// GetRequester returns the payload stored by WithRequester, false for anonymous
+// requests and background jobs.
+func GetRequester(ctx context.Context) (dtos.JwtPayload, bool) {
+	requester, ok := ctx.Value(requesterKey{}).(dtos.JwtPayload)
+	return requester, ok
+}
@@ -0,0 +1,8 @@
+package api
+
+import "github.com/labstack/echo/v4"
+
+type ArchiveApi interface {
+	Export(c echo.Context) error
+	Import(c echo.Context) error
+}
@@ -7,6 +7,7 @@ type CartApi interface {
 	FindAll(c echo.Context) error
 	// FindById(c echo.Context) error // TODO: Implement
 	FindByUserId(c echo.Context) error
+	History(c echo.Context) error
 	// DeleteById(c echo.Context) error // TODO: Implement
 
 	// Requester Cart!!! By default all requester request as anonymous@gmail.com user
@@ -5,7 +5,14 @@ import "github.com/labstack/echo/v4"
 type CategoryApi interface {
 	Store(c echo.Context) error
 	FindAll(c echo.Context) error
+	Export(c echo.Context) error
 	FindBySlug(c echo.Context) error
+	FindSchema(c echo.Context) error
 	UpdateBySlug(c echo.Context) error
 	DeleteBySlug(c echo.Context) error
+	ReconcileProducts(c echo.Context) error
+	FindTrash(c echo.Context) error
+	RestoreBySlug(c echo.Context) error
+	PurgeBySlug(c echo.Context) error
+	History(c echo.Context) error
 }
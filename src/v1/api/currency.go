@@ -0,0 +1,9 @@
+package api
+
+import "github.com/labstack/echo/v4"
+
+type CurrencyApi interface {
+	FindAll(c echo.Context) error
+	StoreRate(c echo.Context) error
+	DeleteRate(c echo.Context) error
+}
@@ -0,0 +1,7 @@
+package api
+
+import "github.com/labstack/echo/v4"
+
+type ImageApi interface {
+	Serve(c echo.Context) error
+}
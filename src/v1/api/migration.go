@@ -0,0 +1,8 @@
+package api
+
+import "github.com/labstack/echo/v4"
+
+type MigrationApi interface {
+	FindAll(c echo.Context) error
+	Migrate(c echo.Context) error
+}
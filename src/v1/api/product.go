@@ -4,8 +4,25 @@ import "github.com/labstack/echo/v4"
 
 type ProductApi interface {
 	Store(c echo.Context) error
+	Import(c echo.Context) error
+	FindImportJob(c echo.Context) error
 	FindAll(c echo.Context) error
+	Export(c echo.Context) error
 	FindBySlug(c echo.Context) error
 	UpdateBySlug(c echo.Context) error
 	DeleteBySlug(c echo.Context) error
+	FindTrash(c echo.Context) error
+	RestoreBySlug(c echo.Context) error
+	PurgeBySlug(c echo.Context) error
+	History(c echo.Context) error
+	FindVariants(c echo.Context) error
+	StoreVariant(c echo.Context) error
+	UpdateVariant(c echo.Context) error
+	DeleteVariant(c echo.Context) error
+	AdjustStock(c echo.Context) error
+	StockHistory(c echo.Context) error
+	FindImages(c echo.Context) error
+	StoreImages(c echo.Context) error
+	OrderImages(c echo.Context) error
+	DeleteImage(c echo.Context) error
 }
@@ -0,0 +1,13 @@
+package api
+
+import "github.com/labstack/echo/v4"
+
+type ReviewApi interface {
+	Store(c echo.Context) error
+	FindByProduct(c echo.Context) error
+	FindAll(c echo.Context) error
+	FindById(c echo.Context) error
+	UpdateById(c echo.Context) error
+	Moderate(c echo.Context) error
+	DeleteById(c echo.Context) error
+}
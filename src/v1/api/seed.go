@@ -0,0 +1,7 @@
+package api
+
+import "github.com/labstack/echo/v4"
+
+type SeedApi interface {
+	Seed(c echo.Context) error
+}
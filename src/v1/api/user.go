@@ -5,7 +5,12 @@ import "github.com/labstack/echo/v4"
 type User interface {
 	Store(c echo.Context) error
 	FindAll(c echo.Context) error
+	Export(c echo.Context) error
 	FindById(c echo.Context) error
 	UpdateById(c echo.Context) error
 	DeleteById(c echo.Context) error
+	FindTrash(c echo.Context) error
+	RestoreById(c echo.Context) error
+	PurgeById(c echo.Context) error
+	History(c echo.Context) error
 }
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+)
+
+var errDatabaseNotConnected = errors.New("database is not connected")
+
+// HealthChecker pings the database and reports whether it is reachable and how
+// long the round trip took.
+type HealthChecker interface {
+	Health(ctx context.Context) dtos.DatabaseHealthDto
+}
+
+func checkHealth(ctx context.Context, database enums.DatabaseType, ping func(ctx context.Context) error) dtos.DatabaseHealthDto {
+	health := dtos.DatabaseHealthDto{
+		Type:      database,
+		State:     enums.CONNECTED,
+		Reachable: true,
+		CheckedAt: time.Now().UTC(),
+	}
+	err := ping(ctx)
+	health.LatencyMs = float64(time.Since(health.CheckedAt).Microseconds()) / 1000
+	if err != nil {
+		health.State = enums.DISCONNECTED
+		health.Reachable = false
+		health.Error = err.Error()
+	}
+	return health
+}
+
+// Health of mongo reports the state of the watcher next to a fresh ping.
+func (dm *DmManager) Health(ctx context.Context) dtos.DatabaseHealthDto {
+	health := checkHealth(ctx, enums.MONGO, dm.ping)
+	health.State = dm.State()
+	return health
+}
+
+func (sm *SqlManager) Health(ctx context.Context) dtos.DatabaseHealthDto {
+	return checkHealth(ctx, sm.Dialect, func(ctx context.Context) error {
+		if sm.DB == nil {
+			return errDatabaseNotConnected
+		}
+		ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+		defer cancel()
+		return sm.DB.PingContext(ctx)
+	})
+}
+
+// Health of the memory database is always reachable.
+func (mm *MemoryManager) Health(ctx context.Context) dtos.DatabaseHealthDto {
+	return checkHealth(ctx, enums.MEMORY, func(context.Context) error { return nil })
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"log"
+	"sync"
+
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryManager keeps every collection in process memory. It is used instead of
+// DmManager when DATABASE is MEMORY, so the api can run without any MongoDB.
+type MemoryManager struct {
+	sync.RWMutex
+	Users      map[primitive.ObjectID]model.User
+	Categories map[primitive.ObjectID]model.Category
+	Products   map[primitive.ObjectID]model.Product
+	Reviews    map[primitive.ObjectID]model.Review
+	Carts      map[primitive.ObjectID]model.Cart
+	Tokens     map[primitive.ObjectID]model.Token
+	// ExchangeRates are keyed by currency
+	ExchangeRates map[string]model.ExchangeRate
+	// AuditLogs is append only, in the order of the changes
+	AuditLogs []model.AuditLog
+	// StockAdjustments is append only as well
+	StockAdjustments []model.StockAdjustment
+}
+
+var singletonMemoryManager *MemoryManager
+var onceMemoryManager sync.Once
+
+func GetMemoryManager() *MemoryManager {
+	onceMemoryManager.Do(func() {
+		singletonMemoryManager = &MemoryManager{
+			Users:         map[primitive.ObjectID]model.User{},
+			Categories:    map[primitive.ObjectID]model.Category{},
+			Products:      map[primitive.ObjectID]model.Product{},
+			Reviews:       map[primitive.ObjectID]model.Review{},
+			Carts:         map[primitive.ObjectID]model.Cart{},
+			Tokens:        map[primitive.ObjectID]model.Token{},
+			ExchangeRates: map[string]model.ExchangeRate{},
+		}
+		log.Println("[INFO] Initialized Singleton Memory Manager")
+	})
+	return singletonMemoryManager
+}
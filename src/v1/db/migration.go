@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+)
+
+// Migrator applies the ordered, versioned schema migrations of a database and
+// reports which of them are applied. Every applied version is recorded, so a
+// migration runs exactly once per database.
+type Migrator interface {
+	Migrate(ctx context.Context) ([]dtos.MigrationStatusDto, error)
+	MigrationStatus(ctx context.Context) ([]dtos.MigrationStatusDto, error)
+}
+
+// migrationStatus merges the known migrations with the applied records.
+func migrationStatus(versions []uint, descriptions []string, applied map[uint]model.Migration) []dtos.MigrationStatusDto {
+	statuses := []dtos.MigrationStatusDto{}
+	for i, version := range versions {
+		status := dtos.MigrationStatusDto{
+			Version:     version,
+			Description: descriptions[i],
+		}
+		if record, ok := applied[version]; ok {
+			appliedAt := record.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// Migrate and MigrationStatus keep the memory database a valid Migrator, it has
+// no schema to migrate.
+
+func (mm *MemoryManager) Migrate(ctx context.Context) ([]dtos.MigrationStatusDto, error) {
+	return []dtos.MigrationStatusDto{}, nil
+}
+
+func (mm *MemoryManager) MigrationStatus(ctx context.Context) ([]dtos.MigrationStatusDto, error) {
+	return []dtos.MigrationStatusDto{}, nil
+}
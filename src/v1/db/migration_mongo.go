@@ -0,0 +1,394 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/config"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/money"
+	"github.com/sajalmia381/store-api/src/search"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoMigration struct {
+	Version     uint
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+}
+
+// mongoMigrations must only be appended to. Up functions have to be safe to run
+// against databases created before migrations existed, the first ones recreate
+// what initializeConnection used to create by hand.
+var mongoMigrations = []MongoMigration{
+	{
+		Version:     1,
+		Description: "create collections",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			collectionNames, err := db.ListCollectionNames(ctx, bson.D{})
+			if err != nil {
+				return err
+			}
+			existing := map[string]bool{}
+			for _, name := range collectionNames {
+				existing[name] = true
+			}
+			for _, name := range enums.COLLECTION_NAMES {
+				if existing[name] {
+					continue
+				}
+				if err := db.CreateCollection(ctx, name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     2,
+		Description: "unique email index on users",
+		Up:          createUniqueIndex(enums.USER_COLLECTION_NAME, "email"),
+	},
+	{
+		Version:     3,
+		Description: "unique userId index on carts",
+		Up:          createUniqueIndex(enums.CART_COLLECTION_NAME, "userId"),
+	},
+	{
+		Version:     4,
+		Description: "unique slug index on products",
+		Up:          createUniqueIndex(enums.PRODUCT_COLLECTION_NAME, "slug"),
+	},
+	{
+		Version:     5,
+		Description: "unique slug index on categories",
+		Up:          createUniqueIndex(enums.CATEGORY_COLLECTION_NAME, "slug"),
+	},
+	{
+		Version:     6,
+		Description: "unique token index on tokens",
+		Up:          createUniqueIndex(enums.TOKEN_COLLECTION_NAME, "token"),
+	},
+	{
+		Version:     7,
+		Description: "clear duplicated user numbers, the oldest user keeps the number",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			coll := db.Collection(string(enums.USER_COLLECTION_NAME))
+			pipeline := mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{"number": bson.M{"$type": "number"}}}},
+				{{Key: "$sort", Value: bson.M{"createdAt": 1}}},
+				{{Key: "$group", Value: bson.M{
+					"_id":   "$number",
+					"ids":   bson.M{"$push": "$_id"},
+					"count": bson.M{"$sum": 1},
+				}}},
+				{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+			}
+			cursor, err := coll.Aggregate(ctx, pipeline)
+			if err != nil {
+				return err
+			}
+			var duplicates []struct {
+				Ids []interface{} `bson:"ids"`
+			}
+			if err := cursor.All(ctx, &duplicates); err != nil {
+				return err
+			}
+			for _, duplicate := range duplicates {
+				filter := bson.M{"_id": bson.M{"$in": duplicate.Ids[1:]}}
+				if _, err := coll.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"number": nil}}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     8,
+		Description: "unique number index on users",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			indexModel := mongo.IndexModel{
+				Keys: bson.D{{Key: "number", Value: 1}},
+				Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+					"number": bson.M{"$type": "number"},
+				}),
+			}
+			_, err := db.Collection(string(enums.USER_COLLECTION_NAME)).Indexes().CreateOne(ctx, indexModel)
+			return err
+		},
+	},
+	{
+		Version:     9,
+		Description: "product validator",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			validator := bson.M{"$jsonSchema": bson.M{
+				"bsonType": "object",
+				"required": bson.A{"title", "slug", "price"},
+				"properties": bson.M{
+					"title": bson.M{"bsonType": "string"},
+					"slug":  bson.M{"bsonType": "string"},
+					"price": bson.M{"bsonType": bson.A{"int", "long", "double", "decimal"}},
+				},
+			}}
+			command := bson.D{
+				{Key: "collMod", Value: string(enums.PRODUCT_COLLECTION_NAME)},
+				{Key: "validator", Value: validator},
+				// Existing invalid documents stay editable
+				{Key: "validationLevel", Value: "moderate"},
+			}
+			return db.RunCommand(ctx, command).Err()
+		},
+	},
+	{
+		Version:     10,
+		Description: "audit log indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			indexModels := []mongo.IndexModel{
+				{Keys: bson.D{{Key: "entity", Value: 1}, {Key: "entityId", Value: 1}, {Key: "createdAt", Value: -1}}},
+				{Keys: bson.D{{Key: "createdAt", Value: -1}}},
+			}
+			_, err := db.Collection(string(enums.AUDIT_COLLECTION_NAME)).Indexes().CreateMany(ctx, indexModels)
+			return err
+		},
+	},
+	{
+		Version:     11,
+		Description: "version of users, categories and products",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			collections := []enums.CollectionName{enums.USER_COLLECTION_NAME, enums.CATEGORY_COLLECTION_NAME, enums.PRODUCT_COLLECTION_NAME}
+			for _, name := range collections {
+				filter := bson.M{"version": bson.M{"$exists": false}}
+				if _, err := db.Collection(string(name)).UpdateMany(ctx, filter, bson.M{"$set": bson.M{"version": int64(0)}}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     12,
+		Description: "createdAt index of products for keyset pagination",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			indexModel := mongo.IndexModel{
+				Keys: bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}},
+			}
+			_, err := db.Collection(string(enums.PRODUCT_COLLECTION_NAME)).Indexes().CreateOne(ctx, indexModel)
+			return err
+		},
+	},
+	{
+		Version:     13,
+		Description: "price and category indexes of products for the list filters",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			indexModels := []mongo.IndexModel{
+				{Keys: bson.D{{Key: "price", Value: 1}, {Key: "_id", Value: 1}}},
+				{Keys: bson.D{{Key: "category", Value: 1}}},
+			}
+			_, err := db.Collection(string(enums.PRODUCT_COLLECTION_NAME)).Indexes().CreateMany(ctx, indexModels)
+			return err
+		},
+	},
+	{
+		Version:     14,
+		Description: "weighted text index of products for the text search",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			weights := bson.D{{Key: "title", Value: search.TitleWeight}, {Key: "description", Value: search.DescriptionWeight}}
+			indexModel := mongo.IndexModel{
+				Keys:    bson.D{{Key: "title", Value: "text"}, {Key: "description", Value: "text"}},
+				Options: options.Index().SetWeights(weights),
+			}
+			_, err := db.Collection(string(enums.PRODUCT_COLLECTION_NAME)).Indexes().CreateOne(ctx, indexModel)
+			return err
+		},
+	},
+	{
+		Version:     15,
+		Description: "variants of products, unique variant sku",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			coll := db.Collection(string(enums.PRODUCT_COLLECTION_NAME))
+			filter := bson.M{"variants": bson.M{"$exists": false}}
+			if _, err := coll.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"variants": bson.A{}}}); err != nil {
+				return err
+			}
+			// products without variants have no sku to collide
+			indexModel := mongo.IndexModel{
+				Keys: bson.D{{Key: "variants.sku", Value: 1}},
+				Options: options.Index().SetUnique(true).
+					SetPartialFilterExpression(bson.M{"variants.sku": bson.M{"$exists": true}}),
+			}
+			_, err := coll.Indexes().CreateOne(ctx, indexModel)
+			return err
+		},
+	},
+	{
+		Version:     16,
+		Description: "stock adjustment index, reservation index of carts",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			indexModel := mongo.IndexModel{
+				Keys: bson.D{{Key: "productId", Value: 1}, {Key: "createdAt", Value: -1}},
+			}
+			if _, err := db.Collection(string(enums.STOCK_ADJUSTMENT_COLLECTION_NAME)).Indexes().CreateOne(ctx, indexModel); err != nil {
+				return err
+			}
+			indexModel = mongo.IndexModel{
+				Keys: bson.D{{Key: "products.reservedUntil", Value: 1}},
+			}
+			_, err := db.Collection(string(enums.CART_COLLECTION_NAME)).Indexes().CreateOne(ctx, indexModel)
+			return err
+		},
+	},
+	{
+		Version:     17,
+		Description: "images of products",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			filter := bson.M{"images": bson.M{"$exists": false}}
+			_, err := db.Collection(string(enums.PRODUCT_COLLECTION_NAME)).UpdateMany(ctx, filter, bson.M{"$set": bson.M{"images": bson.A{}}})
+			return err
+		},
+	},
+	{
+		Version:     18,
+		Description: "reviews of products, rating of products",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// an author reviews a product once
+			indexModels := []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "productId", Value: 1}, {Key: "authorId", Value: 1}},
+					Options: options.Index().SetUnique(true),
+				},
+				{Keys: bson.D{{Key: "status", Value: 1}, {Key: "createdAt", Value: 1}}},
+			}
+			if _, err := db.Collection(string(enums.REVIEW_COLLECTION_NAME)).Indexes().CreateMany(ctx, indexModels); err != nil {
+				return err
+			}
+			coll := db.Collection(string(enums.PRODUCT_COLLECTION_NAME))
+			filter := bson.M{"rating": bson.M{"$exists": false}}
+			if _, err := coll.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"rating": 0.0, "reviewCount": 0}}); err != nil {
+				return err
+			}
+			indexModels = []mongo.IndexModel{
+				{Keys: bson.D{{Key: "rating", Value: 1}, {Key: "_id", Value: 1}}},
+				{Keys: bson.D{{Key: "reviewCount", Value: 1}, {Key: "_id", Value: 1}}},
+			}
+			_, err := coll.Indexes().CreateMany(ctx, indexModels)
+			return err
+		},
+	},
+	{
+		Version:     19,
+		Description: "attribute schemas of categories, attributes of products",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			categories := db.Collection(string(enums.CATEGORY_COLLECTION_NAME))
+			filter := bson.M{"attributes": bson.M{"$exists": false}}
+			if _, err := categories.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"attributes": bson.A{}}}); err != nil {
+				return err
+			}
+			products := db.Collection(string(enums.PRODUCT_COLLECTION_NAME))
+			if _, err := products.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"attributes": bson.M{}}}); err != nil {
+				return err
+			}
+			// the attribute names are up to the categories, a wildcard index
+			// covers the filters on any of them
+			indexModel := mongo.IndexModel{Keys: bson.D{{Key: "attributes.$**", Value: 1}}}
+			_, err := products.Indexes().CreateOne(ctx, indexModel)
+			return err
+		},
+	},
+	{
+		Version:     20,
+		Description: "prices in minor units of the base currency",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// prices were whole units of the base currency so far, a missing
+			// variant price stays null
+			scale := money.Scale(config.BaseCurrency)
+			update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+				"price": bson.M{"$multiply": bson.A{"$price", scale}},
+				"variants": bson.M{"$map": bson.M{
+					"input": bson.M{"$ifNull": bson.A{"$variants", bson.A{}}},
+					"as":    "variant",
+					"in": bson.M{"$mergeObjects": bson.A{"$$variant", bson.M{
+						"price": bson.M{"$multiply": bson.A{"$$variant.price", scale}},
+					}}},
+				}},
+			}}}}
+			_, err := db.Collection(string(enums.PRODUCT_COLLECTION_NAME)).UpdateMany(ctx, bson.D{}, update)
+			return err
+		},
+	},
+}
+
+func createUniqueIndex(collectionName enums.CollectionName, key string) func(ctx context.Context, db *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		indexModel := mongo.IndexModel{
+			Keys:    bson.D{{Key: key, Value: 1}},
+			Options: options.Index().SetUnique(true),
+		}
+		_, err := db.Collection(string(collectionName)).Indexes().CreateOne(ctx, indexModel)
+		return err
+	}
+}
+
+func (dm *DmManager) Migrate(ctx context.Context) ([]dtos.MigrationStatusDto, error) {
+	applied, err := dm.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	coll := dm.Collection(string(enums.MIGRATION_COLLECTION_NAME))
+	for _, migration := range mongoMigrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		log.Printf("[INFO] Applying migration %d: %s", migration.Version, migration.Description)
+		if err := migration.Up(ctx, dm.Database()); err != nil {
+			return nil, fmt.Errorf("migration %d failed: %w", migration.Version, err)
+		}
+		record := model.Migration{
+			Version:     migration.Version,
+			Description: migration.Description,
+			AppliedAt:   time.Now().UTC(),
+		}
+		filter := bson.M{"version": migration.Version}
+		opts := options.Replace().SetUpsert(true)
+		if _, err := coll.ReplaceOne(ctx, filter, record, opts); err != nil {
+			return nil, err
+		}
+	}
+	return dm.MigrationStatus(ctx)
+}
+
+func (dm *DmManager) MigrationStatus(ctx context.Context) ([]dtos.MigrationStatusDto, error) {
+	applied, err := dm.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	versions := []uint{}
+	descriptions := []string{}
+	for _, migration := range mongoMigrations {
+		versions = append(versions, migration.Version)
+		descriptions = append(descriptions, migration.Description)
+	}
+	return migrationStatus(versions, descriptions, applied), nil
+}
+
+func (dm *DmManager) appliedMigrations(ctx context.Context) (map[uint]model.Migration, error) {
+	applied := map[uint]model.Migration{}
+	coll := dm.Collection(string(enums.MIGRATION_COLLECTION_NAME))
+	cursor, err := coll.Find(ctx, bson.D{})
+	if err != nil {
+		return applied, err
+	}
+	var records []model.Migration
+	if err := cursor.All(ctx, &records); err != nil {
+		return applied, err
+	}
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
@@ -0,0 +1,389 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/config"
+	"github.com/sajalmia381/store-api/src/money"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+)
+
+type SqlMigration struct {
+	Version     uint
+	Description string
+	Statements  []string
+	// Up runs after the statements, for the steps that depend on the
+	// configuration
+	Up func(ctx context.Context, tx *sql.Tx) error
+}
+
+// sqlMigrations must only be appended to. Statements are portable between
+// SQLite and PostgreSQL. Ids are the hex form of mongo ObjectIDs so documents
+// can move between databases unchanged.
+var sqlMigrations = []SqlMigration{
+	{
+		Version:     1,
+		Description: "create tables",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS users (
+				id VARCHAR(24) PRIMARY KEY,
+				name TEXT NOT NULL,
+				email VARCHAR(255) NOT NULL UNIQUE,
+				password TEXT NOT NULL,
+				number BIGINT NULL,
+				status BOOLEAN NOT NULL,
+				role VARCHAR(32) NOT NULL,
+				last_login_at TIMESTAMP NULL,
+				created_at TIMESTAMP NOT NULL,
+				updated_at TIMESTAMP NOT NULL
+			)`,
+			`CREATE TABLE IF NOT EXISTS categories (
+				id VARCHAR(24) PRIMARY KEY,
+				parent_id VARCHAR(24) NULL,
+				name TEXT NOT NULL,
+				slug VARCHAR(255) NOT NULL UNIQUE,
+				description TEXT NOT NULL
+			)`,
+			`CREATE TABLE IF NOT EXISTS category_products (
+				category_id VARCHAR(24) NOT NULL,
+				product_id VARCHAR(24) NOT NULL,
+				position BIGINT NOT NULL,
+				PRIMARY KEY (category_id, product_id)
+			)`,
+			`CREATE TABLE IF NOT EXISTS products (
+				id VARCHAR(24) PRIMARY KEY,
+				created_by VARCHAR(255) NOT NULL,
+				category_id VARCHAR(24) NULL,
+				image_source VARCHAR(24) NULL,
+				title TEXT NOT NULL,
+				slug VARCHAR(255) NOT NULL UNIQUE,
+				price BIGINT NOT NULL,
+				image TEXT NOT NULL,
+				description TEXT NOT NULL,
+				created_at TIMESTAMP NOT NULL,
+				updated_at TIMESTAMP NOT NULL,
+				active BOOLEAN NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS products_category_id_idx ON products (category_id)`,
+			`CREATE TABLE IF NOT EXISTS carts (
+				id VARCHAR(24) PRIMARY KEY,
+				user_id VARCHAR(24) NOT NULL UNIQUE,
+				created_at TIMESTAMP NOT NULL,
+				updated_at TIMESTAMP NOT NULL
+			)`,
+			`CREATE TABLE IF NOT EXISTS cart_products (
+				cart_id VARCHAR(24) NOT NULL,
+				product_id VARCHAR(24) NOT NULL,
+				quantity INTEGER NOT NULL,
+				position BIGINT NOT NULL,
+				PRIMARY KEY (cart_id, product_id)
+			)`,
+			`CREATE TABLE IF NOT EXISTS tokens (
+				id VARCHAR(24) PRIMARY KEY,
+				user_id VARCHAR(24) NULL,
+				token TEXT NOT NULL UNIQUE,
+				type VARCHAR(32) NOT NULL,
+				created_at TIMESTAMP NOT NULL
+			)`,
+		},
+	},
+	{
+		Version:     2,
+		Description: "clear duplicated user numbers, the oldest user keeps the number",
+		Statements: []string{
+			`UPDATE users SET number = NULL WHERE number IS NOT NULL AND EXISTS (
+				SELECT 1 FROM users AS other
+				WHERE other.number = users.number
+				AND (other.created_at < users.created_at OR (other.created_at = users.created_at AND other.id < users.id))
+			)`,
+		},
+	},
+	{
+		Version:     3,
+		Description: "unique number index on users",
+		Statements: []string{
+			`CREATE UNIQUE INDEX IF NOT EXISTS users_number_idx ON users (number)`,
+		},
+	},
+	{
+		Version:     4,
+		Description: "soft delete of users, categories and products",
+		Statements: []string{
+			`ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP NULL`,
+			`ALTER TABLE categories ADD COLUMN deleted_at TIMESTAMP NULL`,
+			`ALTER TABLE products ADD COLUMN deleted_at TIMESTAMP NULL`,
+			`CREATE INDEX IF NOT EXISTS users_deleted_at_idx ON users (deleted_at)`,
+			`CREATE INDEX IF NOT EXISTS categories_deleted_at_idx ON categories (deleted_at)`,
+			`CREATE INDEX IF NOT EXISTS products_deleted_at_idx ON products (deleted_at)`,
+		},
+	},
+	{
+		Version:     5,
+		Description: "audit logs",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS audit_logs (
+				id VARCHAR(24) PRIMARY KEY,
+				entity VARCHAR(32) NOT NULL,
+				entity_id VARCHAR(24) NOT NULL,
+				action VARCHAR(32) NOT NULL,
+				actor_id VARCHAR(24) NULL,
+				actor_name TEXT NULL,
+				actor_email VARCHAR(255) NULL,
+				actor_role VARCHAR(32) NULL,
+				changes TEXT NOT NULL,
+				created_at TIMESTAMP NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS audit_logs_entity_idx ON audit_logs (entity, entity_id, created_at)`,
+			`CREATE INDEX IF NOT EXISTS audit_logs_created_at_idx ON audit_logs (created_at)`,
+		},
+	},
+	{
+		Version:     6,
+		Description: "version of users, categories and products",
+		Statements: []string{
+			`ALTER TABLE users ADD COLUMN version BIGINT NOT NULL DEFAULT 0`,
+			`ALTER TABLE categories ADD COLUMN version BIGINT NOT NULL DEFAULT 0`,
+			`ALTER TABLE products ADD COLUMN version BIGINT NOT NULL DEFAULT 0`,
+		},
+	},
+	{
+		Version:     7,
+		Description: "created_at index of products for keyset pagination",
+		Statements: []string{
+			`CREATE INDEX IF NOT EXISTS products_created_at_idx ON products (created_at, id)`,
+		},
+	},
+	{
+		Version:     8,
+		Description: "price index of products for the list filters",
+		Statements: []string{
+			`CREATE INDEX IF NOT EXISTS products_price_idx ON products (price, id)`,
+		},
+	},
+	{
+		Version:     9,
+		Description: "variants of products, cart lines of variants",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS product_variants (
+				id VARCHAR(24) PRIMARY KEY,
+				product_id VARCHAR(24) NOT NULL,
+				sku VARCHAR(255) NOT NULL UNIQUE,
+				price BIGINT NULL,
+				attributes TEXT NOT NULL,
+				active BOOLEAN NOT NULL,
+				position BIGINT NOT NULL,
+				created_at TIMESTAMP NOT NULL,
+				updated_at TIMESTAMP NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS product_variants_product_id_idx ON product_variants (product_id, position)`,
+			// the variant joins the primary key, an empty variant_id is a line
+			// of the product itself
+			`CREATE TABLE cart_products_v2 (
+				cart_id VARCHAR(24) NOT NULL,
+				product_id VARCHAR(24) NOT NULL,
+				variant_id VARCHAR(24) NOT NULL DEFAULT '',
+				quantity INTEGER NOT NULL,
+				position BIGINT NOT NULL,
+				PRIMARY KEY (cart_id, product_id, variant_id)
+			)`,
+			`INSERT INTO cart_products_v2 (cart_id, product_id, quantity, position)
+				SELECT cart_id, product_id, quantity, position FROM cart_products`,
+			`DROP TABLE cart_products`,
+			`ALTER TABLE cart_products_v2 RENAME TO cart_products`,
+		},
+	},
+	{
+		Version:     10,
+		Description: "stock of products, reservations of cart lines, stock adjustments",
+		Statements: []string{
+			`ALTER TABLE products ADD COLUMN stock BIGINT NULL`,
+			`ALTER TABLE products ADD COLUMN available BIGINT NULL`,
+			`ALTER TABLE cart_products ADD COLUMN reserved_until TIMESTAMP NULL`,
+			`CREATE INDEX IF NOT EXISTS cart_products_reserved_until_idx ON cart_products (reserved_until)`,
+			`CREATE TABLE IF NOT EXISTS stock_adjustments (
+				id VARCHAR(24) PRIMARY KEY,
+				product_id VARCHAR(24) NOT NULL,
+				delta BIGINT NOT NULL,
+				reason VARCHAR(32) NOT NULL,
+				note TEXT NOT NULL,
+				stock BIGINT NOT NULL,
+				actor_id VARCHAR(24) NULL,
+				actor_name TEXT NULL,
+				actor_email VARCHAR(255) NULL,
+				actor_role VARCHAR(32) NULL,
+				created_at TIMESTAMP NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS stock_adjustments_product_id_idx ON stock_adjustments (product_id, created_at)`,
+		},
+	},
+	{
+		Version:     11,
+		Description: "images of products",
+		Statements: []string{
+			// the images are only read with their product, so they are kept
+			// as json like the variant attributes
+			`ALTER TABLE products ADD COLUMN images TEXT NOT NULL DEFAULT '[]'`,
+		},
+	},
+	{
+		Version:     12,
+		Description: "reviews of products, rating of products",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS reviews (
+				id VARCHAR(24) PRIMARY KEY,
+				product_id VARCHAR(24) NOT NULL,
+				author_id VARCHAR(24) NOT NULL,
+				author_name TEXT NOT NULL,
+				rating INTEGER NOT NULL,
+				title TEXT NOT NULL,
+				body TEXT NOT NULL,
+				status VARCHAR(32) NOT NULL,
+				created_at TIMESTAMP NOT NULL,
+				updated_at TIMESTAMP NOT NULL,
+				version BIGINT NOT NULL DEFAULT 0
+			)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS reviews_product_id_author_id_idx ON reviews (product_id, author_id)`,
+			`CREATE INDEX IF NOT EXISTS reviews_status_idx ON reviews (status, created_at)`,
+			`ALTER TABLE products ADD COLUMN rating DOUBLE PRECISION NOT NULL DEFAULT 0`,
+			`ALTER TABLE products ADD COLUMN review_count BIGINT NOT NULL DEFAULT 0`,
+			`CREATE INDEX IF NOT EXISTS products_rating_idx ON products (rating, id)`,
+			`CREATE INDEX IF NOT EXISTS products_review_count_idx ON products (review_count, id)`,
+		},
+	},
+	{
+		Version:     13,
+		Description: "attribute schemas of categories, attributes of products",
+		Statements: []string{
+			`ALTER TABLE categories ADD COLUMN attributes TEXT NOT NULL DEFAULT '[]'`,
+			// the attributes are read with their product as json, the rows of
+			// product_attributes only serve the list filters
+			`ALTER TABLE products ADD COLUMN attributes TEXT NOT NULL DEFAULT '{}'`,
+			`CREATE TABLE IF NOT EXISTS product_attributes (
+				product_id VARCHAR(24) NOT NULL,
+				name VARCHAR(50) NOT NULL,
+				value TEXT NOT NULL,
+				number DOUBLE PRECISION NULL,
+				PRIMARY KEY (product_id, name)
+			)`,
+			`CREATE INDEX IF NOT EXISTS product_attributes_value_idx ON product_attributes (name, value)`,
+			`CREATE INDEX IF NOT EXISTS product_attributes_number_idx ON product_attributes (name, number)`,
+		},
+	},
+	{
+		Version:     14,
+		Description: "exchange rates, prices in minor units of the base currency",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS exchange_rates (
+				currency VARCHAR(3) PRIMARY KEY,
+				rate DOUBLE PRECISION NOT NULL,
+				updated_at TIMESTAMP NOT NULL
+			)`,
+		},
+		Up: func(ctx context.Context, tx *sql.Tx) error {
+			// prices were whole units of the base currency so far
+			scale := money.Scale(config.BaseCurrency)
+			statements := []string{
+				fmt.Sprintf(`UPDATE products SET price = price * %d`, scale),
+				fmt.Sprintf(`UPDATE product_variants SET price = price * %d WHERE price IS NOT NULL`, scale),
+			}
+			for _, statement := range statements {
+				if _, err := tx.ExecContext(ctx, statement); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+}
+
+func (sm *SqlManager) Migrate(ctx context.Context) ([]dtos.MigrationStatusDto, error) {
+	applied, err := sm.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, migration := range sqlMigrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		log.Printf("[INFO] Applying migration %d: %s", migration.Version, migration.Description)
+		tx, err := sm.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := sm.applyMigration(ctx, tx, migration); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("migration %d failed: %w", migration.Version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+	}
+	return sm.MigrationStatus(ctx)
+}
+
+func (sm *SqlManager) MigrationStatus(ctx context.Context) ([]dtos.MigrationStatusDto, error) {
+	applied, err := sm.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	versions := []uint{}
+	descriptions := []string{}
+	for _, migration := range sqlMigrations {
+		versions = append(versions, migration.Version)
+		descriptions = append(descriptions, migration.Description)
+	}
+	return migrationStatus(versions, descriptions, applied), nil
+}
+
+func (sm *SqlManager) applyMigration(ctx context.Context, tx *sql.Tx, migration SqlMigration) error {
+	for _, statement := range migration.Statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	if migration.Up != nil {
+		if err := migration.Up(ctx, tx); err != nil {
+			return err
+		}
+	}
+	query := sm.Rebind(`INSERT INTO schema_migrations (version, description, applied_at) VALUES (?, ?, ?)`)
+	_, err := tx.ExecContext(ctx, query, int64(migration.Version), migration.Description, time.Now().UTC())
+	return err
+}
+
+func (sm *SqlManager) appliedMigrations(ctx context.Context) (map[uint]model.Migration, error) {
+	applied := map[uint]model.Migration{}
+	if sm.DB == nil {
+		return applied, errDatabaseNotConnected
+	}
+	_, err := sm.DB.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		description TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return applied, err
+	}
+	rows, err := sm.DB.QueryContext(ctx, `SELECT version, description, applied_at FROM schema_migrations`)
+	if err != nil {
+		return applied, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			record  model.Migration
+			version int64
+		)
+		if err := rows.Scan(&version, &record.Description, &record.AppliedAt); err != nil {
+			return applied, err
+		}
+		record.Version = uint(version)
+		applied[record.Version] = record
+	}
+	return applied, rows.Err()
+}
@@ -4,17 +4,43 @@ import (
 	"context"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/sajalmia381/store-api/src/config"
 	"github.com/sajalmia381/store-api/src/enums"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
+const (
+	// pingTimeout bounds a single health ping.
+	pingTimeout = 2 * time.Second
+	// reconnectAfterFailures consecutive failed pings replace the client.
+	reconnectAfterFailures = 3
+	minReconnectBackoff    = time.Second
+	maxReconnectBackoff    = 30 * time.Second
+	// disconnectGrace bounds how long a replaced client waits for the
+	// operations still using it, streamed exports have no QUERY_TIMEOUT.
+	disconnectGrace = time.Minute
+)
+
+// DmManager owns the only mongo client of the process. A background watcher
+// pings it every HEALTH_CHECK_INTERVAL and, once the database stays
+// unreachable, replaces the client with exponential backoff. Repositories
+// must go through Collection instead of keeping a client of their own, so a
+// reconnect repairs every one of them.
 type DmManager struct {
-	Ctx context.Context
-	DB  *mongo.Database
+	mu     sync.RWMutex
+	client *mongo.Client
+	db     *mongo.Database
+	// supportsTransactions is true on replica sets and sharded clusters, a
+	// standalone server rejects multi document transactions.
+	supportsTransactions bool
+	topologyChecked      bool
+	migrated             bool
+	state                enums.ConnectionState
 }
 
 var singletonDmManager *DmManager
@@ -22,93 +48,199 @@ var onceDmManager sync.Once
 
 func GetDmManager() *DmManager {
 	onceDmManager.Do(func() {
-		singletonDmManager = &DmManager{}
+		singletonDmManager = &DmManager{state: enums.CONNECTING}
 		singletonDmManager.initializeConnection()
 	})
 	return singletonDmManager
 }
 
+// QueryContext bounds a single database operation by QUERY_TIMEOUT, on top of
+// any deadline the caller context already carries.
+func QueryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if config.QueryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, config.QueryTimeout)
+}
+
+// StreamContext is the context of a query whose rows are sent to the client
+// while they are read, like the exports. QUERY_TIMEOUT would cut a large one
+// off, the stream lasts as long as the request reading it.
+func StreamContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithCancel(ctx)
+}
+
 func (dm *DmManager) initializeConnection() {
-	ctx := context.Background()
-	dm.Ctx = ctx
-	clientOpts := options.Client().ApplyURI(config.DBConnectionString)
-	client, err := mongo.Connect(ctx, clientOpts)
+	client, err := connect()
 	if err != nil {
-		log.Println("[ERROR] SingletonDB connection error: ", err.Error())
-		return
+		// mongo.Connect does no I/O, it only fails on an invalid configuration
+		log.Fatal("[ERROR] SingletonDB connection error: ", err.Error())
 	}
-	db := client.Database(config.DatabaseName)
-	collectionNames, err := db.ListCollectionNames(ctx, bson.D{})
-	if err != nil {
-		log.Println("GETTING collection name err:", collectionNames)
+	dm.client = client
+	dm.db = client.Database(config.DatabaseName)
+	if err := dm.ping(context.Background()); err != nil {
+		log.Println("[ERROR] Database is not reachable:", err.Error())
+		dm.setState(enums.DISCONNECTED)
+	} else {
+		dm.connected()
 	}
-	for _, _name := range enums.COLLECTION_NAMES {
-		isExists := false
-		for _, collName := range collectionNames {
-			if collName == _name {
-				isExists = true
-				break
-			}
+	if config.HealthCheckInterval > 0 {
+		go dm.watch()
+	}
+	log.Println("[INFO] Initialized Singleton DB Manager")
+}
+
+func connect() (*mongo.Client, error) {
+	clientOpts := options.Client().ApplyURI(config.DBConnectionString)
+	return mongo.Connect(context.Background(), clientOpts)
+}
+
+// Collection returns the named collection of the current client.
+func (dm *DmManager) Collection(name string, opts ...*options.CollectionOptions) *mongo.Collection {
+	return dm.Database().Collection(name, opts...)
+}
+
+func (dm *DmManager) Database() *mongo.Database {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+	return dm.db
+}
+
+func (dm *DmManager) Client() *mongo.Client {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+	return dm.client
+}
+
+func (dm *DmManager) SupportsTransactions() bool {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+	return dm.supportsTransactions
+}
+
+func (dm *DmManager) State() enums.ConnectionState {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+	return dm.state
+}
+
+func (dm *DmManager) setState(state enums.ConnectionState) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	dm.state = state
+}
+
+func (dm *DmManager) ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	return dm.Client().Ping(ctx, readpref.Primary())
+}
+
+// connected marks the database reachable. The topology check and the startup
+// migrations run on the first successful ping, so they are not lost when the
+// server starts before the database does.
+func (dm *DmManager) connected() {
+	dm.mu.Lock()
+	dm.state = enums.CONNECTED
+	checkTopology := !dm.topologyChecked
+	migrate := config.MigrateOnStartup && !dm.migrated
+	dm.mu.Unlock()
+	if checkTopology {
+		supports, err := supportsTransactions(context.Background(), dm.Client())
+		dm.mu.Lock()
+		dm.supportsTransactions = supports
+		dm.topologyChecked = err == nil
+		dm.mu.Unlock()
+	}
+	if migrate {
+		_, err := dm.Migrate(context.Background())
+		if err != nil {
+			log.Println("[ERROR] Database migration:", err.Error())
 		}
-		if !isExists {
-			db.CreateCollection(ctx, _name)
-			switch _name {
-			case string(enums.USER_COLLECTION_NAME):
-				{
-					indexModel := mongo.IndexModel{
-						Keys:    bson.D{{Key: "email", Value: 1}},
-						Options: options.Index().SetUnique(true),
-					}
-					// indexModelNumber := mongo.IndexModel{
-					// 	Keys:    bson.D{{Key: "number", Value: 1}},
-					// 	Options: options.Index().SetUnique(true),
-					// }
-					_names, _ := db.Collection(_name).Indexes().CreateMany(context.Background(), []mongo.IndexModel{
-						indexModel, // indexModelNumber,
-					})
-					log.Println("Index created! indexNames: ", _names)
-				}
-			case string(enums.CART_COLLECTION_NAME):
-				{
-					indexModel := mongo.IndexModel{
-						Keys:    bson.D{{Key: "userId", Value: 1}},
-						Options: options.Index().SetUnique(true),
-					}
-					_name, _ := db.Collection(_name).Indexes().CreateOne(context.Background(), indexModel)
-					log.Println("Index created! indexName: ", _name)
-				}
-			case string(enums.PRODUCT_COLLECTION_NAME):
-				{
-					indexModel := mongo.IndexModel{
-						Keys:    bson.D{{Key: "slug", Value: 1}},
-						Options: options.Index().SetUnique(true),
-					}
-					_name, _ := db.Collection(_name).Indexes().CreateOne(context.Background(), indexModel)
-					log.Println("Index created! indexName: ", _name)
-				}
-			case string(enums.CATEGORY_COLLECTION_NAME):
-				{
-					indexModel := mongo.IndexModel{
-						Keys:    bson.D{{Key: "slug", Value: 1}},
-						Options: options.Index().SetUnique(true),
-					}
-					_name, _ := db.Collection(_name).Indexes().CreateOne(context.Background(), indexModel)
-					log.Println("Index created! indexName: ", _name)
-				}
-			case string(enums.TOKEN_COLLECTION_NAME):
-				{
-					indexModel := mongo.IndexModel{
-						Keys:    bson.D{{Key: "token", Value: 1}},
-						Options: options.Index().SetUnique(true),
-					}
-					_name, _ := db.Collection(_name).Indexes().CreateOne(context.Background(), indexModel)
-
-					log.Println("Index created! indexName: ", _name)
-				}
-			}
+		dm.mu.Lock()
+		dm.migrated = err == nil
+		dm.mu.Unlock()
+	}
+}
 
+func (dm *DmManager) watch() {
+	log.Println("[INFO] Start database health checker.")
+	failures := 0
+	for {
+		delay := config.HealthCheckInterval
+		if err := dm.ping(context.Background()); err != nil {
+			failures++
+			log.Println("[ERROR] Database ping failed:", err.Error())
+			dm.setState(enums.DISCONNECTED)
+			if failures >= reconnectAfterFailures {
+				dm.reconnect()
+			}
+			delay = reconnectBackoff(failures)
+		} else {
+			if failures > 0 {
+				log.Println("[INFO] Database connection is restored")
+			}
+			failures = 0
+			dm.connected()
 		}
+		time.Sleep(delay)
 	}
-	dm.DB = db
-	log.Println("[INFO] Initialized Singleton DB Manager")
+}
+
+// reconnect swaps in a fresh client once it answers a ping. The old client is
+// disconnected once the operations already using it finish, at most
+// disconnectGrace later.
+func (dm *DmManager) reconnect() {
+	log.Println("[INFO] Try to reconnect database...")
+	dm.setState(enums.RECONNECTING)
+	client, err := connect()
+	if err != nil {
+		log.Println("[ERROR] Database reconnect:", err.Error())
+		dm.setState(enums.DISCONNECTED)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	if err := client.Ping(ctx, readpref.Primary()); err != nil {
+		log.Println("[ERROR] Database reconnect:", err.Error())
+		client.Disconnect(context.Background())
+		dm.setState(enums.DISCONNECTED)
+		return
+	}
+	dm.mu.Lock()
+	old := dm.client
+	dm.client = client
+	dm.db = client.Database(config.DatabaseName)
+	dm.topologyChecked = false
+	dm.mu.Unlock()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), disconnectGrace)
+		defer cancel()
+		old.Disconnect(ctx)
+	}()
+	log.Println("[INFO] Database reconnected")
+}
+
+// reconnectBackoff doubles the delay after every failure, up to
+// maxReconnectBackoff.
+func reconnectBackoff(failures int) time.Duration {
+	delay := minReconnectBackoff
+	for i := 1; i < failures && delay < maxReconnectBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxReconnectBackoff {
+		return maxReconnectBackoff
+	}
+	return delay
+}
+
+func supportsTransactions(ctx context.Context, client *mongo.Client) (bool, error) {
+	var result bson.M
+	err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&result)
+	if err != nil {
+		log.Println("[ERROR] Database topology:", err.Error())
+		return false, err
+	}
+	_, isReplicaSet := result["setName"]
+	return isReplicaSet || result["msg"] == "isdbgrid", nil
 }
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+
+	_ "github.com/lib/pq"
+	"github.com/sajalmia381/store-api/src/config"
+	"github.com/sajalmia381/store-api/src/enums"
+	_ "modernc.org/sqlite"
+)
+
+// SqlManager owns the database/sql pool used when DATABASE is SQLITE or
+// POSTGRES. Queries are written with "?" placeholders and passed through
+// Rebind, so the same statement runs on both dialects.
+type SqlManager struct {
+	DB      *sql.DB
+	Dialect enums.DatabaseType
+}
+
+var singletonSqlManager *SqlManager
+var onceSqlManager sync.Once
+
+func GetSqlManager() *SqlManager {
+	onceSqlManager.Do(func() {
+		singletonSqlManager = &SqlManager{
+			Dialect: enums.DatabaseType(config.Database),
+		}
+		singletonSqlManager.initializeConnection()
+	})
+	return singletonSqlManager
+}
+
+func (sm *SqlManager) initializeConnection() {
+	driverName := "sqlite"
+	if sm.Dialect == enums.POSTGRES {
+		driverName = "postgres"
+	}
+	db, err := sql.Open(driverName, config.SqlDSN)
+	if err != nil {
+		// sql.Open does no I/O, it only fails on an unknown driver or DSN
+		log.Fatal("[ERROR] SingletonSQL connection error: ", err.Error())
+	}
+	if sm.Dialect == enums.SQLITE {
+		// SQLite allows a single writer, sharing one connection avoids "database is locked"
+		db.SetMaxOpenConns(1)
+	}
+	sm.DB = db
+	if config.MigrateOnStartup {
+		if _, err := sm.Migrate(context.Background()); err != nil {
+			log.Println("[ERROR] Database migration:", err.Error())
+		}
+	}
+	log.Println("[INFO] Initialized Singleton SQL Manager")
+}
+
+// Rebind converts "?" placeholders to the "$n" form postgres expects.
+func (sm *SqlManager) Rebind(query string) string {
+	if sm.Dialect != enums.POSTGRES {
+		return query
+	}
+	var builder strings.Builder
+	index := 0
+	for _, char := range query {
+		if char == '?' {
+			index++
+			builder.WriteString("$" + strconv.Itoa(index))
+			continue
+		}
+		builder.WriteRune(char)
+	}
+	return builder.String()
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/sajalmia381/store-api/src/enums"
+)
+
+func TestRebind(t *testing.T) {
+	query := `SELECT id FROM products WHERE slug = ? AND price > ?`
+	postgres := &SqlManager{Dialect: enums.POSTGRES}
+	if got, want := postgres.Rebind(query), `SELECT id FROM products WHERE slug = $1 AND price > $2`; got != want {
+		t.Errorf("postgres: expected %q, got %q", want, got)
+	}
+	sqlite := &SqlManager{Dialect: enums.SQLITE}
+	if got := sqlite.Rebind(query); got != query {
+		t.Errorf("sqlite: expected the query unchanged, got %q", got)
+	}
+}
+
+func TestSqliteMigrationsApplyOnce(t *testing.T) {
+	database, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	// every connection to :memory: is a database of its own
+	database.SetMaxOpenConns(1)
+	sm := &SqlManager{DB: database, Dialect: enums.SQLITE}
+	for run := 1; run <= 2; run++ {
+		statuses, err := sm.Migrate(context.Background())
+		if err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+		if len(statuses) != len(sqlMigrations) {
+			t.Fatalf("run %d: expected %d migrations, got %d", run, len(sqlMigrations), len(statuses))
+		}
+		for _, status := range statuses {
+			if !status.Applied {
+				t.Errorf("run %d: migration %d is not applied", run, status.Version)
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StoreSnapshotDto holds every collection of enums.COLLECTION_NAMES. Orders
+// have no model yet and are kept as plain documents.
+type StoreSnapshotDto struct {
+	Tokens     []model.Token
+	Users      []model.User
+	Categories []model.Category
+	Products   []model.Product
+	Reviews    []model.Review
+	Carts      []model.Cart
+	Orders     []primitive.M
+}
+
+type ArchiveCollectionDto struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// ArchiveManifestDto is the manifest.json entry of an archive.
+type ArchiveManifestDto struct {
+	Format      string                 `json:"format"`
+	Version     int                    `json:"version"`
+	Database    string                 `json:"database"`
+	CreatedAt   time.Time              `json:"createdAt"`
+	Collections []ArchiveCollectionDto `json:"collections"`
+	// Images is the number of image files
+	Images int `json:"images"`
+}
+
+// Documents lists the documents of the snapshot by collection name.
+func (s StoreSnapshotDto) Documents() map[string][]interface{} {
+	documents := map[string][]interface{}{}
+	for _, token := range s.Tokens {
+		documents[string(enums.TOKEN_COLLECTION_NAME)] = append(documents[string(enums.TOKEN_COLLECTION_NAME)], token)
+	}
+	for _, user := range s.Users {
+		documents[string(enums.USER_COLLECTION_NAME)] = append(documents[string(enums.USER_COLLECTION_NAME)], user)
+	}
+	for _, category := range s.Categories {
+		documents[string(enums.CATEGORY_COLLECTION_NAME)] = append(documents[string(enums.CATEGORY_COLLECTION_NAME)], category)
+	}
+	for _, product := range s.Products {
+		documents[string(enums.PRODUCT_COLLECTION_NAME)] = append(documents[string(enums.PRODUCT_COLLECTION_NAME)], product)
+	}
+	for _, review := range s.Reviews {
+		documents[string(enums.REVIEW_COLLECTION_NAME)] = append(documents[string(enums.REVIEW_COLLECTION_NAME)], review)
+	}
+	for _, cart := range s.Carts {
+		documents[string(enums.CART_COLLECTION_NAME)] = append(documents[string(enums.CART_COLLECTION_NAME)], cart)
+	}
+	for _, order := range s.Orders {
+		documents[string(enums.ORDER_COLLECTION_NAME)] = append(documents[string(enums.ORDER_COLLECTION_NAME)], order)
+	}
+	return documents
+}
@@ -0,0 +1,71 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/enums"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+// AuditQueryParams filters the audit feed. Actor matches the id or the email of
+// the requester, Since and Until are RFC 3339 times.
+type AuditQueryParams struct {
+	Limit    uint64            `json:"limit" query:"limit"`
+	Page     uint64            `json:"page" query:"page"`
+	Entity   enums.AuditEntity `json:"entity" query:"entity"`
+	EntityId string            `json:"entityId" query:"entityId"`
+	Action   enums.AuditAction `json:"action" query:"action"`
+	Actor    string            `json:"actor" query:"actor"`
+	Since    time.Time         `json:"since" query:"since"`
+	Until    time.Time         `json:"until" query:"until"`
+}
+
+// Validate checks the filters and defaults the page size, the feed is always
+// paginated.
+func (q *AuditQueryParams) Validate() error {
+	if q.Limit == 0 {
+		q.Limit = defaultAuditLimit
+	}
+	if q.Limit > maxAuditLimit {
+		return domain_error.Validation("limit must not be greater than 500")
+	}
+	if q.Entity != "" && !validAuditEntity(q.Entity) {
+		return domain_error.Validation("entity must be one of PRODUCT, CATEGORY, USER, CART and REVIEW")
+	}
+	if q.Action != "" && !validAuditAction(q.Action) {
+		return domain_error.Validation("action must be one of CREATE, UPDATE, DELETE, RESTORE and PURGE")
+	}
+	if q.EntityId != "" {
+		if _, err := primitive.ObjectIDFromHex(q.EntityId); err != nil {
+			return domain_error.Validation("entityId is not valid")
+		}
+	}
+	if !q.Since.IsZero() && !q.Until.IsZero() && q.Until.Before(q.Since) {
+		return domain_error.Validation("until must not be before since")
+	}
+	return nil
+}
+
+func validAuditEntity(entity enums.AuditEntity) bool {
+	for _, e := range enums.AUDIT_ENTITIES {
+		if e == entity {
+			return true
+		}
+	}
+	return false
+}
+
+func validAuditAction(action enums.AuditAction) bool {
+	for _, a := range enums.AUDIT_ACTIONS {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
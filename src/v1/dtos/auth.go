@@ -1,8 +1,9 @@
 package dtos
 
 import (
-	"errors"
 	"strconv"
+
+	"github.com/sajalmia381/store-api/src/domain_error"
 )
 
 type LoginPayload struct {
@@ -12,10 +13,10 @@ type LoginPayload struct {
 
 func (p LoginPayload) Validate() error {
 	if p.Email == "" {
-		return errors.New("email is required")
+		return domain_error.Validation("email is required")
 	}
 	if p.Password == "" {
-		return errors.New("password is required")
+		return domain_error.Validation("password is required")
 	}
 	return nil
 }
@@ -29,18 +30,18 @@ type RegisterPayload struct {
 
 func (p RegisterPayload) Validate() error {
 	if p.Email == "" {
-		return errors.New("email is required")
+		return domain_error.Validation("email is required")
 	}
 	if p.Password == "" {
-		return errors.New("password is required")
+		return domain_error.Validation("password is required")
 	}
 	if p.Name == "" {
-		return errors.New("name is required")
+		return domain_error.Validation("name is required")
 	}
 	if p.Number != nil {
 		if p.Number != nil {
 			if len(strconv.Itoa((int(*p.Number)))) < 9 || len(strconv.Itoa((int(*p.Number)))) > 11 {
-				return errors.New("number must be GREATER than 9 digit or LESS than 11 digit")
+				return domain_error.Validation("number must be GREATER than 9 digit or LESS than 11 digit")
 			}
 		}
 	}
@@ -53,7 +54,7 @@ type RefreshTokenPayload struct {
 
 func (p RefreshTokenPayload) Validate() error {
 	if p.RefreshToken == "" {
-		return errors.New("refresh token is required")
+		return domain_error.Validation("refresh token is required")
 	}
 	return nil
 }
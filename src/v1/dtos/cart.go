@@ -1,13 +1,60 @@
 package dtos
 
-import "github.com/sajalmia381/store-api/src/v1/model"
+import (
+	"time"
 
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CartResponseDto is a cart with the prices of its lines, all amounts are
+// minor units of Currency.
+type CartResponseDto struct {
+	ID        primitive.ObjectID   `json:"id"`
+	UserId    primitive.ObjectID   `json:"userId"`
+	Products  []CartProductSpecRes `json:"products"`
+	Total     int64                `json:"total"`
+	Currency  string               `json:"currency"`
+	CreatedAt time.Time            `json:"createdAt"`
+	UpdatedAt time.Time            `json:"updatedAt"`
+}
+
+// CartProductSpecRes is a line of the cart with the unit price of its product
+// or variant. Price is nil and Total 0 while the product is gone.
 type CartProductSpecRes struct {
-	Product  model.Product `json:"product" bson:"product"`
-	Quantity uint16        `json:"quantity" bson:"quantity"`
-	Total    uint32        `json:"total" bson:"total"`
+	model.CartProductSpec
+	Price *int64 `json:"price"`
+	Total int64  `json:"total"`
 }
 
+// CartProductId names the line to remove, every line of the product without
+// a VariantId.
 type CartProductId struct {
 	ProductId string `json:"productId"`
+	VariantId string `json:"variantId"`
+}
+
+// CartQueryParams lists the carts, they have nothing to search.
+type CartQueryParams struct {
+	ListQueryParams
+	// Product keeps the carts holding the product of this id.
+	Product string `json:"product" query:"product"`
+}
+
+var cartOrderFields = []string{"id", "createdAt", "updatedAt"}
+
+func (q CartQueryParams) Validate() error {
+	if err := q.ListQueryParams.validate(cartOrderFields); err != nil {
+		return err
+	}
+	if q.Search != "" {
+		return domain_error.Validation("carts can not be searched, filter them by product")
+	}
+	if q.Product != "" {
+		if _, err := primitive.ObjectIDFromHex(q.Product); err != nil {
+			return domain_error.Validation("product id is not valid")
+		}
+	}
+	return nil
 }
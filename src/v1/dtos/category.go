@@ -1,22 +1,111 @@
 package dtos
 
-import "errors"
+import (
+	"regexp"
+	"strings"
 
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CategoryUpdateDto changes the fields that are set, Attributes replaces the
+// whole schema and an empty list clears it.
 type CategoryUpdateDto struct {
-	Name        string `json:"name" bson:"name"`
-	Description string `json:"description" bson:"description"`
-	Slug        string `json:"slug" bson:"slug"`
-	UpdateSlug  bool   `json:"updateSlug" bson:"updateSlug"`
+	Name        string                     `json:"name" bson:"name"`
+	Description string                     `json:"description" bson:"description"`
+	Slug        string                     `json:"slug" bson:"slug"`
+	UpdateSlug  bool                       `json:"updateSlug" bson:"updateSlug"`
+	Attributes  *[]model.CategoryAttribute `json:"attributes" bson:"attributes"`
+}
+
+func (c CategoryUpdateDto) Validate() error {
+	if c.Attributes == nil {
+		return nil
+	}
+	return validateAttributeSchema(*c.Attributes)
 }
 
 type CategoryStoreDto struct {
-	Name        string `json:"name" bson:"name"`
-	Description string `json:"description" bson:"description"`
+	Name        string                    `json:"name" bson:"name"`
+	Description string                    `json:"description" bson:"description"`
+	Attributes  []model.CategoryAttribute `json:"attributes" bson:"attributes"`
 }
 
 func (c *CategoryStoreDto) Validate() error {
 	if c.Name == "" {
-		return errors.New("name is required")
+		return domain_error.Validation("name is required")
+	}
+	return validateAttributeSchema(c.Attributes)
+}
+
+// attributeNamePattern is the form of the attribute names, they are keys of
+// the product documents and of the list filters.
+var attributeNamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]{0,49}$`)
+
+func validateAttributeSchema(attributes []model.CategoryAttribute) error {
+	names := map[string]bool{}
+	for _, attribute := range attributes {
+		if !attributeNamePattern.MatchString(attribute.Name) {
+			return domain_error.Validation("attribute names must start with a letter and have up to 50 letters, digits and underscores")
+		}
+		if names[attribute.Name] {
+			return domain_error.Validation("attribute " + attribute.Name + " is defined twice")
+		}
+		names[attribute.Name] = true
+		if !isAttributeType(attribute.Type) {
+			return domain_error.Validation("type of attribute " + attribute.Name + " must be one of STRING, NUMBER, ENUM and BOOL")
+		}
+		if attribute.Unit != "" && attribute.Type != enums.ATTRIBUTE_NUMBER {
+			return domain_error.Validation("unit of attribute " + attribute.Name + " is only for NUMBER attributes")
+		}
+		if attribute.Type != enums.ATTRIBUTE_ENUM {
+			if len(attribute.Options) > 0 {
+				return domain_error.Validation("options of attribute " + attribute.Name + " are only for ENUM attributes")
+			}
+			continue
+		}
+		if len(attribute.Options) == 0 {
+			return domain_error.Validation("ENUM attribute " + attribute.Name + " needs options")
+		}
+		options := map[string]bool{}
+		for _, option := range attribute.Options {
+			option = strings.TrimSpace(option)
+			if option == "" || options[option] {
+				return domain_error.Validation("options of attribute " + attribute.Name + " must be unique and not empty")
+			}
+			options[option] = true
+		}
 	}
 	return nil
 }
+
+func isAttributeType(attributeType enums.AttributeType) bool {
+	for _, _type := range enums.ATTRIBUTE_TYPES {
+		if _type == attributeType {
+			return true
+		}
+	}
+	return false
+}
+
+// CategoryDriftDto reports how Category.Products differed from the products
+// that reference the category.
+type CategoryDriftDto struct {
+	Category string               `json:"category" bson:"category"`
+	Missing  []primitive.ObjectID `json:"missing" bson:"missing"`
+	Phantom  []primitive.ObjectID `json:"phantom" bson:"phantom"`
+}
+
+// CategoryQueryParams lists the categories, the search matches name and
+// description.
+type CategoryQueryParams struct {
+	ListQueryParams
+}
+
+var categoryOrderFields = []string{"id", "name", "slug"}
+
+func (q CategoryQueryParams) Validate() error {
+	return q.ListQueryParams.validate(categoryOrderFields)
+}
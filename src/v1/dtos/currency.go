@@ -0,0 +1,31 @@
+package dtos
+
+import (
+	"math"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/domain_error"
+)
+
+// ExchangeRateDto sets the rate of a currency, the units of it a unit of the
+// base currency buys.
+type ExchangeRateDto struct {
+	Rate float64 `json:"rate"`
+}
+
+func (d ExchangeRateDto) Validate() error {
+	if d.Rate <= 0 || math.IsInf(d.Rate, 0) || math.IsNaN(d.Rate) {
+		return domain_error.Validation("rate must be a positive number")
+	}
+	return nil
+}
+
+// CurrencyDto is a currency prices can be answered in. Amounts in it have
+// Digits minor unit digits, the base currency has the rate 1 and no UpdatedAt.
+type CurrencyDto struct {
+	Code      string     `json:"code"`
+	Digits    int        `json:"digits"`
+	Rate      float64    `json:"rate"`
+	Base      bool       `json:"base"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,21 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/sajalmia381/store-api/src/enums"
+)
+
+type DatabaseHealthDto struct {
+	Type      enums.DatabaseType    `json:"type"`
+	State     enums.ConnectionState `json:"state"`
+	Reachable bool                  `json:"reachable"`
+	LatencyMs float64               `json:"latencyMs"`
+	Error     string                `json:"error,omitempty"`
+	CheckedAt time.Time             `json:"checkedAt"`
+}
+
+type HealthDto struct {
+	Status   enums.HealthStatus `json:"status"`
+	Database DatabaseHealthDto  `json:"database"`
+}
@@ -0,0 +1,53 @@
+package dtos
+
+import (
+	"strings"
+
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/enums"
+)
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 500
+)
+
+// ListQueryParams page, sort and search a list endpoint. Without a limit the
+// whole list is returned, unless the endpoint pages it by default.
+type ListQueryParams struct {
+	Limit uint64 `json:"limit" query:"limit"`
+	Page  uint64 `json:"page" query:"page"`
+	// Search matches the fields the list searches, case-insensitive.
+	Search string `json:"q" query:"q"`
+	// OrderBy names the field to sort by, the id breaks ties. The id is the
+	// default.
+	OrderBy string     `json:"orderBy" query:"orderBy"`
+	Sort    enums.Sort `json:"sort" query:"sort"`
+}
+
+// PageByDefault pages the list by 20 unless a limit is given, for the lists
+// that were never returned whole.
+func (q *ListQueryParams) PageByDefault() {
+	if q.Limit == 0 {
+		q.Limit = defaultListLimit
+	}
+}
+
+// validate checks the parameters against the fields the list is ordered by.
+func (q ListQueryParams) validate(orderFields []string) error {
+	if q.Limit > maxListLimit {
+		return domain_error.Validation("limit must not be greater than 500")
+	}
+	if q.Sort != "" && q.Sort != enums.ASCENDING && q.Sort != enums.DESCENDING {
+		return domain_error.Validation("sort must be asc or desc")
+	}
+	if q.OrderBy == "" {
+		return nil
+	}
+	for _, field := range orderFields {
+		if q.OrderBy == field {
+			return nil
+		}
+	}
+	return domain_error.Validation("orderBy must be one of " + strings.Join(orderFields, ", "))
+}
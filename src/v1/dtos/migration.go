@@ -0,0 +1,10 @@
+package dtos
+
+import "time"
+
+type MigrationStatusDto struct {
+	Version     uint       `json:"version"`
+	Description string     `json:"description"`
+	Applied     bool       `json:"applied"`
+	AppliedAt   *time.Time `json:"appliedAt"`
+}
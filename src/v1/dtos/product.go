@@ -1,10 +1,12 @@
 package dtos
 
 import (
-	"errors"
+	"strings"
 	"time"
 
+	"github.com/sajalmia381/store-api/src/domain_error"
 	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/v1/model"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -13,18 +15,20 @@ type ProductStoreDto struct {
 	Title       string  `json:"title" bson:"title"`
 	Price       *int    `json:"price" bson:"price"`
 	Description *string `json:"description" bson:"description"`
+	// Attributes are checked against the schema of the category
+	Attributes map[string]interface{} `json:"attributes" bson:"attributes"`
 }
 
 func (p ProductStoreDto) Validate() error {
 	if p.Title == "" {
-		return errors.New("title is required")
+		return domain_error.Validation("title is required")
 	}
 	if p.Price == nil {
-		return errors.New("price is required")
+		return domain_error.Validation("price is required")
 	}
 
 	if p.Category == "" {
-		return errors.New("category is required")
+		return domain_error.Validation("category is required")
 	}
 	return nil
 }
@@ -37,17 +41,67 @@ type ProductUpdateDto struct {
 	Slug        string              `json:"slug" bson:"slug"`
 	UpdateSlug  bool                `json:"updateSlug" bson:"updateSlug"`
 	UpdatedAt   time.Time           `json:"updatedAt" bson:"updatedAt"`
+	// Attributes replace the attributes of the product when set, they and the
+	// attributes kept are checked against the schema of the category.
+	Attributes map[string]interface{} `json:"attributes" bson:"attributes"`
+}
+
+// ProductVariantDto creates or replaces a variant of a product, Active is true
+// unless given.
+type ProductVariantDto struct {
+	SKU        string            `json:"sku"`
+	Price      *int              `json:"price"`
+	Attributes map[string]string `json:"attributes"`
+	Active     *bool             `json:"active"`
+}
+
+func (v ProductVariantDto) Validate() error {
+	if strings.TrimSpace(v.SKU) == "" {
+		return domain_error.Validation("sku is required")
+	}
+	for name := range v.Attributes {
+		if strings.TrimSpace(name) == "" {
+			return domain_error.Validation("attribute names must not be empty")
+		}
+	}
+	return nil
+}
+
+// ProductImageUpload is a file of an image upload.
+type ProductImageUpload struct {
+	Name    string
+	Content []byte
+}
+
+// ProductImageOrderDto orders the images by id, the images not listed follow
+// in their current order. The first image is the primary image.
+type ProductImageOrderDto struct {
+	Images []string `json:"images"`
+}
+
+func (o ProductImageOrderDto) Validate() error {
+	if len(o.Images) == 0 {
+		return domain_error.Validation("images are required")
+	}
+	seen := map[string]bool{}
+	for _, id := range o.Images {
+		if seen[id] {
+			return domain_error.Validation("image " + id + " is listed twice")
+		}
+		seen[id] = true
+	}
+	return nil
 }
 
 // All Product
 
-type category struct {
+type ProductCategory struct {
 	ID   primitive.ObjectID `json:"id" bson:"_id"`
 	Name string             `json:"name" bson:"name"`
 	Slug string             `json:"slug" bson:"slug"`
 }
 
-type user struct {
+type ProductCreator struct {
 	ID        primitive.ObjectID `json:"id" bson:"_id"`
 	Name      string             `json:"name" bson:"name"`
 	Email     string             `json:"email" bson:"email"`
@@ -59,21 +113,130 @@ type user struct {
 }
 
 type ProductResponseDto struct {
-	ID          primitive.ObjectID `json:"id" bson:"_id"`
-	CreatedBy   *user              `json:"createdBy" bson:"createdBy"`
-	Category    *category          `json:"category" bson:"category"`
-	Title       string             `json:"title" bson:"title"`
-	Slug        string             `json:"slug" bson:"slug"`
-	Price       *int               `json:"price" bson:"price"`
-	Description *string            `json:"description" bson:"description"`
-	CreatedAt   time.Time          `json:"createdAt" bson:"createdAt"`
-	UpdatedAt   time.Time          `json:"updatedAt" bson:"updatedAt"`
-	Active      bool               `json:"active" bson:"active"`
+	ID          primitive.ObjectID     `json:"id" bson:"_id"`
+	CreatedBy   *ProductCreator        `json:"createdBy" bson:"createdBy"`
+	Category    *ProductCategory       `json:"category" bson:"category"`
+	Title       string                 `json:"title" bson:"title"`
+	Slug        string                 `json:"slug" bson:"slug"`
+	Price       *int                   `json:"price" bson:"price"`
+	Description *string                `json:"description" bson:"description"`
+	CreatedAt   time.Time              `json:"createdAt" bson:"createdAt"`
+	UpdatedAt   time.Time              `json:"updatedAt" bson:"updatedAt"`
+	Active      bool                   `json:"active" bson:"active"`
+	Version     int64                  `json:"version" bson:"version"`
+	Variants    []model.ProductVariant `json:"variants" bson:"variants"`
+	Image       string                 `json:"image" bson:"image"`
+	Images      []model.ProductImage   `json:"images" bson:"images"`
+	Stock       *int                   `json:"stock" bson:"stock"`
+	Available   *int                   `json:"available" bson:"available"`
+	Rating      float64                `json:"rating" bson:"rating"`
+	ReviewCount int                    `json:"reviewCount" bson:"reviewCount"`
+	Attributes  map[string]interface{} `json:"attributes" bson:"attributes"`
+	// Currency of Price and of the variant prices, see model.Product
+	Currency string `json:"currency,omitempty" bson:"-"`
+	// Score is the relevance of a text search, the higher the better.
+	Score     *float64          `json:"score,omitempty" bson:"score,omitempty"`
+	Highlight *ProductHighlight `json:"highlight,omitempty" bson:"-"`
 }
 
+// ProductHighlight marks the matches of the search with <em>, the text around
+// them is HTML escaped. Description is a snippet around the first match.
+type ProductHighlight struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+// ProductQueryParams lists and filters the products, the search matches title
+// and description as a substring or, in the text SearchMode, by words ranked
+// by relevance. Category is the slug or the id of a category, Subcategories
+// adds the products of its descendants. CreatedBy is the email of the creator,
+// the Since and Until times are RFC 3339 and included.
 type ProductQueryParams struct {
-	Limit  uint64     `json:"limit" query:"limit"`
-	Page   uint64     `json:"page" query:"page"`
-	Search string     `json:"q" query:"q"`
-	Sort   enums.Sort `json:"sort" query:"sort"`
+	ListQueryParams
+	// After and Before are cursors of a previous page, they replace Page.
+	After      string           `json:"after" query:"after"`
+	Before     string           `json:"before" query:"before"`
+	SearchMode enums.SearchMode `json:"searchMode" query:"searchMode"`
+	// Highlight marks the matches of the search in the products.
+	Highlight     bool      `json:"highlight" query:"highlight"`
+	Category      string    `json:"category" query:"category"`
+	Subcategories bool      `json:"subcategories" query:"subcategories"`
+	CreatedBy     string    `json:"createdBy" query:"createdBy"`
+	CreatedSince  time.Time `json:"createdSince" query:"createdSince"`
+	CreatedUntil  time.Time `json:"createdUntil" query:"createdUntil"`
+	UpdatedSince  time.Time `json:"updatedSince" query:"updatedSince"`
+	UpdatedUntil  time.Time `json:"updatedUntil" query:"updatedUntil"`
+	// MinPrice, MaxPrice and Active are set by the api, the binder can not
+	// tell 0 and false from a missing parameter.
+	MinPrice *int  `json:"minPrice"`
+	MaxPrice *int  `json:"maxPrice"`
+	Active   *bool `json:"active"`
+	// Attributes are set by the api from the attr.<name> parameters, a
+	// product matches all of them.
+	Attributes []ProductAttributeFilter `json:"attributes"`
+}
+
+// ProductAttributeFilter matches the products whose attribute Name is one of
+// Values or a number from Min to Max. A value matches a string or ENUM
+// attribute equal to it, a NUMBER attribute equal to it as a number and a
+// BOOL attribute when it is true or false.
+type ProductAttributeFilter struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values,omitempty"`
+	Min    *float64 `json:"min,omitempty"`
+	Max    *float64 `json:"max,omitempty"`
+}
+
+func (f ProductAttributeFilter) Validate() error {
+	if !attributeNamePattern.MatchString(f.Name) {
+		return domain_error.Validation("attr." + f.Name + " is not an attribute name")
+	}
+	if len(f.Values) == 0 && f.Min == nil && f.Max == nil {
+		return domain_error.Validation("attr." + f.Name + " needs a value or a range")
+	}
+	if len(f.Values) > 0 && (f.Min != nil || f.Max != nil) {
+		return domain_error.Validation("attr." + f.Name + " takes values or a range, not both")
+	}
+	if f.Min != nil && f.Max != nil && *f.Max < *f.Min {
+		return domain_error.Validation("attr." + f.Name + " range must not end before it starts")
+	}
+	return nil
+}
+
+var productOrderFields = []string{"createdAt", "updatedAt", "price", "title", "rating", "reviewCount", "relevance"}
+
+func (q ProductQueryParams) Validate() error {
+	if err := q.ListQueryParams.validate(productOrderFields); err != nil {
+		return err
+	}
+	if q.SearchMode != "" && q.SearchMode != enums.SEARCH_REGEX && q.SearchMode != enums.SEARCH_TEXT {
+		return domain_error.Validation("searchMode must be regex or text")
+	}
+	if q.OrderBy == "relevance" && !q.IsTextSearch() {
+		return domain_error.Validation("orderBy relevance needs q and the text searchMode")
+	}
+	if q.MinPrice != nil && q.MaxPrice != nil && *q.MaxPrice < *q.MinPrice {
+		return domain_error.Validation("maxPrice must not be less than minPrice")
+	}
+	for _, filter := range q.Attributes {
+		if err := filter.Validate(); err != nil {
+			return err
+		}
+	}
+	if q.Subcategories && q.Category == "" {
+		return domain_error.Validation("subcategories needs a category")
+	}
+	if !q.CreatedSince.IsZero() && !q.CreatedUntil.IsZero() && q.CreatedUntil.Before(q.CreatedSince) {
+		return domain_error.Validation("createdUntil must not be before createdSince")
+	}
+	if !q.UpdatedSince.IsZero() && !q.UpdatedUntil.IsZero() && q.UpdatedUntil.Before(q.UpdatedSince) {
+		return domain_error.Validation("updatedUntil must not be before updatedSince")
+	}
+	return nil
+}
+
+// IsTextSearch reports whether the products are searched by words and ranked,
+// the relevance is then the default order.
+func (q ProductQueryParams) IsTextSearch() bool {
+	return q.SearchMode == enums.SEARCH_TEXT && q.Search != ""
 }
@@ -0,0 +1,84 @@
+package dtos
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gosimple/slug"
+	"github.com/sajalmia381/store-api/src/enums"
+)
+
+// ProductImportRowDto is a row of a product import. The product of its slug,
+// the slug of its title when empty, is updated or else created. Description,
+// Active and Attributes are left as they are on update when missing.
+type ProductImportRowDto struct {
+	Slug        string  `json:"slug"`
+	Title       string  `json:"title"`
+	Price       *int    `json:"price"`
+	Description *string `json:"description"`
+	// Category is the slug or the id of the category
+	Category string `json:"category"`
+	Active   *bool  `json:"active"`
+	// Attributes are checked against the schema of the category, a csv
+	// cell holds them as a json object
+	Attributes map[string]interface{} `json:"attributes"`
+	// Problems are the values of the file that could not be read
+	Problems []string `json:"-"`
+}
+
+// Errors lists every problem of the row, not only the first.
+func (r ProductImportRowDto) Errors() []string {
+	errors := append([]string{}, r.Problems...)
+	if r.Slug != "" && !slug.IsSlug(r.Slug) {
+		errors = append(errors, "slug may only have lower case letters, digits and dashes")
+	}
+	if strings.TrimSpace(r.Title) == "" {
+		errors = append(errors, "title is required")
+	}
+	if r.Price == nil {
+		errors = append(errors, "price is required")
+	} else if *r.Price < 0 {
+		errors = append(errors, "price must not be negative")
+	}
+	if r.Category == "" {
+		errors = append(errors, "category is required")
+	}
+	return errors
+}
+
+// ProductImportRowResultDto is what importing a row did, or would do in a dry
+// run. Row counts the rows of the file from 1, the CSV header left out.
+type ProductImportRowResultDto struct {
+	Row    int                `json:"row"`
+	Slug   string             `json:"slug"`
+	Action enums.ImportAction `json:"action,omitempty"`
+	Errors []string           `json:"errors,omitempty"`
+}
+
+// ProductImportReportDto counts the actions of the rows without errors.
+// Nothing is written while any row has errors.
+type ProductImportReportDto struct {
+	DryRun    bool                        `json:"dryRun"`
+	Total     int                         `json:"total"`
+	Created   int                         `json:"created"`
+	Updated   int                         `json:"updated"`
+	Unchanged int                         `json:"unchanged"`
+	Failed    int                         `json:"failed"`
+	Rows      []ProductImportRowResultDto `json:"rows"`
+}
+
+// ProductImportJobDto is an import running in the background. Its rows are
+// validated first, then written.
+type ProductImportJobDto struct {
+	ID         string                  `json:"id"`
+	Status     enums.JobStatus         `json:"status"`
+	DryRun     bool                    `json:"dryRun"`
+	Total      int                     `json:"total"`
+	Validated  int                     `json:"validated"`
+	Written    int                     `json:"written"`
+	Report     *ProductImportReportDto `json:"report"`
+	Error      string                  `json:"error,omitempty"`
+	CreatedBy  string                  `json:"createdBy"`
+	CreatedAt  time.Time               `json:"createdAt"`
+	FinishedAt *time.Time              `json:"finishedAt"`
+}
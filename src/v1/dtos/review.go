@@ -0,0 +1,101 @@
+package dtos
+
+import (
+	"unicode/utf8"
+
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/enums"
+)
+
+const (
+	defaultReviewLimit = 20
+	maxReviewTitle     = 200
+	maxReviewBody      = 5000
+)
+
+type ReviewStoreDto struct {
+	Rating int    `json:"rating" bson:"rating"`
+	Title  string `json:"title" bson:"title"`
+	Body   string `json:"body" bson:"body"`
+}
+
+func (d ReviewStoreDto) Validate() error {
+	if d.Rating < 1 || d.Rating > 5 {
+		return domain_error.Validation("rating must be between 1 and 5")
+	}
+	return validateReviewText(d.Title, d.Body)
+}
+
+// ReviewUpdateDto changes the fields that are set.
+type ReviewUpdateDto struct {
+	Rating int    `json:"rating" bson:"rating"`
+	Title  string `json:"title" bson:"title"`
+	Body   string `json:"body" bson:"body"`
+}
+
+func (d ReviewUpdateDto) Validate() error {
+	if d.Rating != 0 && (d.Rating < 1 || d.Rating > 5) {
+		return domain_error.Validation("rating must be between 1 and 5")
+	}
+	return validateReviewText(d.Title, d.Body)
+}
+
+func validateReviewText(title string, body string) error {
+	if utf8.RuneCountInString(title) > maxReviewTitle {
+		return domain_error.Validation("title must not be longer than 200 characters")
+	}
+	if utf8.RuneCountInString(body) > maxReviewBody {
+		return domain_error.Validation("body must not be longer than 5000 characters")
+	}
+	return nil
+}
+
+// ReviewModerationDto sets the moderation state of a review.
+type ReviewModerationDto struct {
+	Status enums.ReviewStatus `json:"status"`
+}
+
+func (d ReviewModerationDto) Validate() error {
+	if !isReviewStatus(d.Status) {
+		return domain_error.Validation("status must be one of PENDING, APPROVED and REJECTED")
+	}
+	return nil
+}
+
+// ReviewQueryParams lists the reviews, of a product or of every product for
+// the moderation. The search matches title and body.
+type ReviewQueryParams struct {
+	ListQueryParams
+	Rating int                `json:"rating" query:"rating"`
+	Status enums.ReviewStatus `json:"status" query:"status"`
+	// Product is a product id, the product endpoint sets it from the path
+	Product string `json:"product" query:"product"`
+}
+
+var reviewOrderFields = []string{"id", "createdAt", "updatedAt", "rating"}
+
+// Validate pages the list by 20 unless asked otherwise.
+func (q *ReviewQueryParams) Validate() error {
+	if q.Limit == 0 {
+		q.Limit = defaultReviewLimit
+	}
+	if err := q.ListQueryParams.validate(reviewOrderFields); err != nil {
+		return err
+	}
+	if q.Rating != 0 && (q.Rating < 1 || q.Rating > 5) {
+		return domain_error.Validation("rating must be between 1 and 5")
+	}
+	if q.Status != "" && !isReviewStatus(q.Status) {
+		return domain_error.Validation("status must be one of PENDING, APPROVED and REJECTED")
+	}
+	return nil
+}
+
+func isReviewStatus(status enums.ReviewStatus) bool {
+	for _, _status := range enums.REVIEW_STATUSES {
+		if status == _status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,15 @@
+package dtos
+
+type SeedCountDto struct {
+	Created int `json:"created"`
+	Skipped int `json:"skipped"`
+}
+
+// SeedReportDto counts the fixtures created and the ones skipped because they
+// already exist.
+type SeedReportDto struct {
+	Categories SeedCountDto `json:"categories"`
+	Products   SeedCountDto `json:"products"`
+	Users      SeedCountDto `json:"users"`
+	Carts      SeedCountDto `json:"carts"`
+}
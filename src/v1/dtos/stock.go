@@ -0,0 +1,46 @@
+package dtos
+
+import (
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/enums"
+)
+
+// StockAdjustmentDto adds Delta to the stock of a product, a negative Delta
+// takes from it. The first adjustment starts tracking the stock.
+type StockAdjustmentDto struct {
+	Delta  int               `json:"delta"`
+	Reason enums.StockReason `json:"reason"`
+	Note   string            `json:"note"`
+}
+
+func (d StockAdjustmentDto) Validate() error {
+	if d.Delta == 0 {
+		return domain_error.Validation("delta must not be 0")
+	}
+	for _, reason := range enums.STOCK_REASONS {
+		if d.Reason == reason {
+			return nil
+		}
+	}
+	return domain_error.Validation("reason must be one of RESTOCK, SALE, RETURN, DAMAGE and CORRECTION")
+}
+
+// StockQueryParams pages the stock history of a product like the audit feed,
+// the newest first.
+type StockQueryParams struct {
+	Limit  uint64            `json:"limit" query:"limit"`
+	Page   uint64            `json:"page" query:"page"`
+	Reason enums.StockReason `json:"reason" query:"reason"`
+	// ProductId is set from the path
+	ProductId string `json:"-" query:"-"`
+}
+
+func (q *StockQueryParams) Validate() error {
+	if q.Limit == 0 {
+		q.Limit = defaultAuditLimit
+	}
+	if q.Limit > maxAuditLimit {
+		return domain_error.Validation("limit must not be greater than 500")
+	}
+	return nil
+}
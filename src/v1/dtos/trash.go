@@ -0,0 +1,10 @@
+package dtos
+
+import "time"
+
+type TrashPurgeReportDto struct {
+	Before     time.Time `json:"before"`
+	Products   int64     `json:"products"`
+	Categories int64     `json:"categories"`
+	Users      int64     `json:"users"`
+}
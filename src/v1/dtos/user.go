@@ -1,9 +1,11 @@
 package dtos
 
 import (
-	"errors"
 	"strconv"
 	"time"
+
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/enums"
 )
 
 type (
@@ -18,7 +20,7 @@ type (
 func (u *UserRegisterDTO) Validate() error {
 	if u.Number != nil {
 		if len(strconv.Itoa((int(*u.Number)))) < 9 || len(strconv.Itoa((int(*u.Number)))) > 11 {
-			return errors.New("number must be GREATER than 9 digit or LESS than 11 digit")
+			return domain_error.Validation("number must be GREATER than 9 digit or LESS than 11 digit")
 		}
 	}
 	return nil
@@ -35,13 +37,29 @@ type UserUpdateDto struct {
 func (u *UserUpdateDto) Validate() error {
 	if u.Number != nil {
 		if len(strconv.Itoa((int(*u.Number)))) < 9 || len(strconv.Itoa((int(*u.Number)))) > 11 {
-			return errors.New("number must be GREATER than 9 digit or LESS than 11 digit")
+			return domain_error.Validation("number must be GREATER than 9 digit or LESS than 11 digit")
 		}
 	}
 	return nil
 }
 
+// UserQuery lists the users, the search matches name and email. Status is set
+// by the api, the query binder can not fill a nil *bool.
 type UserQuery struct {
+	ListQueryParams
 	Status *bool  `json:"status" bson:"status"`
-	Role   string `json:"role" bson:"role"`
+	Role   string `json:"role" bson:"role" query:"role"`
+}
+
+var userOrderFields = []string{"id", "name", "email", "role", "createdAt", "updatedAt"}
+
+func (q UserQuery) Validate() error {
+	if err := q.ListQueryParams.validate(userOrderFields); err != nil {
+		return err
+	}
+	switch enums.Role(q.Role) {
+	case "", enums.ROLE_CUSTOMER, enums.ROLE_ADMIN, enums.ROLE_SUPER_ADMIN:
+		return nil
+	}
+	return domain_error.Validation("role must be one of ROLE_CUSTOMER, ROLE_ADMIN and ROLE_SUPER_ADMIN")
 }
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"github.com/sajalmia381/store-api/src/enums"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditActor is the requester of the change, copied from the jwt so the entry
+// stays readable after the user is gone.
+type AuditActor struct {
+	ID    primitive.ObjectID `json:"id" bson:"_id"`
+	Name  string             `json:"name" bson:"name"`
+	Email string             `json:"email" bson:"email"`
+	Role  string             `json:"role" bson:"role"`
+}
+
+// FieldChange holds the json values of a field before and after the change.
+type FieldChange struct {
+	Field string      `json:"field" bson:"field"`
+	Old   interface{} `json:"old" bson:"old"`
+	New   interface{} `json:"new" bson:"new"`
+}
+
+type AuditLog struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id"`
+	Entity   enums.AuditEntity  `json:"entity" bson:"entity"`
+	EntityId primitive.ObjectID `json:"entityId" bson:"entityId"`
+	Action   enums.AuditAction  `json:"action" bson:"action"`
+	// Actor is nil for changes made without a token, like registration
+	Actor     *AuditActor   `json:"actor" bson:"actor"`
+	Changes   []FieldChange `json:"changes" bson:"changes"`
+	CreatedAt time.Time     `json:"createdAt" bson:"createdAt"`
+}
@@ -6,9 +6,14 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// CartProductSpec is a line of the cart, a product or one of its variants.
 type CartProductSpec struct {
-	ProductId primitive.ObjectID `json:"productId" bson:"productId"`
-	Quantity  uint16             `json:"quantity" bson:"quantity"`
+	ProductId primitive.ObjectID  `json:"productId" bson:"productId"`
+	VariantId *primitive.ObjectID `json:"variantId,omitempty" bson:"variantId,omitempty"`
+	Quantity  uint16              `json:"quantity" bson:"quantity"`
+	// ReservedUntil is set while the quantity is held from the stock of
+	// the product, lines of products without stock are never reserved.
+	ReservedUntil *time.Time `json:"reservedUntil,omitempty" bson:"reservedUntil,omitempty"`
 }
 
 type Cart struct {
@@ -1,6 +1,11 @@
 package model
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"github.com/sajalmia381/store-api/src/enums"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type Category struct {
 	ID          primitive.ObjectID   `json:"id" bson:"_id"`
@@ -9,4 +14,23 @@ type Category struct {
 	Name        string               `json:"name" bson:"name"`
 	Slug        string               `json:"slug" bson:"slug"`
 	Description string               `json:"description" bson:"description"`
+	DeletedAt   *time.Time           `json:"deletedAt" bson:"deletedAt"`
+	Version     int64                `json:"version" bson:"version"`
+	// Attributes is the schema of the attributes of the products of the
+	// category. Subcategories inherit the attributes of their parents.
+	Attributes []CategoryAttribute `json:"attributes" bson:"attributes"`
+}
+
+// CategoryAttribute defines an attribute of the products of a category, like
+// the RAM of a laptop or the material of a shirt.
+type CategoryAttribute struct {
+	// Name is the key of the attribute in Product.Attributes
+	Name     string              `json:"name" bson:"name"`
+	Label    string              `json:"label" bson:"label"`
+	Type     enums.AttributeType `json:"type" bson:"type"`
+	Required bool                `json:"required" bson:"required"`
+	// Unit of a NUMBER attribute, like GB or kg
+	Unit string `json:"unit" bson:"unit"`
+	// Options are the values of an ENUM attribute
+	Options []string `json:"options" bson:"options"`
 }
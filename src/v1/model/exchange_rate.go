@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// ExchangeRate prices a currency against the base currency of the store: a
+// unit of the base currency is Rate units of Currency.
+type ExchangeRate struct {
+	// Currency is the ISO 4217 code
+	Currency  string    `json:"currency" bson:"_id"`
+	Rate      float64   `json:"rate" bson:"rate"`
+	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+}
@@ -0,0 +1,9 @@
+package model
+
+import "time"
+
+type Migration struct {
+	Version     uint      `json:"version" bson:"version"`
+	Description string    `json:"description" bson:"description"`
+	AppliedAt   time.Time `json:"appliedAt" bson:"appliedAt"`
+}
@@ -19,4 +19,52 @@ type Product struct {
 	CreatedAt   time.Time           `json:"createdAt" bson:"createdAt"`
 	UpdatedAt   time.Time           `json:"updatedAt" bson:"updatedAt"`
 	Active      bool                `json:"active" bson:"active"`
+	DeletedAt   *time.Time          `json:"deletedAt" bson:"deletedAt"`
+	Version     int64               `json:"version" bson:"version"`
+	Variants    []ProductVariant    `json:"variants" bson:"variants"`
+	// Images are in display order, the first is the primary image. Image and
+	// ImageSource are its url and id while the product has any.
+	Images []ProductImage `json:"images" bson:"images"`
+	// Stock is the quantity on hand and Available what carts have not
+	// reserved of it, both are nil while the stock is not tracked.
+	Stock     *int `json:"stock" bson:"stock"`
+	Available *int `json:"available" bson:"available"`
+	// Rating is the average rating of the approved reviews, rounded to two
+	// decimals, and ReviewCount their number. The reviews keep them.
+	Rating      float64 `json:"rating" bson:"rating"`
+	ReviewCount int     `json:"reviewCount" bson:"reviewCount"`
+	// Attributes are the values of the attributes the category defines, by
+	// name. Their values are strings, float64 numbers and bools.
+	Attributes map[string]interface{} `json:"attributes" bson:"attributes"`
+	// Currency of the prices, set on the reads that convert them. Prices are
+	// kept in minor units of the base currency.
+	Currency string `json:"currency,omitempty" bson:"-"`
+}
+
+// ProductVariant is an option of a product, like a size or a color. Its SKU
+// is unique in the store.
+type ProductVariant struct {
+	ID  primitive.ObjectID `json:"id" bson:"_id"`
+	SKU string             `json:"sku" bson:"sku"`
+	// Price overrides the price of the product when set
+	Price      *int              `json:"price" bson:"price"`
+	Attributes map[string]string `json:"attributes" bson:"attributes"`
+	Active     bool              `json:"active" bson:"active"`
+	CreatedAt  time.Time         `json:"createdAt" bson:"createdAt"`
+	UpdatedAt  time.Time         `json:"updatedAt" bson:"updatedAt"`
+}
+
+// ProductImage is an uploaded picture of a product. The image storage keeps
+// the original and a thumbnail of every size, the api serves them at the urls.
+type ProductImage struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id"`
+	ContentType string             `json:"contentType" bson:"contentType"`
+	Width       int                `json:"width" bson:"width"`
+	Height      int                `json:"height" bson:"height"`
+	// Size of the original in bytes
+	Size int64  `json:"size" bson:"size"`
+	URL  string `json:"url" bson:"url"`
+	// Thumbnails are the urls of the thumbnails by size name
+	Thumbnails map[string]string `json:"thumbnails" bson:"thumbnails"`
+	CreatedAt  time.Time         `json:"createdAt" bson:"createdAt"`
 }
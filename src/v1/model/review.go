@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+
+	"github.com/sajalmia381/store-api/src/enums"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Review is the rating of a product by a user, a user reviews a product once.
+// The author name is copied from the jwt like the actor of the audit log.
+type Review struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id"`
+	ProductId  primitive.ObjectID `json:"productId" bson:"productId"`
+	AuthorId   primitive.ObjectID `json:"authorId" bson:"authorId"`
+	AuthorName string             `json:"authorName" bson:"authorName"`
+	Rating     int                `json:"rating" bson:"rating"`
+	Title      string             `json:"title" bson:"title"`
+	Body       string             `json:"body" bson:"body"`
+	Status     enums.ReviewStatus `json:"status" bson:"status"`
+	CreatedAt  time.Time          `json:"createdAt" bson:"createdAt"`
+	UpdatedAt  time.Time          `json:"updatedAt" bson:"updatedAt"`
+	Version    int64              `json:"version" bson:"version"`
+}
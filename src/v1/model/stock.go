@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+
+	"github.com/sajalmia381/store-api/src/enums"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StockAdjustment is a change of the stock of a product, Stock is the stock
+// after it.
+type StockAdjustment struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id"`
+	ProductId primitive.ObjectID `json:"productId" bson:"productId"`
+	Delta     int                `json:"delta" bson:"delta"`
+	Reason    enums.StockReason  `json:"reason" bson:"reason"`
+	Note      string             `json:"note" bson:"note"`
+	Stock     int                `json:"stock" bson:"stock"`
+	// Actor is the requester, like in the audit log
+	Actor     *AuditActor `json:"actor" bson:"actor"`
+	CreatedAt time.Time   `json:"createdAt" bson:"createdAt"`
+}
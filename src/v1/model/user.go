@@ -18,4 +18,6 @@ type User struct {
 	LastLoginAt *time.Time         `json:"lastLoginAt" bson:"lastLoginAt"`
 	CreatedAt   time.Time          `json:"createdAt" bson:"createdAt"`
 	UpdatedAt   time.Time          `json:"updatedAt" bson:"updatedAt"`
+	DeletedAt   *time.Time         `json:"deletedAt" bson:"deletedAt"`
+	Version     int64              `json:"version" bson:"version"`
 }
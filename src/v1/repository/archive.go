@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ArchiveRepository interface {
+	// Export reads every collection of enums.COLLECTION_NAMES.
+	Export(ctx context.Context) (dtos.StoreSnapshotDto, error)
+	// Import writes the snapshot keeping its ids. Documents with the same id
+	// are overwritten, ARCHIVE_REPLACE empties the collections first.
+	Import(ctx context.Context, snapshot dtos.StoreSnapshotDto, mode enums.ArchiveMode) error
+}
+
+type archiveRepository struct {
+	dm *db.DmManager
+}
+
+func (r archiveRepository) Export(ctx context.Context) (dtos.StoreSnapshotDto, error) {
+	snapshot := dtos.StoreSnapshotDto{}
+	collections := map[enums.CollectionName]interface{}{
+		enums.TOKEN_COLLECTION_NAME:    &snapshot.Tokens,
+		enums.USER_COLLECTION_NAME:     &snapshot.Users,
+		enums.CATEGORY_COLLECTION_NAME: &snapshot.Categories,
+		enums.PRODUCT_COLLECTION_NAME:  &snapshot.Products,
+		enums.REVIEW_COLLECTION_NAME:   &snapshot.Reviews,
+		enums.CART_COLLECTION_NAME:     &snapshot.Carts,
+		enums.ORDER_COLLECTION_NAME:    &snapshot.Orders,
+	}
+	for _, name := range enums.COLLECTION_NAMES {
+		if err := r.findAll(ctx, name, collections[enums.CollectionName(name)]); err != nil {
+			return snapshot, databaseError(err, "a document of "+name)
+		}
+	}
+	return snapshot, nil
+}
+
+func (r archiveRepository) Import(ctx context.Context, snapshot dtos.StoreSnapshotDto, mode enums.ArchiveMode) error {
+	documents := snapshot.Documents()
+	// collection names the failing collection in the error
+	collection := "archive"
+	err := withMongoTx(ctx, r.dm, func(ctx context.Context) error {
+		for _, name := range enums.COLLECTION_NAMES {
+			collection = name
+			if err := r.replaceAll(ctx, name, documents[name], mode); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return databaseError(err, "a document of "+collection)
+}
+
+func (r archiveRepository) findAll(ctx context.Context, name string, objects interface{}) error {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := r.dm.Collection(name).Find(ctx, bson.D{}, opts)
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, objects)
+}
+
+func (r archiveRepository) replaceAll(ctx context.Context, name string, documents []interface{}, mode enums.ArchiveMode) error {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	coll := r.dm.Collection(name)
+	if mode == enums.ARCHIVE_REPLACE {
+		if _, err := coll.DeleteMany(ctx, bson.D{}); err != nil {
+			return err
+		}
+	}
+	opts := options.Replace().SetUpsert(true)
+	for _, document := range documents {
+		data, err := bson.Marshal(document)
+		if err != nil {
+			return err
+		}
+		id := bson.Raw(data).Lookup("_id")
+		if _, err := coll.ReplaceOne(ctx, bson.D{{Key: "_id", Value: id}}, bson.Raw(data), opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func NewArchiveRepository() ArchiveRepository {
+	return &archiveRepository{
+		dm: db.GetDmManager(),
+	}
+}
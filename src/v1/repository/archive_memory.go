@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type archiveMemoryRepository struct {
+	mm *db.MemoryManager
+}
+
+func (r archiveMemoryRepository) Export(ctx context.Context) (dtos.StoreSnapshotDto, error) {
+	r.mm.RLock()
+	defer r.mm.RUnlock()
+	snapshot := dtos.StoreSnapshotDto{}
+	// ObjectIDs start with their creation time, sorting them keeps the
+	// insertion order like the other databases do.
+	for _, token := range r.mm.Tokens {
+		snapshot.Tokens = append(snapshot.Tokens, token)
+	}
+	sort.Slice(snapshot.Tokens, func(i, j int) bool { return lessObjectId(snapshot.Tokens[i].ID, snapshot.Tokens[j].ID) })
+	for _, user := range r.mm.Users {
+		snapshot.Users = append(snapshot.Users, user)
+	}
+	sort.Slice(snapshot.Users, func(i, j int) bool { return lessObjectId(snapshot.Users[i].ID, snapshot.Users[j].ID) })
+	for _, category := range r.mm.Categories {
+		category.Products = copyObjectIds(category.Products)
+		snapshot.Categories = append(snapshot.Categories, category)
+	}
+	sort.Slice(snapshot.Categories, func(i, j int) bool { return lessObjectId(snapshot.Categories[i].ID, snapshot.Categories[j].ID) })
+	for _, product := range r.mm.Products {
+		snapshot.Products = append(snapshot.Products, product)
+	}
+	sort.Slice(snapshot.Products, func(i, j int) bool { return lessObjectId(snapshot.Products[i].ID, snapshot.Products[j].ID) })
+	for _, review := range r.mm.Reviews {
+		snapshot.Reviews = append(snapshot.Reviews, review)
+	}
+	sort.Slice(snapshot.Reviews, func(i, j int) bool { return lessObjectId(snapshot.Reviews[i].ID, snapshot.Reviews[j].ID) })
+	for _, cart := range r.mm.Carts {
+		cart.Products = append([]model.CartProductSpec{}, cart.Products...)
+		snapshot.Carts = append(snapshot.Carts, cart)
+	}
+	sort.Slice(snapshot.Carts, func(i, j int) bool { return lessObjectId(snapshot.Carts[i].ID, snapshot.Carts[j].ID) })
+	return snapshot, nil
+}
+
+// Import builds the new collections aside and swaps them in only when no
+// unique field is taken twice, so a failed import changes nothing.
+func (r archiveMemoryRepository) Import(ctx context.Context, snapshot dtos.StoreSnapshotDto, mode enums.ArchiveMode) error {
+	if len(snapshot.Orders) > 0 {
+		return domain_error.Validation("orders are not supported by the " + string(enums.MEMORY) + " database")
+	}
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	tokens := map[primitive.ObjectID]model.Token{}
+	users := map[primitive.ObjectID]model.User{}
+	categories := map[primitive.ObjectID]model.Category{}
+	products := map[primitive.ObjectID]model.Product{}
+	reviews := map[primitive.ObjectID]model.Review{}
+	carts := map[primitive.ObjectID]model.Cart{}
+	if mode != enums.ARCHIVE_REPLACE {
+		for id, token := range r.mm.Tokens {
+			tokens[id] = token
+		}
+		for id, user := range r.mm.Users {
+			users[id] = user
+		}
+		for id, category := range r.mm.Categories {
+			categories[id] = category
+		}
+		for id, product := range r.mm.Products {
+			products[id] = product
+		}
+		for id, review := range r.mm.Reviews {
+			reviews[id] = review
+		}
+		for id, cart := range r.mm.Carts {
+			carts[id] = cart
+		}
+	}
+	for _, token := range snapshot.Tokens {
+		tokens[token.ID] = token
+	}
+	for _, user := range snapshot.Users {
+		users[user.ID] = user
+	}
+	for _, category := range snapshot.Categories {
+		category.Products = copyObjectIds(category.Products)
+		categories[category.ID] = category
+	}
+	for _, product := range snapshot.Products {
+		products[product.ID] = product
+	}
+	for _, review := range snapshot.Reviews {
+		reviews[review.ID] = review
+	}
+	for _, cart := range snapshot.Carts {
+		cart.Products = append([]model.CartProductSpec{}, cart.Products...)
+		carts[cart.ID] = cart
+	}
+	unique := map[string][]string{}
+	for _, token := range tokens {
+		unique["token"] = append(unique["token"], token.Token)
+	}
+	for _, user := range users {
+		unique["user email"] = append(unique["user email"], user.Email)
+	}
+	for _, category := range categories {
+		unique["category slug"] = append(unique["category slug"], category.Slug)
+	}
+	for _, product := range products {
+		unique["product slug"] = append(unique["product slug"], product.Slug)
+	}
+	for _, review := range reviews {
+		unique["review author"] = append(unique["review author"], review.ProductId.Hex()+"/"+review.AuthorId.Hex())
+	}
+	for _, cart := range carts {
+		unique["cart user"] = append(unique["cart user"], cart.UserId.Hex())
+	}
+	for field, values := range unique {
+		if err := checkUnique(field, values); err != nil {
+			return err
+		}
+	}
+	r.mm.Tokens = tokens
+	r.mm.Users = users
+	r.mm.Categories = categories
+	r.mm.Products = products
+	r.mm.Reviews = reviews
+	r.mm.Carts = carts
+	return nil
+}
+
+func checkUnique(field string, values []string) error {
+	seen := map[string]bool{}
+	for _, value := range values {
+		if seen[value] {
+			return domain_error.Conflict(field + " " + value + " is already exists")
+		}
+		seen[value] = true
+	}
+	return nil
+}
+
+func NewArchiveMemoryRepository() ArchiveRepository {
+	return &archiveMemoryRepository{
+		mm: db.GetMemoryManager(),
+	}
+}
@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+)
+
+type archiveSqlRepository struct {
+	sm *db.SqlManager
+}
+
+// Export reads inside a single transaction, so the snapshot is consistent.
+func (r archiveSqlRepository) Export(ctx context.Context) (dtos.StoreSnapshotDto, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	snapshot := dtos.StoreSnapshotDto{}
+	err := withTx(ctx, r.sm, func(tx *sql.Tx) error {
+		err := queryAll(ctx, tx, `SELECT id, user_id, token, type, created_at FROM tokens ORDER BY id`, func(rows *sql.Rows) error {
+			var (
+				token  model.Token
+				id     string
+				userId sql.NullString
+			)
+			if err := rows.Scan(&id, &userId, &token.Token, &token.Type, &token.CreatedAt); err != nil {
+				return err
+			}
+			token.ID = parseId(id)
+			token.UserId = parseNullableId(userId)
+			snapshot.Tokens = append(snapshot.Tokens, token)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		err = queryAll(ctx, tx, `SELECT `+userColumns+` FROM users ORDER BY id`, func(rows *sql.Rows) error {
+			user, err := scanUser(rows)
+			if err != nil {
+				return err
+			}
+			snapshot.Users = append(snapshot.Users, user)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		snapshot.Categories, err = categorySqlRepository{sm: r.sm}.findAll(ctx, tx, "")
+		if err != nil {
+			return err
+		}
+		err = queryAll(ctx, tx, `SELECT `+productColumns+` FROM products ORDER BY id`, func(rows *sql.Rows) error {
+			product, err := scanProduct(rows)
+			if err != nil {
+				return err
+			}
+			snapshot.Products = append(snapshot.Products, product)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		variants, err := productSqlRepository{sm: r.sm}.findVariants(ctx, tx, nil)
+		if err != nil {
+			return err
+		}
+		for i := range snapshot.Products {
+			snapshot.Products[i].Variants = variants[snapshot.Products[i].ID]
+			if snapshot.Products[i].Variants == nil {
+				snapshot.Products[i].Variants = []model.ProductVariant{}
+			}
+		}
+		err = queryAll(ctx, tx, `SELECT `+reviewColumns+` FROM reviews ORDER BY id`, func(rows *sql.Rows) error {
+			review, err := scanReview(rows)
+			if err != nil {
+				return err
+			}
+			snapshot.Reviews = append(snapshot.Reviews, review)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		err = queryAll(ctx, tx, `SELECT id, user_id, created_at, updated_at FROM carts ORDER BY id`, func(rows *sql.Rows) error {
+			cart, err := scanCart(rows)
+			if err != nil {
+				return err
+			}
+			snapshot.Carts = append(snapshot.Carts, cart)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		products, err := cartSqlRepository{sm: r.sm}.findProducts(ctx, tx, nil)
+		if err != nil {
+			return err
+		}
+		for i := range snapshot.Carts {
+			if items, ok := products[snapshot.Carts[i].ID]; ok {
+				snapshot.Carts[i].Products = items
+			}
+		}
+		return nil
+	})
+	return snapshot, databaseError(err, "archive")
+}
+
+// Import overwrites rows by id inside a single transaction. Positions of the
+// category and cart products follow the order of the archived arrays.
+func (r archiveSqlRepository) Import(ctx context.Context, snapshot dtos.StoreSnapshotDto, mode enums.ArchiveMode) error {
+	if len(snapshot.Orders) > 0 {
+		return domain_error.Validation("orders are not supported by the " + string(r.sm.Dialect) + " database")
+	}
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	// collection names the failing collection in the error
+	collection := "archive"
+	err := withTx(ctx, r.sm, func(tx *sql.Tx) error {
+		if mode == enums.ARCHIVE_REPLACE {
+			for _, table := range []string{"tokens", "cart_products", "carts", "category_products", "product_variants", "product_attributes", "reviews", "products", "categories", "users"} {
+				if _, err := tx.ExecContext(ctx, `DELETE FROM `+table); err != nil {
+					return err
+				}
+			}
+		}
+		collection = string(enums.TOKEN_COLLECTION_NAME)
+		for _, token := range snapshot.Tokens {
+			if err := r.deleteById(ctx, tx, "tokens", token.ID.Hex()); err != nil {
+				return err
+			}
+			query := r.sm.Rebind(`INSERT INTO tokens (id, user_id, token, type, created_at) VALUES (?, ?, ?, ?, ?)`)
+			if _, err := tx.ExecContext(ctx, query, token.ID.Hex(), nullableId(token.UserId), token.Token, token.Type, token.CreatedAt); err != nil {
+				return err
+			}
+		}
+		collection = string(enums.USER_COLLECTION_NAME)
+		for _, user := range snapshot.Users {
+			if err := r.deleteById(ctx, tx, "users", user.ID.Hex()); err != nil {
+				return err
+			}
+			query := r.sm.Rebind(`INSERT INTO users (` + userColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+			if _, err := tx.ExecContext(ctx, query, userValues(user)...); err != nil {
+				return err
+			}
+		}
+		collection = string(enums.CATEGORY_COLLECTION_NAME)
+		for _, category := range snapshot.Categories {
+			if err := r.deleteById(ctx, tx, "categories", category.ID.Hex()); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, r.sm.Rebind(`DELETE FROM category_products WHERE category_id = ?`), category.ID.Hex()); err != nil {
+				return err
+			}
+			query := r.sm.Rebind(`INSERT INTO categories (` + categoryColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+			values, err := categoryValues(category)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, query, values...); err != nil {
+				return err
+			}
+			query = r.sm.Rebind(`INSERT INTO category_products (category_id, product_id, position) VALUES (?, ?, ?)`)
+			for position, productId := range category.Products {
+				if _, err := tx.ExecContext(ctx, query, category.ID.Hex(), productId.Hex(), position); err != nil {
+					return err
+				}
+			}
+		}
+		collection = string(enums.PRODUCT_COLLECTION_NAME)
+		for _, product := range snapshot.Products {
+			if err := r.deleteById(ctx, tx, "products", product.ID.Hex()); err != nil {
+				return err
+			}
+			query := r.sm.Rebind(`INSERT INTO products (` + productColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+			values, err := productValues(product)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, query, values...); err != nil {
+				return err
+			}
+			if err := (productSqlRepository{sm: r.sm}).putVariants(ctx, tx, product); err != nil {
+				return err
+			}
+			if err := (productSqlRepository{sm: r.sm}).putAttributes(ctx, tx, product); err != nil {
+				return err
+			}
+		}
+		collection = string(enums.REVIEW_COLLECTION_NAME)
+		for _, review := range snapshot.Reviews {
+			if err := r.deleteById(ctx, tx, "reviews", review.ID.Hex()); err != nil {
+				return err
+			}
+			query := r.sm.Rebind(`INSERT INTO reviews (` + reviewColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+			if _, err := tx.ExecContext(ctx, query, reviewValues(review)...); err != nil {
+				return err
+			}
+		}
+		collection = string(enums.CART_COLLECTION_NAME)
+		for _, cart := range snapshot.Carts {
+			if err := r.deleteById(ctx, tx, "carts", cart.ID.Hex()); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, r.sm.Rebind(`DELETE FROM cart_products WHERE cart_id = ?`), cart.ID.Hex()); err != nil {
+				return err
+			}
+			query := r.sm.Rebind(`INSERT INTO carts (id, user_id, created_at, updated_at) VALUES (?, ?, ?, ?)`)
+			if _, err := tx.ExecContext(ctx, query, cart.ID.Hex(), cart.UserId.Hex(), cart.CreatedAt, cart.UpdatedAt); err != nil {
+				return err
+			}
+			query = r.sm.Rebind(`INSERT INTO cart_products (cart_id, product_id, variant_id, quantity, reserved_until, position) VALUES (?, ?, ?, ?, ?, ?)`)
+			for position, item := range cart.Products {
+				_, err := tx.ExecContext(ctx, query, cart.ID.Hex(), item.ProductId.Hex(), variantKey(item.VariantId), int64(item.Quantity),
+					nullableTime(item.ReservedUntil), position)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	return databaseError(err, "a document of "+collection)
+}
+
+func (r archiveSqlRepository) deleteById(ctx context.Context, executor sqlExecutor, table string, id string) error {
+	_, err := executor.ExecContext(ctx, r.sm.Rebind(`DELETE FROM `+table+` WHERE id = ?`), id)
+	return err
+}
+
+// queryAll calls scan for every row of the query.
+func queryAll(ctx context.Context, executor sqlExecutor, query string, scan func(rows *sql.Rows) error, args ...interface{}) error {
+	rows, err := executor.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func NewArchiveSqlRepository() ArchiveRepository {
+	return &archiveSqlRepository{
+		sm: db.GetSqlManager(),
+	}
+}
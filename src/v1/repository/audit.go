@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AuditRepository interface {
+	Store(ctx context.Context, entry model.AuditLog) error
+	// FindAll lists the matching entries, the newest first.
+	FindAll(ctx context.Context, queryParams dtos.AuditQueryParams) ([]model.AuditLog, common.MetaData, error)
+}
+
+// auditCollectionOptions decodes the embedded documents of the change values
+// as maps, the default primitive.D would render as a key value list in json.
+var auditCollectionOptions = options.Collection().SetRegistry(
+	bson.NewRegistryBuilder().RegisterTypeMapEntry(bsontype.EmbeddedDocument, reflect.TypeOf(bson.M{})).Build(),
+)
+
+type auditRepository struct {
+	dm *db.DmManager
+}
+
+func (r auditRepository) Store(ctx context.Context, entry model.AuditLog) error {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	coll := r.dm.Collection(string(enums.AUDIT_COLLECTION_NAME), auditCollectionOptions)
+	_, err := coll.InsertOne(ctx, entry)
+	return databaseError(err, "audit log")
+}
+
+func (r auditRepository) FindAll(ctx context.Context, queryParams dtos.AuditQueryParams) ([]model.AuditLog, common.MetaData, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	objects := []model.AuditLog{}
+	filter := auditFilter(queryParams)
+	coll := r.dm.Collection(string(enums.AUDIT_COLLECTION_NAME), auditCollectionOptions)
+	total, err := coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return objects, common.MetaData{}, databaseError(err, "audit log")
+	}
+	metaData := paginate(queryParams.Limit, queryParams.Page, total)
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64(metaData.PerPage * (metaData.CurrentPage - 1))).
+		SetLimit(int64(metaData.PerPage))
+	cursor, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		return objects, metaData, databaseError(err, "audit log")
+	}
+	if err := cursor.All(ctx, &objects); err != nil {
+		return objects, metaData, databaseError(err, "audit log")
+	}
+	return objects, metaData, nil
+}
+
+func auditFilter(queryParams dtos.AuditQueryParams) bson.D {
+	filter := bson.D{}
+	if queryParams.Entity != "" {
+		filter = append(filter, bson.E{Key: "entity", Value: queryParams.Entity})
+	}
+	if queryParams.EntityId != "" {
+		filter = append(filter, bson.E{Key: "entityId", Value: parseId(queryParams.EntityId)})
+	}
+	if queryParams.Action != "" {
+		filter = append(filter, bson.E{Key: "action", Value: queryParams.Action})
+	}
+	if queryParams.Actor != "" {
+		actor := bson.A{bson.M{"actor.email": queryParams.Actor}}
+		if id, err := primitive.ObjectIDFromHex(queryParams.Actor); err == nil {
+			actor = append(actor, bson.M{"actor._id": id})
+		}
+		filter = append(filter, bson.E{Key: "$or", Value: actor})
+	}
+	createdAt := bson.M{}
+	if !queryParams.Since.IsZero() {
+		createdAt["$gte"] = queryParams.Since
+	}
+	if !queryParams.Until.IsZero() {
+		createdAt["$lte"] = queryParams.Until
+	}
+	if len(createdAt) > 0 {
+		filter = append(filter, bson.E{Key: "createdAt", Value: createdAt})
+	}
+	return filter
+}
+
+func NewAuditRepository() AuditRepository {
+	return &auditRepository{
+		dm: db.GetDmManager(),
+	}
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+)
+
+type auditMemoryRepository struct {
+	mm *db.MemoryManager
+}
+
+func (r auditMemoryRepository) Store(ctx context.Context, entry model.AuditLog) error {
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	r.mm.AuditLogs = append(r.mm.AuditLogs, entry)
+	return nil
+}
+
+func (r auditMemoryRepository) FindAll(ctx context.Context, queryParams dtos.AuditQueryParams) ([]model.AuditLog, common.MetaData, error) {
+	r.mm.RLock()
+	defer r.mm.RUnlock()
+	matches := []model.AuditLog{}
+	for i := len(r.mm.AuditLogs) - 1; i >= 0; i-- {
+		if entry := r.mm.AuditLogs[i]; auditMatches(entry, queryParams) {
+			matches = append(matches, entry)
+		}
+	}
+	metaData := paginate(queryParams.Limit, queryParams.Page, int64(len(matches)))
+	start := metaData.PerPage * (metaData.CurrentPage - 1)
+	end := start + metaData.PerPage
+	if start > uint64(len(matches)) {
+		start = uint64(len(matches))
+	}
+	if end > uint64(len(matches)) {
+		end = uint64(len(matches))
+	}
+	return matches[start:end], metaData, nil
+}
+
+// auditMatches applies the filters of auditFilter in code.
+func auditMatches(entry model.AuditLog, queryParams dtos.AuditQueryParams) bool {
+	if queryParams.Entity != "" && entry.Entity != queryParams.Entity {
+		return false
+	}
+	if queryParams.EntityId != "" && entry.EntityId.Hex() != queryParams.EntityId {
+		return false
+	}
+	if queryParams.Action != "" && entry.Action != queryParams.Action {
+		return false
+	}
+	if queryParams.Actor != "" {
+		if entry.Actor == nil || (entry.Actor.Email != queryParams.Actor && entry.Actor.ID.Hex() != queryParams.Actor) {
+			return false
+		}
+	}
+	if !queryParams.Since.IsZero() && entry.CreatedAt.Before(queryParams.Since) {
+		return false
+	}
+	if !queryParams.Until.IsZero() && entry.CreatedAt.After(queryParams.Until) {
+		return false
+	}
+	return true
+}
+
+func NewAuditMemoryRepository() AuditRepository {
+	return &auditMemoryRepository{
+		mm: db.GetMemoryManager(),
+	}
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+)
+
+// Changes are kept as a json array, the values already are json values.
+const auditColumns = `id, entity, entity_id, action, actor_id, actor_name, actor_email, actor_role, changes, created_at`
+
+type auditSqlRepository struct {
+	sm *db.SqlManager
+}
+
+func (r auditSqlRepository) Store(ctx context.Context, entry model.AuditLog) error {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return err
+	}
+	var actorId, actorName, actorEmail, actorRole interface{}
+	if entry.Actor != nil {
+		actorId, actorName, actorEmail, actorRole = entry.Actor.ID.Hex(), entry.Actor.Name, entry.Actor.Email, entry.Actor.Role
+	}
+	query := r.sm.Rebind(`INSERT INTO audit_logs (` + auditColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	_, err = r.sm.DB.ExecContext(ctx, query, entry.ID.Hex(), entry.Entity, entry.EntityId.Hex(), entry.Action,
+		actorId, actorName, actorEmail, actorRole, string(changes), entry.CreatedAt)
+	return databaseError(err, "audit log")
+}
+
+func (r auditSqlRepository) FindAll(ctx context.Context, queryParams dtos.AuditQueryParams) ([]model.AuditLog, common.MetaData, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	objects := []model.AuditLog{}
+	where, args := auditWhere(queryParams)
+	var total int64
+	if err := r.sm.DB.QueryRowContext(ctx, r.sm.Rebind(`SELECT COUNT(*) FROM audit_logs`+where), args...).Scan(&total); err != nil {
+		return objects, common.MetaData{}, databaseError(err, "audit log")
+	}
+	metaData := paginate(queryParams.Limit, queryParams.Page, total)
+	query := r.sm.Rebind(`SELECT ` + auditColumns + ` FROM audit_logs` + where + ` ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`)
+	args = append(args, metaData.PerPage, metaData.PerPage*(metaData.CurrentPage-1))
+	err := queryAll(ctx, r.sm.DB, query, func(rows *sql.Rows) error {
+		entry, err := scanAuditLog(rows)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, entry)
+		return nil
+	}, args...)
+	if err != nil {
+		return objects, metaData, databaseError(err, "audit log")
+	}
+	return objects, metaData, nil
+}
+
+// auditWhere builds the WHERE clause of auditFilter.
+func auditWhere(queryParams dtos.AuditQueryParams) (string, []interface{}) {
+	conditions := []string{}
+	args := []interface{}{}
+	if queryParams.Entity != "" {
+		conditions = append(conditions, `entity = ?`)
+		args = append(args, queryParams.Entity)
+	}
+	if queryParams.EntityId != "" {
+		conditions = append(conditions, `entity_id = ?`)
+		args = append(args, queryParams.EntityId)
+	}
+	if queryParams.Action != "" {
+		conditions = append(conditions, `action = ?`)
+		args = append(args, queryParams.Action)
+	}
+	if queryParams.Actor != "" {
+		conditions = append(conditions, `(actor_id = ? OR actor_email = ?)`)
+		args = append(args, queryParams.Actor, queryParams.Actor)
+	}
+	if !queryParams.Since.IsZero() {
+		conditions = append(conditions, `created_at >= ?`)
+		args = append(args, queryParams.Since.UTC())
+	}
+	if !queryParams.Until.IsZero() {
+		conditions = append(conditions, `created_at <= ?`)
+		args = append(args, queryParams.Until.UTC())
+	}
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return ` WHERE ` + strings.Join(conditions, ` AND `), args
+}
+
+func scanAuditLog(scanner rowScanner) (model.AuditLog, error) {
+	var (
+		entry                                     model.AuditLog
+		id, entityId, changes                     string
+		actorId, actorName, actorEmail, actorRole sql.NullString
+	)
+	err := scanner.Scan(&id, &entry.Entity, &entityId, &entry.Action, &actorId, &actorName, &actorEmail, &actorRole, &changes, &entry.CreatedAt)
+	if err != nil {
+		return entry, err
+	}
+	entry.ID = parseId(id)
+	entry.EntityId = parseId(entityId)
+	if actorId.Valid {
+		entry.Actor = &model.AuditActor{
+			ID:    parseId(actorId.String),
+			Name:  actorName.String,
+			Email: actorEmail.String,
+			Role:  actorRole.String,
+		}
+	}
+	if err := json.Unmarshal([]byte(changes), &entry.Changes); err != nil {
+		return entry, err
+	}
+	return entry, nil
+}
+
+func NewAuditSqlRepository() AuditRepository {
+	return &auditSqlRepository{
+		sm: db.GetSqlManager(),
+	}
+}
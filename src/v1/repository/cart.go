@@ -1,11 +1,15 @@
 package repository
 
 import (
+	"context"
 	"log"
 	"time"
 
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/domain_error"
 	"github.com/sajalmia381/store-api/src/enums"
 	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
 	"github.com/sajalmia381/store-api/src/v1/model"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -15,15 +19,22 @@ import (
 
 const cartCollectionName = string(enums.CART_COLLECTION_NAME)
 
+// CartRepository writes reserve the stock of the lines with a ReservedUntil
+// and release the stock of the lines they drop, see reserveLines.
 type CartRepository interface {
-	FindAll() ([]model.Cart, error)
-	DeleteByUserId(userId primitive.ObjectID) (*mongo.DeleteResult, error)
-	FindByUserId(userId primitive.ObjectID) (model.Cart, error)
+	FindAll(ctx context.Context, queryParams dtos.CartQueryParams) ([]model.Cart, common.MetaData, error)
+	DeleteByUserId(ctx context.Context, userId primitive.ObjectID) (*mongo.DeleteResult, error)
+	FindByUserId(ctx context.Context, userId primitive.ObjectID) (model.Cart, error)
+	// ReleaseExpired releases the reservations that ran out before now and
+	// returns their number, the lines stay in the carts.
+	ReleaseExpired(ctx context.Context, now time.Time) (int64, error)
 
 	// Requester Cart
-	UpdateCartByProduct(userId primitive.ObjectID, payload model.CartProductSpec) (model.Cart, error)
-	RemoveProductFromCart(userId primitive.ObjectID, productId primitive.ObjectID) (model.Cart, error)
-	UpdateCartByProducts(userId primitive.ObjectID, payload []model.CartProductSpec) (model.Cart, error)
+	UpdateCartByProduct(ctx context.Context, userId primitive.ObjectID, payload model.CartProductSpec) (model.Cart, error)
+	// RemoveProductFromCart removes the line of the variant, every line of the
+	// product when variantId is nil.
+	RemoveProductFromCart(ctx context.Context, userId primitive.ObjectID, productId primitive.ObjectID, variantId *primitive.ObjectID) (model.Cart, error)
+	UpdateCartByProducts(ctx context.Context, userId primitive.ObjectID, payload []model.CartProductSpec) (model.Cart, error)
 }
 
 type cartRepository struct {
@@ -31,151 +42,198 @@ type cartRepository struct {
 }
 
 // Requester Cart
-func (r cartRepository) UpdateCartByProducts(userId primitive.ObjectID, products []model.CartProductSpec) (model.Cart, error) {
-	var cart model.Cart
-	filter := bson.D{
-		{Key: "userId", Value: userId},
-	}
-	payload := bson.D{
-		{Key: "$set", Value: bson.M{
-			"updatedAt": time.Now().UTC(),
-			"products":  products,
-		}},
-		{Key: "$setOnInsert", Value: bson.M{
-			"createdAt": time.Now().UTC(),
-		}},
-	}
-	coll := r.dm.DB.Collection(cartCollectionName)
-	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
-	result := coll.FindOneAndUpdate(r.dm.Ctx, filter, payload, opts)
-	if err := result.Decode(&cart); err != nil {
-		log.Println("[ERROR] product add to cart:", err)
-		return cart, err
-	}
-	return cart, nil
+func (r cartRepository) UpdateCartByProducts(ctx context.Context, userId primitive.ObjectID, products []model.CartProductSpec) (model.Cart, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	return r.update(ctx, userId, true, func(lines []model.CartProductSpec) []model.CartProductSpec {
+		return products
+	})
+}
+
+func (r cartRepository) UpdateCartByProduct(ctx context.Context, userId primitive.ObjectID, payload model.CartProductSpec) (model.Cart, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	return r.update(ctx, userId, true, func(lines []model.CartProductSpec) []model.CartProductSpec {
+		return withCartLine(lines, payload)
+	})
+}
+
+func (r cartRepository) RemoveProductFromCart(ctx context.Context, userId primitive.ObjectID, productId primitive.ObjectID, variantId *primitive.ObjectID) (model.Cart, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	return r.update(ctx, userId, false, func(lines []model.CartProductSpec) []model.CartProductSpec {
+		return withoutCartLines(lines, productId, variantId)
+	})
 }
 
-func (r cartRepository) UpdateCartByProduct(userId primitive.ObjectID, payload model.CartProductSpec) (model.Cart, error) {
+// update changes the lines of the user cart, creating the cart when missing
+// with upsert. Without transactions a concurrent write to the same cart may
+// reserve its lines twice, carts are written by their owner only.
+func (r cartRepository) update(ctx context.Context, userId primitive.ObjectID, upsert bool, change func([]model.CartProductSpec) []model.CartProductSpec) (model.Cart, error) {
 	var cart model.Cart
+	coll := r.dm.Collection(cartCollectionName)
 	filter := bson.D{
 		{Key: "userId", Value: userId},
-		{Key: "products.productId", Value: payload.ProductId},
 	}
-	// arrFilter := options.ArrayFilters{
-	// 	Filters: bson.A{
-	// 		bson.M{"x.productId": payload.ProductId}, // if For Nest Array := bson.M{"y.property_name": filterValue},
-	// 	},
-	// }
-	update := bson.D{
-		{Key: "$set", Value: bson.M{
-			"updatedAt":  time.Now().UTC(),
-			"products.$": payload,
-		}},
-	}
-
-	upsert := true
-	returnDoc := options.After
-
-	opts := &options.FindOneAndUpdateOptions{
-		Upsert:         &upsert,
-		ReturnDocument: &returnDoc,
-		// ArrayFilters:   &arrFilter,
-	}
-
-	coll := r.dm.DB.Collection(cartCollectionName)
-	result := coll.FindOneAndUpdate(r.dm.Ctx, filter, update, opts)
-	if err := result.Decode(&cart); err != nil {
-		// User Cart not exists
-		_filter := bson.D{
-			{Key: "userId", Value: userId},
+	err := withMongoTx(ctx, r.dm, func(ctx context.Context) error {
+		var before model.Cart
+		err := coll.FindOne(ctx, filter).Decode(&before)
+		if err != nil && (err != mongo.ErrNoDocuments || !upsert) {
+			return err
 		}
-		_update := bson.D{
-			{Key: "$push", Value: bson.M{
-				"products": payload,
-			}},
+		products, err := reserveLines(before.Products, change(before.Products), mongoReserve(ctx, r.dm))
+		if err != nil {
+			return err
+		}
+		update := bson.D{
 			{Key: "$set", Value: bson.M{
 				"updatedAt": time.Now().UTC(),
+				"products":  products,
+			}},
+			{Key: "$setOnInsert", Value: bson.M{
+				"createdAt": time.Now().UTC(),
 			}},
-			{
-				Key: "$setOnInsert", Value: bson.M{
-					"createdAt": time.Now().UTC(),
-				},
-			},
-		}
-		_result := coll.FindOneAndUpdate(r.dm.Ctx, _filter, _update, opts)
-		if err := _result.Decode(&cart); err != nil {
-			log.Println("inner err", err)
-			return cart, err
 		}
-		log.Println("[INFO] User Cart Created")
+		opts := options.FindOneAndUpdate().SetUpsert(upsert).SetReturnDocument(options.After)
+		return coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&cart)
+	})
+	if err != nil && domain_error.KindOf(err) == "" {
+		log.Println("[ERROR] cart update:", err)
 	}
-	return cart, nil
+	return cart, databaseError(err, "cart")
 }
 
-func (r cartRepository) RemoveProductFromCart(userId primitive.ObjectID, productId primitive.ObjectID) (model.Cart, error) {
-	var cart model.Cart
-	filter := bson.D{
-		{Key: "userId", Value: userId},
-	}
-	update := bson.D{
-		{Key: "$pull", Value: bson.M{
-			"products": bson.M{
-				"productId": productId,
-			},
-		}},
-	}
-	returnDoc := options.After
-
-	opts := &options.FindOneAndUpdateOptions{
-		ReturnDocument: &returnDoc,
-	}
-
-	coll := r.dm.DB.Collection(cartCollectionName)
-	result := coll.FindOneAndUpdate(r.dm.Ctx, filter, update, opts)
-	if err := result.Decode(&cart); err != nil {
-		log.Println("[ERROR] cart remove product: ", err)
-		return cart, err
+// mongoReserve reserves the stock of the products collection. The update only
+// takes what is available, so concurrent reservations never oversell. It bumps
+// the version, the ETag of a product covers its available stock.
+func mongoReserve(ctx context.Context, dm *db.DmManager) reserveFunc {
+	coll := dm.Collection(string(enums.PRODUCT_COLLECTION_NAME))
+	return func(productId primitive.ObjectID, delta int) (stockHold, error) {
+		if delta != 0 {
+			filter := bson.M{"_id": productId, "available": bson.M{"$gte": delta}}
+			result, err := coll.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"available": -delta, "version": 1}})
+			if err != nil || result.MatchedCount > 0 {
+				return stockHold{tracked: true}, err
+			}
+		}
+		var product model.Product
+		opts := options.FindOne().SetProjection(bson.M{"title": 1, "available": 1})
+		err := coll.FindOne(ctx, bson.M{"_id": productId}, opts).Decode(&product)
+		if err == mongo.ErrNoDocuments || err == nil && product.Available == nil {
+			return stockHold{}, nil
+		}
+		if err != nil {
+			return stockHold{}, err
+		}
+		return stockHold{tracked: true, short: delta > 0, title: product.Title, available: *product.Available}, nil
 	}
-	return cart, nil
 }
 
 // Cart CRUD
-func (r cartRepository) FindAll() ([]model.Cart, error) {
+func (r cartRepository) FindAll(ctx context.Context, queryParams dtos.CartQueryParams) ([]model.Cart, common.MetaData, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
 	var carts []model.Cart
+	var metaData common.MetaData
 
 	filter := bson.D{}
+	if queryParams.Product != "" {
+		productId, _ := primitive.ObjectIDFromHex(queryParams.Product)
+		filter = append(filter, bson.E{Key: "products.productId", Value: productId})
+	}
 
-	coll := r.dm.DB.Collection(cartCollectionName)
-	cursor, err := coll.Find(r.dm.Ctx, filter)
+	coll := r.dm.Collection(cartCollectionName)
+	if queryParams.Limit != 0 {
+		total, err := coll.CountDocuments(ctx, filter)
+		if err != nil {
+			return carts, metaData, databaseError(err, "cart")
+		}
+		metaData = listMetaData(queryParams.ListQueryParams, total)
+	}
+	cursor, err := coll.Find(ctx, filter, listFindOptions(queryParams.ListQueryParams))
 	if err != nil {
-		return carts, err
+		return carts, metaData, databaseError(err, "cart")
 	}
-	if err := cursor.All(r.dm.Ctx, &carts); err != nil {
+	if err := cursor.All(ctx, &carts); err != nil {
 		log.Println("[ERROR] Cart Decade: ", err)
-		panic(err)
+		return carts, metaData, databaseError(err, "cart")
 	}
-	return carts, nil
+	return carts, metaData, nil
 }
 
-func (r cartRepository) FindByUserId(userId primitive.ObjectID) (model.Cart, error) {
+func (r cartRepository) FindByUserId(ctx context.Context, userId primitive.ObjectID) (model.Cart, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
 	var cart model.Cart
 	filter := bson.D{
 		{Key: "userId", Value: userId},
 	}
-	coll := r.dm.DB.Collection(cartCollectionName)
-	if err := coll.FindOne(r.dm.Ctx, filter).Decode(&cart); err != nil {
-		return cart, err
+	coll := r.dm.Collection(cartCollectionName)
+	if err := coll.FindOne(ctx, filter).Decode(&cart); err != nil {
+		return cart, databaseError(err, "cart")
 	}
 	return cart, nil
 }
 
-func (r cartRepository) DeleteByUserId(userId primitive.ObjectID) (*mongo.DeleteResult, error) {
+func (r cartRepository) DeleteByUserId(ctx context.Context, userId primitive.ObjectID) (*mongo.DeleteResult, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
 	filter := bson.D{
 		{Key: "userId", Value: userId},
 	}
-	coll := r.dm.DB.Collection(cartCollectionName)
-	res, err := coll.DeleteOne(r.dm.Ctx, filter)
-	return res, err
+	coll := r.dm.Collection(cartCollectionName)
+	res := &mongo.DeleteResult{}
+	err := withMongoTx(ctx, r.dm, func(ctx context.Context) error {
+		var cart model.Cart
+		if err := coll.FindOne(ctx, filter).Decode(&cart); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil
+			}
+			return err
+		}
+		if _, err := reserveLines(cart.Products, nil, mongoReserve(ctx, r.dm)); err != nil {
+			return err
+		}
+		var err error
+		res, err = coll.DeleteOne(ctx, bson.D{{Key: "_id", Value: cart.ID}})
+		return err
+	})
+	return res, databaseError(err, "cart")
+}
+
+func (r cartRepository) ReleaseExpired(ctx context.Context, now time.Time) (int64, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var carts []model.Cart
+	coll := r.dm.Collection(cartCollectionName)
+	cursor, err := coll.Find(ctx, bson.M{"products.reservedUntil": bson.M{"$lt": now}})
+	if err != nil {
+		return 0, databaseError(err, "cart")
+	}
+	if err := cursor.All(ctx, &carts); err != nil {
+		return 0, databaseError(err, "cart")
+	}
+	var total int64
+	for _, cart := range carts {
+		products, count := expireLines(cart.Products, now)
+		err := withMongoTx(ctx, r.dm, func(ctx context.Context) error {
+			// the lines are released once they are saved, a cart changed
+			// meanwhile is left to the next run
+			filter := bson.M{"_id": cart.ID, "products": cart.Products}
+			result, err := coll.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"products": products}})
+			if err != nil || result.MatchedCount == 0 {
+				count = 0
+				return err
+			}
+			_, err = reserveLines(cart.Products, products, mongoReserve(ctx, r.dm))
+			return err
+		})
+		if err != nil {
+			return total, databaseError(err, "cart")
+		}
+		total += count
+	}
+	return total, nil
 }
 
 func NewCartRepository() CartRepository {
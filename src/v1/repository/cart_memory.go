@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type cartMemoryRepository struct {
+	mm *db.MemoryManager
+}
+
+// Requester Cart
+func (r cartMemoryRepository) UpdateCartByProducts(ctx context.Context, userId primitive.ObjectID, products []model.CartProductSpec) (model.Cart, error) {
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	cart := r.findOrCreateByUserId(userId)
+	return r.save(cart, append([]model.CartProductSpec{}, products...))
+}
+
+func (r cartMemoryRepository) UpdateCartByProduct(ctx context.Context, userId primitive.ObjectID, payload model.CartProductSpec) (model.Cart, error) {
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	cart := r.findOrCreateByUserId(userId)
+	return r.save(cart, withCartLine(cart.Products, payload))
+}
+
+func (r cartMemoryRepository) RemoveProductFromCart(ctx context.Context, userId primitive.ObjectID, productId primitive.ObjectID, variantId *primitive.ObjectID) (model.Cart, error) {
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	cart, ok := r.findByUserId(userId)
+	if !ok {
+		return cart, domain_error.NotFound("cart is not found")
+	}
+	products, err := reserveLines(cart.Products, withoutCartLines(cart.Products, productId, variantId), memoryReserve(r.mm.Products))
+	if err != nil {
+		return cart, err
+	}
+	cart.Products = products
+	r.mm.Carts[cart.ID] = cart
+	return cart, nil
+}
+
+// save reserves the stock of the new lines of the cart and stores it.
+func (r cartMemoryRepository) save(cart model.Cart, products []model.CartProductSpec) (model.Cart, error) {
+	products, err := reserveLines(cart.Products, products, memoryReserve(r.mm.Products))
+	if err != nil {
+		return cart, err
+	}
+	cart.Products = products
+	cart.UpdatedAt = time.Now().UTC()
+	r.mm.Carts[cart.ID] = cart
+	return cart, nil
+}
+
+// Cart CRUD
+func (r cartMemoryRepository) FindAll(ctx context.Context, queryParams dtos.CartQueryParams) ([]model.Cart, common.MetaData, error) {
+	r.mm.RLock()
+	defer r.mm.RUnlock()
+	carts := []model.Cart{}
+	for _, cart := range r.mm.Carts {
+		if queryParams.Product != "" && !cartHoldsProduct(cart, queryParams.Product) {
+			continue
+		}
+		carts = append(carts, cart)
+	}
+	sort.Slice(carts, func(i, j int) bool {
+		return memoryLess(queryParams.ListQueryParams, compareCarts(carts[i], carts[j], queryParams.OrderBy), carts[i].ID, carts[j].ID)
+	})
+	metaData := listMetaData(queryParams.ListQueryParams, int64(len(carts)))
+	start, end := memoryPage(queryParams.ListQueryParams, len(carts))
+	return carts[start:end], metaData, nil
+}
+
+func cartHoldsProduct(cart model.Cart, productId string) bool {
+	for _, item := range cart.Products {
+		if item.ProductId.Hex() == productId {
+			return true
+		}
+	}
+	return false
+}
+
+// compareCarts compares the field of a and b the list is ordered by.
+func compareCarts(a model.Cart, b model.Cart, field string) int {
+	switch field {
+	case "createdAt":
+		return compareTime(a.CreatedAt, b.CreatedAt)
+	case "updatedAt":
+		return compareTime(a.UpdatedAt, b.UpdatedAt)
+	}
+	return 0
+}
+
+func (r cartMemoryRepository) FindByUserId(ctx context.Context, userId primitive.ObjectID) (model.Cart, error) {
+	r.mm.RLock()
+	defer r.mm.RUnlock()
+	cart, ok := r.findByUserId(userId)
+	if !ok {
+		return cart, domain_error.NotFound("cart is not found")
+	}
+	return cart, nil
+}
+
+func (r cartMemoryRepository) DeleteByUserId(ctx context.Context, userId primitive.ObjectID) (*mongo.DeleteResult, error) {
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	cart, ok := r.findByUserId(userId)
+	if !ok {
+		return &mongo.DeleteResult{}, nil
+	}
+	if _, err := reserveLines(cart.Products, nil, memoryReserve(r.mm.Products)); err != nil {
+		return &mongo.DeleteResult{}, err
+	}
+	delete(r.mm.Carts, cart.ID)
+	return &mongo.DeleteResult{DeletedCount: 1}, nil
+}
+
+func (r cartMemoryRepository) ReleaseExpired(ctx context.Context, now time.Time) (int64, error) {
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	var total int64
+	for id, cart := range r.mm.Carts {
+		products, count := expireLines(cart.Products, now)
+		if count == 0 {
+			continue
+		}
+		if _, err := reserveLines(cart.Products, products, memoryReserve(r.mm.Products)); err != nil {
+			return total, err
+		}
+		cart.Products = products
+		r.mm.Carts[id] = cart
+		total += count
+	}
+	return total, nil
+}
+
+// Helpers below expect the caller to hold the memory manager lock.
+
+// memoryReserve reserves the stock of mm.Products and bumps their version.
+func memoryReserve(products map[primitive.ObjectID]model.Product) reserveFunc {
+	return func(productId primitive.ObjectID, delta int) (stockHold, error) {
+		product, ok := products[productId]
+		if !ok || product.Available == nil {
+			return stockHold{}, nil
+		}
+		if delta > 0 && *product.Available < delta {
+			return stockHold{tracked: true, short: true, title: product.Title, available: *product.Available}, nil
+		}
+		available := *product.Available - delta
+		product.Available = &available
+		product.Version++
+		products[productId] = product
+		return stockHold{tracked: true}, nil
+	}
+}
+
+func (r cartMemoryRepository) findByUserId(userId primitive.ObjectID) (model.Cart, bool) {
+	for _, cart := range r.mm.Carts {
+		if cart.UserId == userId {
+			return cart, true
+		}
+	}
+	return model.Cart{}, false
+}
+
+func (r cartMemoryRepository) findOrCreateByUserId(userId primitive.ObjectID) model.Cart {
+	cart, ok := r.findByUserId(userId)
+	if !ok {
+		cart = model.Cart{
+			ID:        primitive.NewObjectID(),
+			UserId:    userId,
+			Products:  []model.CartProductSpec{},
+			CreatedAt: time.Now().UTC(),
+		}
+	}
+	return cart
+}
+
+func NewCartMemoryRepository() CartRepository {
+	return &cartMemoryRepository{
+		mm: db.GetMemoryManager(),
+	}
+}
@@ -0,0 +1,349 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type cartSqlRepository struct {
+	sm *db.SqlManager
+}
+
+// Requester Cart
+func (r cartSqlRepository) UpdateCartByProducts(ctx context.Context, userId primitive.ObjectID, products []model.CartProductSpec) (model.Cart, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	return r.update(ctx, userId, func(lines []model.CartProductSpec) []model.CartProductSpec {
+		return products
+	})
+}
+
+func (r cartSqlRepository) UpdateCartByProduct(ctx context.Context, userId primitive.ObjectID, payload model.CartProductSpec) (model.Cart, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	return r.update(ctx, userId, func(lines []model.CartProductSpec) []model.CartProductSpec {
+		return withCartLine(lines, payload)
+	})
+}
+
+func (r cartSqlRepository) RemoveProductFromCart(ctx context.Context, userId primitive.ObjectID, productId primitive.ObjectID, variantId *primitive.ObjectID) (model.Cart, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var cart model.Cart
+	err := withTx(ctx, r.sm, func(tx *sql.Tx) error {
+		var err error
+		cart, err = r.findByUserId(ctx, tx, userId)
+		if err != nil {
+			return err
+		}
+		if err := r.putProducts(ctx, tx, cart, withoutCartLines(cart.Products, productId, variantId)); err != nil {
+			return err
+		}
+		cart, err = r.findByUserId(ctx, tx, userId)
+		return err
+	})
+	return cart, databaseError(err, "cart")
+}
+
+// update changes the lines of the user cart, creating the cart when missing.
+func (r cartSqlRepository) update(ctx context.Context, userId primitive.ObjectID, change func([]model.CartProductSpec) []model.CartProductSpec) (model.Cart, error) {
+	var cart model.Cart
+	err := withTx(ctx, r.sm, func(tx *sql.Tx) error {
+		if _, err := r.upsertCart(ctx, tx, userId); err != nil {
+			return err
+		}
+		var err error
+		cart, err = r.findByUserId(ctx, tx, userId)
+		if err != nil {
+			return err
+		}
+		if err := r.putProducts(ctx, tx, cart, change(cart.Products)); err != nil {
+			return err
+		}
+		cart, err = r.findByUserId(ctx, tx, userId)
+		return err
+	})
+	return cart, databaseError(err, "cart")
+}
+
+// Cart CRUD
+var cartOrderColumns = map[string]string{
+	"id":        "id",
+	"createdAt": "created_at",
+	"updatedAt": "updated_at",
+}
+
+func (r cartSqlRepository) FindAll(ctx context.Context, queryParams dtos.CartQueryParams) ([]model.Cart, common.MetaData, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	carts := []model.Cart{}
+	var metaData common.MetaData
+	where := ``
+	args := []interface{}{}
+	if queryParams.Product != "" {
+		where = ` WHERE id IN (SELECT cart_id FROM cart_products WHERE product_id = ?)`
+		args = append(args, queryParams.Product)
+	}
+	if queryParams.Limit != 0 {
+		var total int64
+		if err := r.sm.DB.QueryRowContext(ctx, r.sm.Rebind(`SELECT COUNT(*) FROM carts`+where), args...).Scan(&total); err != nil {
+			return carts, metaData, databaseError(err, "cart")
+		}
+		metaData = listMetaData(queryParams.ListQueryParams, total)
+	}
+	clause, clauseArgs := sqlListClause(queryParams.ListQueryParams, cartOrderColumns, "carts")
+	err := queryAll(ctx, r.sm.DB, r.sm.Rebind(`SELECT id, user_id, created_at, updated_at FROM carts`+where+clause), func(rows *sql.Rows) error {
+		cart, err := scanCart(rows)
+		if err != nil {
+			return err
+		}
+		carts = append(carts, cart)
+		return nil
+	}, append(args, clauseArgs...)...)
+	if err != nil {
+		return carts, metaData, databaseError(err, "cart")
+	}
+	products, err := r.findProducts(ctx, r.sm.DB, nil)
+	if err != nil {
+		return carts, metaData, databaseError(err, "cart")
+	}
+	for i := range carts {
+		if items, ok := products[carts[i].ID]; ok {
+			carts[i].Products = items
+		}
+	}
+	return carts, metaData, nil
+}
+
+func (r cartSqlRepository) FindByUserId(ctx context.Context, userId primitive.ObjectID) (model.Cart, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	cart, err := r.findByUserId(ctx, r.sm.DB, userId)
+	return cart, databaseError(err, "cart")
+}
+
+func (r cartSqlRepository) DeleteByUserId(ctx context.Context, userId primitive.ObjectID) (*mongo.DeleteResult, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	result := &mongo.DeleteResult{}
+	err := withTx(ctx, r.sm, func(tx *sql.Tx) error {
+		cart, err := r.findByUserId(ctx, tx, userId)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil
+			}
+			return err
+		}
+		if err := r.putProducts(ctx, tx, cart, nil); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, r.sm.Rebind(`DELETE FROM carts WHERE id = ?`), cart.ID.Hex()); err != nil {
+			return err
+		}
+		result.DeletedCount = 1
+		return nil
+	})
+	return result, databaseError(err, "cart")
+}
+
+func (r cartSqlRepository) ReleaseExpired(ctx context.Context, now time.Time) (int64, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var total int64
+	err := withTx(ctx, r.sm, func(tx *sql.Tx) error {
+		cartIds := []string{}
+		query := r.sm.Rebind(`SELECT DISTINCT cart_id FROM cart_products WHERE reserved_until < ?`)
+		err := queryAll(ctx, tx, query, func(rows *sql.Rows) error {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+			cartIds = append(cartIds, id)
+			return nil
+		}, now)
+		if err != nil {
+			return err
+		}
+		for _, id := range cartIds {
+			cartId := parseId(id)
+			products, err := r.findProducts(ctx, tx, &cartId)
+			if err != nil {
+				return err
+			}
+			lines, count := expireLines(products[cartId], now)
+			if err := r.putProducts(ctx, tx, model.Cart{ID: cartId, Products: products[cartId]}, lines); err != nil {
+				return err
+			}
+			total += count
+		}
+		return nil
+	})
+	return total, databaseError(err, "cart")
+}
+
+// upsertCart returns the id of the user cart, creating the cart when missing.
+func (r cartSqlRepository) upsertCart(ctx context.Context, executor sqlExecutor, userId primitive.ObjectID) (primitive.ObjectID, error) {
+	var id string
+	now := time.Now().UTC()
+	err := executor.QueryRowContext(ctx, r.sm.Rebind(`SELECT id FROM carts WHERE user_id = ?`), userId.Hex()).Scan(&id)
+	if err == sql.ErrNoRows {
+		cartId := primitive.NewObjectID()
+		query := r.sm.Rebind(`INSERT INTO carts (id, user_id, created_at, updated_at) VALUES (?, ?, ?, ?)`)
+		_, err := executor.ExecContext(ctx, query, cartId.Hex(), userId.Hex(), now, now)
+		return cartId, err
+	}
+	if err != nil {
+		return primitive.ObjectID{}, err
+	}
+	_, err = executor.ExecContext(ctx, r.sm.Rebind(`UPDATE carts SET updated_at = ? WHERE id = ?`), now, id)
+	return parseId(id), err
+}
+
+// putProducts reserves the stock of the new lines of the cart and replaces its
+// lines with them.
+func (r cartSqlRepository) putProducts(ctx context.Context, executor sqlExecutor, cart model.Cart, products []model.CartProductSpec) error {
+	products, err := reserveLines(cart.Products, products, sqlReserve(ctx, r.sm, executor))
+	if err != nil {
+		return err
+	}
+	if _, err := executor.ExecContext(ctx, r.sm.Rebind(`DELETE FROM cart_products WHERE cart_id = ?`), cart.ID.Hex()); err != nil {
+		return err
+	}
+	query := r.sm.Rebind(`INSERT INTO cart_products (cart_id, product_id, variant_id, quantity, reserved_until, position) VALUES (?, ?, ?, ?, ?, ?)`)
+	for position, item := range products {
+		_, err := executor.ExecContext(ctx, query, cart.ID.Hex(), item.ProductId.Hex(), variantKey(item.VariantId), int64(item.Quantity),
+			nullableTime(item.ReservedUntil), position)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sqlReserve reserves the stock of the products table. The update only takes
+// what is available, so concurrent reservations never oversell. It bumps the
+// version, the ETag of a product covers its available stock.
+func sqlReserve(ctx context.Context, sm *db.SqlManager, executor sqlExecutor) reserveFunc {
+	return func(productId primitive.ObjectID, delta int) (stockHold, error) {
+		if delta != 0 {
+			query := sm.Rebind(`UPDATE products SET available = available - ?, version = version + 1 WHERE id = ? AND available IS NOT NULL AND available >= ?`)
+			result, err := executor.ExecContext(ctx, query, delta, productId.Hex(), delta)
+			if err != nil {
+				return stockHold{}, err
+			}
+			if count, err := result.RowsAffected(); err != nil || count > 0 {
+				return stockHold{tracked: true}, err
+			}
+		}
+		var (
+			title     string
+			available sql.NullInt64
+		)
+		err := executor.QueryRowContext(ctx, sm.Rebind(`SELECT title, available FROM products WHERE id = ?`), productId.Hex()).Scan(&title, &available)
+		if err == sql.ErrNoRows || err == nil && !available.Valid {
+			return stockHold{}, nil
+		}
+		if err != nil {
+			return stockHold{}, err
+		}
+		return stockHold{tracked: true, short: delta > 0, title: title, available: int(available.Int64)}, nil
+	}
+}
+
+// variantKey is the variant_id of a cart line, empty for a line of the product
+// itself. It is part of the primary key, so it is never NULL.
+func variantKey(id *primitive.ObjectID) string {
+	if id == nil {
+		return ""
+	}
+	return id.Hex()
+}
+
+func parseVariantKey(key string) *primitive.ObjectID {
+	if key == "" {
+		return nil
+	}
+	id := parseId(key)
+	return &id
+}
+
+func (r cartSqlRepository) findByUserId(ctx context.Context, executor sqlExecutor, userId primitive.ObjectID) (model.Cart, error) {
+	query := r.sm.Rebind(`SELECT id, user_id, created_at, updated_at FROM carts WHERE user_id = ?`)
+	cart, err := scanCart(executor.QueryRowContext(ctx, query, userId.Hex()))
+	if err != nil {
+		return cart, err
+	}
+	products, err := r.findProducts(ctx, executor, &cart.ID)
+	if err != nil {
+		return cart, err
+	}
+	if items, ok := products[cart.ID]; ok {
+		cart.Products = items
+	}
+	return cart, nil
+}
+
+// findProducts loads the Cart.Products arrays, of a single cart when cartId is
+// set, otherwise of every cart.
+func (r cartSqlRepository) findProducts(ctx context.Context, executor sqlExecutor, cartId *primitive.ObjectID) (map[primitive.ObjectID][]model.CartProductSpec, error) {
+	products := map[primitive.ObjectID][]model.CartProductSpec{}
+	query := `SELECT cart_id, product_id, variant_id, quantity, reserved_until FROM cart_products`
+	args := []interface{}{}
+	if cartId != nil {
+		query += ` WHERE cart_id = ?`
+		args = append(args, cartId.Hex())
+	}
+	query += ` ORDER BY position`
+	rows, err := executor.QueryContext(ctx, r.sm.Rebind(query), args...)
+	if err != nil {
+		return products, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			_cartId, productId, variantId string
+			quantity                      int64
+			reservedUntil                 sql.NullTime
+		)
+		if err := rows.Scan(&_cartId, &productId, &variantId, &quantity, &reservedUntil); err != nil {
+			return products, err
+		}
+		id := parseId(_cartId)
+		products[id] = append(products[id], model.CartProductSpec{
+			ProductId:     parseId(productId),
+			VariantId:     parseVariantKey(variantId),
+			Quantity:      uint16(quantity),
+			ReservedUntil: parseNullableTime(reservedUntil),
+		})
+	}
+	return products, rows.Err()
+}
+
+func scanCart(scanner rowScanner) (model.Cart, error) {
+	var (
+		cart   model.Cart
+		id     string
+		userId string
+	)
+	if err := scanner.Scan(&id, &userId, &cart.CreatedAt, &cart.UpdatedAt); err != nil {
+		return cart, err
+	}
+	cart.ID = parseId(id)
+	cart.UserId = parseId(userId)
+	cart.Products = []model.CartProductSpec{}
+	return cart, nil
+}
+
+func NewCartSqlRepository() CartRepository {
+	return &cartSqlRepository{
+		sm: db.GetSqlManager(),
+	}
+}
@@ -1,12 +1,15 @@
 package repository
 
 import (
-	"errors"
+	"context"
 	"log"
+	"time"
 
+	"github.com/sajalmia381/store-api/src/api/common"
 	"github.com/sajalmia381/store-api/src/enums"
 	"github.com/sajalmia381/store-api/src/utils"
 	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
 	"github.com/sajalmia381/store-api/src/v1/model"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -15,179 +18,334 @@ import (
 )
 
 type CategoryRepository interface {
-	Store(category model.Category) (model.Category, error)
-	FindAll() ([]model.Category, error)
-	FindBySlug(slug string) (model.Category, error)
-	UpdateBySlug(slug string, payload primitive.M) (model.Category, error)
-	DeleteBySlug(slug string) (*mongo.DeleteResult, error)
-	PushProductToCategory(categoryId primitive.ObjectID, productId primitive.ObjectID) error
-	RemoveProductFromCategory(categoryId primitive.ObjectID, productId primitive.ObjectID) error
-	ChangeProductInCategory(oldCategoryId primitive.ObjectID, newCategoryId primitive.ObjectID, productId primitive.ObjectID) error
+	Store(ctx context.Context, category model.Category) (model.Category, error)
+	FindAll(ctx context.Context, queryParams dtos.CategoryQueryParams) ([]model.Category, common.MetaData, error)
+	// Each calls fn with the categories of the query one at a time, in the
+	// order of the list and not paged, until fn fails.
+	Each(ctx context.Context, queryParams dtos.CategoryQueryParams, fn func(model.Category) error) error
+	FindBySlug(ctx context.Context, slug string) (model.Category, error)
+	// UpdateBySlug and DeleteBySlug fail with PRECONDITION_FAILED unless the
+	// category is at the given version, a nil version skips the check.
+	UpdateBySlug(ctx context.Context, slug string, payload primitive.M, version *int64) (model.Category, error)
+	// DeleteBySlug moves the category to the trash, its products become
+	// uncategorized until it is restored.
+	DeleteBySlug(ctx context.Context, slug string, version *int64) (*mongo.DeleteResult, error)
+	IsSlugExists(ctx context.Context, slug string) bool
+	FindTrash(ctx context.Context) ([]model.Category, error)
+	FindTrashedBySlug(ctx context.Context, slug string) (model.Category, error)
+	// RestoreBySlug takes the category out of the trash and links back the
+	// products it had, unless they are trashed or moved to another category.
+	RestoreBySlug(ctx context.Context, slug string) (model.Category, error)
+	// PurgeBySlug removes a trashed category permanently.
+	PurgeBySlug(ctx context.Context, slug string) (model.Category, error)
+	// PurgeDeletedBefore removes the categories trashed before the given time
+	// permanently and counts them.
+	PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error)
+	// ReconcileProducts rebuilds every Category.Products from the products
+	// referencing the category and reports the drift. dryRun only reports.
+	ReconcileProducts(ctx context.Context, dryRun bool) ([]dtos.CategoryDriftDto, error)
 }
 
 type categoryRepository struct {
 	dm *db.DmManager
 }
 
-func (r categoryRepository) Store(category model.Category) (model.Category, error) {
-	coll := r.dm.DB.Collection(string(enums.CATEGORY_COLLECTION_NAME))
-	category.Slug = utils.GenerateUniqueSlug(category.Name, string(enums.CATEGORY_COLLECTION_NAME))
-	_, err := coll.InsertOne(r.dm.Ctx, &category)
+func (r categoryRepository) Store(ctx context.Context, category model.Category) (model.Category, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	coll := r.dm.Collection(string(enums.CATEGORY_COLLECTION_NAME))
+	category.Slug = utils.GenerateUniqueSlug(ctx, category.Name, r.IsSlugExists)
+	_, err := coll.InsertOne(ctx, &category)
 	if err != nil {
 		log.Println("[ERROR] Category Store err: ", err)
-		return category, err
+		return category, databaseError(err, "category")
 	}
 	return category, nil
 }
 
-func (r categoryRepository) FindAll() ([]model.Category, error) {
+func (r categoryRepository) FindAll(ctx context.Context, queryParams dtos.CategoryQueryParams) ([]model.Category, common.MetaData, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
 	var objects []model.Category
-	coll := r.dm.DB.Collection(string(enums.CATEGORY_COLLECTION_NAME))
-	filter := bson.D{}
-	result, err := coll.Find(r.dm.Ctx, filter)
+	var metaData common.MetaData
+	coll := r.dm.Collection(string(enums.CATEGORY_COLLECTION_NAME))
+	filter := categoryFilter(queryParams)
+	if queryParams.Limit != 0 {
+		total, err := coll.CountDocuments(ctx, filter)
+		if err != nil {
+			return objects, metaData, databaseError(err, "category")
+		}
+		metaData = listMetaData(queryParams.ListQueryParams, total)
+	}
+	result, err := coll.Find(ctx, filter, listFindOptions(queryParams.ListQueryParams))
 	if err != nil {
-		return objects, err
+		return objects, metaData, databaseError(err, "category")
 	}
-	if err := result.All(r.dm.Ctx, &objects); err != nil {
+	if err := result.All(ctx, &objects); err != nil {
 		log.Println("[ERROR] category collection cursor", err.Error())
-		panic(err)
+		return objects, metaData, databaseError(err, "category")
 	}
-	return objects, nil
+	return objects, metaData, nil
+}
+
+func (r categoryRepository) Each(ctx context.Context, queryParams dtos.CategoryQueryParams, fn func(model.Category) error) error {
+	ctx, cancel := db.StreamContext(ctx)
+	defer cancel()
+	queryParams.Limit, queryParams.Page = 0, 0
+	opts := listFindOptions(queryParams.ListQueryParams).SetAllowDiskUse(true)
+	cursor, err := r.dm.Collection(string(enums.CATEGORY_COLLECTION_NAME)).Find(ctx, categoryFilter(queryParams), opts)
+	if err != nil {
+		return databaseError(err, "category")
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var category model.Category
+		if err := cursor.Decode(&category); err != nil {
+			return databaseError(err, "category")
+		}
+		if err := fn(category); err != nil {
+			return err
+		}
+	}
+	return databaseError(cursor.Err(), "category")
+}
+
+func categoryFilter(queryParams dtos.CategoryQueryParams) bson.D {
+	filter := bson.D{notDeleted}
+	if queryParams.Search != "" {
+		filter = append(filter, searchFilter(queryParams.Search, "name", "description"))
+	}
+	return filter
 }
 
-func (r categoryRepository) FindBySlug(slug string) (model.Category, error) {
+func (r categoryRepository) FindBySlug(ctx context.Context, slug string) (model.Category, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
 	var category model.Category
 	filter := bson.D{
 		{Key: "slug", Value: slug},
+		notDeleted,
 	}
-	coll := r.dm.DB.Collection(string(enums.CATEGORY_COLLECTION_NAME))
-	result := coll.FindOne(r.dm.Ctx, filter)
+	coll := r.dm.Collection(string(enums.CATEGORY_COLLECTION_NAME))
+	result := coll.FindOne(ctx, filter)
 	if err := result.Decode(&category); err != nil {
-		if err == mongo.ErrNoDocuments {
-			return category, errors.New("category is not found")
-		}
-		log.Println("[ERROR]:", err.Error())
-		panic(err)
+		return category, databaseError(err, "category")
 	}
 	return category, nil
 }
 
-func (r categoryRepository) UpdateBySlug(slug string, payload primitive.M) (model.Category, error) {
+func (r categoryRepository) UpdateBySlug(ctx context.Context, slug string, payload primitive.M, version *int64) (model.Category, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
 	var category model.Category
-	coll := r.dm.DB.Collection(string(enums.CATEGORY_COLLECTION_NAME))
+	coll := r.dm.Collection(string(enums.CATEGORY_COLLECTION_NAME))
 
 	filter := bson.D{
 		{Key: "slug", Value: slug},
+		notDeleted,
 	}
-	update := bson.D{
-		{Key: "$set", Value: payload},
-	}
+	update := withVersionInc(bson.M{
+		"$set": payload,
+	})
 
 	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 
-	result := coll.FindOneAndUpdate(r.dm.Ctx, filter, update, opts)
+	result := coll.FindOneAndUpdate(ctx, withVersion(filter, version), update, opts)
 	if err := result.Decode(&category); err != nil {
-		if err == mongo.ErrNoDocuments {
-			return category, errors.New("category is not exists")
+		if err != mongo.ErrNoDocuments {
+			log.Println("[ERROR] Update document count", err)
 		}
-		log.Println("[ERROR] Update document count", err)
-		panic(err)
+		return category, databaseError(versionMismatch(ctx, coll, filter, version, "category", err), "category")
 	}
 	return category, nil
 }
 
-func (r categoryRepository) DeleteBySlug(slug string) (*mongo.DeleteResult, error) {
+func (r categoryRepository) DeleteBySlug(ctx context.Context, slug string, version *int64) (*mongo.DeleteResult, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
 	filter := bson.D{
 		{Key: "slug", Value: slug},
+		notDeleted,
 	}
-	coll := r.dm.DB.Collection(string(enums.CATEGORY_COLLECTION_NAME))
-	result, err := coll.DeleteOne(r.dm.Ctx, filter)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return result, errors.New("category is not found! Maybe already deleted")
+	update := withVersionInc(bson.M{"$set": bson.M{"deletedAt": time.Now().UTC()}})
+	coll := r.dm.Collection(string(enums.CATEGORY_COLLECTION_NAME))
+	productColl := r.dm.Collection(string(enums.PRODUCT_COLLECTION_NAME))
+	result := &mongo.DeleteResult{}
+	err := withMongoTx(ctx, r.dm, func(ctx context.Context) error {
+		var category model.Category
+		if err := coll.FindOneAndUpdate(ctx, withVersion(filter, version), update).Decode(&category); err != nil {
+			return versionMismatch(ctx, coll, filter, version, "category", err)
 		}
-	}
-	if result.DeletedCount != 1 {
-		log.Println("[ERROR] Delete document count", result.DeletedCount)
-		return result, errors.New("category is not exists")
+		result.DeletedCount = 1
+		// Products of a trashed category become uncategorized, Category.Products
+		// is kept to link them back on restore.
+		uncategorize := withVersionInc(bson.M{"$set": bson.M{"category": nil}})
+		_, err := productColl.UpdateMany(ctx, bson.M{"category": category.ID}, uncategorize)
+		return err
+	})
+	if err != nil {
+		return result, databaseError(err, "category")
 	}
 	return result, nil
 }
 
-// CRUD END
-func (r categoryRepository) PushProductToCategory(categoryId primitive.ObjectID, productId primitive.ObjectID) error {
-	filter := bson.D{
-		{Key: "_id", Value: categoryId},
+func (r categoryRepository) IsSlugExists(ctx context.Context, slug string) bool {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	coll := r.dm.Collection(string(enums.CATEGORY_COLLECTION_NAME))
+	err := coll.FindOne(ctx, bson.M{"slug": slug}).Err()
+	return err == nil
+}
+
+func (r categoryRepository) ReconcileProducts(ctx context.Context, dryRun bool) ([]dtos.CategoryDriftDto, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	drifts := []dtos.CategoryDriftDto{}
+	coll := r.dm.Collection(string(enums.CATEGORY_COLLECTION_NAME))
+	var categories []model.Category
+	cursor, err := coll.Find(ctx, bson.D{notDeleted})
+	if err != nil {
+		return drifts, databaseError(err, "category")
 	}
-	update := bson.D{
-		{Key: "$push", Value: bson.D{
-			{Key: "products", Value: productId},
-		}},
+	if err := cursor.All(ctx, &categories); err != nil {
+		return drifts, databaseError(err, "category")
 	}
-	coll := r.dm.DB.Collection(string(enums.CATEGORY_COLLECTION_NAME))
-	res, err := coll.UpdateOne(r.dm.Ctx, filter, update)
-	if res.MatchedCount != 1 {
-		log.Println("Push product to category res:", res)
+
+	var products []struct {
+		ID       primitive.ObjectID `bson:"_id"`
+		Category primitive.ObjectID `bson:"category"`
 	}
+	productColl := r.dm.Collection(string(enums.PRODUCT_COLLECTION_NAME))
+	opts := options.Find().
+		SetProjection(bson.M{"_id": 1, "category": 1}).
+		SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err = productColl.Find(ctx, bson.D{{Key: "category", Value: bson.M{"$ne": nil}}, notDeleted}, opts)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return errors.New("category is not found")
+		return drifts, databaseError(err, "product")
+	}
+	if err := cursor.All(ctx, &products); err != nil {
+		return drifts, databaseError(err, "product")
+	}
+	productIds := map[primitive.ObjectID][]primitive.ObjectID{}
+	for _, product := range products {
+		productIds[product.Category] = append(productIds[product.Category], product.ID)
+	}
+
+	for _, category := range categories {
+		ids, drift, changed := reconcileCategoryProducts(category, productIds[category.ID])
+		if !changed {
+			continue
+		}
+		drifts = append(drifts, drift)
+		if dryRun {
+			continue
+		}
+		update := withVersionInc(bson.M{"$set": bson.M{"products": ids}})
+		if _, err := coll.UpdateOne(ctx, bson.M{"_id": category.ID}, update); err != nil {
+			return drifts, databaseError(err, "category")
 		}
-		panic(err)
 	}
-	return nil
+	return drifts, nil
 }
 
-func (r categoryRepository) RemoveProductFromCategory(categoryId primitive.ObjectID, productId primitive.ObjectID) error {
-	filter := bson.D{
-		{Key: "_id", Value: categoryId},
+func (r categoryRepository) FindTrash(ctx context.Context) ([]model.Category, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	objects := []model.Category{}
+	coll := r.dm.Collection(string(enums.CATEGORY_COLLECTION_NAME))
+	cursor, err := coll.Find(ctx, bson.D{isDeleted}, trashOptions())
+	if err != nil {
+		return objects, databaseError(err, "category")
 	}
-	update := bson.D{
-		{Key: "$pull", Value: bson.D{
-			{Key: "products", Value: productId},
-		}},
+	if err := cursor.All(ctx, &objects); err != nil {
+		return objects, databaseError(err, "category")
 	}
-	coll := r.dm.DB.Collection(string(enums.CATEGORY_COLLECTION_NAME))
-	_, err := coll.UpdateOne(r.dm.Ctx, filter, update)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return errors.New("category is not found")
-		}
-		panic(err)
+	return objects, nil
+}
+
+func (r categoryRepository) FindTrashedBySlug(ctx context.Context, slug string) (model.Category, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var category model.Category
+	filter := bson.D{
+		{Key: "slug", Value: slug},
+		isDeleted,
+	}
+	coll := r.dm.Collection(string(enums.CATEGORY_COLLECTION_NAME))
+	if err := coll.FindOne(ctx, filter).Decode(&category); err != nil {
+		return category, databaseError(err, "category in trash")
 	}
-	return nil
+	return category, nil
 }
 
-func (r categoryRepository) ChangeProductInCategory(oldCategoryId primitive.ObjectID, newCategoryId primitive.ObjectID, productId primitive.ObjectID) error {
-	oldFilter := bson.D{
-		{Key: "_id", Value: oldCategoryId},
+func (r categoryRepository) RestoreBySlug(ctx context.Context, slug string) (model.Category, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var category model.Category
+	filter := bson.D{
+		{Key: "slug", Value: slug},
+		isDeleted,
 	}
-	newFilter := bson.D{
-		{Key: "_id", Value: newCategoryId},
+	coll := r.dm.Collection(string(enums.CATEGORY_COLLECTION_NAME))
+	productColl := r.dm.Collection(string(enums.PRODUCT_COLLECTION_NAME))
+	err := withMongoTx(ctx, r.dm, func(ctx context.Context) error {
+		if err := coll.FindOne(ctx, filter).Decode(&category); err != nil {
+			return err
+		}
+		var products []model.Product
+		productFilter := bson.D{
+			{Key: "_id", Value: bson.M{"$in": append([]primitive.ObjectID{}, category.Products...)}},
+			{Key: "category", Value: nil},
+			notDeleted,
+		}
+		cursor, err := productColl.Find(ctx, productFilter, options.Find().SetProjection(bson.M{"_id": 1}))
+		if err != nil {
+			return err
+		}
+		if err := cursor.All(ctx, &products); err != nil {
+			return err
+		}
+		category.Products = relinkedProducts(category.Products, products)
+		category.DeletedAt = nil
+		category.Version++
+		relink := withVersionInc(bson.M{"$set": bson.M{"category": category.ID}})
+		if _, err := productColl.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": category.Products}}, relink); err != nil {
+			return err
+		}
+		update := withVersionInc(bson.M{"$set": bson.M{"deletedAt": nil, "products": category.Products}})
+		_, err = coll.UpdateOne(ctx, bson.M{"_id": category.ID}, update)
+		return err
+	})
+	if err != nil {
+		return category, databaseError(err, "category in trash")
 	}
-	oldUpdate := bson.D{
-		{Key: "$pull", Value: bson.M{
-			"products": productId,
-		}},
+	return category, nil
+}
+
+func (r categoryRepository) PurgeBySlug(ctx context.Context, slug string) (model.Category, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var category model.Category
+	filter := bson.D{
+		{Key: "slug", Value: slug},
+		isDeleted,
 	}
-	newUpdate := bson.D{
-		{
-			Key: "$push", Value: bson.M{
-				"products": productId,
-			},
-		},
+	coll := r.dm.Collection(string(enums.CATEGORY_COLLECTION_NAME))
+	if err := coll.FindOneAndDelete(ctx, filter).Decode(&category); err != nil {
+		return category, databaseError(err, "category in trash")
 	}
-	coll := r.dm.DB.Collection(string(enums.CATEGORY_COLLECTION_NAME))
-	_, err := coll.UpdateOne(r.dm.Ctx, oldFilter, oldUpdate)
-	_, newErr := coll.UpdateOne(r.dm.Ctx, newFilter, newUpdate)
+	return category, nil
+}
 
+func (r categoryRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	coll := r.dm.Collection(string(enums.CATEGORY_COLLECTION_NAME))
+	result, err := coll.DeleteMany(ctx, deletedBefore(before))
 	if err != nil {
-		log.Println("[ERROR] removing product:", err.Error())
-	}
-	if newErr != nil {
-		return err
+		return 0, databaseError(err, "category")
 	}
-	return nil
+	return result.DeletedCount, nil
 }
 
 func NewCategoryRepository() CategoryRepository {
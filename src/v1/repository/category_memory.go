@@ -0,0 +1,294 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/utils"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type categoryMemoryRepository struct {
+	mm *db.MemoryManager
+}
+
+func (r categoryMemoryRepository) Store(ctx context.Context, category model.Category) (model.Category, error) {
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	if _, ok := r.mm.Categories[category.ID]; ok {
+		return category, domain_error.Conflict("category id is already exists")
+	}
+	category.Slug = utils.GenerateUniqueSlug(ctx, category.Name, r.isSlugExists)
+	category.Products = copyObjectIds(category.Products)
+	r.mm.Categories[category.ID] = category
+	return category, nil
+}
+
+func (r categoryMemoryRepository) FindAll(ctx context.Context, queryParams dtos.CategoryQueryParams) ([]model.Category, common.MetaData, error) {
+	r.mm.RLock()
+	defer r.mm.RUnlock()
+	objects := []model.Category{}
+	for _, category := range r.mm.Categories {
+		if category.DeletedAt != nil {
+			continue
+		}
+		if queryParams.Search != "" && !memorySearch(queryParams.Search, category.Name, category.Description) {
+			continue
+		}
+		objects = append(objects, category)
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return memoryLess(queryParams.ListQueryParams, compareCategories(objects[i], objects[j], queryParams.OrderBy), objects[i].ID, objects[j].ID)
+	})
+	metaData := listMetaData(queryParams.ListQueryParams, int64(len(objects)))
+	start, end := memoryPage(queryParams.ListQueryParams, len(objects))
+	objects = objects[start:end]
+	for i := range objects {
+		objects[i].Products = copyObjectIds(objects[i].Products)
+	}
+	return objects, metaData, nil
+}
+
+// compareCategories compares the field of a and b the list is ordered by.
+func compareCategories(a model.Category, b model.Category, field string) int {
+	switch field {
+	case "name":
+		return strings.Compare(a.Name, b.Name)
+	case "slug":
+		return strings.Compare(a.Slug, b.Slug)
+	}
+	return 0
+}
+
+func (r categoryMemoryRepository) Each(ctx context.Context, queryParams dtos.CategoryQueryParams, fn func(model.Category) error) error {
+	queryParams.Limit, queryParams.Page = 0, 0
+	objects, _, err := r.FindAll(ctx, queryParams)
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		if err := fn(object); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r categoryMemoryRepository) FindBySlug(ctx context.Context, slug string) (model.Category, error) {
+	r.mm.RLock()
+	defer r.mm.RUnlock()
+	category, ok := r.findBySlug(slug)
+	if !ok {
+		return category, domain_error.NotFound("category is not found")
+	}
+	category.Products = copyObjectIds(category.Products)
+	return category, nil
+}
+
+func (r categoryMemoryRepository) UpdateBySlug(ctx context.Context, slug string, payload primitive.M, version *int64) (model.Category, error) {
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	category, ok := r.findBySlug(slug)
+	if !ok {
+		return category, domain_error.NotFound("category is not found")
+	}
+	if err := checkVersion("category", category.Version, version); err != nil {
+		return category, err
+	}
+	if err := applySet(&category, payload); err != nil {
+		return category, err
+	}
+	category.Version++
+	r.mm.Categories[category.ID] = category
+	return category, nil
+}
+
+func (r categoryMemoryRepository) DeleteBySlug(ctx context.Context, slug string, version *int64) (*mongo.DeleteResult, error) {
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	category, ok := r.findBySlug(slug)
+	if !ok {
+		return &mongo.DeleteResult{}, domain_error.NotFound("category is not found")
+	}
+	if err := checkVersion("category", category.Version, version); err != nil {
+		return &mongo.DeleteResult{}, err
+	}
+	now := time.Now().UTC()
+	category.DeletedAt = &now
+	category.Version++
+	r.mm.Categories[category.ID] = category
+	// Products of a trashed category become uncategorized, Category.Products
+	// is kept to link them back on restore.
+	for id, product := range r.mm.Products {
+		if product.Category != nil && *product.Category == category.ID {
+			product.Category = nil
+			product.Version++
+			r.mm.Products[id] = product
+		}
+	}
+	return &mongo.DeleteResult{DeletedCount: 1}, nil
+}
+
+func (r categoryMemoryRepository) ReconcileProducts(ctx context.Context, dryRun bool) ([]dtos.CategoryDriftDto, error) {
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	productIds := map[primitive.ObjectID][]primitive.ObjectID{}
+	for _, product := range sortedProducts(r.mm) {
+		if product.Category != nil && product.DeletedAt == nil {
+			productIds[*product.Category] = append(productIds[*product.Category], product.ID)
+		}
+	}
+	drifts := []dtos.CategoryDriftDto{}
+	for _, id := range r.sortedIds() {
+		category := r.mm.Categories[id]
+		if category.DeletedAt != nil {
+			continue
+		}
+		products, drift, drifted := reconcileCategoryProducts(category, productIds[id])
+		if !drifted {
+			continue
+		}
+		drifts = append(drifts, drift)
+		if !dryRun {
+			category.Products = products
+			category.Version++
+			r.mm.Categories[id] = category
+		}
+	}
+	return drifts, nil
+}
+
+func (r categoryMemoryRepository) IsSlugExists(ctx context.Context, slug string) bool {
+	r.mm.RLock()
+	defer r.mm.RUnlock()
+	return r.isSlugExists(ctx, slug)
+}
+
+func (r categoryMemoryRepository) FindTrash(ctx context.Context) ([]model.Category, error) {
+	r.mm.RLock()
+	defer r.mm.RUnlock()
+	objects := []model.Category{}
+	for _, id := range r.sortedIds() {
+		category := r.mm.Categories[id]
+		if category.DeletedAt == nil {
+			continue
+		}
+		category.Products = copyObjectIds(category.Products)
+		objects = append(objects, category)
+	}
+	sort.SliceStable(objects, func(i, j int) bool { return newerTrash(objects[i].DeletedAt, objects[j].DeletedAt) })
+	return objects, nil
+}
+
+func (r categoryMemoryRepository) FindTrashedBySlug(ctx context.Context, slug string) (model.Category, error) {
+	r.mm.RLock()
+	defer r.mm.RUnlock()
+	category, ok := r.findTrashedBySlug(slug)
+	if !ok {
+		return category, domain_error.NotFound("category in trash is not found")
+	}
+	return category, nil
+}
+
+func (r categoryMemoryRepository) RestoreBySlug(ctx context.Context, slug string) (model.Category, error) {
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	category, ok := r.findTrashedBySlug(slug)
+	if !ok {
+		return category, domain_error.NotFound("category in trash is not found")
+	}
+	products := []model.Product{}
+	for _, id := range category.Products {
+		if product, ok := r.mm.Products[id]; ok && product.Category == nil && product.DeletedAt == nil {
+			products = append(products, product)
+		}
+	}
+	category.Products = relinkedProducts(category.Products, products)
+	category.DeletedAt = nil
+	category.Version++
+	for _, id := range category.Products {
+		product := r.mm.Products[id]
+		product.Category = copyObjectId(&category.ID)
+		product.Version++
+		r.mm.Products[id] = product
+	}
+	r.mm.Categories[category.ID] = category
+	category.Products = copyObjectIds(category.Products)
+	return category, nil
+}
+
+func (r categoryMemoryRepository) PurgeBySlug(ctx context.Context, slug string) (model.Category, error) {
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	category, ok := r.findTrashedBySlug(slug)
+	if !ok {
+		return category, domain_error.NotFound("category in trash is not found")
+	}
+	delete(r.mm.Categories, category.ID)
+	return category, nil
+}
+
+func (r categoryMemoryRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	var count int64
+	for id, category := range r.mm.Categories {
+		if trashedBefore(category.DeletedAt, before) {
+			delete(r.mm.Categories, id)
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Helpers below expect the caller to hold the memory manager lock.
+
+// isSlugExists counts trashed categories too, they keep their slug.
+func (r categoryMemoryRepository) isSlugExists(ctx context.Context, slug string) bool {
+	for _, category := range r.mm.Categories {
+		if category.Slug == slug {
+			return true
+		}
+	}
+	return false
+}
+
+func (r categoryMemoryRepository) findBySlug(slug string) (model.Category, bool) {
+	for _, category := range r.mm.Categories {
+		if category.Slug == slug && category.DeletedAt == nil {
+			return category, true
+		}
+	}
+	return model.Category{}, false
+}
+
+func (r categoryMemoryRepository) findTrashedBySlug(slug string) (model.Category, bool) {
+	for _, category := range r.mm.Categories {
+		if category.Slug == slug && category.DeletedAt != nil {
+			return category, true
+		}
+	}
+	return model.Category{}, false
+}
+
+func (r categoryMemoryRepository) sortedIds() []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, 0, len(r.mm.Categories))
+	for id := range r.mm.Categories {
+		ids = append(ids, id)
+	}
+	return sortedObjectIds(ids)
+}
+
+func NewCategoryMemoryRepository() CategoryRepository {
+	return &categoryMemoryRepository{
+		mm: db.GetMemoryManager(),
+	}
+}
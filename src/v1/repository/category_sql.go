@@ -0,0 +1,557 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/utils"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const categoryColumns = "id, parent_id, name, slug, description, deleted_at, version, attributes"
+
+type categorySqlRepository struct {
+	sm *db.SqlManager
+}
+
+func (r categorySqlRepository) Store(ctx context.Context, category model.Category) (model.Category, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	category.Slug = utils.GenerateUniqueSlug(ctx, category.Name, r.IsSlugExists)
+	err := withTx(ctx, r.sm, func(tx *sql.Tx) error {
+		query := r.sm.Rebind(`INSERT INTO categories (` + categoryColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+		values, err := categoryValues(category)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, query, values...); err != nil {
+			return err
+		}
+		query = r.sm.Rebind(`INSERT INTO category_products (category_id, product_id, position) VALUES (?, ?, ?)`)
+		for position, productId := range category.Products {
+			if _, err := tx.ExecContext(ctx, query, category.ID.Hex(), productId.Hex(), position); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return category, databaseError(err, "category")
+}
+
+var categoryOrderColumns = map[string]string{
+	"id":   "id",
+	"name": "name",
+	"slug": "slug",
+}
+
+func (r categorySqlRepository) FindAll(ctx context.Context, queryParams dtos.CategoryQueryParams) ([]model.Category, common.MetaData, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	objects := []model.Category{}
+	var metaData common.MetaData
+	where, args := categoryWhere(queryParams)
+	if queryParams.Limit != 0 {
+		var total int64
+		if err := r.sm.DB.QueryRowContext(ctx, r.sm.Rebind(`SELECT COUNT(*) FROM categories`+where), args...).Scan(&total); err != nil {
+			return objects, metaData, databaseError(err, "category")
+		}
+		metaData = listMetaData(queryParams.ListQueryParams, total)
+	}
+	clause, clauseArgs := sqlListClause(queryParams.ListQueryParams, categoryOrderColumns, "categories")
+	err := queryAll(ctx, r.sm.DB, r.sm.Rebind(`SELECT `+categoryColumns+` FROM categories`+where+clause), func(rows *sql.Rows) error {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, category)
+		return nil
+	}, append(args, clauseArgs...)...)
+	if err != nil {
+		return objects, metaData, databaseError(err, "category")
+	}
+	products, err := r.findProductIds(ctx, r.sm.DB, nil)
+	if err != nil {
+		return objects, metaData, databaseError(err, "category")
+	}
+	for i := range objects {
+		if ids, ok := products[objects[i].ID]; ok {
+			objects[i].Products = ids
+		}
+	}
+	return objects, metaData, nil
+}
+
+func (r categorySqlRepository) Each(ctx context.Context, queryParams dtos.CategoryQueryParams, fn func(model.Category) error) error {
+	ctx, cancel := db.StreamContext(ctx)
+	defer cancel()
+	queryParams.Limit, queryParams.Page = 0, 0
+	products, err := r.findProductIds(ctx, r.sm.DB, nil)
+	if err != nil {
+		return databaseError(err, "category")
+	}
+	where, args := categoryWhere(queryParams)
+	clause, _ := sqlListClause(queryParams.ListQueryParams, categoryOrderColumns, "categories")
+	rows, err := r.sm.DB.QueryContext(ctx, r.sm.Rebind(`SELECT `+categoryColumns+` FROM categories`+where+clause), args...)
+	if err != nil {
+		return databaseError(err, "category")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return databaseError(err, "category")
+		}
+		if ids, ok := products[category.ID]; ok {
+			category.Products = ids
+		}
+		if err := fn(category); err != nil {
+			return err
+		}
+	}
+	return databaseError(rows.Err(), "category")
+}
+
+func categoryWhere(queryParams dtos.CategoryQueryParams) (string, []interface{}) {
+	where := ` WHERE deleted_at IS NULL`
+	args := []interface{}{}
+	if queryParams.Search != "" {
+		search, searchArgs := sqlSearch(queryParams.Search, "name", "description")
+		where += ` AND ` + search
+		args = append(args, searchArgs...)
+	}
+	return where, args
+}
+
+func (r categorySqlRepository) FindBySlug(ctx context.Context, slug string) (model.Category, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	category, err := r.findBySlug(ctx, r.sm.DB, slug)
+	if err != nil {
+		return category, databaseError(err, "category")
+	}
+	return category, nil
+}
+
+func (r categorySqlRepository) UpdateBySlug(ctx context.Context, slug string, payload primitive.M, version *int64) (model.Category, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var category model.Category
+	err := withTx(ctx, r.sm, func(tx *sql.Tx) error {
+		var err error
+		category, err = r.findBySlug(ctx, tx, slug)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion("category", category.Version, version); err != nil {
+			return err
+		}
+		if err := applySet(&category, payload); err != nil {
+			return err
+		}
+		attributes, err := marshalCategoryAttributes(category.Attributes)
+		if err != nil {
+			return err
+		}
+		query := r.sm.Rebind(`UPDATE categories SET parent_id = ?, name = ?, slug = ?, description = ?, attributes = ?, version = version + 1 WHERE id = ? AND version = ?`)
+		result, err := tx.ExecContext(ctx, query, nullableId(category.Parent), category.Name, category.Slug, category.Description, attributes, category.ID.Hex(), category.Version)
+		category.Version++
+		return checkGuardedUpdate("category", result, err)
+	})
+	return category, databaseError(err, "category")
+}
+
+func (r categorySqlRepository) DeleteBySlug(ctx context.Context, slug string, version *int64) (*mongo.DeleteResult, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	result := &mongo.DeleteResult{}
+	err := withTx(ctx, r.sm, func(tx *sql.Tx) error {
+		category, err := r.findBySlug(ctx, tx, slug)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion("category", category.Version, version); err != nil {
+			return err
+		}
+		query := r.sm.Rebind(`UPDATE categories SET deleted_at = ?, version = version + 1 WHERE id = ? AND version = ?`)
+		updated, err := tx.ExecContext(ctx, query, time.Now().UTC(), category.ID.Hex(), category.Version)
+		if err := checkGuardedUpdate("category", updated, err); err != nil {
+			return err
+		}
+		// Products of a trashed category become uncategorized, Category.Products
+		// is kept to link them back on restore.
+		query = r.sm.Rebind(`UPDATE products SET category_id = NULL, version = version + 1 WHERE category_id = ?`)
+		if _, err := tx.ExecContext(ctx, query, category.ID.Hex()); err != nil {
+			return err
+		}
+		result.DeletedCount = 1
+		return nil
+	})
+	return result, databaseError(err, "category")
+}
+
+func (r categorySqlRepository) ReconcileProducts(ctx context.Context, dryRun bool) ([]dtos.CategoryDriftDto, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	drifts := []dtos.CategoryDriftDto{}
+	err := withTx(ctx, r.sm, func(tx *sql.Tx) error {
+		categories, err := r.findAll(ctx, tx, `deleted_at IS NULL`)
+		if err != nil {
+			return err
+		}
+		productIds, err := r.findCategorizedProductIds(ctx, tx)
+		if err != nil {
+			return err
+		}
+		for _, category := range categories {
+			products, drift, drifted := reconcileCategoryProducts(category, productIds[category.ID])
+			if !drifted {
+				continue
+			}
+			drifts = append(drifts, drift)
+			if dryRun {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, r.sm.Rebind(`DELETE FROM category_products WHERE category_id = ?`), category.ID.Hex()); err != nil {
+				return err
+			}
+			query := r.sm.Rebind(`INSERT INTO category_products (category_id, product_id, position) VALUES (?, ?, ?)`)
+			for position, productId := range products {
+				if _, err := tx.ExecContext(ctx, query, category.ID.Hex(), productId.Hex(), position); err != nil {
+					return err
+				}
+			}
+			if err := bumpSqlCategoryVersion(ctx, r.sm, tx, category.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return drifts, databaseError(err, "category")
+}
+
+func (r categorySqlRepository) IsSlugExists(ctx context.Context, slug string) bool {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var count int
+	err := r.sm.DB.QueryRowContext(ctx, r.sm.Rebind(`SELECT COUNT(*) FROM categories WHERE slug = ?`), slug).Scan(&count)
+	return err == nil && count > 0
+}
+
+func (r categorySqlRepository) FindTrash(ctx context.Context) ([]model.Category, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	objects, err := r.findAll(ctx, r.sm.DB, `deleted_at IS NOT NULL`)
+	sort.SliceStable(objects, func(i, j int) bool { return newerTrash(objects[i].DeletedAt, objects[j].DeletedAt) })
+	return objects, databaseError(err, "category")
+}
+
+func (r categorySqlRepository) FindTrashedBySlug(ctx context.Context, slug string) (model.Category, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	category, err := r.findTrashedBySlug(ctx, r.sm.DB, slug)
+	return category, databaseError(err, "category in trash")
+}
+
+func (r categorySqlRepository) RestoreBySlug(ctx context.Context, slug string) (model.Category, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var category model.Category
+	err := withTx(ctx, r.sm, func(tx *sql.Tx) error {
+		var err error
+		category, err = r.findTrashedBySlug(ctx, tx, slug)
+		if err != nil {
+			return err
+		}
+		products := []model.Product{}
+		query := r.sm.Rebind(`SELECT id FROM products WHERE category_id IS NULL AND deleted_at IS NULL
+			AND id IN (SELECT product_id FROM category_products WHERE category_id = ?)`)
+		err = queryAll(ctx, tx, query, func(rows *sql.Rows) error {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+			products = append(products, model.Product{ID: parseId(id)})
+			return nil
+		}, category.ID.Hex())
+		if err != nil {
+			return err
+		}
+		category.Products = relinkedProducts(category.Products, products)
+		category.DeletedAt = nil
+		category.Version++
+		if _, err := tx.ExecContext(ctx, r.sm.Rebind(`DELETE FROM category_products WHERE category_id = ?`), category.ID.Hex()); err != nil {
+			return err
+		}
+		for position, productId := range category.Products {
+			query := r.sm.Rebind(`INSERT INTO category_products (category_id, product_id, position) VALUES (?, ?, ?)`)
+			if _, err := tx.ExecContext(ctx, query, category.ID.Hex(), productId.Hex(), position); err != nil {
+				return err
+			}
+			query = r.sm.Rebind(`UPDATE products SET category_id = ?, version = version + 1 WHERE id = ?`)
+			if _, err := tx.ExecContext(ctx, query, category.ID.Hex(), productId.Hex()); err != nil {
+				return err
+			}
+		}
+		_, err = tx.ExecContext(ctx, r.sm.Rebind(`UPDATE categories SET deleted_at = NULL, version = version + 1 WHERE id = ?`), category.ID.Hex())
+		return err
+	})
+	return category, databaseError(err, "category in trash")
+}
+
+func (r categorySqlRepository) PurgeBySlug(ctx context.Context, slug string) (model.Category, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var category model.Category
+	err := withTx(ctx, r.sm, func(tx *sql.Tx) error {
+		var err error
+		category, err = r.findTrashedBySlug(ctx, tx, slug)
+		if err != nil {
+			return err
+		}
+		return r.purge(ctx, tx, `id = ?`, category.ID.Hex())
+	})
+	return category, databaseError(err, "category in trash")
+}
+
+func (r categorySqlRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var count int64
+	err := withTx(ctx, r.sm, func(tx *sql.Tx) error {
+		query := r.sm.Rebind(`SELECT COUNT(*) FROM categories WHERE deleted_at IS NOT NULL AND deleted_at < ?`)
+		if err := tx.QueryRowContext(ctx, query, before).Scan(&count); err != nil {
+			return err
+		}
+		return r.purge(ctx, tx, `deleted_at IS NOT NULL AND deleted_at < ?`, before)
+	})
+	return count, databaseError(err, "category")
+}
+
+// purge deletes the categories matching where with their Category.Products.
+func (r categorySqlRepository) purge(ctx context.Context, executor sqlExecutor, where string, args ...interface{}) error {
+	query := r.sm.Rebind(`DELETE FROM category_products WHERE category_id IN (SELECT id FROM categories WHERE ` + where + `)`)
+	if _, err := executor.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+	_, err := executor.ExecContext(ctx, r.sm.Rebind(`DELETE FROM categories WHERE `+where), args...)
+	return err
+}
+
+// findAll loads the categories matching where, an empty where loads all.
+func (r categorySqlRepository) findAll(ctx context.Context, executor sqlExecutor, where string) ([]model.Category, error) {
+	objects := []model.Category{}
+	query := `SELECT ` + categoryColumns + ` FROM categories`
+	if where != "" {
+		query += ` WHERE ` + where
+	}
+	rows, err := executor.QueryContext(ctx, query+` ORDER BY id`)
+	if err != nil {
+		return objects, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return objects, err
+		}
+		objects = append(objects, category)
+	}
+	if err := rows.Err(); err != nil {
+		return objects, err
+	}
+	products, err := r.findProductIds(ctx, executor, nil)
+	if err != nil {
+		return objects, err
+	}
+	for i := range objects {
+		if ids, ok := products[objects[i].ID]; ok {
+			objects[i].Products = ids
+		}
+	}
+	return objects, nil
+}
+
+func (r categorySqlRepository) findBySlug(ctx context.Context, executor sqlExecutor, slug string) (model.Category, error) {
+	return r.findOne(ctx, executor, `slug = ? AND deleted_at IS NULL`, slug)
+}
+
+func (r categorySqlRepository) findTrashedBySlug(ctx context.Context, executor sqlExecutor, slug string) (model.Category, error) {
+	return r.findOne(ctx, executor, `slug = ? AND deleted_at IS NOT NULL`, slug)
+}
+
+func (r categorySqlRepository) findOne(ctx context.Context, executor sqlExecutor, where string, args ...interface{}) (model.Category, error) {
+	query := r.sm.Rebind(`SELECT ` + categoryColumns + ` FROM categories WHERE ` + where)
+	category, err := scanCategory(executor.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return category, err
+	}
+	products, err := r.findProductIds(ctx, executor, &category.ID)
+	if err != nil {
+		return category, err
+	}
+	if ids, ok := products[category.ID]; ok {
+		category.Products = ids
+	}
+	return category, nil
+}
+
+// findProductIds loads the Category.Products arrays, of a single category when
+// categoryId is set, otherwise of every category.
+func (r categorySqlRepository) findProductIds(ctx context.Context, executor sqlExecutor, categoryId *primitive.ObjectID) (map[primitive.ObjectID][]primitive.ObjectID, error) {
+	products := map[primitive.ObjectID][]primitive.ObjectID{}
+	query := `SELECT category_id, product_id FROM category_products`
+	args := []interface{}{}
+	if categoryId != nil {
+		query += ` WHERE category_id = ?`
+		args = append(args, categoryId.Hex())
+	}
+	query += ` ORDER BY position`
+	rows, err := executor.QueryContext(ctx, r.sm.Rebind(query), args...)
+	if err != nil {
+		return products, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var _categoryId, productId string
+		if err := rows.Scan(&_categoryId, &productId); err != nil {
+			return products, err
+		}
+		id := parseId(_categoryId)
+		products[id] = append(products[id], parseId(productId))
+	}
+	return products, rows.Err()
+}
+
+// findCategorizedProductIds groups the ids of the products referencing a
+// category, in creation order.
+func (r categorySqlRepository) findCategorizedProductIds(ctx context.Context, executor sqlExecutor) (map[primitive.ObjectID][]primitive.ObjectID, error) {
+	products := map[primitive.ObjectID][]primitive.ObjectID{}
+	query := `SELECT category_id, id FROM products WHERE category_id IS NOT NULL AND deleted_at IS NULL ORDER BY created_at, id`
+	rows, err := executor.QueryContext(ctx, query)
+	if err != nil {
+		return products, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var categoryId, productId string
+		if err := rows.Scan(&categoryId, &productId); err != nil {
+			return products, err
+		}
+		id := parseId(categoryId)
+		products[id] = append(products[id], parseId(productId))
+	}
+	return products, rows.Err()
+}
+
+// checkSqlCategory fails when the product refers to a category that does not
+// exist or is in the trash.
+func checkSqlCategory(ctx context.Context, sm *db.SqlManager, executor sqlExecutor, categoryId *primitive.ObjectID) error {
+	if categoryId == nil {
+		return nil
+	}
+	var count int
+	err := executor.QueryRowContext(ctx, sm.Rebind(`SELECT COUNT(*) FROM categories WHERE id = ? AND deleted_at IS NULL`), categoryId.Hex()).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return domain_error.Validation("category is not found")
+	}
+	return nil
+}
+
+// pushSqlCategoryProduct appends the product to Category.Products unless it is
+// already listed.
+func pushSqlCategoryProduct(ctx context.Context, sm *db.SqlManager, executor sqlExecutor, categoryId *primitive.ObjectID, productId primitive.ObjectID) error {
+	if categoryId == nil {
+		return nil
+	}
+	var count int
+	query := sm.Rebind(`SELECT COUNT(*) FROM category_products WHERE category_id = ? AND product_id = ?`)
+	if err := executor.QueryRowContext(ctx, query, categoryId.Hex(), productId.Hex()).Scan(&count); err != nil || count > 0 {
+		return err
+	}
+	query = sm.Rebind(`INSERT INTO category_products (category_id, product_id, position) VALUES (?, ?, ?)`)
+	if _, err := executor.ExecContext(ctx, query, categoryId.Hex(), productId.Hex(), time.Now().UnixNano()); err != nil {
+		return err
+	}
+	return bumpSqlCategoryVersion(ctx, sm, executor, *categoryId)
+}
+
+func removeSqlCategoryProduct(ctx context.Context, sm *db.SqlManager, executor sqlExecutor, categoryId *primitive.ObjectID, productId primitive.ObjectID) error {
+	if categoryId == nil {
+		return nil
+	}
+	query := sm.Rebind(`DELETE FROM category_products WHERE category_id = ? AND product_id = ?`)
+	result, err := executor.ExecContext(ctx, query, categoryId.Hex(), productId.Hex())
+	if err != nil {
+		return err
+	}
+	if count, err := result.RowsAffected(); err != nil || count == 0 {
+		return err
+	}
+	return bumpSqlCategoryVersion(ctx, sm, executor, *categoryId)
+}
+
+// bumpSqlCategoryVersion marks a change of Category.Products.
+func bumpSqlCategoryVersion(ctx context.Context, sm *db.SqlManager, executor sqlExecutor, categoryId primitive.ObjectID) error {
+	_, err := executor.ExecContext(ctx, sm.Rebind(`UPDATE categories SET version = version + 1 WHERE id = ?`), categoryId.Hex())
+	return err
+}
+
+// categoryValues follows the order of categoryColumns.
+func categoryValues(category model.Category) ([]interface{}, error) {
+	attributes, err := marshalCategoryAttributes(category.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{
+		category.ID.Hex(), nullableId(category.Parent), category.Name, category.Slug, category.Description, nullableTime(category.DeletedAt), category.Version,
+		attributes,
+	}, nil
+}
+
+func marshalCategoryAttributes(attributes []model.CategoryAttribute) (string, error) {
+	if attributes == nil {
+		attributes = []model.CategoryAttribute{}
+	}
+	data, err := json.Marshal(attributes)
+	return string(data), err
+}
+
+func scanCategory(scanner rowScanner) (model.Category, error) {
+	var (
+		category   model.Category
+		id         string
+		parent     sql.NullString
+		deletedAt  sql.NullTime
+		attributes string
+	)
+	err := scanner.Scan(&id, &parent, &category.Name, &category.Slug, &category.Description, &deletedAt, &category.Version, &attributes)
+	if err != nil {
+		return category, err
+	}
+	if err := json.Unmarshal([]byte(attributes), &category.Attributes); err != nil {
+		return category, err
+	}
+	category.DeletedAt = parseNullableTime(deletedAt)
+	category.ID = parseId(id)
+	category.Parent = parseNullableId(parent)
+	category.Products = []primitive.ObjectID{}
+	return category, nil
+}
+
+func NewCategorySqlRepository() CategoryRepository {
+	return &categorySqlRepository{
+		sm: db.GetSqlManager(),
+	}
+}
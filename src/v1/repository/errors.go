@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// databaseError translates a driver error into a domain error. entity names
+// the object in the message, e.g. "product is not found".
+func databaseError(err error, entity string) error {
+	switch {
+	case err == nil:
+		return nil
+	case domain_error.KindOf(err) != "":
+		return err
+	case errors.Is(err, mongo.ErrNoDocuments), errors.Is(err, sql.ErrNoRows):
+		return domain_error.NotFound(entity + " is not found")
+	case mongo.IsDuplicateKeyError(err), isSqlUniqueViolation(err):
+		return domain_error.Conflict(entity + " is already exists")
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled),
+		errors.Is(err, mongo.ErrClientDisconnected), mongo.IsTimeout(err), mongo.IsNetworkError(err):
+		return domain_error.Unavailable(err)
+	}
+	return err
+}
+
+func isSqlUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	return strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
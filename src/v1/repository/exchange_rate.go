@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ExchangeRateRepository interface {
+	// FindAll lists the rates by currency.
+	FindAll(ctx context.Context) ([]model.ExchangeRate, error)
+	FindByCurrency(ctx context.Context, currency string) (model.ExchangeRate, error)
+	// Store sets the rate of the currency, replacing the one it has.
+	Store(ctx context.Context, rate model.ExchangeRate) (model.ExchangeRate, error)
+	DeleteByCurrency(ctx context.Context, currency string) (*mongo.DeleteResult, error)
+}
+
+type exchangeRateRepository struct {
+	dm *db.DmManager
+}
+
+func (r exchangeRateRepository) FindAll(ctx context.Context) ([]model.ExchangeRate, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	rates := []model.ExchangeRate{}
+	coll := r.dm.Collection(string(enums.EXCHANGE_RATE_COLLECTION_NAME))
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := coll.Find(ctx, bson.D{}, opts)
+	if err != nil {
+		return rates, databaseError(err, "exchange rate")
+	}
+	if err := cursor.All(ctx, &rates); err != nil {
+		return rates, databaseError(err, "exchange rate")
+	}
+	return rates, nil
+}
+
+func (r exchangeRateRepository) FindByCurrency(ctx context.Context, currency string) (model.ExchangeRate, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var rate model.ExchangeRate
+	coll := r.dm.Collection(string(enums.EXCHANGE_RATE_COLLECTION_NAME))
+	if err := coll.FindOne(ctx, bson.M{"_id": currency}).Decode(&rate); err != nil {
+		return rate, databaseError(err, "exchange rate")
+	}
+	return rate, nil
+}
+
+func (r exchangeRateRepository) Store(ctx context.Context, rate model.ExchangeRate) (model.ExchangeRate, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	rate.UpdatedAt = time.Now().UTC()
+	coll := r.dm.Collection(string(enums.EXCHANGE_RATE_COLLECTION_NAME))
+	opts := options.Replace().SetUpsert(true)
+	if _, err := coll.ReplaceOne(ctx, bson.M{"_id": rate.Currency}, rate, opts); err != nil {
+		return rate, databaseError(err, "exchange rate")
+	}
+	return rate, nil
+}
+
+func (r exchangeRateRepository) DeleteByCurrency(ctx context.Context, currency string) (*mongo.DeleteResult, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	coll := r.dm.Collection(string(enums.EXCHANGE_RATE_COLLECTION_NAME))
+	result, err := coll.DeleteOne(ctx, bson.M{"_id": currency})
+	if err != nil {
+		return result, databaseError(err, "exchange rate")
+	}
+	return result, nil
+}
+
+func NewExchangeRateRepository() ExchangeRateRepository {
+	return &exchangeRateRepository{
+		dm: db.GetDmManager(),
+	}
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type exchangeRateMemoryRepository struct {
+	mm *db.MemoryManager
+}
+
+func (r exchangeRateMemoryRepository) FindAll(ctx context.Context) ([]model.ExchangeRate, error) {
+	r.mm.RLock()
+	defer r.mm.RUnlock()
+	rates := []model.ExchangeRate{}
+	for _, rate := range r.mm.ExchangeRates {
+		rates = append(rates, rate)
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].Currency < rates[j].Currency
+	})
+	return rates, nil
+}
+
+func (r exchangeRateMemoryRepository) FindByCurrency(ctx context.Context, currency string) (model.ExchangeRate, error) {
+	r.mm.RLock()
+	defer r.mm.RUnlock()
+	rate, ok := r.mm.ExchangeRates[currency]
+	if !ok {
+		return rate, domain_error.NotFound("exchange rate is not found")
+	}
+	return rate, nil
+}
+
+func (r exchangeRateMemoryRepository) Store(ctx context.Context, rate model.ExchangeRate) (model.ExchangeRate, error) {
+	rate.UpdatedAt = time.Now().UTC()
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	r.mm.ExchangeRates[rate.Currency] = rate
+	return rate, nil
+}
+
+func (r exchangeRateMemoryRepository) DeleteByCurrency(ctx context.Context, currency string) (*mongo.DeleteResult, error) {
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	result := &mongo.DeleteResult{}
+	if _, ok := r.mm.ExchangeRates[currency]; ok {
+		delete(r.mm.ExchangeRates, currency)
+		result.DeletedCount = 1
+	}
+	return result, nil
+}
+
+func NewExchangeRateMemoryRepository() ExchangeRateRepository {
+	return &exchangeRateMemoryRepository{
+		mm: db.GetMemoryManager(),
+	}
+}
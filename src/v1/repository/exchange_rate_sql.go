@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type exchangeRateSqlRepository struct {
+	sm *db.SqlManager
+}
+
+func (r exchangeRateSqlRepository) FindAll(ctx context.Context) ([]model.ExchangeRate, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	rates := []model.ExchangeRate{}
+	query := `SELECT currency, rate, updated_at FROM exchange_rates ORDER BY currency`
+	err := queryAll(ctx, r.sm.DB, query, func(rows *sql.Rows) error {
+		var rate model.ExchangeRate
+		if err := rows.Scan(&rate.Currency, &rate.Rate, &rate.UpdatedAt); err != nil {
+			return err
+		}
+		rates = append(rates, rate)
+		return nil
+	})
+	return rates, databaseError(err, "exchange rate")
+}
+
+func (r exchangeRateSqlRepository) FindByCurrency(ctx context.Context, currency string) (model.ExchangeRate, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var rate model.ExchangeRate
+	query := r.sm.Rebind(`SELECT currency, rate, updated_at FROM exchange_rates WHERE currency = ?`)
+	err := r.sm.DB.QueryRowContext(ctx, query, currency).Scan(&rate.Currency, &rate.Rate, &rate.UpdatedAt)
+	return rate, databaseError(err, "exchange rate")
+}
+
+func (r exchangeRateSqlRepository) Store(ctx context.Context, rate model.ExchangeRate) (model.ExchangeRate, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	rate.UpdatedAt = time.Now().UTC()
+	query := r.sm.Rebind(`INSERT INTO exchange_rates (currency, rate, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (currency) DO UPDATE SET rate = excluded.rate, updated_at = excluded.updated_at`)
+	_, err := r.sm.DB.ExecContext(ctx, query, rate.Currency, rate.Rate, rate.UpdatedAt)
+	return rate, databaseError(err, "exchange rate")
+}
+
+func (r exchangeRateSqlRepository) DeleteByCurrency(ctx context.Context, currency string) (*mongo.DeleteResult, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	query := r.sm.Rebind(`DELETE FROM exchange_rates WHERE currency = ?`)
+	result, err := r.sm.DB.ExecContext(ctx, query, currency)
+	if err != nil {
+		return nil, databaseError(err, "exchange rate")
+	}
+	count, _ := result.RowsAffected()
+	return &mongo.DeleteResult{DeletedCount: count}, nil
+}
+
+func NewExchangeRateSqlRepository() ExchangeRateRepository {
+	return &exchangeRateSqlRepository{
+		sm: db.GetSqlManager(),
+	}
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Lists sort by dtos.ListQueryParams.OrderBy and then by id, so pages are
+// stable when the field repeats. The search is a case-insensitive substring
+// match on every backend.
+
+// listSkip is the offset of the requested page.
+func listSkip(queryParams dtos.ListQueryParams) uint64 {
+	if queryParams.Limit == 0 || queryParams.Page <= 1 {
+		return 0
+	}
+	return queryParams.Limit * (queryParams.Page - 1)
+}
+
+// listFindOptions sorts and pages a mongo find. Fields are named like their
+// bson keys, but the id.
+func listFindOptions(queryParams dtos.ListQueryParams) *options.FindOptions {
+	direction := 1
+	if queryParams.Sort == enums.DESCENDING {
+		direction = -1
+	}
+	sort := bson.D{}
+	if queryParams.OrderBy != "" && queryParams.OrderBy != "id" {
+		sort = append(sort, bson.E{Key: queryParams.OrderBy, Value: direction})
+	}
+	sort = append(sort, bson.E{Key: "_id", Value: direction})
+	opts := options.Find().SetSort(sort)
+	if queryParams.Limit != 0 {
+		opts.SetSkip(int64(listSkip(queryParams))).SetLimit(int64(queryParams.Limit))
+	}
+	return opts
+}
+
+// searchFilter matches the search in any of the keys.
+func searchFilter(search string, keys ...string) bson.E {
+	conditions := bson.A{}
+	for _, key := range keys {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(search), Options: "i"}
+		conditions = append(conditions, bson.M{key: bson.M{"$regex": pattern}})
+	}
+	return bson.E{Key: "$or", Value: conditions}
+}
+
+// sqlListClause sorts and pages a query, columns maps the fields to columns.
+func sqlListClause(queryParams dtos.ListQueryParams, columns map[string]string, table string) (string, []interface{}) {
+	direction := ""
+	if queryParams.Sort == enums.DESCENDING {
+		direction = " DESC"
+	}
+	clause := ` ORDER BY `
+	if column, ok := columns[queryParams.OrderBy]; ok && column != "id" {
+		clause += table + `.` + column + direction + `, `
+	}
+	clause += table + `.id` + direction
+	if queryParams.Limit == 0 {
+		return clause, nil
+	}
+	return clause + ` LIMIT ? OFFSET ?`, []interface{}{queryParams.Limit, listSkip(queryParams)}
+}
+
+// likeEscaper escapes the LIKE wildcards, so they match literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// sqlSearch matches the search in any of the columns.
+func sqlSearch(search string, columns ...string) (string, []interface{}) {
+	pattern := "%" + likeEscaper.Replace(strings.ToLower(search)) + "%"
+	conditions := []string{}
+	args := []interface{}{}
+	for _, column := range columns {
+		conditions = append(conditions, `LOWER(`+column+`) LIKE ? ESCAPE '\'`)
+		args = append(args, pattern)
+	}
+	return `(` + strings.Join(conditions, ` OR `) + `)`, args
+}
+
+// memorySearch reports whether any of the values contains the search.
+func memorySearch(search string, values ...string) bool {
+	search = strings.ToLower(search)
+	for _, value := range values {
+		if strings.Contains(strings.ToLower(value), search) {
+			return true
+		}
+	}
+	return false
+}
+
+// memoryLess orders two list elements, compare is the result of comparing
+// their OrderBy fields.
+func memoryLess(queryParams dtos.ListQueryParams, compare int, a primitive.ObjectID, b primitive.ObjectID) bool {
+	if compare == 0 {
+		compare = strings.Compare(a.Hex(), b.Hex())
+	}
+	if queryParams.Sort == enums.DESCENDING {
+		return compare > 0
+	}
+	return compare < 0
+}
+
+func compareTime(a time.Time, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+// memoryPage bounds the requested page of n elements.
+func memoryPage(queryParams dtos.ListQueryParams, n int) (int, int) {
+	if queryParams.Limit == 0 {
+		return 0, n
+	}
+	start := listSkip(queryParams)
+	if start > uint64(n) {
+		start = uint64(n)
+	}
+	end := start + queryParams.Limit
+	if end > uint64(n) {
+		end = uint64(n)
+	}
+	return int(start), int(end)
+}
+
+// listMetaData is paginate for lists that may be unpaged.
+func listMetaData(queryParams dtos.ListQueryParams, total int64) common.MetaData {
+	if queryParams.Limit == 0 {
+		return common.MetaData{}
+	}
+	return paginate(queryParams.Limit, queryParams.Page, total)
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// applySet mimics a mongo $set on an in-memory object: the payload is decoded
+// into a new value of the object type and only the payload fields are copied
+// over. The other fields are left untouched, pointers shared with the stored
+// object are never written through.
+func applySet(object interface{}, payload primitive.M) error {
+	data, err := bson.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	value := reflect.ValueOf(object).Elem()
+	decoded := reflect.New(value.Type())
+	if err := bson.Unmarshal(data, decoded.Interface()); err != nil {
+		return err
+	}
+	for i := 0; i < value.NumField(); i++ {
+		key := strings.Split(value.Type().Field(i).Tag.Get("bson"), ",")[0]
+		if _, ok := payload[key]; ok {
+			value.Field(i).Set(decoded.Elem().Field(i))
+		}
+	}
+	return nil
+}
+
+// sortedObjectIds returns ids in insertion (natural) order. ObjectIDs start
+// with their creation timestamp, so byte order follows insertion order.
+func sortedObjectIds(ids []primitive.ObjectID) []primitive.ObjectID {
+	sort.Slice(ids, func(i, j int) bool {
+		return lessObjectId(ids[i], ids[j])
+	})
+	return ids
+}
+
+func lessObjectId(a primitive.ObjectID, b primitive.ObjectID) bool {
+	return bytes.Compare(a[:], b[:]) < 0
+}
+
+func copyObjectIds(ids []primitive.ObjectID) []primitive.ObjectID {
+	if ids == nil {
+		return nil
+	}
+	return append([]primitive.ObjectID{}, ids...)
+}
+
+func removeObjectId(ids []primitive.ObjectID, id primitive.ObjectID) []primitive.ObjectID {
+	result := []primitive.ObjectID{}
+	for _, _id := range ids {
+		if _id != id {
+			result = append(result, _id)
+		}
+	}
+	return result
+}
+
+// The helpers below maintain Category.Products and expect the caller to hold
+// the memory manager lock.
+
+// checkMemoryCategory fails when the category does not exist or is in the trash.
+func checkMemoryCategory(mm *db.MemoryManager, categoryId *primitive.ObjectID) error {
+	if categoryId == nil {
+		return nil
+	}
+	if category, ok := mm.Categories[*categoryId]; !ok || category.DeletedAt != nil {
+		return domain_error.Validation("category is not found")
+	}
+	return nil
+}
+
+func pushMemoryCategoryProduct(mm *db.MemoryManager, categoryId *primitive.ObjectID, productId primitive.ObjectID) {
+	if categoryId == nil {
+		return
+	}
+	category, ok := mm.Categories[*categoryId]
+	if !ok {
+		return
+	}
+	for _, id := range category.Products {
+		if id == productId {
+			return
+		}
+	}
+	category.Products = append(copyObjectIds(category.Products), productId)
+	category.Version++
+	mm.Categories[category.ID] = category
+}
+
+func removeMemoryCategoryProduct(mm *db.MemoryManager, categoryId *primitive.ObjectID, productId primitive.ObjectID) {
+	if categoryId == nil {
+		return
+	}
+	category, ok := mm.Categories[*categoryId]
+	if !ok {
+		return
+	}
+	products := removeObjectId(category.Products, productId)
+	if len(products) == len(category.Products) {
+		return
+	}
+	category.Products = products
+	category.Version++
+	mm.Categories[category.ID] = category
+}
+
+// sortedProducts returns the products ordered by creation time, the order in
+// which they join their category.
+func sortedProducts(mm *db.MemoryManager) []model.Product {
+	products := make([]model.Product, 0, len(mm.Products))
+	for _, product := range mm.Products {
+		products = append(products, product)
+	}
+	sort.Slice(products, func(i, j int) bool {
+		if !products[i].CreatedAt.Equal(products[j].CreatedAt) {
+			return products[i].CreatedAt.Before(products[j].CreatedAt)
+		}
+		return lessObjectId(products[i].ID, products[j].ID)
+	})
+	return products
+}
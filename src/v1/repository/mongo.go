@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// withMongoTx runs fn inside a transaction when the server supports them. On a
+// standalone server fn runs without one, writes are then ordered so a failure
+// leaves at most a stale Category.Products entry that reconciliation repairs.
+func withMongoTx(ctx context.Context, dm *db.DmManager, fn func(ctx context.Context) error) error {
+	if !dm.SupportsTransactions() {
+		return fn(ctx)
+	}
+	session, err := dm.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+	_, err = session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessionCtx)
+	})
+	return err
+}
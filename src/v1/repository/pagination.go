@@ -0,0 +1,333 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// paginate builds the list MetaData for the requested limit and page out of the
+// total number of elements.
+func paginate(limit uint64, page uint64, total int64) common.MetaData {
+	var metaData common.MetaData
+	metaData.TotalElements = uint64(total)
+	metaData.PerPage = limit
+	if page <= 0 {
+		page = 1
+	}
+	metaData.CurrentPage = page
+	metaData.TotalPages = totalPages(limit, total)
+	if metaData.CurrentPage < metaData.TotalPages {
+		_nextPage := metaData.CurrentPage + 1
+		metaData.NextPage = &_nextPage
+	}
+	if metaData.CurrentPage > 1 {
+		_prevPage := metaData.CurrentPage - 1
+		metaData.PrevPage = &_prevPage
+	}
+	return metaData
+}
+
+// totalPages counts a partial last page as a page.
+func totalPages(limit uint64, total int64) uint64 {
+	if limit == 0 || total <= 0 {
+		return 0
+	}
+	return (uint64(total) + limit - 1) / limit
+}
+
+// keysetCursor is the sort key of the element a keyset page continues from,
+// the value of its OrderBy field and its id. Clients get it base64 encoded and
+// pass it back as is.
+type keysetCursor struct {
+	OrderBy string             `json:"orderBy"`
+	Value   json.RawMessage    `json:"value"`
+	ID      primitive.ObjectID `json:"id"`
+	// value is Value read as the type of the field.
+	value interface{}
+}
+
+func encodeCursor(orderBy string, value interface{}, id primitive.ObjectID) *string {
+	raw, _ := json.Marshal(value)
+	data, _ := json.Marshal(keysetCursor{OrderBy: orderBy, Value: raw, ID: id})
+	cursor := base64.RawURLEncoding.EncodeToString(data)
+	return &cursor
+}
+
+// decodeCursor reads a cursor of a page ordered by orderBy, the cursors of
+// other orders are not valid.
+func decodeCursor(cursor string, orderBy string) (keysetCursor, error) {
+	var key keysetCursor
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || json.Unmarshal(data, &key) != nil || key.ID.IsZero() || key.OrderBy != orderBy {
+		return key, domain_error.Validation("cursor is not valid")
+	}
+	if key.value, err = decodeSortValue(orderBy, key.Value); err != nil {
+		return key, domain_error.Validation("cursor is not valid")
+	}
+	return key, nil
+}
+
+// decodeSortValue reads a sort value of a product field.
+func decodeSortValue(field string, data json.RawMessage) (interface{}, error) {
+	switch field {
+	case "price", "reviewCount":
+		var n int
+		err := json.Unmarshal(data, &n)
+		return n, err
+	case "title":
+		var title string
+		err := json.Unmarshal(data, &title)
+		return title, err
+	case "rating", "relevance":
+		var score float64
+		err := json.Unmarshal(data, &score)
+		return score, err
+	}
+	var t time.Time
+	err := json.Unmarshal(data, &t)
+	return t, err
+}
+
+// productSortValue is the value of the product field the list is ordered by.
+func productSortValue(product dtos.ProductResponseDto, field string) interface{} {
+	switch field {
+	case "updatedAt":
+		return product.UpdatedAt
+	case "price":
+		if product.Price == nil {
+			return 0
+		}
+		return *product.Price
+	case "title":
+		return product.Title
+	case "rating":
+		return product.Rating
+	case "reviewCount":
+		return product.ReviewCount
+	case "relevance":
+		if product.Score == nil {
+			return 0.0
+		}
+		return *product.Score
+	}
+	return product.CreatedAt
+}
+
+// compareSortValues compares two values of the same field.
+func compareSortValues(a interface{}, b interface{}) int {
+	switch a := a.(type) {
+	case int:
+		switch b := b.(int); {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	case float64:
+		switch b := b.(float64); {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	case string:
+		return strings.Compare(a, b.(string))
+	case time.Time:
+		return compareTime(a, b.(time.Time))
+	}
+	return 0
+}
+
+// productPage pages the product list, sorted by orderBy and id, either by
+// offset or after or before a cursor. A keyset page neither skips nor repeats
+// products when others are added or removed meanwhile.
+type productPage struct {
+	limit   uint64
+	page    uint64
+	orderBy string
+	cursor  *keysetCursor
+	// backward is true for a page before the cursor, it is read in the
+	// reverse order and turned around.
+	backward bool
+	// descending is the order the page is read in.
+	descending bool
+}
+
+func newProductPage(queryParams dtos.ProductQueryParams) (productPage, error) {
+	page := productPage{
+		limit:      queryParams.Limit,
+		page:       queryParams.Page,
+		orderBy:    queryParams.OrderBy,
+		descending: queryParams.Sort == enums.DESCENDING,
+	}
+	if page.orderBy == "" {
+		page.orderBy = "createdAt"
+		if queryParams.IsTextSearch() {
+			page.orderBy = "relevance"
+		}
+	}
+	// the most relevant come first unless asked otherwise
+	if page.orderBy == "relevance" && queryParams.Sort == "" {
+		page.descending = true
+	}
+	if queryParams.After == "" && queryParams.Before == "" {
+		return page, nil
+	}
+	if queryParams.After != "" && queryParams.Before != "" {
+		return page, domain_error.Validation("after and before can not be combined")
+	}
+	if queryParams.Page != 0 {
+		return page, domain_error.Validation("page can not be combined with after or before")
+	}
+	if queryParams.Limit == 0 {
+		return page, domain_error.Validation("limit is required with after or before")
+	}
+	cursor := queryParams.After
+	if queryParams.Before != "" {
+		cursor = queryParams.Before
+		page.backward = true
+		page.descending = !page.descending
+	}
+	key, err := decodeCursor(cursor, page.orderBy)
+	if err != nil {
+		return page, err
+	}
+	page.cursor = &key
+	return page, nil
+}
+
+// skip is the offset of the page, keyset pages start at their cursor.
+func (p productPage) skip() uint64 {
+	if p.limit == 0 || p.cursor != nil {
+		return 0
+	}
+	if p.page <= 1 {
+		return 0
+	}
+	return p.limit * (p.page - 1)
+}
+
+// fetch is the number of products to read, 0 reads all. A keyset page reads
+// one more to learn whether the list goes on.
+func (p productPage) fetch() uint64 {
+	if p.cursor != nil {
+		return p.limit + 1
+	}
+	return p.limit
+}
+
+// compare orders two products in the order the page is read in.
+func (p productPage) compare(a dtos.ProductResponseDto, b dtos.ProductResponseDto) int {
+	compare := compareSortValues(productSortValue(a, p.orderBy), productSortValue(b, p.orderBy))
+	if compare == 0 {
+		compare = strings.Compare(a.ID.Hex(), b.ID.Hex())
+	}
+	if p.descending {
+		return -compare
+	}
+	return compare
+}
+
+// follows reports whether the product belongs to the page, the memory
+// repository filters in code.
+func (p productPage) follows(product dtos.ProductResponseDto) bool {
+	if p.cursor == nil {
+		return true
+	}
+	compare := compareSortValues(productSortValue(product, p.orderBy), p.cursor.value)
+	if compare == 0 {
+		compare = strings.Compare(product.ID.Hex(), p.cursor.ID.Hex())
+	}
+	if p.descending {
+		return compare < 0
+	}
+	return compare > 0
+}
+
+// slice sorts the products and cuts out the page, for the products ordered in
+// process. It returns the page and the number of products.
+func (p productPage) slice(objects []dtos.ProductResponseDto) ([]dtos.ProductResponseDto, int64) {
+	sort.Slice(objects, func(i, j int) bool {
+		return p.compare(objects[i], objects[j]) < 0
+	})
+	if p.limit == 0 {
+		return objects, int64(len(objects))
+	}
+	paged := []dtos.ProductResponseDto{}
+	skip := p.skip()
+	for _, object := range objects {
+		if uint64(len(paged)) == p.fetch() {
+			break
+		}
+		if !p.follows(object) {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		paged = append(paged, object)
+	}
+	return paged, int64(len(objects))
+}
+
+// cursorOf is the cursor of a product of the page.
+func (p productPage) cursorOf(product dtos.ProductResponseDto) *string {
+	return encodeCursor(p.orderBy, productSortValue(product, p.orderBy), product.ID)
+}
+
+// result turns the products read into the page and its MetaData. total counts
+// every product matching the filters.
+func (p productPage) result(objects []dtos.ProductResponseDto, total int64) ([]dtos.ProductResponseDto, common.MetaData) {
+	if p.limit == 0 {
+		return objects, common.MetaData{}
+	}
+	if p.cursor == nil {
+		metaData := paginate(p.limit, p.page, total)
+		if len(objects) > 0 {
+			if metaData.NextPage != nil {
+				last := objects[len(objects)-1]
+				metaData.NextCursor = p.cursorOf(last)
+			}
+			if metaData.PrevPage != nil {
+				metaData.PrevCursor = p.cursorOf(objects[0])
+			}
+		}
+		return objects, metaData
+	}
+	more := uint64(len(objects)) > p.limit
+	if more {
+		objects = objects[:p.limit]
+	}
+	if p.backward {
+		for i, j := 0, len(objects)-1; i < j; i, j = i+1, j-1 {
+			objects[i], objects[j] = objects[j], objects[i]
+		}
+	}
+	metaData := common.MetaData{
+		PerPage:       p.limit,
+		TotalElements: uint64(total),
+		TotalPages:    totalPages(p.limit, total),
+	}
+	if len(objects) == 0 {
+		return objects, metaData
+	}
+	first, last := objects[0], objects[len(objects)-1]
+	// the cursor itself lies on the other side of the page
+	if more || p.backward {
+		metaData.NextCursor = p.cursorOf(last)
+	}
+	if more || !p.backward {
+		metaData.PrevCursor = p.cursorOf(first)
+	}
+	return objects, metaData
+}
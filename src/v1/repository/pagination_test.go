@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPaginate(t *testing.T) {
+	metaData := paginate(10, 2, 25)
+	if metaData.TotalPages != 3 || metaData.CurrentPage != 2 || *metaData.NextPage != 3 || *metaData.PrevPage != 1 {
+		t.Errorf("unexpected metadata %+v", metaData)
+	}
+	if last := paginate(10, 3, 25); last.NextPage != nil {
+		t.Errorf("expected no next page after the last, got %d", *last.NextPage)
+	}
+	if empty := paginate(10, 1, 0); empty.TotalPages != 0 || empty.NextPage != nil {
+		t.Errorf("unexpected metadata of an empty list %+v", empty)
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	id := primitive.NewObjectID()
+	createdAt := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+	for _, c := range []struct {
+		orderBy string
+		value   interface{}
+	}{
+		{"price", 1999},
+		{"title", "Pixel Phone"},
+		{"rating", 4.5},
+		{"createdAt", createdAt},
+	} {
+		cursor := encodeCursor(c.orderBy, c.value, id)
+		key, err := decodeCursor(*cursor, c.orderBy)
+		if err != nil {
+			t.Fatalf("%s: %v", c.orderBy, err)
+		}
+		if key.ID != id {
+			t.Errorf("%s: expected id %s, got %s", c.orderBy, id.Hex(), key.ID.Hex())
+		}
+		if value, ok := key.value.(time.Time); ok {
+			if !value.Equal(createdAt) {
+				t.Errorf("%s: expected %v, got %v", c.orderBy, createdAt, value)
+			}
+		} else if key.value != c.value {
+			t.Errorf("%s: expected %v, got %v", c.orderBy, c.value, key.value)
+		}
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	priced := *encodeCursor("price", 1999, primitive.NewObjectID())
+	for name, c := range map[string]struct{ cursor, orderBy string }{
+		"another order": {priced, "title"},
+		"not base64":    {"%%%", "price"},
+		"not json":      {"bm90IGpzb24", "price"},
+		"no id":         {*encodeCursor("price", 1999, primitive.NilObjectID), "price"},
+		"wrong value":   {*encodeCursor("price", "cheap", primitive.NewObjectID()), "price"},
+	} {
+		if _, err := decodeCursor(c.cursor, c.orderBy); domain_error.KindOf(err) != domain_error.VALIDATION {
+			t.Errorf("%s: expected a validation error, got %v", name, err)
+		}
+	}
+}
+
+func TestProductKeysetPagesWalkTheList(t *testing.T) {
+	ctx := context.Background()
+	repo := productSqlRepository{sm: newTestSqlManager(t)}
+	// two products share a price, the id breaks the tie
+	for i, price := range []int{500, 300, 300, 900, 100} {
+		product := newTestProduct("Product "+string(rune('A'+i)), price)
+		if _, err := repo.Store(ctx, product); err != nil {
+			t.Fatal(err)
+		}
+	}
+	queryParams := dtos.ProductQueryParams{}
+	queryParams.Limit = 2
+	queryParams.OrderBy = "price"
+	prices := []int{}
+	seen := map[primitive.ObjectID]bool{}
+	for page := 0; page < 5; page++ {
+		products, metaData, err := repo.FindAll(ctx, queryParams)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, product := range products {
+			if seen[product.ID] {
+				t.Fatalf("product %s is on two pages", product.Title)
+			}
+			seen[product.ID] = true
+			prices = append(prices, *product.Price)
+		}
+		if metaData.NextCursor == nil {
+			break
+		}
+		queryParams.After = *metaData.NextCursor
+	}
+	want := []int{100, 300, 300, 500, 900}
+	if len(prices) != len(want) {
+		t.Fatalf("expected %v, got %v", want, prices)
+	}
+	for i := range want {
+		if prices[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, prices)
+		}
+	}
+}
@@ -1,14 +1,16 @@
 package repository
 
 import (
-	"errors"
+	"context"
 	"log"
-	"math"
+	"strings"
 	"time"
 
 	"github.com/sajalmia381/store-api/src/api/common"
 	"github.com/sajalmia381/store-api/src/config"
+	"github.com/sajalmia381/store-api/src/domain_error"
 	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/search"
 	"github.com/sajalmia381/store-api/src/utils"
 	"github.com/sajalmia381/store-api/src/v1/db"
 	"github.com/sajalmia381/store-api/src/v1/dtos"
@@ -20,85 +22,173 @@ import (
 )
 
 type ProductRepository interface {
-	Store(product model.Product) (model.Product, error)
-	FindAll(queryParams dtos.ProductQueryParams) ([]dtos.ProductResponseDto, common.MetaData, error)
-	FindBySlug(slug string) (model.Product, error)
-	UpdateBySlug(slug string, payload primitive.M) (model.Product, error)
-	DeleteBySlug(slug string) (model.Product, error)
+	// Store generates the slug from the title unless the product has one.
+	Store(ctx context.Context, product model.Product) (model.Product, error)
+	FindAll(ctx context.Context, queryParams dtos.ProductQueryParams) ([]dtos.ProductResponseDto, common.MetaData, error)
+	// Each calls fn with the products of the query one at a time, in the
+	// order of the list and not paged, until fn fails. Unlike FindAll it
+	// leaves out the variants.
+	Each(ctx context.Context, queryParams dtos.ProductQueryParams, fn func(dtos.ProductResponseDto) error) error
+	FindBySlug(ctx context.Context, slug string) (model.Product, error)
+	// FindById finds a live product.
+	FindById(ctx context.Context, id primitive.ObjectID) (model.Product, error)
+	// UpdateBySlug and DeleteBySlug fail with PRECONDITION_FAILED unless the
+	// product is at the given version, a nil version skips the check.
+	UpdateBySlug(ctx context.Context, slug string, payload primitive.M, version *int64) (model.Product, error)
+	// AdjustStock adds delta to the stock and the available stock of the
+	// product, see adjustStock. Like UpdateBySlug it checks the version.
+	AdjustStock(ctx context.Context, slug string, delta int, version *int64) (model.Product, error)
+	// DeleteBySlug moves the product to the trash.
+	DeleteBySlug(ctx context.Context, slug string, version *int64) (model.Product, error)
+	IsSlugExists(ctx context.Context, slug string) bool
+	// IsSkuExists reports whether a variant of another product than productId,
+	// trashed ones included, has the sku.
+	IsSkuExists(ctx context.Context, sku string, productId primitive.ObjectID) bool
+	FindTrash(ctx context.Context) ([]model.Product, error)
+	FindTrashedBySlug(ctx context.Context, slug string) (model.Product, error)
+	// RestoreBySlug takes the product out of the trash and back into its
+	// category, a category that is gone meanwhile leaves it uncategorized.
+	RestoreBySlug(ctx context.Context, slug string) (model.Product, error)
+	// PurgeBySlug removes a trashed product permanently.
+	PurgeBySlug(ctx context.Context, slug string) (model.Product, error)
+	// PurgeDeletedBefore removes the products trashed before the given time
+	// permanently and counts them.
+	PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error)
 }
 
 type productRepository struct {
 	dm *db.DmManager
 }
 
-func (p productRepository) Store(product model.Product) (model.Product, error) {
+func (p productRepository) Store(ctx context.Context, product model.Product) (model.Product, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
 	if product.CreatedBy == "" {
 		product.CreatedBy = config.DefaultUserEmail
 	}
-	coll := p.dm.DB.Collection(string(enums.PRODUCT_COLLECTION_NAME))
-	product.Slug = utils.GenerateUniqueSlug(product.Title, string(enums.PRODUCT_COLLECTION_NAME))
-	_, err := coll.InsertOne(p.dm.Ctx, &product)
+	coll := p.dm.Collection(string(enums.PRODUCT_COLLECTION_NAME))
+	// a given slug is kept, the unique index refuses it when taken
+	if product.Slug == "" {
+		product.Slug = utils.GenerateUniqueSlug(ctx, product.Title, p.IsSlugExists)
+	}
+	err := withMongoTx(ctx, p.dm, func(ctx context.Context) error {
+		if err := p.checkCategory(ctx, product.Category); err != nil {
+			return err
+		}
+		if _, err := coll.InsertOne(ctx, &product); err != nil {
+			return err
+		}
+		return p.addToCategory(ctx, product.Category, product.ID)
+	})
 	if err != nil {
 		log.Println("[ERROR] Product Store err: ", err)
-		return product, err
+		return product, databaseError(err, "product")
 	}
 	return product, nil
 }
 
-func (p productRepository) FindAll(queryParams dtos.ProductQueryParams) ([]dtos.ProductResponseDto, common.MetaData, error) {
+func (p productRepository) FindAll(ctx context.Context, queryParams dtos.ProductQueryParams) ([]dtos.ProductResponseDto, common.MetaData, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
 	var objects []dtos.ProductResponseDto
-	var aggPipeline mongo.Pipeline
-	if queryParams.Search != "" {
-		aggPipeline = append(aggPipeline, bson.D{
-			{Key: "$match", Value: bson.D{
-				{Key: "$or", Value: bson.A{
-					bson.M{"title": bson.M{"$regex": primitive.Regex{Pattern: queryParams.Search, Options: "i"}}},
-					bson.M{"description": bson.M{"$regex": primitive.Regex{Pattern: queryParams.Search, Options: "i"}}},
-				}},
-			}},
-		})
+	var metaData common.MetaData
+	page, err := newProductPage(queryParams)
+	if err != nil {
+		return objects, metaData, err
 	}
-
-	if queryParams.Sort == enums.DESCENDING {
-		aggPipeline = append(aggPipeline, bson.D{
-			{Key: "$sort", Value: bson.M{
-				"createdAt": -1,
-			}},
-		})
+	filter, err := p.findAllFilter(ctx, queryParams)
+	if err != nil {
+		return objects, metaData, databaseError(err, "product")
 	}
-	coll := p.dm.DB.Collection(string(enums.PRODUCT_COLLECTION_NAME))
+	aggPipeline := sortedPipeline(filter, queryParams, page)
+	coll := p.dm.Collection(string(enums.PRODUCT_COLLECTION_NAME))
 	// Pagination
-	var metaData common.MetaData
+	var total int64
 	if queryParams.Limit != 0 {
-		// MetaData Calc
-		totalProducts, err := coll.CountDocuments(p.dm.Ctx, bson.M{})
+		total, err = coll.CountDocuments(ctx, filter)
 		if err != nil {
-			panic(err)
-		}
-		metaData.TotalElements = uint64(totalProducts)
-		metaData.PerPage = queryParams.Limit
-		if queryParams.Page <= 0 {
-			queryParams.Page = 1
-		}
-		metaData.CurrentPage = queryParams.Page
-		metaData.TotalPages = uint64(math.Round(float64(totalProducts) / float64(queryParams.Limit)))
-		if metaData.CurrentPage < metaData.TotalPages {
-			_nextPage := metaData.CurrentPage + 1
-			metaData.NextPage = &_nextPage
-		}
-		if metaData.CurrentPage > 1 {
-			_prevPage := metaData.CurrentPage - 1
-			metaData.PrevPage = &_prevPage
-		}
-		_skipItems := metaData.PerPage * (metaData.CurrentPage - 1)
-		skipStage := bson.D{{Key: "$skip", Value: _skipItems}}
-		limitStage := bson.D{{Key: "$limit", Value: metaData.PerPage}}
+			return objects, metaData, databaseError(err, "product")
+		}
+		skipStage := bson.D{{Key: "$skip", Value: int64(page.skip())}}
+		limitStage := bson.D{{Key: "$limit", Value: int64(page.fetch())}}
 		aggPipeline = append(aggPipeline, skipStage, limitStage)
 	}
 	// end pagination
 
-	// ForeignKey Aggregation
-	categoryLookup := bson.D{
+	aggPipeline = append(aggPipeline, productLookups...)
+
+	cursor, err := coll.Aggregate(ctx, aggPipeline)
+	if err != nil {
+		return objects, metaData, databaseError(err, "product")
+	}
+	if err = cursor.All(ctx, &objects); err != nil {
+		log.Println("[ERROR]", err)
+		return objects, metaData, databaseError(err, "product")
+	}
+	objects, metaData = page.result(objects, total)
+	return objects, metaData, nil
+}
+
+func (p productRepository) Each(ctx context.Context, queryParams dtos.ProductQueryParams, fn func(dtos.ProductResponseDto) error) error {
+	ctx, cancel := db.StreamContext(ctx)
+	defer cancel()
+	queryParams.Limit, queryParams.Page, queryParams.After, queryParams.Before = 0, 0, "", ""
+	page, err := newProductPage(queryParams)
+	if err != nil {
+		return err
+	}
+	filter, err := p.findAllFilter(ctx, queryParams)
+	if err != nil {
+		return databaseError(err, "product")
+	}
+	aggPipeline := sortedPipeline(filter, queryParams, page)
+	aggPipeline = append(aggPipeline, bson.D{{Key: "$project", Value: bson.M{"variants": 0}}})
+	aggPipeline = append(aggPipeline, productLookups...)
+	// the sort of a large list spills to disk instead of failing
+	opts := options.Aggregate().SetAllowDiskUse(true)
+	cursor, err := p.dm.Collection(string(enums.PRODUCT_COLLECTION_NAME)).Aggregate(ctx, aggPipeline, opts)
+	if err != nil {
+		return databaseError(err, "product")
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var object dtos.ProductResponseDto
+		if err := cursor.Decode(&object); err != nil {
+			return databaseError(err, "product")
+		}
+		if err := fn(object); err != nil {
+			return err
+		}
+	}
+	return databaseError(cursor.Err(), "product")
+}
+
+// sortedPipeline matches the products of the query from the cursor of the
+// page on and sorts them in the order the page is read in.
+func sortedPipeline(filter bson.D, queryParams dtos.ProductQueryParams, page productPage) mongo.Pipeline {
+	aggPipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+	}
+	if queryParams.IsTextSearch() {
+		aggPipeline = append(aggPipeline, bson.D{
+			{Key: "$addFields", Value: bson.M{"score": bson.M{"$meta": "textScore"}}},
+		})
+	}
+	if page.cursor != nil {
+		aggPipeline = append(aggPipeline, bson.D{{Key: "$match", Value: keysetFilter(page)}})
+	}
+	direction := 1
+	if page.descending {
+		direction = -1
+	}
+	return append(aggPipeline, bson.D{
+		{Key: "$sort", Value: bson.D{{Key: productSortKey(page.orderBy), Value: direction}, {Key: "_id", Value: direction}}},
+	})
+}
+
+// productLookups put the category and the creator in place of their keys.
+var productLookups = mongo.Pipeline{
+	bson.D{
 		{
 			Key: "$lookup", Value: bson.M{
 				"from":         "categories", // the collection name
@@ -107,16 +197,14 @@ func (p productRepository) FindAll(queryParams dtos.ProductQueryParams) ([]dtos.
 				"as":           "category",   // the field to populate into
 			},
 		},
-	}
-
-	categoryUnwind := bson.D{
+	},
+	bson.D{
 		{Key: "$unwind", Value: bson.M{
 			"path":                       "$category",
 			"preserveNullAndEmptyArrays": true,
 		}},
-	}
-	
-	userLookup := bson.D{
+	},
+	bson.D{
 		{
 			Key: "$lookup", Value: bson.M{
 				"from":         "users",
@@ -125,97 +213,451 @@ func (p productRepository) FindAll(queryParams dtos.ProductQueryParams) ([]dtos.
 				"as":           "createdBy",
 			},
 		},
-	}
-
-	userUnwind := bson.D{
+	},
+	bson.D{
 		{
 			Key: "$unwind", Value: bson.M{
 				"path":                       "$createdBy",
 				"preserveNullAndEmptyArrays": true,
 			},
 		},
-	}
-
-	aggPipeline = append(aggPipeline, categoryLookup, categoryUnwind, userLookup, userUnwind)
+	},
+}
 
-	cursor, err := coll.Aggregate(p.dm.Ctx, aggPipeline)
-	if err != nil {
-		if queryParams.Limit != 0 {
-			return objects, metaData, err
+// findAllFilter matches the products of the query. The category is looked up
+// first, together with its descendants when asked for.
+func (p productRepository) findAllFilter(ctx context.Context, queryParams dtos.ProductQueryParams) (bson.D, error) {
+	filter := bson.D{notDeleted}
+	if queryParams.IsTextSearch() {
+		// the words only, the other databases know no phrases and negations
+		terms := strings.Join(search.Terms(queryParams.Search), " ")
+		filter = append(filter, bson.E{Key: "$text", Value: bson.M{"$search": terms}})
+	} else if queryParams.Search != "" {
+		filter = append(filter, searchFilter(queryParams.Search, "title", "description"))
+	}
+	if queryParams.Category != "" {
+		var categories []model.Category
+		opts := options.Find().SetProjection(bson.M{"_id": 1, "slug": 1, "parent": 1})
+		cursor, err := p.dm.Collection(string(enums.CATEGORY_COLLECTION_NAME)).Find(ctx, bson.D{notDeleted}, opts)
+		if err != nil {
+			return filter, err
 		}
-		return objects, metaData, err
+		if err := cursor.All(ctx, &categories); err != nil {
+			return filter, err
+		}
+		family := categoryFamily(categories, queryParams.Category, queryParams.Subcategories)
+		filter = append(filter, bson.E{Key: "category", Value: bson.M{"$in": family}})
 	}
-	if err = cursor.All(p.dm.Ctx, &objects); err != nil {
-		log.Println("[ERROR]", err)
-		panic(err)
+	price := bson.M{}
+	if queryParams.MinPrice != nil {
+		price["$gte"] = *queryParams.MinPrice
 	}
-	if queryParams.Limit != 0 {
-		return objects, metaData, nil
+	if queryParams.MaxPrice != nil {
+		price["$lte"] = *queryParams.MaxPrice
 	}
-	return objects, metaData, nil
+	if len(price) > 0 {
+		filter = append(filter, bson.E{Key: "price", Value: price})
+	}
+	if queryParams.Active != nil {
+		filter = append(filter, bson.E{Key: "active", Value: *queryParams.Active})
+	}
+	if queryParams.CreatedBy != "" {
+		filter = append(filter, bson.E{Key: "createdBy", Value: queryParams.CreatedBy})
+	}
+	for _, attribute := range queryParams.Attributes {
+		match := bson.M{"$in": attributeFilterValues(attribute.Values)}
+		if attribute.Min != nil || attribute.Max != nil {
+			// the comparisons only match numbers to numbers
+			match = bson.M{}
+			if attribute.Min != nil {
+				match["$gte"] = *attribute.Min
+			}
+			if attribute.Max != nil {
+				match["$lte"] = *attribute.Max
+			}
+		}
+		filter = append(filter, bson.E{Key: "attributes." + attribute.Name, Value: match})
+	}
+	if createdAt := timeRange(queryParams.CreatedSince, queryParams.CreatedUntil); len(createdAt) > 0 {
+		filter = append(filter, bson.E{Key: "createdAt", Value: createdAt})
+	}
+	if updatedAt := timeRange(queryParams.UpdatedSince, queryParams.UpdatedUntil); len(updatedAt) > 0 {
+		filter = append(filter, bson.E{Key: "updatedAt", Value: updatedAt})
+	}
+	return filter, nil
+}
+
+// timeRange matches the times from since until until, zero times are open.
+func timeRange(since time.Time, until time.Time) bson.M {
+	match := bson.M{}
+	if !since.IsZero() {
+		match["$gte"] = since
+	}
+	if !until.IsZero() {
+		match["$lte"] = until
+	}
+	return match
+}
+
+// keysetFilter matches the products after the cursor of the page in the order
+// it is read in.
+func keysetFilter(page productPage) bson.D {
+	operator := "$gt"
+	if page.descending {
+		operator = "$lt"
+	}
+	key := productSortKey(page.orderBy)
+	return bson.D{{Key: "$or", Value: bson.A{
+		bson.M{key: bson.M{operator: page.cursor.value}},
+		bson.M{key: page.cursor.value, "_id": bson.M{operator: page.cursor.ID}},
+	}}}
 }
 
-func (p productRepository) FindBySlug(slug string) (model.Product, error) {
+// productSortKey is the key of the field the products are ordered by, the
+// relevance is the text score.
+func productSortKey(orderBy string) string {
+	if orderBy == "relevance" {
+		return "score"
+	}
+	return orderBy
+}
+
+func (p productRepository) FindBySlug(ctx context.Context, slug string) (model.Product, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
 	var product model.Product
 
 	filter := bson.D{
 		{Key: "slug", Value: slug},
+		notDeleted,
 	}
 
-	coll := p.dm.DB.Collection(string(enums.PRODUCT_COLLECTION_NAME))
-	result := coll.FindOne(p.dm.Ctx, filter)
+	coll := p.dm.Collection(string(enums.PRODUCT_COLLECTION_NAME))
+	result := coll.FindOne(ctx, filter)
 	if err := result.Decode(&product); err != nil {
-		if err == mongo.ErrNoDocuments {
-			return product, errors.New("product is not found")
-		}
-		log.Println("[ERROR]", err)
-		panic(err)
+		return product, databaseError(err, "product")
 	}
 	return product, nil
 }
 
-func (p productRepository) UpdateBySlug(slug string, payload primitive.M) (model.Product, error) {
+func (p productRepository) FindById(ctx context.Context, id primitive.ObjectID) (model.Product, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var product model.Product
+	filter := bson.D{
+		{Key: "_id", Value: id},
+		notDeleted,
+	}
+	coll := p.dm.Collection(string(enums.PRODUCT_COLLECTION_NAME))
+	if err := coll.FindOne(ctx, filter).Decode(&product); err != nil {
+		return product, databaseError(err, "product")
+	}
+	return product, nil
+}
+
+func (p productRepository) UpdateBySlug(ctx context.Context, slug string, payload primitive.M, version *int64) (model.Product, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
 
 	filter := bson.D{
 		{Key: "slug", Value: slug},
+		notDeleted,
 	}
-	coll := p.dm.DB.Collection(string(enums.PRODUCT_COLLECTION_NAME))
+	coll := p.dm.Collection(string(enums.PRODUCT_COLLECTION_NAME))
 	payload["updatedAt"] = time.Now().UTC()
 
-	update := bson.M{
+	update := withVersionInc(bson.M{
 		"$set": payload,
-	}
+	})
 	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 
-	result := coll.FindOneAndUpdate(p.dm.Ctx, filter, update, opts)
 	var product model.Product
-	if err := result.Decode(&product); err != nil {
+	err := withMongoTx(ctx, p.dm, func(ctx context.Context) error {
+		var old model.Product
+		if err := coll.FindOne(ctx, filter).Decode(&old); err != nil {
+			return err
+		}
+		if err := checkVersion("product", old.Version, version); err != nil {
+			return err
+		}
+		if category, ok := payload["category"].(*primitive.ObjectID); ok {
+			if err := p.checkCategory(ctx, category); err != nil {
+				return err
+			}
+		}
+		if err := coll.FindOneAndUpdate(ctx, withVersion(filter, version), update, opts).Decode(&product); err != nil {
+			return versionMismatch(ctx, coll, filter, version, "product", err)
+		}
+		if sameObjectId(old.Category, product.Category) {
+			return nil
+		}
+		if err := p.removeFromCategory(ctx, old.Category, product.ID); err != nil {
+			return err
+		}
+		return p.addToCategory(ctx, product.Category, product.ID)
+	})
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Println("[ERROR] product update doc:", err)
+		}
+		return product, databaseError(err, "product")
+	}
+	return product, nil
+}
+
+func (p productRepository) AdjustStock(ctx context.Context, slug string, delta int, version *int64) (model.Product, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var product model.Product
+	filter := bson.D{
+		{Key: "slug", Value: slug},
+		notDeleted,
+	}
+	coll := p.dm.Collection(string(enums.PRODUCT_COLLECTION_NAME))
+	if err := coll.FindOne(ctx, filter).Decode(&product); err != nil {
+		return product, databaseError(err, "product")
+	}
+	if err := checkVersion("product", product.Version, version); err != nil {
+		return product, err
+	}
+	if _, _, err := adjustStock(product, delta); err != nil {
+		return product, err
+	}
+	// the update only applies to the stock it was checked against, the
+	// reservations change the available stock meanwhile
+	guard := bson.D{{Key: "_id", Value: product.ID}, {Key: "version", Value: product.Version}}
+	update := withVersionInc(bson.M{"$set": bson.M{"updatedAt": time.Now().UTC()}})
+	if product.Available == nil {
+		guard = append(guard, bson.E{Key: "available", Value: nil})
+		update["$set"] = bson.M{"updatedAt": time.Now().UTC(), "stock": delta, "available": delta}
+	} else {
+		guard = append(guard, bson.E{Key: "available", Value: bson.M{"$gte": -delta}})
+		update["$inc"] = bson.M{"version": 1, "stock": delta, "available": delta}
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if err := coll.FindOneAndUpdate(ctx, guard, update, opts).Decode(&product); err != nil {
 		if err == mongo.ErrNoDocuments {
-			return product, errors.New("product is not found")
+			return product, domain_error.PreconditionFailed("product was modified meanwhile")
 		}
-		log.Println("[ERROR] product update doc:", err)
-		panic(err)
+		return product, databaseError(err, "product")
 	}
 	return product, nil
 }
 
-func (p productRepository) DeleteBySlug(slug string) (model.Product, error) {
+func (p productRepository) DeleteBySlug(ctx context.Context, slug string, version *int64) (model.Product, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
 	var product model.Product
 	filter := bson.D{
 		{Key: "slug", Value: slug},
+		notDeleted,
 	}
-	coll := p.dm.DB.Collection(string(enums.PRODUCT_COLLECTION_NAME))
-	err := coll.FindOneAndDelete(p.dm.Ctx, filter).Decode(&product)
+	update := withVersionInc(bson.M{"$set": bson.M{"deletedAt": time.Now().UTC()}})
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	coll := p.dm.Collection(string(enums.PRODUCT_COLLECTION_NAME))
+	err := withMongoTx(ctx, p.dm, func(ctx context.Context) error {
+		if err := coll.FindOneAndUpdate(ctx, withVersion(filter, version), update, opts).Decode(&product); err != nil {
+			return versionMismatch(ctx, coll, filter, version, "product", err)
+		}
+		return p.removeFromCategory(ctx, product.Category, product.ID)
+	})
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return product, errors.New("product is not exists")
+		return product, databaseError(err, "product")
+	}
+	return product, nil
+}
+
+func (p productRepository) IsSlugExists(ctx context.Context, slug string) bool {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	coll := p.dm.Collection(string(enums.PRODUCT_COLLECTION_NAME))
+	err := coll.FindOne(ctx, bson.M{"slug": slug}).Err()
+	return err == nil
+}
+
+func (p productRepository) IsSkuExists(ctx context.Context, sku string, productId primitive.ObjectID) bool {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	coll := p.dm.Collection(string(enums.PRODUCT_COLLECTION_NAME))
+	err := coll.FindOne(ctx, bson.M{"variants.sku": sku, "_id": bson.M{"$ne": productId}}).Err()
+	return err == nil
+}
+
+func (p productRepository) FindTrash(ctx context.Context) ([]model.Product, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	objects := []model.Product{}
+	coll := p.dm.Collection(string(enums.PRODUCT_COLLECTION_NAME))
+	cursor, err := coll.Find(ctx, bson.D{isDeleted}, trashOptions())
+	if err != nil {
+		return objects, databaseError(err, "product")
+	}
+	if err := cursor.All(ctx, &objects); err != nil {
+		return objects, databaseError(err, "product")
+	}
+	return objects, nil
+}
+
+func (p productRepository) FindTrashedBySlug(ctx context.Context, slug string) (model.Product, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var product model.Product
+	filter := bson.D{
+		{Key: "slug", Value: slug},
+		isDeleted,
+	}
+	coll := p.dm.Collection(string(enums.PRODUCT_COLLECTION_NAME))
+	if err := coll.FindOne(ctx, filter).Decode(&product); err != nil {
+		return product, databaseError(err, "product in trash")
+	}
+	return product, nil
+}
+
+func (p productRepository) RestoreBySlug(ctx context.Context, slug string) (model.Product, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var product model.Product
+	filter := bson.D{
+		{Key: "slug", Value: slug},
+		isDeleted,
+	}
+	coll := p.dm.Collection(string(enums.PRODUCT_COLLECTION_NAME))
+	err := withMongoTx(ctx, p.dm, func(ctx context.Context) error {
+		if err := coll.FindOne(ctx, filter).Decode(&product); err != nil {
+			return err
+		}
+		err := p.checkCategory(ctx, product.Category)
+		if domain_error.Is(err, domain_error.VALIDATION) {
+			product.Category = nil
+		} else if err != nil {
+			return err
+		}
+		product.DeletedAt = nil
+		product.Version++
+		update := withVersionInc(bson.M{"$set": bson.M{"deletedAt": nil, "category": product.Category}})
+		if _, err := coll.UpdateOne(ctx, bson.M{"_id": product.ID}, update); err != nil {
+			return err
+		}
+		return p.addToCategory(ctx, product.Category, product.ID)
+	})
+	if err != nil {
+		return product, databaseError(err, "product in trash")
+	}
+	return product, nil
+}
+
+func (p productRepository) PurgeBySlug(ctx context.Context, slug string) (model.Product, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var product model.Product
+	filter := bson.D{
+		{Key: "slug", Value: slug},
+		isDeleted,
+	}
+	coll := p.dm.Collection(string(enums.PRODUCT_COLLECTION_NAME))
+	err := withMongoTx(ctx, p.dm, func(ctx context.Context) error {
+		if err := coll.FindOneAndDelete(ctx, filter).Decode(&product); err != nil {
+			return err
+		}
+		if err := p.deleteReviews(ctx, []primitive.ObjectID{product.ID}); err != nil {
+			return err
 		}
-		log.Println("mongo err", err)
-		panic(err)
+		return p.pullFromCategories(ctx, []primitive.ObjectID{product.ID})
+	})
+	if err != nil {
+		return product, databaseError(err, "product in trash")
 	}
 	return product, nil
 }
 
+func (p productRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var count int64
+	coll := p.dm.Collection(string(enums.PRODUCT_COLLECTION_NAME))
+	err := withMongoTx(ctx, p.dm, func(ctx context.Context) error {
+		var products []model.Product
+		cursor, err := coll.Find(ctx, deletedBefore(before), options.Find().SetProjection(bson.M{"_id": 1}))
+		if err != nil {
+			return err
+		}
+		if err := cursor.All(ctx, &products); err != nil {
+			return err
+		}
+		ids := []primitive.ObjectID{}
+		for _, product := range products {
+			ids = append(ids, product.ID)
+		}
+		result, err := coll.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+		if err != nil {
+			return err
+		}
+		count = result.DeletedCount
+		if err := p.deleteReviews(ctx, ids); err != nil {
+			return err
+		}
+		return p.pullFromCategories(ctx, ids)
+	})
+	return count, databaseError(err, "product")
+}
+
+// deleteReviews deletes the reviews of purged products.
+func (p productRepository) deleteReviews(ctx context.Context, ids []primitive.ObjectID) error {
+	coll := p.dm.Collection(string(enums.REVIEW_COLLECTION_NAME))
+	_, err := coll.DeleteMany(ctx, bson.M{"productId": bson.M{"$in": ids}})
+	return err
+}
+
+// checkCategory fails when the product refers to a category that does not
+// exist or is in the trash.
+func (p productRepository) checkCategory(ctx context.Context, categoryId *primitive.ObjectID) error {
+	if categoryId == nil {
+		return nil
+	}
+	coll := p.dm.Collection(string(enums.CATEGORY_COLLECTION_NAME))
+	count, err := coll.CountDocuments(ctx, bson.D{{Key: "_id", Value: categoryId}, notDeleted})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return domain_error.Validation("category is not found")
+	}
+	return nil
+}
+
+func (p productRepository) addToCategory(ctx context.Context, categoryId *primitive.ObjectID, productId primitive.ObjectID) error {
+	if categoryId == nil {
+		return nil
+	}
+	coll := p.dm.Collection(string(enums.CATEGORY_COLLECTION_NAME))
+	filter := bson.M{"_id": categoryId, "products": bson.M{"$ne": productId}}
+	update := withVersionInc(bson.M{"$push": bson.M{"products": productId}})
+	_, err := coll.UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (p productRepository) removeFromCategory(ctx context.Context, categoryId *primitive.ObjectID, productId primitive.ObjectID) error {
+	if categoryId == nil {
+		return nil
+	}
+	coll := p.dm.Collection(string(enums.CATEGORY_COLLECTION_NAME))
+	update := withVersionInc(bson.M{"$pull": bson.M{"products": productId}})
+	_, err := coll.UpdateOne(ctx, bson.M{"_id": categoryId, "products": productId}, update)
+	return err
+}
+
+// pullFromCategories removes purged products from every Category.Products,
+// trashed categories included.
+func (p productRepository) pullFromCategories(ctx context.Context, productIds []primitive.ObjectID) error {
+	if len(productIds) == 0 {
+		return nil
+	}
+	coll := p.dm.Collection(string(enums.CATEGORY_COLLECTION_NAME))
+	update := withVersionInc(bson.M{"$pull": bson.M{"products": bson.M{"$in": productIds}}})
+	_, err := coll.UpdateMany(ctx, bson.M{"products": bson.M{"$in": productIds}}, update)
+	return err
+}
+
 func NewProductRepository() ProductRepository {
 	return &productRepository{
 		dm: db.GetDmManager(),
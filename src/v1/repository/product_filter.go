@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/sajalmia381/store-api/src/search"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// productOrderColumns maps the product fields the list is ordered by to their
+// sql columns, mongo and memory use the field names.
+var productOrderColumns = map[string]string{
+	"createdAt":   "created_at",
+	"updatedAt":   "updated_at",
+	"price":       "price",
+	"title":       "title",
+	"rating":      "rating",
+	"reviewCount": "review_count",
+}
+
+// categoryFamily is the id of the category whose slug or id is ref and, with
+// subcategories, the ids of its descendants by Category.Parent. categories are
+// the live ones, so an unknown or trashed category has no family and a trashed
+// one cuts off its descendants.
+func categoryFamily(categories []model.Category, ref string, subcategories bool) []primitive.ObjectID {
+	family := []primitive.ObjectID{}
+	for _, category := range categories {
+		if category.Slug == ref || category.ID.Hex() == ref {
+			family = append(family, category.ID)
+			break
+		}
+	}
+	if !subcategories || len(family) == 0 {
+		return family
+	}
+	children := map[primitive.ObjectID][]primitive.ObjectID{}
+	for _, category := range categories {
+		if category.Parent != nil {
+			children[*category.Parent] = append(children[*category.Parent], category.ID)
+		}
+	}
+	seen := map[primitive.ObjectID]bool{family[0]: true}
+	for i := 0; i < len(family); i++ {
+		for _, child := range children[family[i]] {
+			if !seen[child] {
+				seen[child] = true
+				family = append(family, child)
+			}
+		}
+	}
+	return family
+}
+
+// rankProducts scores the products for the terms of a text search and drops
+// the ones no word matches. Mongo ranks by its text index, the other
+// databases rank in process.
+func rankProducts(objects []dtos.ProductResponseDto, terms []string) []dtos.ProductResponseDto {
+	ranked := []dtos.ProductResponseDto{}
+	for _, object := range objects {
+		description := ""
+		if object.Description != nil {
+			description = *object.Description
+		}
+		score := search.Score(terms, object.Title, description)
+		if score == 0 {
+			continue
+		}
+		object.Score = &score
+		ranked = append(ranked, object)
+	}
+	return ranked
+}
+
+// attributeFilterValues are the values a filter value matches, the string
+// itself, the number it reads as and true or false.
+func attributeFilterValues(values []string) []interface{} {
+	matches := []interface{}{}
+	for _, value := range values {
+		matches = append(matches, value)
+		if number, ok := parseAttributeNumber(value); ok {
+			matches = append(matches, number)
+		}
+		if value == "true" || value == "false" {
+			matches = append(matches, value == "true")
+		}
+	}
+	return matches
+}
+
+func parseAttributeNumber(value string) (float64, bool) {
+	number, err := strconv.ParseFloat(value, 64)
+	return number, err == nil && !math.IsInf(number, 0) && !math.IsNaN(number)
+}
+
+// matchesAttributeFilter checks the attribute value of a product against the
+// filter like the databases do.
+func matchesAttributeFilter(value interface{}, filter dtos.ProductAttributeFilter) bool {
+	if filter.Min != nil || filter.Max != nil {
+		number, ok := value.(float64)
+		return ok && (filter.Min == nil || number >= *filter.Min) && (filter.Max == nil || number <= *filter.Max)
+	}
+	for _, match := range attributeFilterValues(filter.Values) {
+		if match == value {
+			return true
+		}
+	}
+	return false
+}
+
+// attributeText is the text and the number sql keeps of an attribute value,
+// the number is nil unless the value is a number.
+func attributeText(value interface{}) (string, *float64) {
+	switch value := value.(type) {
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), &value
+	case bool:
+		return strconv.FormatBool(value), nil
+	case string:
+		return value, nil
+	}
+	return "", nil
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+)
+
+func TestMatchesAttributeFilter(t *testing.T) {
+	eight, sixteen := 8.0, 16.0
+	filters := []struct {
+		value  interface{}
+		filter dtos.ProductAttributeFilter
+		match  bool
+	}{
+		{"black", dtos.ProductAttributeFilter{Values: []string{"white", "black"}}, true},
+		{"black", dtos.ProductAttributeFilter{Values: []string{"Black"}}, false},
+		{16.0, dtos.ProductAttributeFilter{Values: []string{"16.0"}}, true},
+		{true, dtos.ProductAttributeFilter{Values: []string{"true"}}, true},
+		{false, dtos.ProductAttributeFilter{Values: []string{"true"}}, false},
+		{nil, dtos.ProductAttributeFilter{Values: []string{""}}, false},
+		{16.0, dtos.ProductAttributeFilter{Min: &eight, Max: &sixteen}, true},
+		{32.0, dtos.ProductAttributeFilter{Min: &eight}, true},
+		{4.0, dtos.ProductAttributeFilter{Min: &eight}, false},
+		{"16", dtos.ProductAttributeFilter{Max: &sixteen}, false},
+	}
+	for _, f := range filters {
+		if got := matchesAttributeFilter(f.value, f.filter); got != f.match {
+			t.Errorf("%v against %+v got %v, want %v", f.value, f.filter, got, f.match)
+		}
+	}
+}
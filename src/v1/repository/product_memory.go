@@ -0,0 +1,449 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/config"
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/search"
+	"github.com/sajalmia381/store-api/src/utils"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type productMemoryRepository struct {
+	mm *db.MemoryManager
+}
+
+func (p productMemoryRepository) Store(ctx context.Context, product model.Product) (model.Product, error) {
+	if product.CreatedBy == "" {
+		product.CreatedBy = config.DefaultUserEmail
+	}
+	p.mm.Lock()
+	defer p.mm.Unlock()
+	if _, ok := p.mm.Products[product.ID]; ok {
+		return product, domain_error.Conflict("product id is already exists")
+	}
+	if err := checkMemoryCategory(p.mm, product.Category); err != nil {
+		return product, err
+	}
+	if p.isSkuTaken(product) {
+		return product, domain_error.Conflict("sku is already exists")
+	}
+	// a given slug is kept, it fails when taken
+	if product.Slug == "" {
+		product.Slug = utils.GenerateUniqueSlug(ctx, product.Title, p.isSlugExists)
+	} else if p.isSlugExists(ctx, product.Slug) {
+		return product, domain_error.Conflict("product is already exists")
+	}
+	p.mm.Products[product.ID] = product
+	pushMemoryCategoryProduct(p.mm, product.Category, product.ID)
+	return product, nil
+}
+
+func (p productMemoryRepository) FindAll(ctx context.Context, queryParams dtos.ProductQueryParams) ([]dtos.ProductResponseDto, common.MetaData, error) {
+	objects := []dtos.ProductResponseDto{}
+	var metaData common.MetaData
+	page, err := newProductPage(queryParams)
+	if err != nil {
+		return objects, metaData, err
+	}
+
+	p.mm.RLock()
+	defer p.mm.RUnlock()
+	var family map[primitive.ObjectID]bool
+	if queryParams.Category != "" {
+		categories := []model.Category{}
+		for _, category := range p.mm.Categories {
+			if category.DeletedAt == nil {
+				categories = append(categories, category)
+			}
+		}
+		family = map[primitive.ObjectID]bool{}
+		for _, id := range categoryFamily(categories, queryParams.Category, queryParams.Subcategories) {
+			family[id] = true
+		}
+	}
+	for _, product := range p.mm.Products {
+		if product.DeletedAt != nil {
+			continue
+		}
+		if queryParams.Search != "" && !queryParams.IsTextSearch() && !memorySearch(queryParams.Search, product.Title, product.Description) {
+			continue
+		}
+		if family != nil && (product.Category == nil || !family[*product.Category]) {
+			continue
+		}
+		if !matchesProductFilters(product, queryParams) {
+			continue
+		}
+		objects = append(objects, p.toResponseDto(product))
+	}
+	if queryParams.IsTextSearch() {
+		objects = rankProducts(objects, search.Terms(queryParams.Search))
+	}
+	objects, total := page.slice(objects)
+	objects, metaData = page.result(objects, total)
+	return objects, metaData, nil
+}
+
+// matchesProductFilters checks the price, active, creator, attribute and time
+// filters.
+func matchesProductFilters(product model.Product, queryParams dtos.ProductQueryParams) bool {
+	if queryParams.MinPrice != nil && product.Price < *queryParams.MinPrice {
+		return false
+	}
+	if queryParams.MaxPrice != nil && product.Price > *queryParams.MaxPrice {
+		return false
+	}
+	if queryParams.Active != nil && product.Active != *queryParams.Active {
+		return false
+	}
+	if queryParams.CreatedBy != "" && product.CreatedBy != queryParams.CreatedBy {
+		return false
+	}
+	for _, filter := range queryParams.Attributes {
+		if !matchesAttributeFilter(product.Attributes[filter.Name], filter) {
+			return false
+		}
+	}
+	return inTimeRange(product.CreatedAt, queryParams.CreatedSince, queryParams.CreatedUntil) &&
+		inTimeRange(product.UpdatedAt, queryParams.UpdatedSince, queryParams.UpdatedUntil)
+}
+
+// inTimeRange reports whether t lies from since until until, zero times are
+// open.
+func inTimeRange(t time.Time, since time.Time, until time.Time) bool {
+	return (since.IsZero() || !t.Before(since)) && (until.IsZero() || !t.After(until))
+}
+
+func (p productMemoryRepository) Each(ctx context.Context, queryParams dtos.ProductQueryParams, fn func(dtos.ProductResponseDto) error) error {
+	queryParams.Limit, queryParams.Page, queryParams.After, queryParams.Before = 0, 0, "", ""
+	objects, _, err := p.FindAll(ctx, queryParams)
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		object.Variants = nil
+		if err := fn(object); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p productMemoryRepository) FindBySlug(ctx context.Context, slug string) (model.Product, error) {
+	p.mm.RLock()
+	defer p.mm.RUnlock()
+	product, ok := p.findBySlug(slug)
+	if !ok {
+		return product, domain_error.NotFound("product is not found")
+	}
+	return product, nil
+}
+
+func (p productMemoryRepository) FindById(ctx context.Context, id primitive.ObjectID) (model.Product, error) {
+	p.mm.RLock()
+	defer p.mm.RUnlock()
+	product, ok := p.mm.Products[id]
+	if !ok || product.DeletedAt != nil {
+		return product, domain_error.NotFound("product is not found")
+	}
+	return product, nil
+}
+
+func (p productMemoryRepository) UpdateBySlug(ctx context.Context, slug string, payload primitive.M, version *int64) (model.Product, error) {
+	payload["updatedAt"] = time.Now().UTC()
+	p.mm.Lock()
+	defer p.mm.Unlock()
+	product, ok := p.findBySlug(slug)
+	if !ok {
+		return product, domain_error.NotFound("product is not found")
+	}
+	if err := checkVersion("product", product.Version, version); err != nil {
+		return product, err
+	}
+	oldCategory := product.Category
+	if err := applySet(&product, payload); err != nil {
+		return product, err
+	}
+	if p.isSkuTaken(product) {
+		return product, domain_error.Conflict("sku is already exists")
+	}
+	if !sameObjectId(oldCategory, product.Category) {
+		if err := checkMemoryCategory(p.mm, product.Category); err != nil {
+			return product, err
+		}
+		removeMemoryCategoryProduct(p.mm, oldCategory, product.ID)
+		pushMemoryCategoryProduct(p.mm, product.Category, product.ID)
+	}
+	product.Version++
+	p.mm.Products[product.ID] = product
+	return product, nil
+}
+
+func (p productMemoryRepository) AdjustStock(ctx context.Context, slug string, delta int, version *int64) (model.Product, error) {
+	p.mm.Lock()
+	defer p.mm.Unlock()
+	product, ok := p.findBySlug(slug)
+	if !ok {
+		return product, domain_error.NotFound("product is not found")
+	}
+	if err := checkVersion("product", product.Version, version); err != nil {
+		return product, err
+	}
+	stock, available, err := adjustStock(product, delta)
+	if err != nil {
+		return product, err
+	}
+	product.Stock = &stock
+	product.Available = &available
+	product.UpdatedAt = time.Now().UTC()
+	product.Version++
+	p.mm.Products[product.ID] = product
+	return product, nil
+}
+
+func (p productMemoryRepository) DeleteBySlug(ctx context.Context, slug string, version *int64) (model.Product, error) {
+	p.mm.Lock()
+	defer p.mm.Unlock()
+	product, ok := p.findBySlug(slug)
+	if !ok {
+		return product, domain_error.NotFound("product is not found")
+	}
+	if err := checkVersion("product", product.Version, version); err != nil {
+		return product, err
+	}
+	now := time.Now().UTC()
+	product.DeletedAt = &now
+	product.Version++
+	p.mm.Products[product.ID] = product
+	removeMemoryCategoryProduct(p.mm, product.Category, product.ID)
+	return product, nil
+}
+
+func (p productMemoryRepository) IsSlugExists(ctx context.Context, slug string) bool {
+	p.mm.RLock()
+	defer p.mm.RUnlock()
+	return p.isSlugExists(ctx, slug)
+}
+
+func (p productMemoryRepository) IsSkuExists(ctx context.Context, sku string, productId primitive.ObjectID) bool {
+	p.mm.RLock()
+	defer p.mm.RUnlock()
+	for _, product := range p.mm.Products {
+		if product.ID == productId {
+			continue
+		}
+		for _, variant := range product.Variants {
+			if variant.SKU == sku {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p productMemoryRepository) FindTrash(ctx context.Context) ([]model.Product, error) {
+	p.mm.RLock()
+	defer p.mm.RUnlock()
+	objects := []model.Product{}
+	for _, id := range p.sortedIds() {
+		if product := p.mm.Products[id]; product.DeletedAt != nil {
+			objects = append(objects, product)
+		}
+	}
+	sort.SliceStable(objects, func(i, j int) bool { return newerTrash(objects[i].DeletedAt, objects[j].DeletedAt) })
+	return objects, nil
+}
+
+func (p productMemoryRepository) FindTrashedBySlug(ctx context.Context, slug string) (model.Product, error) {
+	p.mm.RLock()
+	defer p.mm.RUnlock()
+	product, ok := p.findTrashedBySlug(slug)
+	if !ok {
+		return product, domain_error.NotFound("product in trash is not found")
+	}
+	return product, nil
+}
+
+func (p productMemoryRepository) RestoreBySlug(ctx context.Context, slug string) (model.Product, error) {
+	p.mm.Lock()
+	defer p.mm.Unlock()
+	product, ok := p.findTrashedBySlug(slug)
+	if !ok {
+		return product, domain_error.NotFound("product in trash is not found")
+	}
+	if checkMemoryCategory(p.mm, product.Category) != nil {
+		product.Category = nil
+	}
+	product.DeletedAt = nil
+	product.Version++
+	p.mm.Products[product.ID] = product
+	pushMemoryCategoryProduct(p.mm, product.Category, product.ID)
+	return product, nil
+}
+
+func (p productMemoryRepository) PurgeBySlug(ctx context.Context, slug string) (model.Product, error) {
+	p.mm.Lock()
+	defer p.mm.Unlock()
+	product, ok := p.findTrashedBySlug(slug)
+	if !ok {
+		return product, domain_error.NotFound("product in trash is not found")
+	}
+	p.purge(product.ID)
+	return product, nil
+}
+
+func (p productMemoryRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	p.mm.Lock()
+	defer p.mm.Unlock()
+	var count int64
+	for id, product := range p.mm.Products {
+		if trashedBefore(product.DeletedAt, before) {
+			p.purge(id)
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Helpers below expect the caller to hold the memory manager lock.
+
+// isSlugExists counts trashed products too, they keep their slug.
+func (p productMemoryRepository) isSlugExists(ctx context.Context, slug string) bool {
+	for _, product := range p.mm.Products {
+		if product.Slug == slug {
+			return true
+		}
+	}
+	return false
+}
+
+// isSkuTaken stands in for the unique sku index of the other databases.
+func (p productMemoryRepository) isSkuTaken(product model.Product) bool {
+	skus := map[string]bool{}
+	for _, variant := range product.Variants {
+		if skus[variant.SKU] {
+			return true
+		}
+		skus[variant.SKU] = true
+	}
+	for _, other := range p.mm.Products {
+		if other.ID == product.ID {
+			continue
+		}
+		for _, variant := range other.Variants {
+			if skus[variant.SKU] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p productMemoryRepository) findBySlug(slug string) (model.Product, bool) {
+	for _, product := range p.mm.Products {
+		if product.Slug == slug && product.DeletedAt == nil {
+			return product, true
+		}
+	}
+	return model.Product{}, false
+}
+
+func (p productMemoryRepository) findTrashedBySlug(slug string) (model.Product, bool) {
+	for _, product := range p.mm.Products {
+		if product.Slug == slug && product.DeletedAt != nil {
+			return product, true
+		}
+	}
+	return model.Product{}, false
+}
+
+// purge drops the product and its entries in every Category.Products, trashed
+// categories included.
+func (p productMemoryRepository) purge(id primitive.ObjectID) {
+	delete(p.mm.Products, id)
+	for reviewId, review := range p.mm.Reviews {
+		if review.ProductId == id {
+			delete(p.mm.Reviews, reviewId)
+		}
+	}
+	for categoryId := range p.mm.Categories {
+		removeMemoryCategoryProduct(p.mm, &categoryId, id)
+	}
+}
+
+// toResponseDto resolves the category and createdBy references the same way
+// the $lookup stages of the mongo aggregation do.
+func (p productMemoryRepository) toResponseDto(product model.Product) dtos.ProductResponseDto {
+	price := product.Price
+	description := product.Description
+	object := dtos.ProductResponseDto{
+		ID:          product.ID,
+		Title:       product.Title,
+		Slug:        product.Slug,
+		Price:       &price,
+		Description: &description,
+		CreatedAt:   product.CreatedAt,
+		UpdatedAt:   product.UpdatedAt,
+		Active:      product.Active,
+		Version:     product.Version,
+		Variants:    product.Variants,
+		Image:       product.Image,
+		Images:      product.Images,
+		Stock:       product.Stock,
+		Available:   product.Available,
+		Rating:      product.Rating,
+		ReviewCount: product.ReviewCount,
+		Attributes:  product.Attributes,
+	}
+	if product.Category != nil {
+		if category, ok := p.mm.Categories[*product.Category]; ok {
+			object.Category = &dtos.ProductCategory{
+				ID:   category.ID,
+				Name: category.Name,
+				Slug: category.Slug,
+			}
+		}
+	}
+	for _, user := range p.mm.Users {
+		if user.Email != product.CreatedBy {
+			continue
+		}
+		object.CreatedBy = &dtos.ProductCreator{
+			ID:        user.ID,
+			Name:      user.Name,
+			Email:     user.Email,
+			Status:    user.Status,
+			Role:      user.Role,
+			CreatedAt: user.CreatedAt,
+			UpdateAt:  user.UpdatedAt,
+		}
+		if user.Number != nil {
+			number := int(*user.Number)
+			object.CreatedBy.Number = &number
+		}
+		break
+	}
+	return object
+}
+
+func (p productMemoryRepository) sortedIds() []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, 0, len(p.mm.Products))
+	for id := range p.mm.Products {
+		ids = append(ids, id)
+	}
+	return sortedObjectIds(ids)
+}
+
+func NewProductMemoryRepository() ProductRepository {
+	return &productMemoryRepository{
+		mm: db.GetMemoryManager(),
+	}
+}
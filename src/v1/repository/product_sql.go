@@ -0,0 +1,855 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/config"
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/search"
+	"github.com/sajalmia381/store-api/src/utils"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const productColumns = "id, created_by, category_id, image_source, title, slug, price, image, description, created_at, updated_at, active, deleted_at, version, images, stock, available, rating, review_count, attributes"
+
+type productSqlRepository struct {
+	sm *db.SqlManager
+}
+
+func (p productSqlRepository) Store(ctx context.Context, product model.Product) (model.Product, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	if product.CreatedBy == "" {
+		product.CreatedBy = config.DefaultUserEmail
+	}
+	// a given slug is kept, the unique index refuses it when taken
+	if product.Slug == "" {
+		product.Slug = utils.GenerateUniqueSlug(ctx, product.Title, p.IsSlugExists)
+	}
+	err := withTx(ctx, p.sm, func(tx *sql.Tx) error {
+		if err := checkSqlCategory(ctx, p.sm, tx, product.Category); err != nil {
+			return err
+		}
+		query := p.sm.Rebind(`INSERT INTO products (` + productColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+		values, err := productValues(product)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, query, values...); err != nil {
+			return err
+		}
+		if err := p.putVariants(ctx, tx, product); err != nil {
+			return err
+		}
+		if err := p.putAttributes(ctx, tx, product); err != nil {
+			return err
+		}
+		return pushSqlCategoryProduct(ctx, p.sm, tx, product.Category, product.ID)
+	})
+	if err != nil {
+		return product, databaseError(err, "product")
+	}
+	return product, nil
+}
+
+// productListQuery reads the products with their category and creator, the
+// rows are read by scanProductResponseDto.
+const productListQuery = `SELECT p.id, p.title, p.slug, p.price, p.description, p.created_at, p.updated_at, p.active, p.version, p.image, p.images, p.stock, p.available, p.rating, p.review_count, p.attributes,
+		c.id, c.name, c.slug,
+		u.id, u.name, u.email, u.number, u.status, u.role, u.created_at, u.updated_at
+		FROM products p
+		LEFT JOIN categories c ON c.id = p.category_id
+		LEFT JOIN users u ON u.email = p.created_by`
+
+// FindAll joins categories and users the same way the mongo aggregation
+// resolves them with $lookup. The search is a case-insensitive substring match,
+// a text search is ranked in process.
+func (p productSqlRepository) FindAll(ctx context.Context, queryParams dtos.ProductQueryParams) ([]dtos.ProductResponseDto, common.MetaData, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	objects := []dtos.ProductResponseDto{}
+	var metaData common.MetaData
+	page, err := newProductPage(queryParams)
+	if err != nil {
+		return objects, metaData, err
+	}
+	where, args, err := p.findAllWhere(ctx, queryParams)
+	if err != nil {
+		return objects, metaData, databaseError(err, "product")
+	}
+	// a text search is ranked in process, the query only narrows it down
+	ranked := queryParams.IsTextSearch()
+	// Pagination
+	var total int64
+	if queryParams.Limit != 0 && !ranked {
+		if err := p.sm.DB.QueryRowContext(ctx, p.sm.Rebind(`SELECT COUNT(*) FROM products p`+where), args...).Scan(&total); err != nil {
+			return objects, metaData, databaseError(err, "product")
+		}
+	}
+	query := productListQuery + where
+	if !ranked {
+		if page.cursor != nil {
+			operator := ">"
+			if page.descending {
+				operator = "<"
+			}
+			column := `p.` + productOrderColumns[page.orderBy]
+			query += ` AND (` + column + ` ` + operator + ` ? OR (` + column + ` = ? AND p.id ` + operator + ` ?))`
+			args = append(args, page.cursor.value, page.cursor.value, page.cursor.ID.Hex())
+		}
+		query += ` ORDER BY p.` + productOrderColumns[page.orderBy]
+		if page.descending {
+			query += ` DESC, p.id DESC`
+		} else {
+			query += `, p.id`
+		}
+		if queryParams.Limit != 0 {
+			query += ` LIMIT ? OFFSET ?`
+			args = append(args, page.fetch(), page.skip())
+		}
+	}
+	rows, err := p.sm.DB.QueryContext(ctx, p.sm.Rebind(query), args...)
+	if err != nil {
+		return objects, metaData, databaseError(err, "product")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		object, err := scanProductResponseDto(rows)
+		if err != nil {
+			return objects, metaData, databaseError(err, "product")
+		}
+		objects = append(objects, object)
+	}
+	if err := rows.Err(); err != nil {
+		return objects, metaData, databaseError(err, "product")
+	}
+	if ranked {
+		objects, total = page.slice(rankProducts(objects, search.Terms(queryParams.Search)))
+	}
+	objects, metaData = page.result(objects, total)
+	ids := []string{}
+	for _, object := range objects {
+		ids = append(ids, object.ID.Hex())
+	}
+	variants, err := p.findVariants(ctx, p.sm.DB, ids)
+	if err != nil {
+		return objects, metaData, databaseError(err, "product")
+	}
+	for i := range objects {
+		objects[i].Variants = variants[objects[i].ID]
+	}
+	return objects, metaData, nil
+}
+
+func (p productSqlRepository) Each(ctx context.Context, queryParams dtos.ProductQueryParams, fn func(dtos.ProductResponseDto) error) error {
+	ctx, cancel := db.StreamContext(ctx)
+	defer cancel()
+	queryParams.Limit, queryParams.Page, queryParams.After, queryParams.Before = 0, 0, "", ""
+	page, err := newProductPage(queryParams)
+	if err != nil {
+		return err
+	}
+	where, args, err := p.findAllWhere(ctx, queryParams)
+	if err != nil {
+		return databaseError(err, "product")
+	}
+	// a text search is ranked in process, so its products are read first
+	if queryParams.IsTextSearch() {
+		objects := []dtos.ProductResponseDto{}
+		err := queryAll(ctx, p.sm.DB, p.sm.Rebind(productListQuery+where), func(rows *sql.Rows) error {
+			object, err := scanProductResponseDto(rows)
+			objects = append(objects, object)
+			return err
+		}, args...)
+		if err != nil {
+			return databaseError(err, "product")
+		}
+		objects, _ = page.slice(rankProducts(objects, search.Terms(queryParams.Search)))
+		for _, object := range objects {
+			if err := fn(object); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	query := productListQuery + where + ` ORDER BY p.` + productOrderColumns[page.orderBy]
+	if page.descending {
+		query += ` DESC, p.id DESC`
+	} else {
+		query += `, p.id`
+	}
+	rows, err := p.sm.DB.QueryContext(ctx, p.sm.Rebind(query), args...)
+	if err != nil {
+		return databaseError(err, "product")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		object, err := scanProductResponseDto(rows)
+		if err != nil {
+			return databaseError(err, "product")
+		}
+		if err := fn(object); err != nil {
+			return err
+		}
+	}
+	return databaseError(rows.Err(), "product")
+}
+
+// findAllWhere matches the products of the query. The category is looked up
+// first, together with its descendants when asked for.
+func (p productSqlRepository) findAllWhere(ctx context.Context, queryParams dtos.ProductQueryParams) (string, []interface{}, error) {
+	where := ` WHERE p.deleted_at IS NULL`
+	args := []interface{}{}
+	if queryParams.IsTextSearch() {
+		// any word of the search, plural or not, the ranking drops the
+		// products where it is only part of a word
+		conditions := []string{`1 = 0`}
+		for _, term := range search.Terms(queryParams.Search) {
+			if len(term) > 1 {
+				term = strings.TrimSuffix(term, "s")
+			}
+			condition, termArgs := sqlSearch(term, "p.title", "p.description")
+			conditions = append(conditions, condition)
+			args = append(args, termArgs...)
+		}
+		where += ` AND (` + strings.Join(conditions, ` OR `) + `)`
+	} else if queryParams.Search != "" {
+		condition, searchArgs := sqlSearch(queryParams.Search, "p.title", "p.description")
+		where += ` AND ` + condition
+		args = append(args, searchArgs...)
+	}
+	if queryParams.Category != "" {
+		categories := []model.Category{}
+		err := queryAll(ctx, p.sm.DB, `SELECT id, parent_id, slug FROM categories WHERE deleted_at IS NULL`, func(rows *sql.Rows) error {
+			var (
+				category model.Category
+				id       string
+				parentId sql.NullString
+			)
+			if err := rows.Scan(&id, &parentId, &category.Slug); err != nil {
+				return err
+			}
+			category.ID = parseId(id)
+			category.Parent = parseNullableId(parentId)
+			categories = append(categories, category)
+			return nil
+		})
+		if err != nil {
+			return where, args, err
+		}
+		family := categoryFamily(categories, queryParams.Category, queryParams.Subcategories)
+		if len(family) == 0 {
+			where += ` AND 1 = 0`
+		} else {
+			where += ` AND p.category_id IN (?` + strings.Repeat(`, ?`, len(family)-1) + `)`
+			for _, id := range family {
+				args = append(args, id.Hex())
+			}
+		}
+	}
+	if queryParams.MinPrice != nil {
+		where += ` AND p.price >= ?`
+		args = append(args, *queryParams.MinPrice)
+	}
+	if queryParams.MaxPrice != nil {
+		where += ` AND p.price <= ?`
+		args = append(args, *queryParams.MaxPrice)
+	}
+	if queryParams.Active != nil {
+		where += ` AND p.active = ?`
+		args = append(args, *queryParams.Active)
+	}
+	if queryParams.CreatedBy != "" {
+		where += ` AND p.created_by = ?`
+		args = append(args, queryParams.CreatedBy)
+	}
+	for _, attribute := range queryParams.Attributes {
+		where += ` AND EXISTS (SELECT 1 FROM product_attributes a WHERE a.product_id = p.id AND a.name = ?`
+		args = append(args, attribute.Name)
+		if attribute.Min != nil || attribute.Max != nil {
+			where += ` AND a.number IS NOT NULL`
+			if attribute.Min != nil {
+				where += ` AND a.number >= ?`
+				args = append(args, *attribute.Min)
+			}
+			if attribute.Max != nil {
+				where += ` AND a.number <= ?`
+				args = append(args, *attribute.Max)
+			}
+			where += `)`
+			continue
+		}
+		// a value matches the text of a string, number or bool value and,
+		// when it reads as one, the number of a number value
+		texts, numbers := []interface{}{}, []interface{}{}
+		for _, value := range attribute.Values {
+			texts = append(texts, value)
+			if number, ok := parseAttributeNumber(value); ok {
+				numbers = append(numbers, number)
+			}
+		}
+		where += ` AND (a.value IN (?` + strings.Repeat(`, ?`, len(texts)-1) + `)`
+		args = append(args, texts...)
+		if len(numbers) > 0 {
+			where += ` OR a.number IN (?` + strings.Repeat(`, ?`, len(numbers)-1) + `)`
+			args = append(args, numbers...)
+		}
+		where += `))`
+	}
+	for _, bound := range []struct {
+		condition string
+		value     time.Time
+	}{
+		{` AND p.created_at >= ?`, queryParams.CreatedSince},
+		{` AND p.created_at <= ?`, queryParams.CreatedUntil},
+		{` AND p.updated_at >= ?`, queryParams.UpdatedSince},
+		{` AND p.updated_at <= ?`, queryParams.UpdatedUntil},
+	} {
+		if !bound.value.IsZero() {
+			where += bound.condition
+			args = append(args, bound.value.UTC())
+		}
+	}
+	return where, args, nil
+}
+
+func (p productSqlRepository) FindBySlug(ctx context.Context, slug string) (model.Product, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	product, err := p.findBySlug(ctx, p.sm.DB, slug)
+	if err != nil {
+		return product, databaseError(err, "product")
+	}
+	return product, nil
+}
+
+func (p productSqlRepository) FindById(ctx context.Context, id primitive.ObjectID) (model.Product, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	query := p.sm.Rebind(`SELECT ` + productColumns + ` FROM products WHERE id = ? AND deleted_at IS NULL`)
+	product, err := p.withVariants(ctx, p.sm.DB)(scanProduct(p.sm.DB.QueryRowContext(ctx, query, id.Hex())))
+	return product, databaseError(err, "product")
+}
+
+func (p productSqlRepository) UpdateBySlug(ctx context.Context, slug string, payload primitive.M, version *int64) (model.Product, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	payload["updatedAt"] = time.Now().UTC()
+	var product model.Product
+	err := withTx(ctx, p.sm, func(tx *sql.Tx) error {
+		var err error
+		product, err = p.findBySlug(ctx, tx, slug)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion("product", product.Version, version); err != nil {
+			return err
+		}
+		oldCategory := product.Category
+		if err := applySet(&product, payload); err != nil {
+			return err
+		}
+		categoryChanged := !sameObjectId(oldCategory, product.Category)
+		if categoryChanged {
+			if err := checkSqlCategory(ctx, p.sm, tx, product.Category); err != nil {
+				return err
+			}
+		}
+		product.Version++
+		query := p.sm.Rebind(`UPDATE products SET created_by = ?, category_id = ?, image_source = ?, title = ?, slug = ?, price = ?, image = ?, description = ?, created_at = ?, updated_at = ?, active = ?, deleted_at = ?, version = ?, images = ?, attributes = ? WHERE id = ? AND version = ?`)
+		// stock and available are only written by AdjustStock and the
+		// reservations of the carts, rating and review_count by the reviews
+		values, err := productValues(product)
+		if err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, query, append(values[1:15], values[19], product.ID.Hex(), product.Version-1)...)
+		if err := checkGuardedUpdate("product", result, err); err != nil {
+			return err
+		}
+		if _, ok := payload["variants"]; ok {
+			if err := p.putVariants(ctx, tx, product); err != nil {
+				return err
+			}
+		}
+		if _, ok := payload["attributes"]; ok {
+			if err := p.putAttributes(ctx, tx, product); err != nil {
+				return err
+			}
+		}
+		if !categoryChanged {
+			return nil
+		}
+		if err := removeSqlCategoryProduct(ctx, p.sm, tx, oldCategory, product.ID); err != nil {
+			return err
+		}
+		return pushSqlCategoryProduct(ctx, p.sm, tx, product.Category, product.ID)
+	})
+	return product, databaseError(err, "product")
+}
+
+func (p productSqlRepository) AdjustStock(ctx context.Context, slug string, delta int, version *int64) (model.Product, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var product model.Product
+	err := withTx(ctx, p.sm, func(tx *sql.Tx) error {
+		var err error
+		product, err = p.findBySlug(ctx, tx, slug)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion("product", product.Version, version); err != nil {
+			return err
+		}
+		if _, _, err := adjustStock(product, delta); err != nil {
+			return err
+		}
+		// the update only applies to the stock it was checked against, the
+		// reservations change the available stock meanwhile
+		query := `UPDATE products SET stock = stock + ?, available = available + ?, updated_at = ?, version = version + 1
+			WHERE id = ? AND version = ? AND available + ? >= 0`
+		if product.Available == nil {
+			query = `UPDATE products SET stock = ?, available = ?, updated_at = ?, version = version + 1
+				WHERE id = ? AND version = ? AND available IS NULL AND ? >= 0`
+		}
+		result, err := tx.ExecContext(ctx, p.sm.Rebind(query), delta, delta, time.Now().UTC(), product.ID.Hex(), product.Version, delta)
+		if err := checkGuardedUpdate("product", result, err); err != nil {
+			return err
+		}
+		product, err = p.findBySlug(ctx, tx, slug)
+		return err
+	})
+	return product, databaseError(err, "product")
+}
+
+func (p productSqlRepository) DeleteBySlug(ctx context.Context, slug string, version *int64) (model.Product, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var product model.Product
+	err := withTx(ctx, p.sm, func(tx *sql.Tx) error {
+		var err error
+		product, err = p.findBySlug(ctx, tx, slug)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion("product", product.Version, version); err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		product.DeletedAt = &now
+		query := p.sm.Rebind(`UPDATE products SET deleted_at = ?, version = version + 1 WHERE id = ? AND version = ?`)
+		result, err := tx.ExecContext(ctx, query, now, product.ID.Hex(), product.Version)
+		if err := checkGuardedUpdate("product", result, err); err != nil {
+			return err
+		}
+		product.Version++
+		return removeSqlCategoryProduct(ctx, p.sm, tx, product.Category, product.ID)
+	})
+	return product, databaseError(err, "product")
+}
+
+func (p productSqlRepository) IsSlugExists(ctx context.Context, slug string) bool {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var count int
+	err := p.sm.DB.QueryRowContext(ctx, p.sm.Rebind(`SELECT COUNT(*) FROM products WHERE slug = ?`), slug).Scan(&count)
+	return err == nil && count > 0
+}
+
+func (p productSqlRepository) IsSkuExists(ctx context.Context, sku string, productId primitive.ObjectID) bool {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var count int
+	query := p.sm.Rebind(`SELECT COUNT(*) FROM product_variants WHERE sku = ? AND product_id <> ?`)
+	err := p.sm.DB.QueryRowContext(ctx, query, sku, productId.Hex()).Scan(&count)
+	return err == nil && count > 0
+}
+
+func (p productSqlRepository) FindTrash(ctx context.Context) ([]model.Product, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	objects := []model.Product{}
+	query := `SELECT ` + productColumns + ` FROM products WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id`
+	err := queryAll(ctx, p.sm.DB, query, func(rows *sql.Rows) error {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, product)
+		return nil
+	})
+	if err != nil {
+		return objects, databaseError(err, "product")
+	}
+	err = p.attachVariants(ctx, p.sm.DB, objects)
+	return objects, databaseError(err, "product")
+}
+
+func (p productSqlRepository) FindTrashedBySlug(ctx context.Context, slug string) (model.Product, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	product, err := p.findTrashedBySlug(ctx, p.sm.DB, slug)
+	return product, databaseError(err, "product in trash")
+}
+
+func (p productSqlRepository) RestoreBySlug(ctx context.Context, slug string) (model.Product, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var product model.Product
+	err := withTx(ctx, p.sm, func(tx *sql.Tx) error {
+		var err error
+		product, err = p.findTrashedBySlug(ctx, tx, slug)
+		if err != nil {
+			return err
+		}
+		err = checkSqlCategory(ctx, p.sm, tx, product.Category)
+		if domain_error.Is(err, domain_error.VALIDATION) {
+			product.Category = nil
+		} else if err != nil {
+			return err
+		}
+		product.DeletedAt = nil
+		product.Version++
+		query := p.sm.Rebind(`UPDATE products SET category_id = ?, deleted_at = NULL, version = version + 1 WHERE id = ?`)
+		if _, err := tx.ExecContext(ctx, query, nullableId(product.Category), product.ID.Hex()); err != nil {
+			return err
+		}
+		return pushSqlCategoryProduct(ctx, p.sm, tx, product.Category, product.ID)
+	})
+	return product, databaseError(err, "product in trash")
+}
+
+func (p productSqlRepository) PurgeBySlug(ctx context.Context, slug string) (model.Product, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var product model.Product
+	err := withTx(ctx, p.sm, func(tx *sql.Tx) error {
+		var err error
+		product, err = p.findTrashedBySlug(ctx, tx, slug)
+		if err != nil {
+			return err
+		}
+		return p.purge(ctx, tx, `id = ?`, product.ID.Hex())
+	})
+	return product, databaseError(err, "product in trash")
+}
+
+func (p productSqlRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var count int64
+	err := withTx(ctx, p.sm, func(tx *sql.Tx) error {
+		query := p.sm.Rebind(`SELECT COUNT(*) FROM products WHERE deleted_at IS NOT NULL AND deleted_at < ?`)
+		if err := tx.QueryRowContext(ctx, query, before).Scan(&count); err != nil {
+			return err
+		}
+		return p.purge(ctx, tx, `deleted_at IS NOT NULL AND deleted_at < ?`, before)
+	})
+	return count, databaseError(err, "product")
+}
+
+// purge deletes the trashed products matching where, their variants, their
+// reviews and their entries in every Category.Products, trashed categories included.
+func (p productSqlRepository) purge(ctx context.Context, executor sqlExecutor, where string, args ...interface{}) error {
+	query := p.sm.Rebind(`UPDATE categories SET version = version + 1 WHERE id IN
+		(SELECT category_id FROM category_products WHERE product_id IN (SELECT id FROM products WHERE ` + where + `))`)
+	if _, err := executor.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+	for _, table := range []string{"category_products", "product_variants", "product_attributes", "reviews"} {
+		query = p.sm.Rebind(`DELETE FROM ` + table + ` WHERE product_id IN (SELECT id FROM products WHERE ` + where + `)`)
+		if _, err := executor.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+	_, err := executor.ExecContext(ctx, p.sm.Rebind(`DELETE FROM products WHERE `+where), args...)
+	return err
+}
+
+func (p productSqlRepository) findBySlug(ctx context.Context, executor sqlExecutor, slug string) (model.Product, error) {
+	query := p.sm.Rebind(`SELECT ` + productColumns + ` FROM products WHERE slug = ? AND deleted_at IS NULL`)
+	return p.withVariants(ctx, executor)(scanProduct(executor.QueryRowContext(ctx, query, slug)))
+}
+
+func (p productSqlRepository) findTrashedBySlug(ctx context.Context, executor sqlExecutor, slug string) (model.Product, error) {
+	query := p.sm.Rebind(`SELECT ` + productColumns + ` FROM products WHERE slug = ? AND deleted_at IS NOT NULL`)
+	return p.withVariants(ctx, executor)(scanProduct(executor.QueryRowContext(ctx, query, slug)))
+}
+
+// withVariants loads the variants of a scanned product.
+func (p productSqlRepository) withVariants(ctx context.Context, executor sqlExecutor) func(model.Product, error) (model.Product, error) {
+	return func(product model.Product, err error) (model.Product, error) {
+		if err != nil {
+			return product, err
+		}
+		products := []model.Product{product}
+		err = p.attachVariants(ctx, executor, products)
+		return products[0], err
+	}
+}
+
+// attachVariants loads the Product.Variants arrays of the products.
+func (p productSqlRepository) attachVariants(ctx context.Context, executor sqlExecutor, products []model.Product) error {
+	ids := []string{}
+	for _, product := range products {
+		ids = append(ids, product.ID.Hex())
+	}
+	variants, err := p.findVariants(ctx, executor, ids)
+	if err != nil {
+		return err
+	}
+	for i := range products {
+		products[i].Variants = variants[products[i].ID]
+	}
+	return nil
+}
+
+// findVariants loads the variants of the products by id, of every product
+// when productIds is nil. Every product id gets an array, empty without
+// variants.
+func (p productSqlRepository) findVariants(ctx context.Context, executor sqlExecutor, productIds []string) (map[primitive.ObjectID][]model.ProductVariant, error) {
+	variants := map[primitive.ObjectID][]model.ProductVariant{}
+	query := `SELECT product_id, id, sku, price, attributes, active, created_at, updated_at FROM product_variants`
+	args := []interface{}{}
+	if productIds != nil {
+		if len(productIds) == 0 {
+			return variants, nil
+		}
+		query += ` WHERE product_id IN (?` + strings.Repeat(`, ?`, len(productIds)-1) + `)`
+		for _, id := range productIds {
+			variants[parseId(id)] = []model.ProductVariant{}
+			args = append(args, id)
+		}
+	}
+	err := queryAll(ctx, executor, p.sm.Rebind(query+` ORDER BY product_id, position`), func(rows *sql.Rows) error {
+		var (
+			variant    model.ProductVariant
+			productId  string
+			id         string
+			price      sql.NullInt64
+			attributes string
+		)
+		if err := rows.Scan(&productId, &id, &variant.SKU, &price, &attributes, &variant.Active, &variant.CreatedAt, &variant.UpdatedAt); err != nil {
+			return err
+		}
+		variant.ID = parseId(id)
+		if price.Valid {
+			_price := int(price.Int64)
+			variant.Price = &_price
+		}
+		if err := json.Unmarshal([]byte(attributes), &variant.Attributes); err != nil {
+			return err
+		}
+		_productId := parseId(productId)
+		variants[_productId] = append(variants[_productId], variant)
+		return nil
+	}, args...)
+	return variants, err
+}
+
+// putVariants replaces the variant rows of the product with its Variants.
+func (p productSqlRepository) putVariants(ctx context.Context, executor sqlExecutor, product model.Product) error {
+	if _, err := executor.ExecContext(ctx, p.sm.Rebind(`DELETE FROM product_variants WHERE product_id = ?`), product.ID.Hex()); err != nil {
+		return err
+	}
+	query := p.sm.Rebind(`INSERT INTO product_variants (id, product_id, sku, price, attributes, active, position, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	for position, variant := range product.Variants {
+		var price interface{}
+		if variant.Price != nil {
+			price = int64(*variant.Price)
+		}
+		attributes, err := json.Marshal(variant.Attributes)
+		if err != nil {
+			return err
+		}
+		_, err = executor.ExecContext(ctx, query, variant.ID.Hex(), product.ID.Hex(), variant.SKU, price, string(attributes),
+			variant.Active, position, variant.CreatedAt, variant.UpdatedAt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// putAttributes replaces the product_attributes rows of the product with its
+// Attributes.
+func (p productSqlRepository) putAttributes(ctx context.Context, executor sqlExecutor, product model.Product) error {
+	if _, err := executor.ExecContext(ctx, p.sm.Rebind(`DELETE FROM product_attributes WHERE product_id = ?`), product.ID.Hex()); err != nil {
+		return err
+	}
+	query := p.sm.Rebind(`INSERT INTO product_attributes (product_id, name, value, number) VALUES (?, ?, ?, ?)`)
+	for name, value := range product.Attributes {
+		text, number := attributeText(value)
+		var _number interface{}
+		if number != nil {
+			_number = *number
+		}
+		if _, err := executor.ExecContext(ctx, query, product.ID.Hex(), name, text, _number); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// productValues follows the order of productColumns.
+func productValues(product model.Product) ([]interface{}, error) {
+	images, err := marshalImages(product.Images)
+	if err != nil {
+		return nil, err
+	}
+	attributes, err := marshalAttributes(product.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{
+		product.ID.Hex(), product.CreatedBy, nullableId(product.Category), nullableId(product.ImageSource), product.Title, product.Slug,
+		int64(product.Price), product.Image, product.Description, product.CreatedAt, product.UpdatedAt, product.Active, nullableTime(product.DeletedAt),
+		product.Version, images, nullableInt(product.Stock), nullableInt(product.Available), product.Rating, int64(product.ReviewCount), attributes,
+	}, nil
+}
+
+func marshalImages(images []model.ProductImage) (string, error) {
+	if images == nil {
+		images = []model.ProductImage{}
+	}
+	data, err := json.Marshal(images)
+	return string(data), err
+}
+
+func unmarshalImages(data string) ([]model.ProductImage, error) {
+	images := []model.ProductImage{}
+	err := json.Unmarshal([]byte(data), &images)
+	return images, err
+}
+
+func marshalAttributes(attributes map[string]interface{}) (string, error) {
+	if attributes == nil {
+		attributes = map[string]interface{}{}
+	}
+	data, err := json.Marshal(attributes)
+	return string(data), err
+}
+
+func unmarshalAttributes(data string) (map[string]interface{}, error) {
+	attributes := map[string]interface{}{}
+	err := json.Unmarshal([]byte(data), &attributes)
+	return attributes, err
+}
+
+func scanProduct(scanner rowScanner) (model.Product, error) {
+	var (
+		product     model.Product
+		id          string
+		category    sql.NullString
+		imageSource sql.NullString
+		price       int64
+		deletedAt   sql.NullTime
+		images      string
+		stock       sql.NullInt64
+		available   sql.NullInt64
+		reviewCount int64
+		attributes  string
+	)
+	err := scanner.Scan(&id, &product.CreatedBy, &category, &imageSource, &product.Title, &product.Slug,
+		&price, &product.Image, &product.Description, &product.CreatedAt, &product.UpdatedAt, &product.Active, &deletedAt, &product.Version,
+		&images, &stock, &available, &product.Rating, &reviewCount, &attributes)
+	if err != nil {
+		return product, err
+	}
+	if product.Images, err = unmarshalImages(images); err != nil {
+		return product, err
+	}
+	if product.Attributes, err = unmarshalAttributes(attributes); err != nil {
+		return product, err
+	}
+	product.ID = parseId(id)
+	product.Category = parseNullableId(category)
+	product.ImageSource = parseNullableId(imageSource)
+	product.Price = int(price)
+	product.DeletedAt = parseNullableTime(deletedAt)
+	product.Stock = parseNullableInt(stock)
+	product.Available = parseNullableInt(available)
+	product.ReviewCount = int(reviewCount)
+	return product, nil
+}
+
+func scanProductResponseDto(scanner rowScanner) (dtos.ProductResponseDto, error) {
+	var (
+		object      dtos.ProductResponseDto
+		id          string
+		price       int64
+		description string
+		categoryId  sql.NullString
+		category    struct{ Name, Slug sql.NullString }
+		userId      sql.NullString
+		user        struct{ Name, Email, Role sql.NullString }
+		number      sql.NullInt64
+		status      sql.NullBool
+		createdAt   sql.NullTime
+		updatedAt   sql.NullTime
+		images      string
+		stock       sql.NullInt64
+		available   sql.NullInt64
+		reviewCount int64
+		attributes  string
+	)
+	err := scanner.Scan(&id, &object.Title, &object.Slug, &price, &description, &object.CreatedAt, &object.UpdatedAt, &object.Active, &object.Version,
+		&object.Image, &images, &stock, &available, &object.Rating, &reviewCount, &attributes,
+		&categoryId, &category.Name, &category.Slug,
+		&userId, &user.Name, &user.Email, &number, &status, &user.Role, &createdAt, &updatedAt)
+	if err != nil {
+		return object, err
+	}
+	if object.Images, err = unmarshalImages(images); err != nil {
+		return object, err
+	}
+	if object.Attributes, err = unmarshalAttributes(attributes); err != nil {
+		return object, err
+	}
+	object.ID = parseId(id)
+	_price := int(price)
+	object.Price = &_price
+	object.Description = &description
+	object.Stock = parseNullableInt(stock)
+	object.Available = parseNullableInt(available)
+	object.ReviewCount = int(reviewCount)
+	if categoryId.Valid {
+		object.Category = &dtos.ProductCategory{
+			ID:   parseId(categoryId.String),
+			Name: category.Name.String,
+			Slug: category.Slug.String,
+		}
+	}
+	if userId.Valid {
+		object.CreatedBy = &dtos.ProductCreator{
+			ID:        parseId(userId.String),
+			Name:      user.Name.String,
+			Email:     user.Email.String,
+			Status:    status.Bool,
+			Role:      enums.Role(user.Role.String),
+			CreatedAt: createdAt.Time,
+			UpdateAt:  updatedAt.Time,
+		}
+		if number.Valid {
+			_number := int(number.Int64)
+			object.CreatedBy.Number = &_number
+		}
+	}
+	return object, nil
+}
+
+func NewProductSqlRepository() ProductRepository {
+	return &productSqlRepository{
+		sm: db.GetSqlManager(),
+	}
+}
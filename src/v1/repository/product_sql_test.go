@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newTestSqlManager migrates a private in-memory SQLite database.
+func newTestSqlManager(t *testing.T) *db.SqlManager {
+	t.Helper()
+	database, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	// every connection to :memory: is a database of its own
+	database.SetMaxOpenConns(1)
+	sm := &db.SqlManager{DB: database, Dialect: enums.SQLITE}
+	if _, err := sm.Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return sm
+}
+
+func newTestProduct(title string, price int) model.Product {
+	now := time.Now().UTC().Truncate(time.Second)
+	return model.Product{
+		ID:          primitive.NewObjectID(),
+		CreatedBy:   "admin@example.com",
+		Title:       title,
+		Price:       price,
+		Description: title + " description",
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Active:      true,
+	}
+}
+
+func TestProductSqlRepositoryStoresAndFinds(t *testing.T) {
+	ctx := context.Background()
+	repo := productSqlRepository{sm: newTestSqlManager(t)}
+	stored, err := repo.Store(ctx, newTestProduct("Pixel Phone", 59900))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Slug == "" {
+		t.Fatal("expected a slug")
+	}
+	found, err := repo.FindBySlug(ctx, stored.Slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.ID != stored.ID || found.Title != stored.Title || found.Price != stored.Price || found.Description != stored.Description {
+		t.Errorf("expected %+v, got %+v", stored, found)
+	}
+	if _, err := repo.FindBySlug(ctx, "missing"); domain_error.KindOf(err) != domain_error.NOT_FOUND {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func TestProductSqlRepositoryFiltersAndPages(t *testing.T) {
+	ctx := context.Background()
+	repo := productSqlRepository{sm: newTestSqlManager(t)}
+	for _, product := range []model.Product{
+		newTestProduct("Pixel Phone", 59900),
+		newTestProduct("Galaxy Phone", 79900),
+		newTestProduct("Feature Phone", 3899),
+		newTestProduct("Gaming Laptop", 149900),
+	} {
+		if _, err := repo.Store(ctx, product); err != nil {
+			t.Fatal(err)
+		}
+	}
+	minPrice := 5000
+	queryParams := dtos.ProductQueryParams{MinPrice: &minPrice}
+	queryParams.Search = "PHONE"
+	queryParams.Limit = 1
+	products, metaData, err := repo.FindAll(ctx, queryParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(products) != 1 {
+		t.Fatalf("expected a page of 1, got %d", len(products))
+	}
+	if metaData.TotalElements != 2 || metaData.TotalPages != 2 {
+		t.Errorf("expected 2 matches on 2 pages, got %d on %d", metaData.TotalElements, metaData.TotalPages)
+	}
+}
+
+func TestProductSqlRepositoryChecksTheVersion(t *testing.T) {
+	ctx := context.Background()
+	repo := productSqlRepository{sm: newTestSqlManager(t)}
+	stored, err := repo.Store(ctx, newTestProduct("Pixel Phone", 59900))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := stored.Version + 1
+	_, err = repo.UpdateBySlug(ctx, stored.Slug, primitive.M{"title": "Pixel Phone 2"}, &stale)
+	if domain_error.KindOf(err) != domain_error.PRECONDITION_FAILED {
+		t.Fatalf("expected a failed precondition, got %v", err)
+	}
+	updated, err := repo.UpdateBySlug(ctx, stored.Slug, primitive.M{"title": "Pixel Phone 2"}, &stored.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Title != "Pixel Phone 2" || updated.Version != stored.Version+1 {
+		t.Errorf("expected the new title at version %d, got %q at %d", stored.Version+1, updated.Title, updated.Version)
+	}
+}
+
+func TestProductSqlRepositoryFiltersByAttributes(t *testing.T) {
+	ctx := context.Background()
+	repo := productSqlRepository{sm: newTestSqlManager(t)}
+	for _, attributes := range []map[string]interface{}{
+		{"ram": 8.0, "colour": "black", "wireless": true},
+		{"ram": 16.0, "colour": "white", "wireless": false},
+		{"ram": 32.0, "colour": "black"},
+	} {
+		product := newTestProduct("Laptop", 99900)
+		product.Attributes = attributes
+		if _, err := repo.Store(ctx, product); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ten := 10.0
+	filters := []struct {
+		filters []dtos.ProductAttributeFilter
+		matches int
+	}{
+		{[]dtos.ProductAttributeFilter{{Name: "colour", Values: []string{"black"}}}, 2},
+		{[]dtos.ProductAttributeFilter{{Name: "ram", Values: []string{"16.0"}}}, 1},
+		{[]dtos.ProductAttributeFilter{{Name: "wireless", Values: []string{"false"}}}, 1},
+		{[]dtos.ProductAttributeFilter{{Name: "ram", Min: &ten}}, 2},
+		{[]dtos.ProductAttributeFilter{{Name: "ram", Min: &ten}, {Name: "colour", Values: []string{"black", "silver"}}}, 1},
+		{[]dtos.ProductAttributeFilter{{Name: "colour", Min: &ten}}, 0},
+	}
+	for _, f := range filters {
+		products, _, err := repo.FindAll(ctx, dtos.ProductQueryParams{Attributes: f.filters})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(products) != f.matches {
+			t.Errorf("%+v matched %d products, want %d", f.filters, len(products), f.matches)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// reconcileCategoryProducts compares category.Products with productIds, the
+// products whose category is this category. The returned list keeps the order
+// of the current entries and appends the missing ones. Duplicated entries count
+// as phantom.
+func reconcileCategoryProducts(category model.Category, productIds []primitive.ObjectID) ([]primitive.ObjectID, dtos.CategoryDriftDto, bool) {
+	drift := dtos.CategoryDriftDto{
+		Category: category.Slug,
+		Missing:  []primitive.ObjectID{},
+		Phantom:  []primitive.ObjectID{},
+	}
+	expected := map[primitive.ObjectID]bool{}
+	for _, id := range productIds {
+		expected[id] = true
+	}
+	products := []primitive.ObjectID{}
+	listed := map[primitive.ObjectID]bool{}
+	for _, id := range category.Products {
+		if !expected[id] || listed[id] {
+			drift.Phantom = append(drift.Phantom, id)
+			continue
+		}
+		listed[id] = true
+		products = append(products, id)
+	}
+	for _, id := range productIds {
+		if !listed[id] {
+			drift.Missing = append(drift.Missing, id)
+			products = append(products, id)
+		}
+	}
+	return products, drift, len(drift.Missing) > 0 || len(drift.Phantom) > 0
+}
+
+// relinkedProducts keeps the entries of a restored category whose product is
+// still live and uncategorized, in their current order.
+func relinkedProducts(ids []primitive.ObjectID, products []model.Product) []primitive.ObjectID {
+	live := map[primitive.ObjectID]bool{}
+	for _, product := range products {
+		live[product.ID] = true
+	}
+	relinked := []primitive.ObjectID{}
+	for _, id := range ids {
+		if live[id] {
+			relinked = append(relinked, id)
+			live[id] = false
+		}
+	}
+	return relinked
+}
+
+func sameObjectId(a *primitive.ObjectID, b *primitive.ObjectID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func copyObjectId(id *primitive.ObjectID) *primitive.ObjectID {
+	if id == nil {
+		return nil
+	}
+	_id := *id
+	return &_id
+}
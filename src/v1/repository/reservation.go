@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Cart lines with a ReservedUntil hold their quantity from Product.Available.
+// Every cart write works out the lines before and after it, reserves the
+// difference per product and saves the lines after it, so the held quantities
+// and the available stock move together.
+
+// stockHold is what reserving stock of a product found.
+type stockHold struct {
+	// tracked is false for products without stock and missing products,
+	// nothing is reserved of them
+	tracked bool
+	// short is set when less than the reservation was available, nothing
+	// is reserved then
+	short     bool
+	title     string
+	available int
+}
+
+// reserveFunc takes delta from the available stock of the product, a negative
+// delta gives it back. A 0 delta only tells whether the stock is tracked.
+type reserveFunc func(productId primitive.ObjectID, delta int) (stockHold, error)
+
+// reserveLines reserves the quantities held by after but not by before and
+// releases the others. It returns after, with the lines of untracked products
+// not reserved. Products are reserved in id order, so concurrent writes lock
+// them in the same order. When a product fails the products reserved before it
+// are given back, Mongo runs it without a transaction on standalone servers.
+func reserveLines(before []model.CartProductSpec, after []model.CartProductSpec, reserve reserveFunc) ([]model.CartProductSpec, error) {
+	held, holding := heldQuantities(before), heldQuantities(after)
+	productIds := []primitive.ObjectID{}
+	for productId := range held {
+		productIds = append(productIds, productId)
+	}
+	for productId := range holding {
+		if _, ok := held[productId]; !ok {
+			productIds = append(productIds, productId)
+		}
+	}
+	sort.Slice(productIds, func(i, j int) bool {
+		return productIds[i].Hex() < productIds[j].Hex()
+	})
+	untracked := map[primitive.ObjectID]bool{}
+	reserved := []primitive.ObjectID{}
+	for _, productId := range productIds {
+		hold, err := reserve(productId, holding[productId]-held[productId])
+		if err == nil && hold.short {
+			err = outOfStock(hold.title, hold.available+held[productId])
+		}
+		if err != nil {
+			return after, undoReservations(reserved, held, holding, reserve, err)
+		}
+		if hold.tracked {
+			reserved = append(reserved, productId)
+		}
+		untracked[productId] = !hold.tracked
+	}
+	lines := []model.CartProductSpec{}
+	for _, item := range after {
+		if untracked[item.ProductId] {
+			item.ReservedUntil = nil
+		}
+		lines = append(lines, item)
+	}
+	return lines, nil
+}
+
+// undoReservations gives back the deltas reserveLines took of the products
+// and returns err, a product that can not be given back is logged.
+func undoReservations(productIds []primitive.ObjectID, held, holding map[primitive.ObjectID]int, reserve reserveFunc, err error) error {
+	for i := len(productIds) - 1; i >= 0; i-- {
+		productId := productIds[i]
+		delta := holding[productId] - held[productId]
+		if delta == 0 {
+			continue
+		}
+		if _, undoErr := reserve(productId, -delta); undoErr != nil {
+			log.Println("[ERROR] undo reservation of", productId.Hex()+":", undoErr)
+		}
+	}
+	return err
+}
+
+// heldQuantities sums the reserved quantities of the lines per product, the
+// variants of a product share its stock.
+func heldQuantities(lines []model.CartProductSpec) map[primitive.ObjectID]int {
+	quantities := map[primitive.ObjectID]int{}
+	for _, item := range lines {
+		if item.ReservedUntil != nil {
+			quantities[item.ProductId] += int(item.Quantity)
+		}
+	}
+	return quantities
+}
+
+func outOfStock(title string, left int) error {
+	if left <= 0 {
+		return domain_error.Conflict(title + " is sold out")
+	}
+	return domain_error.Conflict(fmt.Sprintf("only %d of %s left", left, title))
+}
+
+// adjustStock checks that delta can be added to the stock of the product and
+// returns the stock and the available stock after it. The first adjustment of
+// an untracked product starts tracking it, reserved stock can not be taken.
+func adjustStock(product model.Product, delta int) (int, int, error) {
+	if product.Stock == nil || product.Available == nil {
+		if delta < 0 {
+			return 0, 0, domain_error.Conflict("stock can not go below 0")
+		}
+		return delta, delta, nil
+	}
+	if *product.Available+delta < 0 {
+		if reserved := *product.Stock - *product.Available; reserved > 0 {
+			return 0, 0, domain_error.Conflict(fmt.Sprintf("stock can not go below the %d reserved in carts", reserved))
+		}
+		return 0, 0, domain_error.Conflict("stock can not go below 0")
+	}
+	return *product.Stock + delta, *product.Available + delta, nil
+}
+
+// withCartLine puts the line in place of the line of the same product and
+// variant, or appends it.
+func withCartLine(lines []model.CartProductSpec, line model.CartProductSpec) []model.CartProductSpec {
+	after := append([]model.CartProductSpec{}, lines...)
+	for i, item := range after {
+		if sameCartLine(item, line.ProductId, line.VariantId) {
+			after[i] = line
+			return after
+		}
+	}
+	return append(after, line)
+}
+
+// withoutCartLines drops the line of the variant, every line of the product
+// when variantId is nil.
+func withoutCartLines(lines []model.CartProductSpec, productId primitive.ObjectID, variantId *primitive.ObjectID) []model.CartProductSpec {
+	after := []model.CartProductSpec{}
+	for _, item := range lines {
+		if item.ProductId != productId || variantId != nil && !sameObjectId(item.VariantId, variantId) {
+			after = append(after, item)
+		}
+	}
+	return after
+}
+
+// expireLines stops the reservations that ran out before now, it returns the
+// lines and the number of reservations stopped.
+func expireLines(lines []model.CartProductSpec, now time.Time) ([]model.CartProductSpec, int64) {
+	after := []model.CartProductSpec{}
+	var count int64
+	for _, item := range lines {
+		if item.ReservedUntil != nil && item.ReservedUntil.Before(now) {
+			item.ReservedUntil = nil
+			count++
+		}
+		after = append(after, item)
+	}
+	return after, count
+}
+
+// sameCartLine reports whether item is the line of the product and variant.
+func sameCartLine(item model.CartProductSpec, productId primitive.ObjectID, variantId *primitive.ObjectID) bool {
+	return item.ProductId == productId && sameObjectId(item.VariantId, variantId)
+}
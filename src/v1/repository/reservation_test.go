@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestReserveLinesGivesBackWhenAProductIsShort(t *testing.T) {
+	first, second := primitive.NewObjectID(), primitive.NewObjectID()
+	if first.Hex() > second.Hex() {
+		first, second = second, first
+	}
+	stock := func(available int) *int {
+		return &available
+	}
+	products := map[primitive.ObjectID]model.Product{
+		first:  {ID: first, Title: "first", Stock: stock(5), Available: stock(5)},
+		second: {ID: second, Title: "second", Stock: stock(1), Available: stock(1)},
+	}
+	until := time.Now().Add(time.Hour)
+	after := []model.CartProductSpec{
+		{ProductId: first, Quantity: 3, ReservedUntil: &until},
+		{ProductId: second, Quantity: 2, ReservedUntil: &until},
+	}
+
+	_, err := reserveLines(nil, after, memoryReserve(products))
+	if domain_error.KindOf(err) != domain_error.CONFLICT {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+	if available := *products[first].Available; available != 5 {
+		t.Errorf("expected the first product to have 5 available, got %d", available)
+	}
+	if available := *products[second].Available; available != 1 {
+		t.Errorf("expected the second product to have 1 available, got %d", available)
+	}
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"math"
+
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ReviewRepository keeps the reviews. Every write recomputes Product.Rating
+// and Product.ReviewCount of the reviewed product from its approved reviews,
+// in the same transaction. Like the cart reservations of the stock, a change
+// of them bumps the product version.
+type ReviewRepository interface {
+	// Store fails with CONFLICT when the author already reviewed the product.
+	Store(ctx context.Context, review model.Review) (model.Review, error)
+	FindAll(ctx context.Context, queryParams dtos.ReviewQueryParams) ([]model.Review, common.MetaData, error)
+	FindById(ctx context.Context, id string) (model.Review, error)
+	// UpdateById and DeleteById fail with PRECONDITION_FAILED unless the
+	// review is at the given version, a nil version skips the check.
+	UpdateById(ctx context.Context, id string, payload primitive.M, version *int64) (model.Review, error)
+	DeleteById(ctx context.Context, id string, version *int64) (model.Review, error)
+}
+
+type reviewRepository struct {
+	dm *db.DmManager
+}
+
+func (r reviewRepository) Store(ctx context.Context, review model.Review) (model.Review, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	coll := r.dm.Collection(string(enums.REVIEW_COLLECTION_NAME))
+	err := withMongoTx(ctx, r.dm, func(ctx context.Context) error {
+		if _, err := coll.InsertOne(ctx, review); err != nil {
+			return err
+		}
+		return r.refreshRating(ctx, review.ProductId)
+	})
+	return review, databaseError(err, "review")
+}
+
+func (r reviewRepository) FindAll(ctx context.Context, queryParams dtos.ReviewQueryParams) ([]model.Review, common.MetaData, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	objects := []model.Review{}
+	var metaData common.MetaData
+	coll := r.dm.Collection(string(enums.REVIEW_COLLECTION_NAME))
+	filter := reviewFilter(queryParams)
+	if queryParams.Limit != 0 {
+		total, err := coll.CountDocuments(ctx, filter)
+		if err != nil {
+			return objects, metaData, databaseError(err, "review")
+		}
+		metaData = listMetaData(queryParams.ListQueryParams, total)
+	}
+	cursor, err := coll.Find(ctx, filter, listFindOptions(queryParams.ListQueryParams))
+	if err != nil {
+		return objects, metaData, databaseError(err, "review")
+	}
+	if err := cursor.All(ctx, &objects); err != nil {
+		return objects, metaData, databaseError(err, "review")
+	}
+	return objects, metaData, nil
+}
+
+func reviewFilter(queryParams dtos.ReviewQueryParams) bson.D {
+	filter := bson.D{}
+	if queryParams.Product != "" {
+		filter = append(filter, bson.E{Key: "productId", Value: parseId(queryParams.Product)})
+	}
+	if queryParams.Status != "" {
+		filter = append(filter, bson.E{Key: "status", Value: queryParams.Status})
+	}
+	if queryParams.Rating != 0 {
+		filter = append(filter, bson.E{Key: "rating", Value: queryParams.Rating})
+	}
+	if queryParams.Search != "" {
+		filter = append(filter, searchFilter(queryParams.Search, "title", "body"))
+	}
+	return filter
+}
+
+func (r reviewRepository) FindById(ctx context.Context, id string) (model.Review, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var review model.Review
+	coll := r.dm.Collection(string(enums.REVIEW_COLLECTION_NAME))
+	err := coll.FindOne(ctx, bson.M{"_id": parseId(id)}).Decode(&review)
+	return review, databaseError(err, "review")
+}
+
+func (r reviewRepository) UpdateById(ctx context.Context, id string, payload primitive.M, version *int64) (model.Review, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var review model.Review
+	coll := r.dm.Collection(string(enums.REVIEW_COLLECTION_NAME))
+	filter := bson.D{{Key: "_id", Value: parseId(id)}}
+	update := withVersionInc(bson.M{"$set": payload})
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := withMongoTx(ctx, r.dm, func(ctx context.Context) error {
+		if err := coll.FindOneAndUpdate(ctx, withVersion(filter, version), update, opts).Decode(&review); err != nil {
+			return versionMismatch(ctx, coll, filter, version, "review", err)
+		}
+		return r.refreshRating(ctx, review.ProductId)
+	})
+	return review, databaseError(err, "review")
+}
+
+func (r reviewRepository) DeleteById(ctx context.Context, id string, version *int64) (model.Review, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var review model.Review
+	coll := r.dm.Collection(string(enums.REVIEW_COLLECTION_NAME))
+	filter := bson.D{{Key: "_id", Value: parseId(id)}}
+	err := withMongoTx(ctx, r.dm, func(ctx context.Context) error {
+		if err := coll.FindOneAndDelete(ctx, withVersion(filter, version)).Decode(&review); err != nil {
+			return versionMismatch(ctx, coll, filter, version, "review", err)
+		}
+		return r.refreshRating(ctx, review.ProductId)
+	})
+	return review, databaseError(err, "review")
+}
+
+// refreshRating recomputes the rating of the product from its approved
+// reviews, the version only changes with them.
+func (r reviewRepository) refreshRating(ctx context.Context, productId primitive.ObjectID) error {
+	coll := r.dm.Collection(string(enums.REVIEW_COLLECTION_NAME))
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"productId": productId, "status": enums.REVIEW_APPROVED}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "sum": bson.M{"$sum": "$rating"}, "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	var totals []struct {
+		Sum   int `bson:"sum"`
+		Count int `bson:"count"`
+	}
+	if err := cursor.All(ctx, &totals); err != nil {
+		return err
+	}
+	sum, count := 0, 0
+	if len(totals) > 0 {
+		sum, count = totals[0].Sum, totals[0].Count
+	}
+	rating := averageRating(sum, count)
+	filter := bson.M{"_id": productId, "$or": bson.A{bson.M{"rating": bson.M{"$ne": rating}}, bson.M{"reviewCount": bson.M{"$ne": count}}}}
+	update := bson.M{"$set": bson.M{"rating": rating, "reviewCount": count}, "$inc": bson.M{"version": 1}}
+	_, err = r.dm.Collection(string(enums.PRODUCT_COLLECTION_NAME)).UpdateOne(ctx, filter, update)
+	return err
+}
+
+// averageRating is the average of count ratings adding up to sum, rounded to
+// two decimals, 0 without ratings.
+func averageRating(sum int, count int) float64 {
+	if count == 0 {
+		return 0
+	}
+	return math.Round(float64(sum)/float64(count)*100) / 100
+}
+
+func NewReviewRepository() ReviewRepository {
+	return &reviewRepository{
+		dm: db.GetDmManager(),
+	}
+}
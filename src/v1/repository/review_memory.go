@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type reviewMemoryRepository struct {
+	mm *db.MemoryManager
+}
+
+func (r reviewMemoryRepository) Store(ctx context.Context, review model.Review) (model.Review, error) {
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	for _, _review := range r.mm.Reviews {
+		if _review.ID == review.ID || (_review.ProductId == review.ProductId && _review.AuthorId == review.AuthorId) {
+			return review, domain_error.Conflict("review is already exists")
+		}
+	}
+	r.mm.Reviews[review.ID] = review
+	refreshMemoryRating(r.mm, review.ProductId)
+	return review, nil
+}
+
+func (r reviewMemoryRepository) FindAll(ctx context.Context, queryParams dtos.ReviewQueryParams) ([]model.Review, common.MetaData, error) {
+	r.mm.RLock()
+	defer r.mm.RUnlock()
+	objects := []model.Review{}
+	for _, review := range r.mm.Reviews {
+		if queryParams.Product != "" && review.ProductId.Hex() != queryParams.Product {
+			continue
+		}
+		if queryParams.Status != "" && review.Status != queryParams.Status {
+			continue
+		}
+		if queryParams.Rating != 0 && review.Rating != queryParams.Rating {
+			continue
+		}
+		if queryParams.Search != "" && !memorySearch(queryParams.Search, review.Title, review.Body) {
+			continue
+		}
+		objects = append(objects, review)
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return memoryLess(queryParams.ListQueryParams, compareReviews(objects[i], objects[j], queryParams.OrderBy), objects[i].ID, objects[j].ID)
+	})
+	metaData := listMetaData(queryParams.ListQueryParams, int64(len(objects)))
+	start, end := memoryPage(queryParams.ListQueryParams, len(objects))
+	return objects[start:end], metaData, nil
+}
+
+// compareReviews compares the field of a and b the list is ordered by.
+func compareReviews(a model.Review, b model.Review, field string) int {
+	switch field {
+	case "createdAt":
+		return compareTime(a.CreatedAt, b.CreatedAt)
+	case "updatedAt":
+		return compareTime(a.UpdatedAt, b.UpdatedAt)
+	case "rating":
+		return a.Rating - b.Rating
+	}
+	return 0
+}
+
+func (r reviewMemoryRepository) FindById(ctx context.Context, id string) (model.Review, error) {
+	r.mm.RLock()
+	defer r.mm.RUnlock()
+	review, ok := r.mm.Reviews[parseId(id)]
+	if !ok {
+		return review, domain_error.NotFound("review is not found")
+	}
+	return review, nil
+}
+
+func (r reviewMemoryRepository) UpdateById(ctx context.Context, id string, payload primitive.M, version *int64) (model.Review, error) {
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	review, ok := r.mm.Reviews[parseId(id)]
+	if !ok {
+		return review, domain_error.NotFound("review is not found")
+	}
+	if err := checkVersion("review", review.Version, version); err != nil {
+		return review, err
+	}
+	if err := applySet(&review, payload); err != nil {
+		return review, err
+	}
+	review.Version++
+	r.mm.Reviews[review.ID] = review
+	refreshMemoryRating(r.mm, review.ProductId)
+	return review, nil
+}
+
+func (r reviewMemoryRepository) DeleteById(ctx context.Context, id string, version *int64) (model.Review, error) {
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	review, ok := r.mm.Reviews[parseId(id)]
+	if !ok {
+		return review, domain_error.NotFound("review is not found")
+	}
+	if err := checkVersion("review", review.Version, version); err != nil {
+		return review, err
+	}
+	delete(r.mm.Reviews, review.ID)
+	refreshMemoryRating(r.mm, review.ProductId)
+	return review, nil
+}
+
+// refreshMemoryRating recomputes the rating of the product from its approved
+// reviews, the version only changes with them. The caller holds the memory
+// manager lock.
+func refreshMemoryRating(mm *db.MemoryManager, productId primitive.ObjectID) {
+	product, ok := mm.Products[productId]
+	if !ok {
+		return
+	}
+	sum, count := 0, 0
+	for _, review := range mm.Reviews {
+		if review.ProductId == productId && review.Status == enums.REVIEW_APPROVED {
+			sum += review.Rating
+			count++
+		}
+	}
+	rating := averageRating(sum, count)
+	if product.Rating == rating && product.ReviewCount == count {
+		return
+	}
+	product.Rating = rating
+	product.ReviewCount = count
+	product.Version++
+	mm.Products[productId] = product
+}
+
+func NewReviewMemoryRepository() ReviewRepository {
+	return &reviewMemoryRepository{
+		mm: db.GetMemoryManager(),
+	}
+}
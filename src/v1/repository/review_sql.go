@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const reviewColumns = "id, product_id, author_id, author_name, rating, title, body, status, created_at, updated_at, version"
+
+var reviewOrderColumns = map[string]string{
+	"id":        "id",
+	"createdAt": "created_at",
+	"updatedAt": "updated_at",
+	"rating":    "rating",
+}
+
+type reviewSqlRepository struct {
+	sm *db.SqlManager
+}
+
+func (r reviewSqlRepository) Store(ctx context.Context, review model.Review) (model.Review, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	err := withTx(ctx, r.sm, func(tx *sql.Tx) error {
+		query := r.sm.Rebind(`INSERT INTO reviews (` + reviewColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+		if _, err := tx.ExecContext(ctx, query, reviewValues(review)...); err != nil {
+			return err
+		}
+		return r.refreshRating(ctx, tx, review.ProductId)
+	})
+	return review, databaseError(err, "review")
+}
+
+func (r reviewSqlRepository) FindAll(ctx context.Context, queryParams dtos.ReviewQueryParams) ([]model.Review, common.MetaData, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	objects := []model.Review{}
+	var metaData common.MetaData
+	where, args := reviewWhere(queryParams)
+	if queryParams.Limit != 0 {
+		var total int64
+		if err := r.sm.DB.QueryRowContext(ctx, r.sm.Rebind(`SELECT COUNT(*) FROM reviews`+where), args...).Scan(&total); err != nil {
+			return objects, metaData, databaseError(err, "review")
+		}
+		metaData = listMetaData(queryParams.ListQueryParams, total)
+	}
+	clause, clauseArgs := sqlListClause(queryParams.ListQueryParams, reviewOrderColumns, "reviews")
+	err := queryAll(ctx, r.sm.DB, r.sm.Rebind(`SELECT `+reviewColumns+` FROM reviews`+where+clause), func(rows *sql.Rows) error {
+		review, err := scanReview(rows)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, review)
+		return nil
+	}, append(args, clauseArgs...)...)
+	if err != nil {
+		return objects, metaData, databaseError(err, "review")
+	}
+	return objects, metaData, nil
+}
+
+func reviewWhere(queryParams dtos.ReviewQueryParams) (string, []interface{}) {
+	conditions := []string{}
+	args := []interface{}{}
+	if queryParams.Product != "" {
+		conditions = append(conditions, `product_id = ?`)
+		args = append(args, queryParams.Product)
+	}
+	if queryParams.Status != "" {
+		conditions = append(conditions, `status = ?`)
+		args = append(args, queryParams.Status)
+	}
+	if queryParams.Rating != 0 {
+		conditions = append(conditions, `rating = ?`)
+		args = append(args, queryParams.Rating)
+	}
+	if queryParams.Search != "" {
+		search, searchArgs := sqlSearch(queryParams.Search, "title", "body")
+		conditions = append(conditions, search)
+		args = append(args, searchArgs...)
+	}
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return ` WHERE ` + strings.Join(conditions, ` AND `), args
+}
+
+func (r reviewSqlRepository) FindById(ctx context.Context, id string) (model.Review, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	review, err := r.findById(ctx, r.sm.DB, id)
+	return review, databaseError(err, "review")
+}
+
+func (r reviewSqlRepository) UpdateById(ctx context.Context, id string, payload primitive.M, version *int64) (model.Review, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var review model.Review
+	err := withTx(ctx, r.sm, func(tx *sql.Tx) error {
+		var err error
+		review, err = r.findById(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion("review", review.Version, version); err != nil {
+			return err
+		}
+		if err := applySet(&review, payload); err != nil {
+			return err
+		}
+		query := r.sm.Rebind(`UPDATE reviews SET rating = ?, title = ?, body = ?, status = ?, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?`)
+		result, err := tx.ExecContext(ctx, query, int64(review.Rating), review.Title, review.Body, review.Status, review.UpdatedAt, review.ID.Hex(), review.Version)
+		review.Version++
+		if err := checkGuardedUpdate("review", result, err); err != nil {
+			return err
+		}
+		return r.refreshRating(ctx, tx, review.ProductId)
+	})
+	return review, databaseError(err, "review")
+}
+
+func (r reviewSqlRepository) DeleteById(ctx context.Context, id string, version *int64) (model.Review, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var review model.Review
+	err := withTx(ctx, r.sm, func(tx *sql.Tx) error {
+		var err error
+		review, err = r.findById(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion("review", review.Version, version); err != nil {
+			return err
+		}
+		query := r.sm.Rebind(`DELETE FROM reviews WHERE id = ? AND version = ?`)
+		result, err := tx.ExecContext(ctx, query, review.ID.Hex(), review.Version)
+		if err := checkGuardedUpdate("review", result, err); err != nil {
+			return err
+		}
+		return r.refreshRating(ctx, tx, review.ProductId)
+	})
+	return review, databaseError(err, "review")
+}
+
+func (r reviewSqlRepository) findById(ctx context.Context, executor sqlExecutor, id string) (model.Review, error) {
+	query := r.sm.Rebind(`SELECT ` + reviewColumns + ` FROM reviews WHERE id = ?`)
+	return scanReview(executor.QueryRowContext(ctx, query, id))
+}
+
+// refreshRating recomputes the rating of the product from its approved
+// reviews, the version only changes with them.
+func (r reviewSqlRepository) refreshRating(ctx context.Context, executor sqlExecutor, productId primitive.ObjectID) error {
+	var sum, count int64
+	query := r.sm.Rebind(`SELECT COALESCE(SUM(rating), 0), COUNT(*) FROM reviews WHERE product_id = ? AND status = ?`)
+	if err := executor.QueryRowContext(ctx, query, productId.Hex(), enums.REVIEW_APPROVED).Scan(&sum, &count); err != nil {
+		return err
+	}
+	rating := averageRating(int(sum), int(count))
+	query = r.sm.Rebind(`UPDATE products SET rating = ?, review_count = ?, version = version + 1
+		WHERE id = ? AND (rating <> ? OR review_count <> ?)`)
+	_, err := executor.ExecContext(ctx, query, rating, count, productId.Hex(), rating, count)
+	return err
+}
+
+// reviewValues follows the order of reviewColumns.
+func reviewValues(review model.Review) []interface{} {
+	return []interface{}{
+		review.ID.Hex(), review.ProductId.Hex(), review.AuthorId.Hex(), review.AuthorName, int64(review.Rating), review.Title, review.Body,
+		review.Status, review.CreatedAt, review.UpdatedAt, review.Version,
+	}
+}
+
+func scanReview(scanner rowScanner) (model.Review, error) {
+	var (
+		review              model.Review
+		id, product, author string
+		rating              int64
+	)
+	err := scanner.Scan(&id, &product, &author, &review.AuthorName, &rating, &review.Title, &review.Body,
+		&review.Status, &review.CreatedAt, &review.UpdatedAt, &review.Version)
+	if err != nil {
+		return review, err
+	}
+	review.ID = parseId(id)
+	review.ProductId = parseId(product)
+	review.AuthorId = parseId(author)
+	review.Rating = int(rating)
+	return review, nil
+}
+
+func NewReviewSqlRepository() ReviewRepository {
+	return &reviewSqlRepository{
+		sm: db.GetSqlManager(),
+	}
+}
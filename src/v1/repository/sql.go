@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// sqlExecutor is satisfied by both *sql.DB and *sql.Tx.
+type sqlExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// withTx runs fn inside a transaction, committing when it returns nil.
+func withTx(ctx context.Context, sm *db.SqlManager, fn func(tx *sql.Tx) error) error {
+	tx, err := sm.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func nullableId(id *primitive.ObjectID) interface{} {
+	if id == nil {
+		return nil
+	}
+	return id.Hex()
+}
+
+func nullableTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
+
+func nullableInt(n *int) interface{} {
+	if n == nil {
+		return nil
+	}
+	return int64(*n)
+}
+
+func parseNullableInt(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	_n := int(n.Int64)
+	return &_n
+}
+
+func parseNullableTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	_t := t.Time
+	return &_t
+}
+
+func parseId(hex string) primitive.ObjectID {
+	id, _ := primitive.ObjectIDFromHex(hex)
+	return id
+}
+
+func parseNullableId(hex sql.NullString) *primitive.ObjectID {
+	if !hex.Valid {
+		return nil
+	}
+	id := parseId(hex.String)
+	return &id
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type StockRepository interface {
+	Store(ctx context.Context, adjustment model.StockAdjustment) error
+	// FindByProduct lists the adjustments of the product, the newest first.
+	FindByProduct(ctx context.Context, queryParams dtos.StockQueryParams) ([]model.StockAdjustment, common.MetaData, error)
+}
+
+type stockRepository struct {
+	dm *db.DmManager
+}
+
+func (r stockRepository) Store(ctx context.Context, adjustment model.StockAdjustment) error {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	coll := r.dm.Collection(string(enums.STOCK_ADJUSTMENT_COLLECTION_NAME))
+	_, err := coll.InsertOne(ctx, adjustment)
+	return databaseError(err, "stock adjustment")
+}
+
+func (r stockRepository) FindByProduct(ctx context.Context, queryParams dtos.StockQueryParams) ([]model.StockAdjustment, common.MetaData, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	objects := []model.StockAdjustment{}
+	filter := bson.D{{Key: "productId", Value: parseId(queryParams.ProductId)}}
+	if queryParams.Reason != "" {
+		filter = append(filter, bson.E{Key: "reason", Value: queryParams.Reason})
+	}
+	coll := r.dm.Collection(string(enums.STOCK_ADJUSTMENT_COLLECTION_NAME))
+	total, err := coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return objects, common.MetaData{}, databaseError(err, "stock adjustment")
+	}
+	metaData := paginate(queryParams.Limit, queryParams.Page, total)
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64(metaData.PerPage * (metaData.CurrentPage - 1))).
+		SetLimit(int64(metaData.PerPage))
+	cursor, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		return objects, metaData, databaseError(err, "stock adjustment")
+	}
+	if err := cursor.All(ctx, &objects); err != nil {
+		return objects, metaData, databaseError(err, "stock adjustment")
+	}
+	return objects, metaData, nil
+}
+
+func NewStockRepository() StockRepository {
+	return &stockRepository{
+		dm: db.GetDmManager(),
+	}
+}
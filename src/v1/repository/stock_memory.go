@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+)
+
+type stockMemoryRepository struct {
+	mm *db.MemoryManager
+}
+
+func (r stockMemoryRepository) Store(ctx context.Context, adjustment model.StockAdjustment) error {
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	r.mm.StockAdjustments = append(r.mm.StockAdjustments, adjustment)
+	return nil
+}
+
+func (r stockMemoryRepository) FindByProduct(ctx context.Context, queryParams dtos.StockQueryParams) ([]model.StockAdjustment, common.MetaData, error) {
+	r.mm.RLock()
+	defer r.mm.RUnlock()
+	matches := []model.StockAdjustment{}
+	for i := len(r.mm.StockAdjustments) - 1; i >= 0; i-- {
+		adjustment := r.mm.StockAdjustments[i]
+		if adjustment.ProductId.Hex() != queryParams.ProductId {
+			continue
+		}
+		if queryParams.Reason != "" && adjustment.Reason != queryParams.Reason {
+			continue
+		}
+		matches = append(matches, adjustment)
+	}
+	metaData := paginate(queryParams.Limit, queryParams.Page, int64(len(matches)))
+	start := metaData.PerPage * (metaData.CurrentPage - 1)
+	end := start + metaData.PerPage
+	if start > uint64(len(matches)) {
+		start = uint64(len(matches))
+	}
+	if end > uint64(len(matches)) {
+		end = uint64(len(matches))
+	}
+	return matches[start:end], metaData, nil
+}
+
+func NewStockMemoryRepository() StockRepository {
+	return &stockMemoryRepository{
+		mm: db.GetMemoryManager(),
+	}
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+)
+
+const stockAdjustmentColumns = `id, product_id, delta, reason, note, stock, actor_id, actor_name, actor_email, actor_role, created_at`
+
+type stockSqlRepository struct {
+	sm *db.SqlManager
+}
+
+func (r stockSqlRepository) Store(ctx context.Context, adjustment model.StockAdjustment) error {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var actorId, actorName, actorEmail, actorRole interface{}
+	if adjustment.Actor != nil {
+		actorId, actorName, actorEmail, actorRole = adjustment.Actor.ID.Hex(), adjustment.Actor.Name, adjustment.Actor.Email, adjustment.Actor.Role
+	}
+	query := r.sm.Rebind(`INSERT INTO stock_adjustments (` + stockAdjustmentColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	_, err := r.sm.DB.ExecContext(ctx, query, adjustment.ID.Hex(), adjustment.ProductId.Hex(), int64(adjustment.Delta), adjustment.Reason,
+		adjustment.Note, int64(adjustment.Stock), actorId, actorName, actorEmail, actorRole, adjustment.CreatedAt)
+	return databaseError(err, "stock adjustment")
+}
+
+func (r stockSqlRepository) FindByProduct(ctx context.Context, queryParams dtos.StockQueryParams) ([]model.StockAdjustment, common.MetaData, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	objects := []model.StockAdjustment{}
+	where := ` WHERE product_id = ?`
+	args := []interface{}{queryParams.ProductId}
+	if queryParams.Reason != "" {
+		where += ` AND reason = ?`
+		args = append(args, queryParams.Reason)
+	}
+	var total int64
+	if err := r.sm.DB.QueryRowContext(ctx, r.sm.Rebind(`SELECT COUNT(*) FROM stock_adjustments`+where), args...).Scan(&total); err != nil {
+		return objects, common.MetaData{}, databaseError(err, "stock adjustment")
+	}
+	metaData := paginate(queryParams.Limit, queryParams.Page, total)
+	query := r.sm.Rebind(`SELECT ` + stockAdjustmentColumns + ` FROM stock_adjustments` + where + ` ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`)
+	args = append(args, metaData.PerPage, metaData.PerPage*(metaData.CurrentPage-1))
+	err := queryAll(ctx, r.sm.DB, query, func(rows *sql.Rows) error {
+		var (
+			adjustment                                model.StockAdjustment
+			id, productId                             string
+			delta, stock                              int64
+			actorId, actorName, actorEmail, actorRole sql.NullString
+		)
+		err := rows.Scan(&id, &productId, &delta, &adjustment.Reason, &adjustment.Note, &stock,
+			&actorId, &actorName, &actorEmail, &actorRole, &adjustment.CreatedAt)
+		if err != nil {
+			return err
+		}
+		adjustment.ID = parseId(id)
+		adjustment.ProductId = parseId(productId)
+		adjustment.Delta = int(delta)
+		adjustment.Stock = int(stock)
+		if actorId.Valid {
+			adjustment.Actor = &model.AuditActor{
+				ID:    parseId(actorId.String),
+				Name:  actorName.String,
+				Email: actorEmail.String,
+				Role:  actorRole.String,
+			}
+		}
+		objects = append(objects, adjustment)
+		return nil
+	}, args...)
+	if err != nil {
+		return objects, metaData, databaseError(err, "stock adjustment")
+	}
+	return objects, metaData, nil
+}
+
+func NewStockSqlRepository() StockRepository {
+	return &stockSqlRepository{
+		sm: db.GetSqlManager(),
+	}
+}
@@ -1,7 +1,7 @@
 package repository
 
 import (
-	"errors"
+	"context"
 	"time"
 
 	"github.com/sajalmia381/store-api/src/enums"
@@ -16,55 +16,55 @@ const (
 )
 
 type TokenRepository interface {
-	Store(payload model.Token) (model.Token, error)
-	FindByToken(token string) (model.Token, error)
-	DeleteByToken(token string) (*mongo.DeleteResult, error)
+	Store(ctx context.Context, payload model.Token) (model.Token, error)
+	FindByToken(ctx context.Context, token string) (model.Token, error)
+	DeleteByToken(ctx context.Context, token string) (*mongo.DeleteResult, error)
 }
 
 type tokenRepository struct {
 	dm *db.DmManager
 }
 
-func (r tokenRepository) Store(payload model.Token) (model.Token, error) {
+func (r tokenRepository) Store(ctx context.Context, payload model.Token) (model.Token, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
 	payload.CreatedAt = time.Now().UTC()
 	if payload.Type == "" {
 		payload.Type = string(enums.REFRESH_TOKEN)
 	}
-	coll := r.dm.DB.Collection(TokenCollectionName)
-	_, err := coll.InsertOne(r.dm.Ctx, payload)
+	coll := r.dm.Collection(TokenCollectionName)
+	_, err := coll.InsertOne(ctx, payload)
 	if err != nil {
-		return payload, err
+		return payload, databaseError(err, "token")
 	}
 	return payload, nil
 }
 
-func (r tokenRepository) FindByToken(token string) (model.Token, error) {
+func (r tokenRepository) FindByToken(ctx context.Context, token string) (model.Token, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
 	var object model.Token
 	filter := bson.D{
 		{Key: "token", Value: token},
 	}
-	coll := r.dm.DB.Collection(TokenCollectionName)
-	result := coll.FindOne(r.dm.Ctx, filter)
+	coll := r.dm.Collection(TokenCollectionName)
+	result := coll.FindOne(ctx, filter)
 	if err := result.Decode(&object); err != nil {
-		if err == mongo.ErrNoDocuments {
-			return object, errors.New("token object is not found")
-		}
-		panic(err)
+		return object, databaseError(err, "token")
 	}
 	return object, nil
 }
 
-func (r tokenRepository) DeleteByToken(token string) (*mongo.DeleteResult, error) {
+func (r tokenRepository) DeleteByToken(ctx context.Context, token string) (*mongo.DeleteResult, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
 	filter := bson.D{
 		{Key: "token", Value: token},
 	}
-	coll := r.dm.DB.Collection(TokenCollectionName)
-	result, err := coll.DeleteOne(r.dm.Ctx, filter)
+	coll := r.dm.Collection(TokenCollectionName)
+	result, err := coll.DeleteOne(ctx, filter)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return result, errors.New("token object is not found")
-		}
-		panic(err)
+		return result, databaseError(err, "token")
 	}
 	return result, nil
 }
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type tokenMemoryRepository struct {
+	mm *db.MemoryManager
+}
+
+func (r tokenMemoryRepository) Store(ctx context.Context, payload model.Token) (model.Token, error) {
+	payload.CreatedAt = time.Now().UTC()
+	if payload.Type == "" {
+		payload.Type = string(enums.REFRESH_TOKEN)
+	}
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	if _, ok := r.mm.Tokens[payload.ID]; ok {
+		return payload, domain_error.Conflict("token id is already exists")
+	}
+	for _, token := range r.mm.Tokens {
+		if token.Token == payload.Token {
+			return payload, domain_error.Conflict("token is already exists")
+		}
+	}
+	r.mm.Tokens[payload.ID] = payload
+	return payload, nil
+}
+
+func (r tokenMemoryRepository) FindByToken(ctx context.Context, token string) (model.Token, error) {
+	r.mm.RLock()
+	defer r.mm.RUnlock()
+	for _, object := range r.mm.Tokens {
+		if object.Token == token {
+			return object, nil
+		}
+	}
+	return model.Token{}, domain_error.NotFound("token is not found")
+}
+
+func (r tokenMemoryRepository) DeleteByToken(ctx context.Context, token string) (*mongo.DeleteResult, error) {
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	result := &mongo.DeleteResult{}
+	for id, object := range r.mm.Tokens {
+		if object.Token == token {
+			delete(r.mm.Tokens, id)
+			result.DeletedCount = 1
+			break
+		}
+	}
+	return result, nil
+}
+
+func NewTokenMemoryRepository() TokenRepository {
+	return &tokenMemoryRepository{
+		mm: db.GetMemoryManager(),
+	}
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type tokenSqlRepository struct {
+	sm *db.SqlManager
+}
+
+func (r tokenSqlRepository) Store(ctx context.Context, payload model.Token) (model.Token, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	payload.CreatedAt = time.Now().UTC()
+	if payload.Type == "" {
+		payload.Type = string(enums.REFRESH_TOKEN)
+	}
+	query := r.sm.Rebind(`INSERT INTO tokens (id, user_id, token, type, created_at) VALUES (?, ?, ?, ?, ?)`)
+	_, err := r.sm.DB.ExecContext(ctx, query, payload.ID.Hex(), nullableId(payload.UserId), payload.Token, payload.Type, payload.CreatedAt)
+	if err != nil {
+		return payload, databaseError(err, "token")
+	}
+	return payload, nil
+}
+
+func (r tokenSqlRepository) FindByToken(ctx context.Context, token string) (model.Token, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var (
+		object model.Token
+		id     string
+		userId sql.NullString
+	)
+	query := r.sm.Rebind(`SELECT id, user_id, token, type, created_at FROM tokens WHERE token = ?`)
+	err := r.sm.DB.QueryRowContext(ctx, query, token).Scan(&id, &userId, &object.Token, &object.Type, &object.CreatedAt)
+	if err != nil {
+		return object, databaseError(err, "token")
+	}
+	object.ID = parseId(id)
+	object.UserId = parseNullableId(userId)
+	return object, nil
+}
+
+func (r tokenSqlRepository) DeleteByToken(ctx context.Context, token string) (*mongo.DeleteResult, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	query := r.sm.Rebind(`DELETE FROM tokens WHERE token = ?`)
+	result, err := r.sm.DB.ExecContext(ctx, query, token)
+	if err != nil {
+		return nil, databaseError(err, "token")
+	}
+	count, _ := result.RowsAffected()
+	return &mongo.DeleteResult{DeletedCount: count}, nil
+}
+
+func NewTokenSqlRepository() TokenRepository {
+	return &tokenSqlRepository{
+		sm: db.GetSqlManager(),
+	}
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Soft deleted documents carry a deletedAt time and stay out of every query
+// but the trash ones. A missing deletedAt matches nil as well, so documents
+// written before soft deletion existed are live.
+var notDeleted = bson.E{Key: "deletedAt", Value: nil}
+var isDeleted = bson.E{Key: "deletedAt", Value: bson.M{"$ne": nil}}
+
+// trashOptions lists the most recently deleted first.
+func trashOptions() *options.FindOptions {
+	return options.Find().SetSort(bson.D{{Key: "deletedAt", Value: -1}, {Key: "_id", Value: 1}})
+}
+
+// deletedBefore matches the trash older than before.
+func deletedBefore(before time.Time) bson.D {
+	return bson.D{{Key: "deletedAt", Value: bson.M{"$ne": nil, "$lt": before}}}
+}
+
+// trashedBefore reports whether deletedAt is older than before, for the memory
+// repositories that filter in code.
+func trashedBefore(deletedAt *time.Time, before time.Time) bool {
+	return deletedAt != nil && deletedAt.Before(before)
+}
+
+// newerTrash orders the memory trash like trashOptions.
+func newerTrash(a *time.Time, b *time.Time) bool {
+	if a == nil || b == nil {
+		return b == nil && a != nil
+	}
+	return a.After(*b)
+}
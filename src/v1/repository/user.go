@@ -1,10 +1,12 @@
 package repository
 
 import (
-	"errors"
+	"context"
 	"log"
 	"time"
 
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/domain_error"
 	"github.com/sajalmia381/store-api/src/enums"
 	"github.com/sajalmia381/store-api/src/v1/db"
 	"github.com/sajalmia381/store-api/src/v1/dtos"
@@ -16,33 +18,95 @@ import (
 )
 
 type UserRepository interface {
-	Store(user model.User) (model.User, error)
-	FindAll(queryParams dtos.UserQuery) ([]model.User, error)
-	FindById(id primitive.ObjectID) (model.User, error)
-	FindByEmail(email string) (model.User, error)
-	UpdateById(id primitive.ObjectID, payload primitive.M) (model.User, error)
-	UpdateLoginTime(id primitive.ObjectID) (model.User, error)
-	DeleteById(id primitive.ObjectID) (*mongo.DeleteResult, error)
+	Store(ctx context.Context, user model.User) (model.User, error)
+	FindAll(ctx context.Context, queryParams dtos.UserQuery) ([]model.User, common.MetaData, error)
+	// Each calls fn with the users of the query one at a time, in the order
+	// of the list and not paged, until fn fails.
+	Each(ctx context.Context, queryParams dtos.UserQuery, fn func(model.User) error) error
+	FindById(ctx context.Context, id primitive.ObjectID) (model.User, error)
+	FindByEmail(ctx context.Context, email string) (model.User, error)
+	// UpdateById and DeleteById fail with PRECONDITION_FAILED unless the user
+	// is at the given version, a nil version skips the check.
+	UpdateById(ctx context.Context, id primitive.ObjectID, payload primitive.M, version *int64) (model.User, error)
+	UpdateLoginTime(ctx context.Context, id primitive.ObjectID) (model.User, error)
+	// DeleteById moves the user to the trash, a trashed user can not log in.
+	DeleteById(ctx context.Context, id primitive.ObjectID, version *int64) (*mongo.DeleteResult, error)
+	FindTrash(ctx context.Context) ([]model.User, error)
+	FindTrashedById(ctx context.Context, id primitive.ObjectID) (model.User, error)
+	RestoreById(ctx context.Context, id primitive.ObjectID) (model.User, error)
+	// PurgeById removes a trashed user permanently.
+	PurgeById(ctx context.Context, id primitive.ObjectID) (model.User, error)
+	// PurgeDeletedBefore removes the users trashed before the given time
+	// permanently and counts them.
+	PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error)
 }
 
 type userRepository struct {
 	dm *db.DmManager
 }
 
-func (r userRepository) Store(user model.User) (model.User, error) {
-	coll := r.dm.DB.Collection(string(enums.USER_COLLECTION_NAME))
-	_, err := coll.InsertOne(r.dm.Ctx, user)
+func (r userRepository) Store(ctx context.Context, user model.User) (model.User, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	coll := r.dm.Collection(string(enums.USER_COLLECTION_NAME))
+	_, err := coll.InsertOne(ctx, user)
 	if err != nil {
 		log.Println("[ERROR] Insert document:", err.Error())
 		// log.Printf("type %T", err)
-		return user, err
+		return user, databaseError(err, "user")
 	}
 	return user, nil
 }
 
-func (r userRepository) FindAll(filterData dtos.UserQuery) ([]model.User, error) {
+func (r userRepository) FindAll(ctx context.Context, filterData dtos.UserQuery) ([]model.User, common.MetaData, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
 	var objects []model.User
-	query := bson.D{}
+	var metaData common.MetaData
+	query := userFilter(filterData)
+	coll := r.dm.Collection(string(enums.USER_COLLECTION_NAME))
+	if filterData.Limit != 0 {
+		total, err := coll.CountDocuments(ctx, query)
+		if err != nil {
+			return objects, metaData, databaseError(err, "user")
+		}
+		metaData = listMetaData(filterData.ListQueryParams, total)
+	}
+	cursor, err := coll.Find(ctx, query, listFindOptions(filterData.ListQueryParams))
+	if err != nil {
+		return objects, metaData, databaseError(err, "user")
+	}
+	if err := cursor.All(ctx, &objects); err != nil {
+		log.Println("[ERROR]:", err.Error())
+		return objects, metaData, databaseError(err, "user")
+	}
+	return objects, metaData, nil
+}
+
+func (r userRepository) Each(ctx context.Context, filterData dtos.UserQuery, fn func(model.User) error) error {
+	ctx, cancel := db.StreamContext(ctx)
+	defer cancel()
+	filterData.Limit, filterData.Page = 0, 0
+	opts := listFindOptions(filterData.ListQueryParams).SetAllowDiskUse(true)
+	cursor, err := r.dm.Collection(string(enums.USER_COLLECTION_NAME)).Find(ctx, userFilter(filterData), opts)
+	if err != nil {
+		return databaseError(err, "user")
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var user model.User
+		if err := cursor.Decode(&user); err != nil {
+			return databaseError(err, "user")
+		}
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+	return databaseError(cursor.Err(), "user")
+}
+
+func userFilter(filterData dtos.UserQuery) bson.D {
+	query := bson.D{notDeleted}
 	if filterData.Role != "" {
 		query = append(query, bson.E{
 			Key: "role", Value: filterData.Role,
@@ -53,115 +117,195 @@ func (r userRepository) FindAll(filterData dtos.UserQuery) ([]model.User, error)
 			Key: "status", Value: *filterData.Status,
 		})
 	}
-	coll := r.dm.DB.Collection(string(enums.USER_COLLECTION_NAME))
-	cursor, err := coll.Find(r.dm.Ctx, query)
-	if err != nil {
-		return objects, err
-	}
-	if err := cursor.All(r.dm.Ctx, &objects); err != nil {
-		log.Println("[ERROR]:", err.Error())
-		panic(err)
+	if filterData.Search != "" {
+		query = append(query, searchFilter(filterData.Search, "name", "email"))
 	}
-	return objects, nil
+	return query
 }
 
-func (r userRepository) FindById(id primitive.ObjectID) (model.User, error) {
+func (r userRepository) FindById(ctx context.Context, id primitive.ObjectID) (model.User, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
 	var user model.User
 	query := bson.D{
 		{Key: "_id", Value: id},
+		notDeleted,
 	}
-	coll := r.dm.DB.Collection(string(enums.USER_COLLECTION_NAME))
-	result := coll.FindOne(r.dm.Ctx, query)
+	coll := r.dm.Collection(string(enums.USER_COLLECTION_NAME))
+	result := coll.FindOne(ctx, query)
 	if err := result.Decode(&user); err != nil {
-		if err == mongo.ErrNoDocuments {
-			return user, errors.New("user is not found")
+		if err != mongo.ErrNoDocuments {
+			log.Println("[ERROR]:", err.Error())
 		}
-		log.Println("[ERROR]:", err.Error())
-		panic(err)
+		return user, databaseError(err, "user")
 	}
 	return user, nil
 }
 
-func (r userRepository) FindByEmail(email string) (model.User, error) {
+func (r userRepository) FindByEmail(ctx context.Context, email string) (model.User, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
 	var user model.User
 	filter := bson.D{
 		{Key: "email", Value: email},
+		notDeleted,
 	}
-	coll := r.dm.DB.Collection(string(enums.USER_COLLECTION_NAME))
-	result := coll.FindOne(r.dm.Ctx, filter)
+	coll := r.dm.Collection(string(enums.USER_COLLECTION_NAME))
+	result := coll.FindOne(ctx, filter)
 
 	if err := result.Decode(&user); err != nil {
-		if err == mongo.ErrNoDocuments {
-			return user, errors.New("user is not found")
+		if err != mongo.ErrNoDocuments {
+			log.Println("[ERROR]:", err.Error())
 		}
-		log.Println("[ERROR]:", err.Error())
-		panic(err)
+		return user, databaseError(err, "user")
 	}
 
 	return user, nil
 }
 
-func (r userRepository) UpdateById(id primitive.ObjectID, payload primitive.M) (model.User, error) {
+func (r userRepository) UpdateById(ctx context.Context, id primitive.ObjectID, payload primitive.M, version *int64) (model.User, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
 	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 	payload["updatedAt"] = time.Now().UTC()
 	filter := bson.D{
 		{Key: "_id", Value: id},
+		notDeleted,
 	}
-	update := bson.D{
-		{Key: "$set", Value: payload},
-	}
-	coll := r.dm.DB.Collection(string(enums.USER_COLLECTION_NAME))
-	result := coll.FindOneAndUpdate(r.dm.Ctx, filter, update, opts)
+	update := withVersionInc(bson.M{
+		"$set": payload,
+	})
+	coll := r.dm.Collection(string(enums.USER_COLLECTION_NAME))
+	result := coll.FindOneAndUpdate(ctx, withVersion(filter, version), update, opts)
 	var user model.User
 	if err := result.Decode(&user); err != nil {
-		if err == mongo.ErrNoDocuments {
-			return user, err
+		if err != mongo.ErrNoDocuments {
+			log.Println("[ERROR] Update document:", err.Error())
 		}
-		log.Println("[ERROR] Update document:", err.Error())
-		panic(err)
+		return user, databaseError(versionMismatch(ctx, coll, filter, version, "user", err), "user")
 	}
 
 	return user, nil
 }
 
-func (r userRepository) UpdateLoginTime(id primitive.ObjectID) (model.User, error) {
+func (r userRepository) UpdateLoginTime(ctx context.Context, id primitive.ObjectID) (model.User, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
 	var user model.User
 	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 	filter := bson.D{
 		{Key: "_id", Value: id},
+		notDeleted,
 	}
-	update := bson.D{
-		{Key: "$set", Value: bson.M{
+	update := withVersionInc(bson.M{
+		"$set": bson.M{
 			"lastLoginAt": time.Now().UTC(),
-		}},
-	}
-	coll := r.dm.DB.Collection(string(enums.USER_COLLECTION_NAME))
-	result := coll.FindOneAndUpdate(r.dm.Ctx, filter, update, opts)
+		},
+	})
+	coll := r.dm.Collection(string(enums.USER_COLLECTION_NAME))
+	result := coll.FindOneAndUpdate(ctx, filter, update, opts)
 	if err := result.Decode(&user); err != nil {
-		if err == mongo.ErrNoDocuments {
-			return user, err
+		if err != mongo.ErrNoDocuments {
+			log.Println("[ERROR] Update Login time document:", err.Error())
 		}
-		log.Println("[ERROR] Update Login time document:", err.Error())
-		panic(err)
+		return user, databaseError(err, "user")
 	}
 	return user, nil
 }
 
-func (r userRepository) DeleteById(id primitive.ObjectID) (*mongo.DeleteResult, error) {
+func (r userRepository) DeleteById(ctx context.Context, id primitive.ObjectID, version *int64) (*mongo.DeleteResult, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
 	query := bson.D{
 		{Key: "_id", Value: id},
+		notDeleted,
+	}
+	update := withVersionInc(bson.M{"$set": bson.M{"deletedAt": time.Now().UTC()}})
+	coll := r.dm.Collection(string(enums.USER_COLLECTION_NAME))
+	result, err := coll.UpdateOne(ctx, withVersion(query, version), update)
+	if err != nil {
+		return nil, databaseError(err, "user")
 	}
+	if result.MatchedCount != 1 {
+		if err := versionMismatch(ctx, coll, query, version, "user", mongo.ErrNoDocuments); err != mongo.ErrNoDocuments {
+			return &mongo.DeleteResult{}, databaseError(err, "user")
+		}
+		return &mongo.DeleteResult{}, domain_error.NotFound("user is not found")
+	}
+	return &mongo.DeleteResult{DeletedCount: 1}, nil
+}
 
-	coll := r.dm.DB.Collection(string(enums.USER_COLLECTION_NAME))
-	result, err := coll.DeleteOne(r.dm.Ctx, query)
+func (r userRepository) FindTrash(ctx context.Context) ([]model.User, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	objects := []model.User{}
+	coll := r.dm.Collection(string(enums.USER_COLLECTION_NAME))
+	cursor, err := coll.Find(ctx, bson.D{isDeleted}, trashOptions())
 	if err != nil {
-		return nil, err
+		return objects, databaseError(err, "user")
+	}
+	if err := cursor.All(ctx, &objects); err != nil {
+		return objects, databaseError(err, "user")
 	}
-	if result.DeletedCount != 1 {
-		log.Println("[ERROR] Delete document count", result.DeletedCount)
-		return result, errors.New("user is not exists")
+	return objects, nil
+}
+
+func (r userRepository) FindTrashedById(ctx context.Context, id primitive.ObjectID) (model.User, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var user model.User
+	filter := bson.D{
+		{Key: "_id", Value: id},
+		isDeleted,
+	}
+	coll := r.dm.Collection(string(enums.USER_COLLECTION_NAME))
+	if err := coll.FindOne(ctx, filter).Decode(&user); err != nil {
+		return user, databaseError(err, "user in trash")
+	}
+	return user, nil
+}
+
+func (r userRepository) RestoreById(ctx context.Context, id primitive.ObjectID) (model.User, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var user model.User
+	filter := bson.D{
+		{Key: "_id", Value: id},
+		isDeleted,
+	}
+	update := withVersionInc(bson.M{"$set": bson.M{"deletedAt": nil}})
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	coll := r.dm.Collection(string(enums.USER_COLLECTION_NAME))
+	if err := coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&user); err != nil {
+		return user, databaseError(err, "user in trash")
+	}
+	return user, nil
+}
+
+func (r userRepository) PurgeById(ctx context.Context, id primitive.ObjectID) (model.User, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var user model.User
+	filter := bson.D{
+		{Key: "_id", Value: id},
+		isDeleted,
+	}
+	coll := r.dm.Collection(string(enums.USER_COLLECTION_NAME))
+	if err := coll.FindOneAndDelete(ctx, filter).Decode(&user); err != nil {
+		return user, databaseError(err, "user in trash")
+	}
+	return user, nil
+}
+
+func (r userRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	coll := r.dm.Collection(string(enums.USER_COLLECTION_NAME))
+	result, err := coll.DeleteMany(ctx, deletedBefore(before))
+	if err != nil {
+		return 0, databaseError(err, "user")
 	}
-	return result, nil
+	return result.DeletedCount, nil
 }
 
 func NewUserRepository() UserRepository {
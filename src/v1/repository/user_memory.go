@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type userMemoryRepository struct {
+	mm *db.MemoryManager
+}
+
+func (r userMemoryRepository) Store(ctx context.Context, user model.User) (model.User, error) {
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	if _, ok := r.mm.Users[user.ID]; ok {
+		return user, domain_error.Conflict("user id is already exists")
+	}
+	for _, object := range r.mm.Users {
+		if object.Email == user.Email {
+			return user, domain_error.Conflict("user email is already exists")
+		}
+	}
+	r.mm.Users[user.ID] = user
+	return user, nil
+}
+
+func (r userMemoryRepository) FindAll(ctx context.Context, filterData dtos.UserQuery) ([]model.User, common.MetaData, error) {
+	r.mm.RLock()
+	defer r.mm.RUnlock()
+	objects := []model.User{}
+	for _, user := range r.mm.Users {
+		if user.DeletedAt != nil {
+			continue
+		}
+		if filterData.Role != "" && string(user.Role) != filterData.Role {
+			continue
+		}
+		if filterData.Status != nil && user.Status != *filterData.Status {
+			continue
+		}
+		if filterData.Search != "" && !memorySearch(filterData.Search, user.Name, user.Email) {
+			continue
+		}
+		objects = append(objects, user)
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return memoryLess(filterData.ListQueryParams, compareUsers(objects[i], objects[j], filterData.OrderBy), objects[i].ID, objects[j].ID)
+	})
+	metaData := listMetaData(filterData.ListQueryParams, int64(len(objects)))
+	start, end := memoryPage(filterData.ListQueryParams, len(objects))
+	return objects[start:end], metaData, nil
+}
+
+func (r userMemoryRepository) Each(ctx context.Context, queryParams dtos.UserQuery, fn func(model.User) error) error {
+	queryParams.Limit, queryParams.Page = 0, 0
+	objects, _, err := r.FindAll(ctx, queryParams)
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		if err := fn(object); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r userMemoryRepository) FindById(ctx context.Context, id primitive.ObjectID) (model.User, error) {
+	r.mm.RLock()
+	defer r.mm.RUnlock()
+	user, ok := r.findById(id)
+	if !ok {
+		return user, domain_error.NotFound("user is not found")
+	}
+	return user, nil
+}
+
+func (r userMemoryRepository) FindByEmail(ctx context.Context, email string) (model.User, error) {
+	r.mm.RLock()
+	defer r.mm.RUnlock()
+	for _, user := range r.mm.Users {
+		if user.Email == email && user.DeletedAt == nil {
+			return user, nil
+		}
+	}
+	return model.User{}, domain_error.NotFound("user is not found")
+}
+
+func (r userMemoryRepository) UpdateById(ctx context.Context, id primitive.ObjectID, payload primitive.M, version *int64) (model.User, error) {
+	payload["updatedAt"] = time.Now().UTC()
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	user, ok := r.findById(id)
+	if !ok {
+		return user, domain_error.NotFound("user is not found")
+	}
+	if err := checkVersion("user", user.Version, version); err != nil {
+		return user, err
+	}
+	if err := applySet(&user, payload); err != nil {
+		return user, err
+	}
+	user.Version++
+	r.mm.Users[id] = user
+	return user, nil
+}
+
+func (r userMemoryRepository) UpdateLoginTime(ctx context.Context, id primitive.ObjectID) (model.User, error) {
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	user, ok := r.findById(id)
+	if !ok {
+		return user, domain_error.NotFound("user is not found")
+	}
+	now := time.Now().UTC()
+	user.LastLoginAt = &now
+	user.Version++
+	r.mm.Users[id] = user
+	return user, nil
+}
+
+func (r userMemoryRepository) DeleteById(ctx context.Context, id primitive.ObjectID, version *int64) (*mongo.DeleteResult, error) {
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	user, ok := r.findById(id)
+	if !ok {
+		return &mongo.DeleteResult{}, domain_error.NotFound("user is not found")
+	}
+	if err := checkVersion("user", user.Version, version); err != nil {
+		return &mongo.DeleteResult{}, err
+	}
+	now := time.Now().UTC()
+	user.DeletedAt = &now
+	user.Version++
+	r.mm.Users[id] = user
+	return &mongo.DeleteResult{DeletedCount: 1}, nil
+}
+
+func (r userMemoryRepository) FindTrash(ctx context.Context) ([]model.User, error) {
+	r.mm.RLock()
+	defer r.mm.RUnlock()
+	objects := []model.User{}
+	for _, id := range r.sortedIds() {
+		if user := r.mm.Users[id]; user.DeletedAt != nil {
+			objects = append(objects, user)
+		}
+	}
+	sort.SliceStable(objects, func(i, j int) bool { return newerTrash(objects[i].DeletedAt, objects[j].DeletedAt) })
+	return objects, nil
+}
+
+func (r userMemoryRepository) FindTrashedById(ctx context.Context, id primitive.ObjectID) (model.User, error) {
+	r.mm.RLock()
+	defer r.mm.RUnlock()
+	user, ok := r.mm.Users[id]
+	if !ok || user.DeletedAt == nil {
+		return model.User{}, domain_error.NotFound("user in trash is not found")
+	}
+	return user, nil
+}
+
+func (r userMemoryRepository) RestoreById(ctx context.Context, id primitive.ObjectID) (model.User, error) {
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	user, ok := r.mm.Users[id]
+	if !ok || user.DeletedAt == nil {
+		return user, domain_error.NotFound("user in trash is not found")
+	}
+	user.DeletedAt = nil
+	user.Version++
+	r.mm.Users[id] = user
+	return user, nil
+}
+
+func (r userMemoryRepository) PurgeById(ctx context.Context, id primitive.ObjectID) (model.User, error) {
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	user, ok := r.mm.Users[id]
+	if !ok || user.DeletedAt == nil {
+		return user, domain_error.NotFound("user in trash is not found")
+	}
+	delete(r.mm.Users, id)
+	return user, nil
+}
+
+func (r userMemoryRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	r.mm.Lock()
+	defer r.mm.Unlock()
+	var count int64
+	for id, user := range r.mm.Users {
+		if trashedBefore(user.DeletedAt, before) {
+			delete(r.mm.Users, id)
+			count++
+		}
+	}
+	return count, nil
+}
+
+// findById skips trashed users, the caller holds the memory manager lock.
+func (r userMemoryRepository) findById(id primitive.ObjectID) (model.User, bool) {
+	user, ok := r.mm.Users[id]
+	if !ok || user.DeletedAt != nil {
+		return model.User{}, false
+	}
+	return user, true
+}
+
+// compareUsers compares the field of a and b the list is ordered by.
+func compareUsers(a model.User, b model.User, field string) int {
+	switch field {
+	case "name":
+		return strings.Compare(a.Name, b.Name)
+	case "email":
+		return strings.Compare(a.Email, b.Email)
+	case "role":
+		return strings.Compare(string(a.Role), string(b.Role))
+	case "createdAt":
+		return compareTime(a.CreatedAt, b.CreatedAt)
+	case "updatedAt":
+		return compareTime(a.UpdatedAt, b.UpdatedAt)
+	}
+	return 0
+}
+
+func (r userMemoryRepository) sortedIds() []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, 0, len(r.mm.Users))
+	for id := range r.mm.Users {
+		ids = append(ids, id)
+	}
+	return sortedObjectIds(ids)
+}
+
+func NewUserMemoryRepository() UserRepository {
+	return &userMemoryRepository{
+		mm: db.GetMemoryManager(),
+	}
+}
@@ -0,0 +1,325 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const userColumns = "id, name, email, password, number, status, role, last_login_at, created_at, updated_at, deleted_at, version"
+
+type userSqlRepository struct {
+	sm *db.SqlManager
+}
+
+func (r userSqlRepository) Store(ctx context.Context, user model.User) (model.User, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	query := r.sm.Rebind(`INSERT INTO users (` + userColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	_, err := r.sm.DB.ExecContext(ctx, query, userValues(user)...)
+	if err != nil {
+		return user, databaseError(err, "user")
+	}
+	return user, nil
+}
+
+var userOrderColumns = map[string]string{
+	"id":        "id",
+	"name":      "name",
+	"email":     "email",
+	"role":      "role",
+	"createdAt": "created_at",
+	"updatedAt": "updated_at",
+}
+
+func (r userSqlRepository) FindAll(ctx context.Context, filterData dtos.UserQuery) ([]model.User, common.MetaData, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	objects := []model.User{}
+	var metaData common.MetaData
+	where, args := userWhere(filterData)
+	if filterData.Limit != 0 {
+		var total int64
+		if err := r.sm.DB.QueryRowContext(ctx, r.sm.Rebind(`SELECT COUNT(*) FROM users`+where), args...).Scan(&total); err != nil {
+			return objects, metaData, databaseError(err, "user")
+		}
+		metaData = listMetaData(filterData.ListQueryParams, total)
+	}
+	clause, clauseArgs := sqlListClause(filterData.ListQueryParams, userOrderColumns, "users")
+	rows, err := r.sm.DB.QueryContext(ctx, r.sm.Rebind(`SELECT `+userColumns+` FROM users`+where+clause), append(args, clauseArgs...)...)
+	if err != nil {
+		return objects, metaData, databaseError(err, "user")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return objects, metaData, databaseError(err, "user")
+		}
+		objects = append(objects, user)
+	}
+	return objects, metaData, databaseError(rows.Err(), "user")
+}
+
+func (r userSqlRepository) Each(ctx context.Context, filterData dtos.UserQuery, fn func(model.User) error) error {
+	ctx, cancel := db.StreamContext(ctx)
+	defer cancel()
+	filterData.Limit, filterData.Page = 0, 0
+	where, args := userWhere(filterData)
+	clause, _ := sqlListClause(filterData.ListQueryParams, userOrderColumns, "users")
+	rows, err := r.sm.DB.QueryContext(ctx, r.sm.Rebind(`SELECT `+userColumns+` FROM users`+where+clause), args...)
+	if err != nil {
+		return databaseError(err, "user")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return databaseError(err, "user")
+		}
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+	return databaseError(rows.Err(), "user")
+}
+
+func userWhere(filterData dtos.UserQuery) (string, []interface{}) {
+	where := ` WHERE deleted_at IS NULL`
+	args := []interface{}{}
+	if filterData.Role != "" {
+		where += ` AND role = ?`
+		args = append(args, filterData.Role)
+	}
+	if filterData.Status != nil {
+		where += ` AND status = ?`
+		args = append(args, *filterData.Status)
+	}
+	if filterData.Search != "" {
+		search, searchArgs := sqlSearch(filterData.Search, "name", "email")
+		where += ` AND ` + search
+		args = append(args, searchArgs...)
+	}
+	return where, args
+}
+
+func (r userSqlRepository) FindById(ctx context.Context, id primitive.ObjectID) (model.User, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	query := r.sm.Rebind(`SELECT ` + userColumns + ` FROM users WHERE id = ? AND deleted_at IS NULL`)
+	user, err := scanUser(r.sm.DB.QueryRowContext(ctx, query, id.Hex()))
+	if err != nil {
+		return user, databaseError(err, "user")
+	}
+	return user, nil
+}
+
+func (r userSqlRepository) FindByEmail(ctx context.Context, email string) (model.User, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	query := r.sm.Rebind(`SELECT ` + userColumns + ` FROM users WHERE email = ? AND deleted_at IS NULL`)
+	user, err := scanUser(r.sm.DB.QueryRowContext(ctx, query, email))
+	if err != nil {
+		return user, databaseError(err, "user")
+	}
+	return user, nil
+}
+
+func (r userSqlRepository) UpdateById(ctx context.Context, id primitive.ObjectID, payload primitive.M, version *int64) (model.User, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	payload["updatedAt"] = time.Now().UTC()
+	var user model.User
+	err := withTx(ctx, r.sm, func(tx *sql.Tx) error {
+		var err error
+		query := r.sm.Rebind(`SELECT ` + userColumns + ` FROM users WHERE id = ? AND deleted_at IS NULL`)
+		user, err = scanUser(tx.QueryRowContext(ctx, query, id.Hex()))
+		if err != nil {
+			return err
+		}
+		if err := checkVersion("user", user.Version, version); err != nil {
+			return err
+		}
+		if err := applySet(&user, payload); err != nil {
+			return err
+		}
+		user.Version++
+		return r.update(ctx, tx, user)
+	})
+	return user, databaseError(err, "user")
+}
+
+func (r userSqlRepository) UpdateLoginTime(ctx context.Context, id primitive.ObjectID) (model.User, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	query := r.sm.Rebind(`UPDATE users SET last_login_at = ?, version = version + 1 WHERE id = ? AND deleted_at IS NULL`)
+	result, err := r.sm.DB.ExecContext(ctx, query, time.Now().UTC(), id.Hex())
+	if err != nil {
+		return model.User{}, databaseError(err, "user")
+	}
+	if count, _ := result.RowsAffected(); count == 0 {
+		return model.User{}, domain_error.NotFound("user is not found")
+	}
+	return r.FindById(ctx, id)
+}
+
+func (r userSqlRepository) DeleteById(ctx context.Context, id primitive.ObjectID, version *int64) (*mongo.DeleteResult, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	query := `UPDATE users SET deleted_at = ?, version = version + 1 WHERE id = ? AND deleted_at IS NULL`
+	args := []interface{}{time.Now().UTC(), id.Hex()}
+	if version != nil {
+		query += ` AND version = ?`
+		args = append(args, *version)
+	}
+	result, err := r.sm.DB.ExecContext(ctx, r.sm.Rebind(query), args...)
+	if err != nil {
+		return nil, databaseError(err, "user")
+	}
+	count, _ := result.RowsAffected()
+	if count != 1 {
+		if user, err := r.FindById(ctx, id); err == nil && version != nil {
+			return &mongo.DeleteResult{DeletedCount: count}, domain_error.StaleVersion("user", user.Version)
+		}
+		return &mongo.DeleteResult{DeletedCount: count}, domain_error.NotFound("user is not found")
+	}
+	return &mongo.DeleteResult{DeletedCount: count}, nil
+}
+
+func (r userSqlRepository) FindTrash(ctx context.Context) ([]model.User, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	objects := []model.User{}
+	query := `SELECT ` + userColumns + ` FROM users WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id`
+	err := queryAll(ctx, r.sm.DB, query, func(rows *sql.Rows) error {
+		user, err := scanUser(rows)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, user)
+		return nil
+	})
+	return objects, databaseError(err, "user")
+}
+
+func (r userSqlRepository) FindTrashedById(ctx context.Context, id primitive.ObjectID) (model.User, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	user, err := r.findTrashedById(ctx, r.sm.DB, id)
+	return user, databaseError(err, "user in trash")
+}
+
+func (r userSqlRepository) RestoreById(ctx context.Context, id primitive.ObjectID) (model.User, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var user model.User
+	err := withTx(ctx, r.sm, func(tx *sql.Tx) error {
+		var err error
+		user, err = r.findTrashedById(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		user.DeletedAt = nil
+		user.Version++
+		_, err = tx.ExecContext(ctx, r.sm.Rebind(`UPDATE users SET deleted_at = NULL, version = version + 1 WHERE id = ?`), id.Hex())
+		return err
+	})
+	return user, databaseError(err, "user in trash")
+}
+
+func (r userSqlRepository) PurgeById(ctx context.Context, id primitive.ObjectID) (model.User, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	var user model.User
+	err := withTx(ctx, r.sm, func(tx *sql.Tx) error {
+		var err error
+		user, err = r.findTrashedById(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, r.sm.Rebind(`DELETE FROM users WHERE id = ?`), id.Hex())
+		return err
+	})
+	return user, databaseError(err, "user in trash")
+}
+
+func (r userSqlRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := db.QueryContext(ctx)
+	defer cancel()
+	query := r.sm.Rebind(`DELETE FROM users WHERE deleted_at IS NOT NULL AND deleted_at < ?`)
+	result, err := r.sm.DB.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, databaseError(err, "user")
+	}
+	count, _ := result.RowsAffected()
+	return count, nil
+}
+
+func (r userSqlRepository) findTrashedById(ctx context.Context, executor sqlExecutor, id primitive.ObjectID) (model.User, error) {
+	query := r.sm.Rebind(`SELECT ` + userColumns + ` FROM users WHERE id = ? AND deleted_at IS NOT NULL`)
+	return scanUser(executor.QueryRowContext(ctx, query, id.Hex()))
+}
+
+// update writes the user at its new version, guarded by the version before.
+func (r userSqlRepository) update(ctx context.Context, executor sqlExecutor, user model.User) error {
+	query := r.sm.Rebind(`UPDATE users SET name = ?, email = ?, password = ?, number = ?, status = ?, role = ?, last_login_at = ?, created_at = ?, updated_at = ?, deleted_at = ?, version = ? WHERE id = ? AND version = ?`)
+	values := userValues(user)
+	result, err := executor.ExecContext(ctx, query, append(values[1:], user.ID.Hex(), user.Version-1)...)
+	return checkGuardedUpdate("user", result, err)
+}
+
+// userValues follows the order of userColumns.
+func userValues(user model.User) []interface{} {
+	var number interface{}
+	if user.Number != nil {
+		number = int64(*user.Number)
+	}
+	var lastLoginAt interface{}
+	if user.LastLoginAt != nil {
+		lastLoginAt = *user.LastLoginAt
+	}
+	return []interface{}{
+		user.ID.Hex(), user.Name, user.Email, user.Password, number, user.Status, string(user.Role), lastLoginAt, user.CreatedAt, user.UpdatedAt, nullableTime(user.DeletedAt), user.Version,
+	}
+}
+
+func scanUser(scanner rowScanner) (model.User, error) {
+	var (
+		user        model.User
+		id          string
+		role        string
+		number      sql.NullInt64
+		lastLoginAt sql.NullTime
+		deletedAt   sql.NullTime
+	)
+	err := scanner.Scan(&id, &user.Name, &user.Email, &user.Password, &number, &user.Status, &role, &lastLoginAt, &user.CreatedAt, &user.UpdatedAt, &deletedAt, &user.Version)
+	if err != nil {
+		return user, err
+	}
+	user.ID = parseId(id)
+	user.Role = enums.Role(role)
+	if number.Valid {
+		_number := uint(number.Int64)
+		user.Number = &_number
+	}
+	if lastLoginAt.Valid {
+		user.LastLoginAt = &lastLoginAt.Time
+	}
+	user.DeletedAt = parseNullableTime(deletedAt)
+	return user, nil
+}
+
+func NewUserSqlRepository() UserRepository {
+	return &userSqlRepository{
+		sm: db.GetSqlManager(),
+	}
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Products, categories and users carry a version that every write changing
+// their representation increments, the api hands it out as ETag. Conditional
+// writes pass the version they expect, nil writes unconditionally.
+
+// withVersionInc adds the version increment to a mongo update document.
+func withVersionInc(update bson.M) bson.M {
+	update["$inc"] = bson.M{"version": 1}
+	return update
+}
+
+// withVersion narrows a mongo filter to the expected version.
+func withVersion(filter bson.D, version *int64) bson.D {
+	if version == nil {
+		return filter
+	}
+	return append(append(bson.D{}, filter...), bson.E{Key: "version", Value: *version})
+}
+
+// checkVersion fails a conditional write on an outdated version.
+func checkVersion(entity string, current int64, version *int64) error {
+	if version != nil && *version != current {
+		return domain_error.StaleVersion(entity, current)
+	}
+	return nil
+}
+
+// versionMismatch tells why a conditional mongo write matched no document:
+// when one matches the filter without the version, that version is outdated.
+func versionMismatch(ctx context.Context, coll *mongo.Collection, filter bson.D, version *int64, entity string, err error) error {
+	if err != mongo.ErrNoDocuments || version == nil {
+		return err
+	}
+	var current struct {
+		Version int64 `bson:"version"`
+	}
+	if coll.FindOne(ctx, filter).Decode(&current) != nil || current.Version == *version {
+		return err
+	}
+	return domain_error.StaleVersion(entity, current.Version)
+}
+
+// checkGuardedUpdate fails an sql update guarded by the version read earlier
+// in the transaction, when a concurrent write changed it meanwhile.
+func checkGuardedUpdate(entity string, result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return domain_error.PreconditionFailed(entity + " was modified meanwhile")
+	}
+	return nil
+}
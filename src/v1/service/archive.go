@@ -0,0 +1,335 @@
+package service
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/config"
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/money"
+	"github.com/sajalmia381/store-api/src/storage"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"github.com/sajalmia381/store-api/src/v1/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	archiveFormat   = "store-api-archive"
+	archiveVersion  = 3
+	archiveManifest = "manifest.json"
+	archiveImages   = "images/"
+)
+
+// An archive is a tar file holding manifest.json and one <collection>.ndjson
+// file per collection, a document per line in relaxed extended JSON so ids and
+// dates survive the round trip. The files of the uploaded product images follow
+// as images/<key>. Version 1 archives have prices in whole units of the base
+// currency, version 2 in minor units, version 3 adds the image files.
+type ArchiveService interface {
+	Export(ctx context.Context, writer io.Writer) (dtos.ArchiveManifestDto, error)
+	Import(ctx context.Context, reader io.Reader, mode enums.ArchiveMode) (dtos.ArchiveManifestDto, error)
+}
+
+type archiveService struct {
+	repo    repository.ArchiveRepository
+	storage storage.Storage
+	cache   *CatalogCache
+}
+
+func (s archiveService) Export(ctx context.Context, writer io.Writer) (dtos.ArchiveManifestDto, error) {
+	manifest := dtos.ArchiveManifestDto{
+		Format:      archiveFormat,
+		Version:     archiveVersion,
+		Database:    config.Database,
+		CreatedAt:   time.Now().UTC(),
+		Collections: []dtos.ArchiveCollectionDto{},
+	}
+	snapshot, err := s.repo.Export(ctx)
+	if err != nil {
+		return manifest, err
+	}
+	documents := snapshot.Documents()
+	files := map[string][]byte{}
+	for _, name := range enums.COLLECTION_NAMES {
+		var buffer bytes.Buffer
+		for _, document := range documents[name] {
+			line, err := bson.MarshalExtJSON(document, false, false)
+			if err != nil {
+				return manifest, err
+			}
+			buffer.Write(line)
+			buffer.WriteByte('\n')
+		}
+		files[name] = buffer.Bytes()
+		manifest.Collections = append(manifest.Collections, dtos.ArchiveCollectionDto{Name: name, Count: len(documents[name])})
+	}
+	images, err := s.imageFiles(ctx, snapshot.Products)
+	if err != nil {
+		return manifest, err
+	}
+	manifest.Images = len(images)
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, err
+	}
+	archive := tar.NewWriter(writer)
+	if err := writeTarFile(archive, archiveManifest, data, manifest.CreatedAt); err != nil {
+		return manifest, err
+	}
+	for _, name := range enums.COLLECTION_NAMES {
+		if err := writeTarFile(archive, name+".ndjson", files[name], manifest.CreatedAt); err != nil {
+			return manifest, err
+		}
+	}
+	for _, image := range images {
+		if err := writeTarFile(archive, archiveImages+image.key, image.content, manifest.CreatedAt); err != nil {
+			return manifest, err
+		}
+	}
+	return manifest, archive.Close()
+}
+
+type imageFile struct {
+	key     string
+	content []byte
+}
+
+// imageFiles reads the stored files of the images of the products, the files
+// missing from the storage are left out.
+func (s archiveService) imageFiles(ctx context.Context, products []model.Product) ([]imageFile, error) {
+	files := []imageFile{}
+	for _, product := range products {
+		for _, image := range product.Images {
+			for _, key := range imageKeys(image) {
+				file, err := s.storage.Open(ctx, key)
+				if errors.Is(err, storage.ErrNotExist) || errors.Is(err, storage.ErrInvalidKey) {
+					log.Println("[WARN] archive export: image file", key, "is missing")
+					continue
+				}
+				if err != nil {
+					return nil, domain_error.Unavailable(err)
+				}
+				content, err := io.ReadAll(file)
+				file.Close()
+				if err != nil {
+					return nil, domain_error.Unavailable(err)
+				}
+				files = append(files, imageFile{key: key, content: content})
+			}
+		}
+	}
+	return files, nil
+}
+
+func (s archiveService) Import(ctx context.Context, reader io.Reader, mode enums.ArchiveMode) (dtos.ArchiveManifestDto, error) {
+	var manifest dtos.ArchiveManifestDto
+	if mode == "" {
+		mode = enums.ARCHIVE_MERGE
+	}
+	if mode != enums.ARCHIVE_MERGE && mode != enums.ARCHIVE_REPLACE {
+		return manifest, domain_error.Validation("mode must be " + string(enums.ARCHIVE_MERGE) + " or " + string(enums.ARCHIVE_REPLACE))
+	}
+	snapshot := dtos.StoreSnapshotDto{}
+	collections := map[string]func(line []byte) error{
+		string(enums.TOKEN_COLLECTION_NAME): func(line []byte) error {
+			var token model.Token
+			err := bson.UnmarshalExtJSON(line, false, &token)
+			snapshot.Tokens = append(snapshot.Tokens, token)
+			return err
+		},
+		string(enums.USER_COLLECTION_NAME): func(line []byte) error {
+			var user model.User
+			err := bson.UnmarshalExtJSON(line, false, &user)
+			snapshot.Users = append(snapshot.Users, user)
+			return err
+		},
+		string(enums.CATEGORY_COLLECTION_NAME): func(line []byte) error {
+			var category model.Category
+			err := bson.UnmarshalExtJSON(line, false, &category)
+			snapshot.Categories = append(snapshot.Categories, category)
+			return err
+		},
+		string(enums.PRODUCT_COLLECTION_NAME): func(line []byte) error {
+			var product model.Product
+			err := bson.UnmarshalExtJSON(line, false, &product)
+			snapshot.Products = append(snapshot.Products, product)
+			return err
+		},
+		string(enums.REVIEW_COLLECTION_NAME): func(line []byte) error {
+			var review model.Review
+			err := bson.UnmarshalExtJSON(line, false, &review)
+			snapshot.Reviews = append(snapshot.Reviews, review)
+			return err
+		},
+		string(enums.CART_COLLECTION_NAME): func(line []byte) error {
+			var cart model.Cart
+			err := bson.UnmarshalExtJSON(line, false, &cart)
+			snapshot.Carts = append(snapshot.Carts, cart)
+			return err
+		},
+		string(enums.ORDER_COLLECTION_NAME): func(line []byte) error {
+			order := primitive.M{}
+			err := bson.UnmarshalExtJSON(line, false, &order)
+			snapshot.Orders = append(snapshot.Orders, order)
+			return err
+		},
+	}
+	images := map[string][]byte{}
+	archive := tar.NewReader(reader)
+	hasManifest := false
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return manifest, domain_error.Validation("archive is not a valid tar file: " + err.Error())
+		}
+		if header.Name == archiveManifest {
+			if err := json.NewDecoder(archive).Decode(&manifest); err != nil {
+				return manifest, domain_error.Validation("archive manifest is not valid: " + err.Error())
+			}
+			if manifest.Format != archiveFormat || manifest.Version < 1 || manifest.Version > archiveVersion {
+				return manifest, domain_error.Validation(fmt.Sprintf("archive format %s version %d is not supported", manifest.Format, manifest.Version))
+			}
+			hasManifest = true
+			continue
+		}
+		if strings.HasPrefix(header.Name, archiveImages) {
+			key := strings.TrimPrefix(header.Name, archiveImages)
+			if !storage.ValidKey(key) {
+				return manifest, domain_error.Validation("archive has an invalid image file " + header.Name)
+			}
+			if images[key], err = io.ReadAll(archive); err != nil {
+				return manifest, domain_error.Validation(header.Name + ": " + err.Error())
+			}
+			continue
+		}
+		name := strings.TrimSuffix(header.Name, ".ndjson")
+		decode, ok := collections[name]
+		if !ok || name == header.Name {
+			return manifest, domain_error.Validation("archive has an unknown file " + header.Name)
+		}
+		if err := readNdjson(archive, decode); err != nil {
+			return manifest, domain_error.Validation(header.Name + ": " + err.Error())
+		}
+	}
+	if !hasManifest {
+		return manifest, domain_error.Validation("archive has no " + archiveManifest)
+	}
+	if manifest.Version < 2 {
+		scaleArchivePrices(snapshot.Products, money.Scale(config.BaseCurrency))
+	}
+	if err := s.restoreImages(ctx, snapshot.Products, images); err != nil {
+		return manifest, err
+	}
+	err := s.repo.Import(ctx, snapshot, mode)
+	s.cache.Invalidate()
+	return manifest, err
+}
+
+// restoreImages writes the image files of the archive to the storage before
+// the products refer to them. Images whose original is neither in the archive
+// nor in the storage are dropped from the products, older archives have no
+// files.
+func (s archiveService) restoreImages(ctx context.Context, products []model.Product, files map[string][]byte) error {
+	for i, product := range products {
+		images := []model.ProductImage{}
+		for _, image := range product.Images {
+			if s.hasImageFile(ctx, imageKeys(image)[0], files) {
+				images = append(images, image)
+			} else {
+				log.Println("[WARN] archive import: image", image.URL, "of", product.Slug, "has no file, it is dropped")
+			}
+		}
+		if len(images) == len(product.Images) {
+			continue
+		}
+		products[i].Images = images
+		if product.ImageSource != nil {
+			products[i].Image, products[i].ImageSource = primaryImage(images)
+		}
+	}
+	for key, content := range files {
+		if err := s.storage.Put(ctx, key, content); err != nil {
+			return domain_error.Unavailable(err)
+		}
+	}
+	return nil
+}
+
+func (s archiveService) hasImageFile(ctx context.Context, key string, files map[string][]byte) bool {
+	if _, ok := files[key]; ok {
+		return true
+	}
+	file, err := s.storage.Open(ctx, key)
+	if err != nil {
+		return false
+	}
+	file.Close()
+	return true
+}
+
+// scaleArchivePrices turns the whole unit prices of a version 1 archive into
+// minor units, like the version 20 mongo migration.
+func scaleArchivePrices(products []model.Product, scale int64) {
+	for i := range products {
+		products[i].Price *= int(scale)
+		for j := range products[i].Variants {
+			if price := products[i].Variants[j].Price; price != nil {
+				scaled := *price * int(scale)
+				products[i].Variants[j].Price = &scaled
+			}
+		}
+	}
+}
+
+func writeTarFile(archive *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := archive.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := archive.Write(data)
+	return err
+}
+
+// readNdjson hands every non empty line to decode.
+func readNdjson(reader io.Reader, decode func(line []byte) error) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for number := 1; scanner.Scan(); number++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := decode(line); err != nil {
+			return fmt.Errorf("line %d: %w", number, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func NewArchiveService(repo repository.ArchiveRepository, storage storage.Storage, cache *CatalogCache) ArchiveService {
+	return &archiveService{
+		repo:    repo,
+		storage: storage,
+		cache:   cache,
+	}
+}
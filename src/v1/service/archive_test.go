@@ -0,0 +1,155 @@
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/cache"
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/storage"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"github.com/sajalmia381/store-api/src/v1/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newTestArchiveService(files storage.Storage) ArchiveService {
+	return NewArchiveService(repository.NewArchiveMemoryRepository(), files, NewCatalogCache(cache.NewNoop()))
+}
+
+func storeTestProduct(t *testing.T, product model.Product) model.Product {
+	t.Helper()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	product.ID = primitive.NewObjectID()
+	product.Slug = strings.ToLower(product.Title) + "-" + product.ID.Hex()
+	product.Active = true
+	product.CreatedAt, product.UpdatedAt = now, now
+	if product.Images == nil {
+		product.Images = []model.ProductImage{}
+	}
+	stored, err := repository.NewProductMemoryRepository().Store(context.Background(), product)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stored
+}
+
+func TestReadNdjson(t *testing.T) {
+	lines := []string{}
+	err := readNdjson(strings.NewReader("{\"a\":1}\n\n  \n{\"b\":2}\n"), func(line []byte) error {
+		lines = append(lines, string(line))
+		return nil
+	})
+	if err != nil || len(lines) != 2 || lines[1] != `{"b":2}` {
+		t.Fatalf("expected the two documents, got %q and %v", lines, err)
+	}
+	err = readNdjson(strings.NewReader("{}\n\n{"), func(line []byte) error {
+		if string(line) == "{" {
+			return domain_error.Validation("broken")
+		}
+		return nil
+	})
+	if err == nil || !strings.HasPrefix(err.Error(), "line 3:") {
+		t.Errorf("expected an error on line 3, got %v", err)
+	}
+}
+
+func TestArchiveRoundTripReplaces(t *testing.T) {
+	ctx := context.Background()
+	archives := newTestArchiveService(storage.NewMemory())
+	kept := storeTestProduct(t, model.Product{Title: "Archived", Price: 1999, Description: "kept"})
+	var archive bytes.Buffer
+	manifest, err := archives.Export(ctx, &archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Format != archiveFormat || manifest.Version != archiveVersion {
+		t.Errorf("expected %s version %d, got %s version %d", archiveFormat, archiveVersion, manifest.Format, manifest.Version)
+	}
+	dropped := storeTestProduct(t, model.Product{Title: "Later", Price: 500, Description: "dropped"})
+
+	if _, err := archives.Import(ctx, bytes.NewReader(archive.Bytes()), enums.ARCHIVE_REPLACE); err != nil {
+		t.Fatal(err)
+	}
+	products := db.GetMemoryManager().Products
+	if product, ok := products[kept.ID]; !ok || product.Price != 1999 || product.Description != "kept" {
+		t.Errorf("expected the archived product back, got %+v", product)
+	}
+	if _, ok := products[dropped.ID]; ok {
+		t.Error("expected replace to drop the product written after the export")
+	}
+}
+
+func TestArchiveImportRejectsUnknownVersions(t *testing.T) {
+	archives := newTestArchiveService(storage.NewMemory())
+	for _, manifest := range []dtos.ArchiveManifestDto{
+		{Format: archiveFormat, Version: archiveVersion + 1},
+		{Format: "other", Version: 1},
+	} {
+		data, _ := json.Marshal(manifest)
+		var archive bytes.Buffer
+		writer := tar.NewWriter(&archive)
+		if err := writeTarFile(writer, archiveManifest, data, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		writer.Close()
+		_, err := archives.Import(context.Background(), &archive, enums.ARCHIVE_MERGE)
+		if domain_error.KindOf(err) != domain_error.VALIDATION {
+			t.Errorf("%s version %d: expected a validation error, got %v", manifest.Format, manifest.Version, err)
+		}
+	}
+}
+
+func TestScaleArchivePrices(t *testing.T) {
+	variantPrice := 12
+	products := []model.Product{{Price: 10, Variants: []model.ProductVariant{{Price: &variantPrice}, {}}}}
+	scaleArchivePrices(products, 100)
+	if products[0].Price != 1000 || *products[0].Variants[0].Price != 1200 || products[0].Variants[1].Price != nil {
+		t.Errorf("got the prices %d, %v and %v", products[0].Price, *products[0].Variants[0].Price, products[0].Variants[1].Price)
+	}
+}
+
+func TestArchiveCarriesTheImageFiles(t *testing.T) {
+	ctx := context.Background()
+	files := storage.NewMemory()
+	kept := model.ProductImage{ID: primitive.NewObjectID(), URL: imageUrlPrefix + "kept.png", Thumbnails: map[string]string{"small": imageUrlPrefix + "kept-small.png"}}
+	missing := model.ProductImage{ID: primitive.NewObjectID(), URL: imageUrlPrefix + "missing.png"}
+	for _, key := range []string{"kept.png", "kept-small.png"} {
+		if err := files.Put(ctx, key, []byte(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	product := storeTestProduct(t, model.Product{Title: "Pictured", Price: 100, Images: []model.ProductImage{missing, kept}, Image: missing.URL, ImageSource: &missing.ID})
+	var archive bytes.Buffer
+	manifest, err := newTestArchiveService(files).Export(ctx, &archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Images != 2 {
+		t.Errorf("expected the 2 stored files in the archive, got %d", manifest.Images)
+	}
+
+	restored := storage.NewMemory()
+	if _, err := newTestArchiveService(restored).Import(ctx, &archive, enums.ARCHIVE_REPLACE); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"kept.png", "kept-small.png"} {
+		if _, err := restored.Open(ctx, key); err != nil {
+			t.Errorf("expected %s in the storage, got %v", key, err)
+		}
+	}
+	imported := db.GetMemoryManager().Products[product.ID]
+	if len(imported.Images) != 1 || imported.Images[0].ID != kept.ID {
+		t.Fatalf("expected only the image with a file, got %+v", imported.Images)
+	}
+	if imported.Image != kept.URL || imported.ImageSource == nil || *imported.ImageSource != kept.ID {
+		t.Errorf("expected the kept image to become the primary one, got %s", imported.Image)
+	}
+}
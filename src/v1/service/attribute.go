@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"github.com/sajalmia381/store-api/src/v1/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxAttributeText bounds the STRING attribute values.
+const maxAttributeText = 200
+
+// categorySchema is the attribute schema of the category among the live
+// categories, the attributes of its parents first. A subcategory attribute
+// replaces the parent attribute of the same name. An unknown category has no
+// schema.
+func categorySchema(categories []model.Category, id primitive.ObjectID) []model.CategoryAttribute {
+	byId := map[primitive.ObjectID]model.Category{}
+	for _, category := range categories {
+		byId[category.ID] = category
+	}
+	lineage := []model.Category{}
+	seen := map[primitive.ObjectID]bool{}
+	category, ok := byId[id]
+	for ok && !seen[category.ID] {
+		seen[category.ID] = true
+		lineage = append([]model.Category{category}, lineage...)
+		if category.Parent == nil {
+			break
+		}
+		category, ok = byId[*category.Parent]
+	}
+	schema := []model.CategoryAttribute{}
+	positions := map[string]int{}
+	for _, category := range lineage {
+		for _, attribute := range category.Attributes {
+			if i, ok := positions[attribute.Name]; ok {
+				schema[i] = attribute
+				continue
+			}
+			positions[attribute.Name] = len(schema)
+			schema = append(schema, attribute)
+		}
+	}
+	return schema
+}
+
+// findCategorySchema reads the schema of the category, none without one.
+func findCategorySchema(ctx context.Context, categoryRepo repository.CategoryRepository, category *primitive.ObjectID) ([]model.CategoryAttribute, error) {
+	if category == nil {
+		return []model.CategoryAttribute{}, nil
+	}
+	categories, _, err := categoryRepo.FindAll(ctx, dtos.CategoryQueryParams{})
+	if err != nil {
+		return nil, err
+	}
+	return categorySchema(categories, *category), nil
+}
+
+// checkAttributes checks the attribute values against the schema and returns
+// them as they are kept: numbers as float64, strings trimmed. Null and empty
+// string values are left out.
+func checkAttributes(schema []model.CategoryAttribute, values map[string]interface{}) (map[string]interface{}, error) {
+	attributes := map[string]interface{}{}
+	defined := map[string]model.CategoryAttribute{}
+	for _, attribute := range schema {
+		defined[attribute.Name] = attribute
+	}
+	names := []string{}
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		attribute, ok := defined[name]
+		if !ok {
+			return attributes, domain_error.Validation("attribute " + name + " is not defined by the category")
+		}
+		value, err := checkAttributeValue(attribute, values[name])
+		if err != nil {
+			return attributes, err
+		}
+		if value != nil {
+			attributes[name] = value
+		}
+	}
+	for _, attribute := range schema {
+		if _, ok := attributes[attribute.Name]; attribute.Required && !ok {
+			return attributes, domain_error.Validation("attribute " + attribute.Name + " is required")
+		}
+	}
+	return attributes, nil
+}
+
+func checkAttributeValue(attribute model.CategoryAttribute, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	switch attribute.Type {
+	case enums.ATTRIBUTE_NUMBER:
+		switch number := value.(type) {
+		case float64:
+			return number, nil
+		case int:
+			return float64(number), nil
+		case int32:
+			return float64(number), nil
+		case int64:
+			return float64(number), nil
+		}
+		return nil, domain_error.Validation("attribute " + attribute.Name + " must be a number")
+	case enums.ATTRIBUTE_BOOL:
+		if _, ok := value.(bool); !ok {
+			return nil, domain_error.Validation("attribute " + attribute.Name + " must be true or false")
+		}
+		return value, nil
+	}
+	text, ok := value.(string)
+	if !ok {
+		return nil, domain_error.Validation("attribute " + attribute.Name + " must be a string")
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+	if attribute.Type == enums.ATTRIBUTE_ENUM {
+		for _, option := range attribute.Options {
+			if option == text {
+				return text, nil
+			}
+		}
+		return nil, domain_error.Validation("attribute " + attribute.Name + " must be one of " + strings.Join(attribute.Options, ", "))
+	}
+	if utf8.RuneCountInString(text) > maxAttributeText {
+		return nil, domain_error.Validation("attribute " + attribute.Name + " must not be longer than 200 characters")
+	}
+	return text, nil
+}
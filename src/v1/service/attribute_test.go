@@ -0,0 +1,75 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCategorySchemaInheritsFromTheParents(t *testing.T) {
+	root, child := primitive.NewObjectID(), primitive.NewObjectID()
+	categories := []model.Category{
+		{ID: child, Parent: &root, Attributes: []model.CategoryAttribute{
+			{Name: "ram", Type: enums.ATTRIBUTE_NUMBER, Required: true},
+			{Name: "screen", Type: enums.ATTRIBUTE_STRING},
+		}},
+		{ID: root, Attributes: []model.CategoryAttribute{
+			{Name: "brand", Type: enums.ATTRIBUTE_STRING},
+			{Name: "ram", Type: enums.ATTRIBUTE_NUMBER},
+		}},
+	}
+	schema := categorySchema(categories, child)
+	names := []string{}
+	for _, attribute := range schema {
+		names = append(names, attribute.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"brand", "ram", "screen"}) {
+		t.Fatalf("schema has %v", names)
+	}
+	if !schema[1].Required {
+		t.Error("the attribute of the subcategory should replace the one of its parent")
+	}
+	if schema := categorySchema(categories, primitive.NewObjectID()); len(schema) != 0 {
+		t.Errorf("unknown category has the schema %v", schema)
+	}
+}
+
+func TestCheckAttributes(t *testing.T) {
+	schema := []model.CategoryAttribute{
+		{Name: "ram", Type: enums.ATTRIBUTE_NUMBER, Required: true},
+		{Name: "colour", Type: enums.ATTRIBUTE_ENUM, Options: []string{"black", "white"}},
+		{Name: "wireless", Type: enums.ATTRIBUTE_BOOL},
+		{Name: "model", Type: enums.ATTRIBUTE_STRING},
+	}
+	_, err := checkAttributes(schema, map[string]interface{}{"ram": 16, "screen": nil})
+	if !domain_error.Is(err, domain_error.VALIDATION) {
+		t.Fatalf("an attribute the category does not define got %v", err)
+	}
+	attributes, err := checkAttributes(schema, map[string]interface{}{"ram": 16, "colour": " black ", "wireless": true, "model": ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"ram": float64(16), "colour": "black", "wireless": true}
+	if !reflect.DeepEqual(attributes, want) {
+		t.Errorf("got %v, want %v", attributes, want)
+	}
+	invalid := []map[string]interface{}{
+		{},
+		{"ram": nil},
+		{"ram": "16"},
+		{"ram": 16, "colour": "red"},
+		{"ram": 16, "wireless": "yes"},
+		{"ram": 16, "model": 7},
+		{"ram": 16, "model": strings.Repeat("x", maxAttributeText+1)},
+	}
+	for _, values := range invalid {
+		if _, err := checkAttributes(schema, values); !domain_error.Is(err, domain_error.VALIDATION) {
+			t.Errorf("%v got %v, want a validation error", values, err)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/utils"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"github.com/sajalmia381/store-api/src/v1/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const redactedValue = "[REDACTED]"
+
+// Fields left out of the diff, they change on every write or never change.
+var unauditedFields = map[string]bool{
+	"id":        true,
+	"updatedAt": true,
+	"version":   true,
+}
+
+// Fields recorded as changed without their values.
+var redactedFields = map[string]bool{
+	"password": true,
+}
+
+type AuditService interface {
+	// Record stores the change of an entity by the requester of ctx. before is
+	// nil for a create and after is nil for a purge. The change is already
+	// written when Record runs, so a failing record is logged and not returned.
+	Record(ctx context.Context, entity enums.AuditEntity, action enums.AuditAction, id primitive.ObjectID, before interface{}, after interface{})
+	FindAll(ctx context.Context, queryParams dtos.AuditQueryParams) ([]model.AuditLog, common.MetaData, error)
+}
+
+type auditService struct {
+	repo repository.AuditRepository
+}
+
+func (s auditService) Record(ctx context.Context, entity enums.AuditEntity, action enums.AuditAction, id primitive.ObjectID, before interface{}, after interface{}) {
+	changes := auditChanges(before, after)
+	if action == enums.AUDIT_UPDATE && len(changes) == 0 {
+		return
+	}
+	entry := model.AuditLog{
+		ID:        primitive.NewObjectID(),
+		Entity:    entity,
+		EntityId:  id,
+		Action:    action,
+		Changes:   changes,
+		CreatedAt: time.Now().UTC(),
+	}
+	if requester, ok := utils.GetRequester(ctx); ok {
+		entry.Actor = &model.AuditActor{
+			ID:    requester.ID,
+			Name:  requester.Name,
+			Email: requester.Email,
+			Role:  requester.Role,
+		}
+	}
+	if err := s.repo.Store(ctx, entry); err != nil {
+		log.Println("[ERROR] Audit log of", entity, id.Hex()+":", err.Error())
+	}
+}
+
+func (s auditService) FindAll(ctx context.Context, queryParams dtos.AuditQueryParams) ([]model.AuditLog, common.MetaData, error) {
+	if err := queryParams.Validate(); err != nil {
+		return []model.AuditLog{}, common.MetaData{}, err
+	}
+	entries, metaData, err := s.repo.FindAll(ctx, queryParams)
+	return entries, metaData, err
+}
+
+// auditChanges compares the json fields of before and after, so the diff reads
+// like the api responses whatever the database is.
+func auditChanges(before interface{}, after interface{}) []model.FieldChange {
+	oldFields, newFields := auditFields(before), auditFields(after)
+	names := []string{}
+	for name := range oldFields {
+		names = append(names, name)
+	}
+	for name := range newFields {
+		if _, ok := oldFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	changes := []model.FieldChange{}
+	for _, name := range names {
+		oldValue, newValue := oldFields[name], newFields[name]
+		if unauditedFields[name] || reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		if redactedFields[name] {
+			oldValue, newValue = redact(oldValue), redact(newValue)
+		}
+		changes = append(changes, model.FieldChange{Field: name, Old: oldValue, New: newValue})
+	}
+	return changes
+}
+
+func auditFields(object interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if object == nil {
+		return fields
+	}
+	data, err := json.Marshal(object)
+	if err != nil {
+		log.Println("[ERROR] Audit fields:", err.Error())
+		return fields
+	}
+	json.Unmarshal(data, &fields)
+	return fields
+}
+
+func redact(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	return redactedValue
+}
+
+func NewAuditService(repo repository.AuditRepository) AuditService {
+	return &auditService{
+		repo: repo,
+	}
+}
@@ -1,8 +1,9 @@
 package service
 
 import (
-	"errors"
+	"context"
 
+	"github.com/sajalmia381/store-api/src/domain_error"
 	"github.com/sajalmia381/store-api/src/enums"
 	"github.com/sajalmia381/store-api/src/v1/dtos"
 	"github.com/sajalmia381/store-api/src/v1/model"
@@ -11,50 +12,56 @@ import (
 )
 
 type AuthService interface {
-	Login(payload dtos.LoginPayload) (model.User, error)
-	Register(payload dtos.RegisterPayload) (model.User, error)
-	RefreshToken(payload dtos.RefreshTokenPayload)
-	UpdateUserLoginTime(id primitive.ObjectID) (model.User, error)
+	Login(ctx context.Context, payload dtos.LoginPayload) (model.User, error)
+	Register(ctx context.Context, payload dtos.RegisterPayload) (model.User, error)
+	RefreshToken(ctx context.Context, payload dtos.RefreshTokenPayload)
+	UpdateUserLoginTime(ctx context.Context, id primitive.ObjectID) (model.User, error)
 }
 
 type authService struct {
 	userRepo  repository.UserRepository
 	tokenRepo repository.TokenRepository
+	audit     AuditService
 }
 
-func (s authService) Login(payload dtos.LoginPayload) (model.User, error) {
-	user, err := s.userRepo.FindByEmail(payload.Email)
-	if err != nil {
-		return user, errors.New("user is not found")
-	}
-	return user, nil
+func (s authService) Login(ctx context.Context, payload dtos.LoginPayload) (model.User, error) {
+	user, err := s.userRepo.FindByEmail(ctx, payload.Email)
+	return user, err
 }
 
-func (s authService) Register(payload dtos.RegisterPayload) (model.User, error) {
-	user, err := s.userRepo.FindByEmail(payload.Email)
+func (s authService) Register(ctx context.Context, payload dtos.RegisterPayload) (model.User, error) {
+	user, err := s.userRepo.FindByEmail(ctx, payload.Email)
 	if err == nil {
-		return user, errors.New("user is exists! try with another email")
+		return user, domain_error.Conflict("user is exists! try with another email")
+	}
+	if !domain_error.Is(err, domain_error.NOT_FOUND) {
+		return user, err
 	}
 	user = mergeRegisterDataToUser(payload)
 	user.Role = enums.Role("ROLE_CUSTOMER")
 	user.Status = true
-	newUser, err := s.userRepo.Store(user)
-	return newUser, err
+	newUser, err := s.userRepo.Store(ctx, user)
+	if err != nil {
+		return newUser, err
+	}
+	s.audit.Record(ctx, enums.AUDIT_USER, enums.AUDIT_CREATE, newUser.ID, nil, newUser)
+	return newUser, nil
 }
 
-func (s authService) RefreshToken(payload dtos.RefreshTokenPayload) {
+func (s authService) RefreshToken(ctx context.Context, payload dtos.RefreshTokenPayload) {
 	panic("not implemented") // TODO: Implement
 }
 
-func (s authService) UpdateUserLoginTime(id primitive.ObjectID) (model.User, error) {
-	user, err := s.userRepo.UpdateLoginTime(id)
+func (s authService) UpdateUserLoginTime(ctx context.Context, id primitive.ObjectID) (model.User, error) {
+	user, err := s.userRepo.UpdateLoginTime(ctx, id)
 	return user, err
 }
 
-func NewAuthService(userRepo repository.UserRepository, tokenRepo repository.TokenRepository) AuthService {
+func NewAuthService(userRepo repository.UserRepository, tokenRepo repository.TokenRepository, audit AuditService) AuthService {
 	return &authService{
 		userRepo:  userRepo,
 		tokenRepo: tokenRepo,
+		audit:     audit,
 	}
 }
 
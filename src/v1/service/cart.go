@@ -1,8 +1,17 @@
 package service
 
 import (
-	"errors"
+	"context"
+	"fmt"
+	"math"
+	"time"
 
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/config"
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/money"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
 	"github.com/sajalmia381/store-api/src/v1/model"
 	"github.com/sajalmia381/store-api/src/v1/repository"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -10,72 +19,229 @@ import (
 )
 
 type CartService interface {
-	FindAll() ([]model.Cart, error)
-	FindByUserId(userId primitive.ObjectID) (model.Cart, error)
-	DeleteByUserId(userId primitive.ObjectID) (*mongo.DeleteResult, error)
+	FindAll(ctx context.Context, queryParams dtos.CartQueryParams) ([]model.Cart, common.MetaData, error)
+	FindByUserId(ctx context.Context, userId primitive.ObjectID) (model.Cart, error)
+	DeleteByUserId(ctx context.Context, userId primitive.ObjectID) (*mongo.DeleteResult, error)
 	// Requester Cart
-	UpdateCartByProduct(userId primitive.ObjectID, payload model.CartProductSpec) (model.Cart, error)
-	RemoveProductFromCart(userId primitive.ObjectID, productId string) (model.Cart, error)
-	UpdateCartByProducts(userId primitive.ObjectID, payload []model.CartProductSpec) (model.Cart, error)
+	UpdateCartByProduct(ctx context.Context, userId primitive.ObjectID, payload model.CartProductSpec) (model.Cart, error)
+	// RemoveProductFromCart removes the line of the variant, every line of the
+	// product without a variantId.
+	RemoveProductFromCart(ctx context.Context, userId primitive.ObjectID, productId string, variantId string) (model.Cart, error)
+	UpdateCartByProducts(ctx context.Context, userId primitive.ObjectID, payload []model.CartProductSpec) (model.Cart, error)
+	// History lists the changes of the cart of the user.
+	History(ctx context.Context, userId primitive.ObjectID, queryParams dtos.AuditQueryParams) ([]model.AuditLog, common.MetaData, error)
+	// Price prices the lines of the cart in the currency of the converter.
+	Price(ctx context.Context, cart model.Cart, converter money.Converter) (dtos.CartResponseDto, error)
+	// ReleaseExpiredReservations gives the stock held by lines not touched
+	// for CART_RESERVATION_TTL back and counts the lines.
+	ReleaseExpiredReservations(ctx context.Context) (int64, error)
 }
 
 type cartService struct {
-	repo repository.CartRepository
+	repo        repository.CartRepository
+	productRepo repository.ProductRepository
+	audit       AuditService
+	cache       *CatalogCache
 }
 
 // Cart CRUD
-func (s cartService) FindAll() ([]model.Cart, error) {
-	carts, err := s.repo.FindAll()
-	return carts, err
+func (s cartService) FindAll(ctx context.Context, queryParams dtos.CartQueryParams) ([]model.Cart, common.MetaData, error) {
+	carts, metaData, err := s.repo.FindAll(ctx, queryParams)
+	return carts, metaData, err
 }
 
-func (s cartService) FindByUserId(userId primitive.ObjectID) (model.Cart, error) {
-	cart, err := s.repo.FindByUserId(userId)
+func (s cartService) FindByUserId(ctx context.Context, userId primitive.ObjectID) (model.Cart, error) {
+	cart, err := s.repo.FindByUserId(ctx, userId)
 	return cart, err
 }
 
-func (s cartService) DeleteByUserId(userId primitive.ObjectID) (*mongo.DeleteResult, error) {
-	result, err := s.repo.DeleteByUserId(userId)
-	return result, err
+func (s cartService) DeleteByUserId(ctx context.Context, userId primitive.ObjectID) (*mongo.DeleteResult, error) {
+	before, err := s.repo.FindByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.repo.DeleteByUserId(ctx, userId)
+	s.cache.Invalidate()
+	if err != nil {
+		return result, err
+	}
+	s.audit.Record(ctx, enums.AUDIT_CART, enums.AUDIT_DELETE, before.ID, before, nil)
+	return result, nil
 }
 
 // Requester Cart
-func (s cartService) UpdateCartByProducts(userId primitive.ObjectID, productSpec []model.CartProductSpec) (model.Cart, error) {
-	specMap := make(map[string]uint16)
+func (s cartService) UpdateCartByProducts(ctx context.Context, userId primitive.ObjectID, productSpec []model.CartProductSpec) (model.Cart, error) {
+	// lines of the same product and variant add up
+	specMap := make(map[string]int)
+	payload := []model.CartProductSpec{}
 	for _, item := range productSpec {
-		_, ok := specMap[item.ProductId.Hex()]
-		if ok {
-			specMap[item.ProductId.Hex()] = specMap[item.ProductId.Hex()] + item.Quantity
+		key := item.ProductId.Hex()
+		if item.VariantId != nil {
+			key += "/" + item.VariantId.Hex()
+		}
+		if i, ok := specMap[key]; ok {
+			if payload[i].Quantity > math.MaxUint16-item.Quantity {
+				return model.Cart{}, domain_error.Validation(fmt.Sprintf("quantity of a line must not be greater than %d", math.MaxUint16))
+			}
+			payload[i].Quantity += item.Quantity
 			continue
 		}
-		specMap[item.ProductId.Hex()] = item.Quantity
+		if err := s.checkVariant(ctx, item); err != nil {
+			return model.Cart{}, err
+		}
+		specMap[key] = len(payload)
+		payload = append(payload, model.CartProductSpec{ProductId: item.ProductId, VariantId: item.VariantId, Quantity: item.Quantity})
 	}
-	payload := []model.CartProductSpec{}
-	for key, value := range specMap {
-		prodId, _ := primitive.ObjectIDFromHex(key)
-		payload = append(payload, model.CartProductSpec{ProductId: prodId, Quantity: value})
+	until := reservedUntil(userId)
+	for i := range payload {
+		payload[i].ReservedUntil = until
 	}
-	cart, err := s.repo.UpdateCartByProducts(userId, payload)
-	return cart, err
+	return s.update(ctx, userId, func() (model.Cart, error) {
+		return s.repo.UpdateCartByProducts(ctx, userId, payload)
+	})
 }
 
-func (s cartService) UpdateCartByProduct(userId primitive.ObjectID, payload model.CartProductSpec) (model.Cart, error) {
-	cart, err := s.repo.UpdateCartByProduct(userId, payload)
-	return cart, err
+func (s cartService) UpdateCartByProduct(ctx context.Context, userId primitive.ObjectID, payload model.CartProductSpec) (model.Cart, error) {
+	if err := s.checkVariant(ctx, payload); err != nil {
+		return model.Cart{}, err
+	}
+	payload.ReservedUntil = reservedUntil(userId)
+	return s.update(ctx, userId, func() (model.Cart, error) {
+		return s.repo.UpdateCartByProduct(ctx, userId, payload)
+	})
 }
 
-func (s cartService) RemoveProductFromCart(userId primitive.ObjectID, productId string) (model.Cart, error) {
+func (s cartService) RemoveProductFromCart(ctx context.Context, userId primitive.ObjectID, productId string, variantId string) (model.Cart, error) {
 	var cart model.Cart
 	_productId, err := primitive.ObjectIDFromHex(productId)
 	if err != nil {
-		return cart, errors.New("invalid ProductID id")
+		return cart, domain_error.Validation("invalid ProductID id")
 	}
-	cart, err = s.repo.RemoveProductFromCart(userId, _productId)
-	return cart, err
+	var _variantId *primitive.ObjectID
+	if variantId != "" {
+		id, err := primitive.ObjectIDFromHex(variantId)
+		if err != nil {
+			return cart, domain_error.Validation("invalid VariantID id")
+		}
+		_variantId = &id
+	}
+	return s.update(ctx, userId, func() (model.Cart, error) {
+		return s.repo.RemoveProductFromCart(ctx, userId, _productId, _variantId)
+	})
+}
+
+func (s cartService) ReleaseExpiredReservations(ctx context.Context) (int64, error) {
+	count, err := s.repo.ReleaseExpired(ctx, time.Now().UTC())
+	if count > 0 {
+		s.cache.Invalidate()
+	}
+	return count, err
+}
+
+func (s cartService) Price(ctx context.Context, cart model.Cart, converter money.Converter) (dtos.CartResponseDto, error) {
+	response := dtos.CartResponseDto{
+		ID:        cart.ID,
+		UserId:    cart.UserId,
+		Products:  []dtos.CartProductSpecRes{},
+		Currency:  converter.To,
+		CreatedAt: cart.CreatedAt,
+		UpdatedAt: cart.UpdatedAt,
+	}
+	for _, item := range cart.Products {
+		line := dtos.CartProductSpecRes{CartProductSpec: item}
+		product, err := s.productRepo.FindById(ctx, item.ProductId)
+		if err != nil && !domain_error.Is(err, domain_error.NOT_FOUND) {
+			return response, err
+		}
+		if err == nil {
+			price := int64(product.Price)
+			for _, variant := range product.Variants {
+				if item.VariantId != nil && variant.ID == *item.VariantId && variant.Price != nil {
+					price = int64(*variant.Price)
+				}
+			}
+			price = converter.Convert(price)
+			line.Price = &price
+			line.Total = price * int64(item.Quantity)
+			response.Total += line.Total
+		}
+		response.Products = append(response.Products, line)
+	}
+	return response, nil
+}
+
+// reservedUntil is the end of the reservation of a line of the user written
+// now, every write of a line renews it. A CART_RESERVATION_TTL of 0 reserves
+// nothing, nor does the cart of the default user that anonymous requesters
+// share.
+func reservedUntil(userId primitive.ObjectID) *time.Time {
+	if config.CartReservationTTL <= 0 || config.DefaultUserId != nil && userId == *config.DefaultUserId {
+		return nil
+	}
+	until := time.Now().UTC().Add(config.CartReservationTTL)
+	return &until
+}
+
+// checkVariant fails when the line names a variant that is not an active
+// variant of its product.
+func (s cartService) checkVariant(ctx context.Context, item model.CartProductSpec) error {
+	if item.VariantId == nil {
+		return nil
+	}
+	product, err := s.productRepo.FindById(ctx, item.ProductId)
+	if domain_error.Is(err, domain_error.NOT_FOUND) {
+		return domain_error.Validation("product is not found")
+	} else if err != nil {
+		return err
+	}
+	for _, variant := range product.Variants {
+		if variant.ID == *item.VariantId {
+			if !variant.Active {
+				return domain_error.Validation("variant is not active")
+			}
+			return nil
+		}
+	}
+	return domain_error.Validation("variant is not found")
+}
+
+func (s cartService) History(ctx context.Context, userId primitive.ObjectID, queryParams dtos.AuditQueryParams) ([]model.AuditLog, common.MetaData, error) {
+	cart, err := s.repo.FindByUserId(ctx, userId)
+	if err != nil {
+		return []model.AuditLog{}, common.MetaData{}, err
+	}
+	queryParams.Entity = enums.AUDIT_CART
+	queryParams.EntityId = cart.ID.Hex()
+	return s.audit.FindAll(ctx, queryParams)
+}
+
+// update records the write of the cart, the first write of a user creates it.
+func (s cartService) update(ctx context.Context, userId primitive.ObjectID, write func() (model.Cart, error)) (model.Cart, error) {
+	var before interface{}
+	action := enums.AUDIT_UPDATE
+	cart, err := s.repo.FindByUserId(ctx, userId)
+	if domain_error.Is(err, domain_error.NOT_FOUND) {
+		action = enums.AUDIT_CREATE
+	} else if err != nil {
+		return cart, err
+	} else {
+		before = cart
+	}
+	cart, err = write()
+	// the reservations change the available stock of the products
+	s.cache.Invalidate()
+	if err != nil {
+		return cart, err
+	}
+	s.audit.Record(ctx, enums.AUDIT_CART, action, cart.ID, before, cart)
+	return cart, nil
 }
 
-func NewCartService(cartRepo repository.CartRepository) CartService {
+func NewCartService(cartRepo repository.CartRepository, productRepo repository.ProductRepository, audit AuditService, cache *CatalogCache) CartService {
 	return &cartService{
-		repo: cartRepo,
+		repo:        cartRepo,
+		productRepo: productRepo,
+		audit:       audit,
+		cache:       cache,
 	}
 }
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestUpdateCartByProductsRejectsOverflowingLines(t *testing.T) {
+	productId := primitive.NewObjectID()
+	lines := []model.CartProductSpec{
+		{ProductId: productId, Quantity: 65000},
+		{ProductId: productId, Quantity: 1000},
+	}
+	_, err := cartService{}.UpdateCartByProducts(context.Background(), primitive.NewObjectID(), lines)
+	if domain_error.KindOf(err) != domain_error.VALIDATION {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+}
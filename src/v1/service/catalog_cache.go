@@ -0,0 +1,66 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/sajalmia381/store-api/src/cache"
+)
+
+// CatalogCache serves the product and category reads from a cache. Any write
+// to the catalog invalidates all of it: a product embeds its category and its
+// creator, a category lists its products, so a narrower invalidation misses
+// entries. Values are stored as JSON, callers never share a cached value.
+type CatalogCache struct {
+	cache cache.Cache
+	// generation prefixes the keys. A read that started before an
+	// invalidation stores its result under the old generation, where no
+	// later read looks.
+	generation uint64
+}
+
+// load decodes the cached value of key into value, on a miss find fills value
+// and it is cached. Errors are never cached.
+func (c *CatalogCache) load(key string, value interface{}, find func() error) error {
+	key = strconv.FormatUint(atomic.LoadUint64(&c.generation), 10) + ":" + key
+	if data, ok := c.cache.Get(key); ok {
+		if err := json.Unmarshal(data, value); err == nil {
+			return nil
+		}
+	}
+	if err := find(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Println("[ERROR] catalog cache:", err.Error())
+		return nil
+	}
+	c.cache.Set(key, data)
+	return nil
+}
+
+// Invalidate drops every cached read, writers call it whether or not the write
+// succeeded, a failed write may have changed part of the catalog.
+func (c *CatalogCache) Invalidate() {
+	atomic.AddUint64(&c.generation, 1)
+	c.cache.Clear()
+}
+
+// queryKey names a read by its query parameters.
+func queryKey(prefix string, queryParams interface{}) string {
+	data, err := json.Marshal(queryParams)
+	if err != nil {
+		return fmt.Sprintf("%s%+v", prefix, queryParams)
+	}
+	return prefix + string(data)
+}
+
+func NewCatalogCache(cache cache.Cache) *CatalogCache {
+	return &CatalogCache{
+		cache: cache,
+	}
+}
@@ -1,6 +1,11 @@
 package service
 
 import (
+	"context"
+	"strings"
+
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/domain_error"
 	"github.com/sajalmia381/store-api/src/enums"
 	"github.com/sajalmia381/store-api/src/utils"
 	"github.com/sajalmia381/store-api/src/v1/dtos"
@@ -12,50 +17,112 @@ import (
 )
 
 type CategoryService interface {
-	Store(payload dtos.CategoryStoreDto) (model.Category, error)
-	FindAll() ([]model.Category, error)
-	FindBySlug(slug string) (model.Category, error)
-	UpdateBySlug(slug string, payload dtos.CategoryUpdateDto) (model.Category, error)
-	DeleteBySlug(slug string) (*mongo.DeleteResult, error)
-	PushProductToCategory(categoryId primitive.ObjectID, productId primitive.ObjectID) error
-	RemoveProductFromCategory(categoryId primitive.ObjectID, productId primitive.ObjectID) error
-	ChangeProductInCategory(categoryId primitive.ObjectID, oldProductId primitive.ObjectID, newProductId primitive.ObjectID) error
+	Store(ctx context.Context, payload dtos.CategoryStoreDto) (model.Category, error)
+	FindAll(ctx context.Context, queryParams dtos.CategoryQueryParams) ([]model.Category, common.MetaData, error)
+	FindBySlug(ctx context.Context, slug string) (model.Category, error)
+	// FindSchema is the attribute schema of the products of a live category,
+	// its attributes and the ones it inherits.
+	FindSchema(ctx context.Context, slug string) ([]model.CategoryAttribute, error)
+	// UpdateBySlug and DeleteBySlug write only while the category is at the
+	// given version, nil writes unconditionally.
+	UpdateBySlug(ctx context.Context, slug string, payload dtos.CategoryUpdateDto, version *int64) (model.Category, error)
+	DeleteBySlug(ctx context.Context, slug string, version *int64) (*mongo.DeleteResult, error)
+	ReconcileProducts(ctx context.Context, dryRun bool) ([]dtos.CategoryDriftDto, error)
+	// Trash
+	FindTrash(ctx context.Context) ([]model.Category, error)
+	RestoreBySlug(ctx context.Context, slug string) (model.Category, error)
+	PurgeBySlug(ctx context.Context, slug string) (model.Category, error)
+	// History lists the changes of a live or trashed category.
+	History(ctx context.Context, slug string, queryParams dtos.AuditQueryParams) ([]model.AuditLog, common.MetaData, error)
 	// Fake Action
-	FakeStore(payload dtos.CategoryStoreDto) model.Category
-	FakeUpdateBySlug(slug string, payload dtos.CategoryUpdateDto) (model.Category, error)
+	FakeStore(ctx context.Context, payload dtos.CategoryStoreDto) model.Category
+	FakeUpdateBySlug(ctx context.Context, slug string, payload dtos.CategoryUpdateDto, version *int64) (model.Category, error)
+	FakeDeleteBySlug(ctx context.Context, slug string, version *int64) (model.Category, error)
 }
 
 type categoryService struct {
-	repo repository.CategoryRepository
+	repo  repository.CategoryRepository
+	audit AuditService
+	cache *CatalogCache
+}
+
+// categoryPage is the cached result of FindAll.
+type categoryPage struct {
+	Categories []model.Category `json:"categories"`
+	MetaData   common.MetaData  `json:"metaData"`
 }
 
-func (s categoryService) Store(payload dtos.CategoryStoreDto) (model.Category, error) {
+func (s categoryService) Store(ctx context.Context, payload dtos.CategoryStoreDto) (model.Category, error) {
 	category := model.Category{
 		ID:          primitive.NewObjectID(),
 		Name:        payload.Name,
 		Description: payload.Description,
 		Products:    []primitive.ObjectID{},
+		Attributes:  newAttributeSchema(payload.Attributes),
 	}
 
-	category, err := s.repo.Store(category)
+	category, err := s.repo.Store(ctx, category)
+	s.cache.Invalidate()
 	if err != nil {
 		return category, err
 	}
-	return category, err
+	s.audit.Record(ctx, enums.AUDIT_CATEGORY, enums.AUDIT_CREATE, category.ID, nil, category)
+	return category, nil
 }
 
-func (s categoryService) FindAll() ([]model.Category, error) {
-	objects, err := s.repo.FindAll()
-	return objects, err
+func (s categoryService) FindAll(ctx context.Context, queryParams dtos.CategoryQueryParams) ([]model.Category, common.MetaData, error) {
+	var page categoryPage
+	err := s.cache.load(queryKey("categories:", queryParams), &page, func() (err error) {
+		page.Categories, page.MetaData, err = s.repo.FindAll(ctx, queryParams)
+		return err
+	})
+	return page.Categories, page.MetaData, err
 }
 
-func (s categoryService) FindBySlug(slug string) (model.Category, error) {
-	category, err := s.repo.FindBySlug(slug)
+func (s categoryService) FindBySlug(ctx context.Context, slug string) (model.Category, error) {
+	var category model.Category
+	err := s.cache.load("category:"+slug, &category, func() (err error) {
+		category, err = s.repo.FindBySlug(ctx, slug)
+		return err
+	})
 	return category, err
 }
 
-func (s categoryService) UpdateBySlug(slug string, formData dtos.CategoryUpdateDto) (model.Category, error) {
+func (s categoryService) FindSchema(ctx context.Context, slug string) ([]model.CategoryAttribute, error) {
+	category, err := s.FindBySlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	categories, _, err := s.repo.FindAll(ctx, dtos.CategoryQueryParams{})
+	if err != nil {
+		return nil, err
+	}
+	return categorySchema(categories, category.ID), nil
+}
+
+// newAttributeSchema trims the labels, units and options of the schema. The
+// products written before a schema change keep their attributes until they
+// are written again.
+func newAttributeSchema(attributes []model.CategoryAttribute) []model.CategoryAttribute {
+	schema := []model.CategoryAttribute{}
+	for _, attribute := range attributes {
+		attribute.Label = strings.TrimSpace(attribute.Label)
+		attribute.Unit = strings.TrimSpace(attribute.Unit)
+		options := []string{}
+		for _, option := range attribute.Options {
+			options = append(options, strings.TrimSpace(option))
+		}
+		attribute.Options = options
+		schema = append(schema, attribute)
+	}
+	return schema
+}
 
+func (s categoryService) UpdateBySlug(ctx context.Context, slug string, formData dtos.CategoryUpdateDto, version *int64) (model.Category, error) {
+	before, err := s.repo.FindBySlug(ctx, slug)
+	if err != nil {
+		return before, err
+	}
 	payload := bson.M{}
 	if formData.Name != "" {
 		payload["name"] = formData.Name
@@ -64,49 +131,108 @@ func (s categoryService) UpdateBySlug(slug string, formData dtos.CategoryUpdateD
 		payload["description"] = formData.Description
 	}
 	if formData.UpdateSlug && formData.Name != "" {
-		payload["slug"] = utils.GenerateUniqueSlug(formData.Name, string(enums.CATEGORY_COLLECTION_NAME), slug)
+		payload["slug"] = utils.GenerateUniqueSlug(ctx, formData.Name, s.repo.IsSlugExists, slug)
 	}
-	category, err := s.repo.UpdateBySlug(slug, payload)
-	return category, err
+	if formData.Attributes != nil {
+		payload["attributes"] = newAttributeSchema(*formData.Attributes)
+	}
+	category, err := s.repo.UpdateBySlug(ctx, slug, payload, version)
+	s.cache.Invalidate()
+	if err != nil {
+		return category, err
+	}
+	s.audit.Record(ctx, enums.AUDIT_CATEGORY, enums.AUDIT_UPDATE, category.ID, before, category)
+	return category, nil
+}
+
+func (s categoryService) DeleteBySlug(ctx context.Context, slug string, version *int64) (*mongo.DeleteResult, error) {
+	before, err := s.repo.FindBySlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.repo.DeleteBySlug(ctx, slug, version)
+	s.cache.Invalidate()
+	if err != nil {
+		return result, err
+	}
+	if after, err := s.repo.FindTrashedBySlug(ctx, slug); err == nil {
+		s.audit.Record(ctx, enums.AUDIT_CATEGORY, enums.AUDIT_DELETE, after.ID, before, after)
+	}
+	return result, nil
 }
 
-func (s categoryService) DeleteBySlug(slug string) (*mongo.DeleteResult, error) {
-	result, err := s.repo.DeleteBySlug(slug)
-	return result, err
+func (s categoryService) ReconcileProducts(ctx context.Context, dryRun bool) ([]dtos.CategoryDriftDto, error) {
+	drifts, err := s.repo.ReconcileProducts(ctx, dryRun)
+	if !dryRun {
+		s.cache.Invalidate()
+	}
+	return drifts, err
 }
 
-func (s categoryService) PushProductToCategory(categoryId primitive.ObjectID, productId primitive.ObjectID) error {
-	err := s.repo.PushProductToCategory(categoryId, productId)
-	return err
+func (s categoryService) FindTrash(ctx context.Context) ([]model.Category, error) {
+	categories, err := s.repo.FindTrash(ctx)
+	return categories, err
 }
 
-func (s categoryService) RemoveProductFromCategory(categoryId primitive.ObjectID, productId primitive.ObjectID) error {
-	err := s.repo.RemoveProductFromCategory(categoryId, productId)
-	return err
+func (s categoryService) RestoreBySlug(ctx context.Context, slug string) (model.Category, error) {
+	before, err := s.repo.FindTrashedBySlug(ctx, slug)
+	if err != nil {
+		return before, err
+	}
+	category, err := s.repo.RestoreBySlug(ctx, slug)
+	s.cache.Invalidate()
+	if err != nil {
+		return category, err
+	}
+	s.audit.Record(ctx, enums.AUDIT_CATEGORY, enums.AUDIT_RESTORE, category.ID, before, category)
+	return category, nil
 }
 
-func (s categoryService) ChangeProductInCategory(categoryId primitive.ObjectID, oldProductId primitive.ObjectID, newProductId primitive.ObjectID) error {
-	err := s.repo.ChangeProductInCategory(categoryId, oldProductId, newProductId)
-	return err
+func (s categoryService) PurgeBySlug(ctx context.Context, slug string) (model.Category, error) {
+	category, err := s.repo.PurgeBySlug(ctx, slug)
+	s.cache.Invalidate()
+	if err != nil {
+		return category, err
+	}
+	s.audit.Record(ctx, enums.AUDIT_CATEGORY, enums.AUDIT_PURGE, category.ID, category, nil)
+	return category, nil
+}
+
+func (s categoryService) History(ctx context.Context, slug string, queryParams dtos.AuditQueryParams) ([]model.AuditLog, common.MetaData, error) {
+	category, err := s.repo.FindBySlug(ctx, slug)
+	if domain_error.Is(err, domain_error.NOT_FOUND) {
+		category, err = s.repo.FindTrashedBySlug(ctx, slug)
+		if domain_error.Is(err, domain_error.NOT_FOUND) {
+			err = domain_error.NotFound("category is not found")
+		}
+	}
+	if err != nil {
+		return []model.AuditLog{}, common.MetaData{}, err
+	}
+	queryParams.Entity = enums.AUDIT_CATEGORY
+	queryParams.EntityId = category.ID.Hex()
+	return s.audit.FindAll(ctx, queryParams)
 }
 
 // Fake
-func (s categoryService) FakeStore(payload dtos.CategoryStoreDto) model.Category {
+func (s categoryService) FakeStore(ctx context.Context, payload dtos.CategoryStoreDto) model.Category {
 	category := model.Category{
 		ID:          primitive.NewObjectID(),
 		Name:        payload.Name,
 		Description: payload.Description,
 		Products:    []primitive.ObjectID{},
 		Slug:        utils.GenerateFakeUniqueSlug(payload.Name, false),
+		Attributes:  newAttributeSchema(payload.Attributes),
 	}
 	return category
 }
 
-func (s categoryService) FakeUpdateBySlug(slug string, payload dtos.CategoryUpdateDto) (model.Category, error) {
-	category, err := s.FindBySlug(slug)
+func (s categoryService) FakeUpdateBySlug(ctx context.Context, slug string, payload dtos.CategoryUpdateDto, version *int64) (model.Category, error) {
+	category, err := s.findAtVersion(ctx, slug, version)
 	if err != nil {
 		return category, err
 	}
+	category.Version++
 	if payload.Name != "" {
 		category.Name = payload.Name
 	}
@@ -116,11 +242,33 @@ func (s categoryService) FakeUpdateBySlug(slug string, payload dtos.CategoryUpda
 	if payload.UpdateSlug && payload.Name != "" {
 		category.Slug = utils.GenerateFakeUniqueSlug(payload.Name, false)
 	}
+	if payload.Attributes != nil {
+		category.Attributes = newAttributeSchema(*payload.Attributes)
+	}
+	return category, nil
+}
+
+func (s categoryService) FakeDeleteBySlug(ctx context.Context, slug string, version *int64) (model.Category, error) {
+	return s.findAtVersion(ctx, slug, version)
+}
+
+// findAtVersion checks the category like a conditional write does, for the
+// fake writes that change nothing.
+func (s categoryService) findAtVersion(ctx context.Context, slug string, version *int64) (model.Category, error) {
+	category, err := s.repo.FindBySlug(ctx, slug)
+	if err != nil {
+		return category, err
+	}
+	if version != nil && *version != category.Version {
+		return category, domain_error.StaleVersion("category", category.Version)
+	}
 	return category, nil
 }
 
-func NewCategoryService(repo repository.CategoryRepository) CategoryService {
+func NewCategoryService(repo repository.CategoryRepository, audit AuditService, cache *CatalogCache) CategoryService {
 	return &categoryService{
-		repo: repo,
+		repo:  repo,
+		audit: audit,
+		cache: cache,
 	}
 }
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/sajalmia381/store-api/src/config"
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/money"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"github.com/sajalmia381/store-api/src/v1/repository"
+)
+
+type CurrencyService interface {
+	// FindAll lists the base currency first, then the currencies with an
+	// exchange rate.
+	FindAll(ctx context.Context) ([]dtos.CurrencyDto, error)
+	// StoreRate sets the exchange rate of a currency other than the base
+	// currency.
+	StoreRate(ctx context.Context, code string, payload dtos.ExchangeRateDto) (model.ExchangeRate, error)
+	DeleteRate(ctx context.Context, code string) error
+	// Converter converts prices of the base currency to the currency, an
+	// empty code keeps the base currency.
+	Converter(ctx context.Context, code string) (money.Converter, error)
+}
+
+type currencyService struct {
+	repo  repository.ExchangeRateRepository
+	cache *CatalogCache
+}
+
+func (s currencyService) FindAll(ctx context.Context) ([]dtos.CurrencyDto, error) {
+	currencies := []dtos.CurrencyDto{{
+		Code:   config.BaseCurrency,
+		Digits: money.Digits(config.BaseCurrency),
+		Rate:   1,
+		Base:   true,
+	}}
+	rates, err := s.repo.FindAll(ctx)
+	if err != nil {
+		return currencies, err
+	}
+	for i := range rates {
+		// the rate of a former base currency is left over
+		if rates[i].Currency == config.BaseCurrency {
+			continue
+		}
+		currencies = append(currencies, dtos.CurrencyDto{
+			Code:      rates[i].Currency,
+			Digits:    money.Digits(rates[i].Currency),
+			Rate:      rates[i].Rate,
+			UpdatedAt: &rates[i].UpdatedAt,
+		})
+	}
+	return currencies, nil
+}
+
+func (s currencyService) StoreRate(ctx context.Context, code string, payload dtos.ExchangeRateDto) (model.ExchangeRate, error) {
+	code, err := currencyCode(code)
+	if err != nil {
+		return model.ExchangeRate{}, err
+	}
+	if code == config.BaseCurrency {
+		return model.ExchangeRate{}, domain_error.Validation("the base currency " + code + " has no exchange rate")
+	}
+	rate, err := s.repo.Store(ctx, model.ExchangeRate{Currency: code, Rate: payload.Rate})
+	// the converted prices change with the rate
+	s.cache.Invalidate()
+	return rate, err
+}
+
+func (s currencyService) DeleteRate(ctx context.Context, code string) error {
+	code, err := currencyCode(code)
+	if err != nil {
+		return err
+	}
+	result, err := s.repo.DeleteByCurrency(ctx, code)
+	s.cache.Invalidate()
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return domain_error.NotFound("exchange rate is not found")
+	}
+	return nil
+}
+
+func (s currencyService) Converter(ctx context.Context, code string) (money.Converter, error) {
+	if strings.TrimSpace(code) == "" {
+		return money.Identity(config.BaseCurrency), nil
+	}
+	code, err := currencyCode(code)
+	if err != nil {
+		return money.Converter{}, err
+	}
+	if code == config.BaseCurrency {
+		return money.Identity(code), nil
+	}
+	var rate model.ExchangeRate
+	err = s.cache.load("currencies:"+code, &rate, func() (err error) {
+		rate, err = s.repo.FindByCurrency(ctx, code)
+		return err
+	})
+	if domain_error.Is(err, domain_error.NOT_FOUND) {
+		return money.Converter{}, domain_error.Validation("currency " + code + " has no exchange rate")
+	} else if err != nil {
+		return money.Converter{}, err
+	}
+	return money.Converter{From: config.BaseCurrency, To: code, Rate: rate.Rate}, nil
+}
+
+// currencyCode reads an ISO 4217 code in any case.
+func currencyCode(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !money.IsCurrency(code) {
+		return code, domain_error.Validation("currency " + code + " is not supported")
+	}
+	return code, nil
+}
+
+// PriceProduct converts the prices of the product and of its variants. The
+// variants are copied first, the repositories may share them.
+func PriceProduct(converter money.Converter, product *model.Product) {
+	product.Price = int(converter.Convert(int64(product.Price)))
+	product.Currency = converter.To
+	product.Variants = priceVariants(converter, product.Variants)
+}
+
+// PriceProducts converts the prices of the listed products.
+func PriceProducts(converter money.Converter, products []dtos.ProductResponseDto) {
+	for i := range products {
+		if products[i].Price != nil {
+			price := int(converter.Convert(int64(*products[i].Price)))
+			products[i].Price = &price
+		}
+		products[i].Currency = converter.To
+		products[i].Variants = priceVariants(converter, products[i].Variants)
+	}
+}
+
+// priceVariants returns a converted copy of the variants.
+func priceVariants(converter money.Converter, variants []model.ProductVariant) []model.ProductVariant {
+	if variants == nil {
+		return nil
+	}
+	priced := append([]model.ProductVariant(nil), variants...)
+	for i := range priced {
+		if priced[i].Price != nil {
+			price := int(converter.Convert(int64(*priced[i].Price)))
+			priced[i].Price = &price
+		}
+	}
+	return priced
+}
+
+func NewCurrencyService(repo repository.ExchangeRateRepository, cache *CatalogCache) CurrencyService {
+	return &currencyService{
+		repo:  repo,
+		cache: cache,
+	}
+}
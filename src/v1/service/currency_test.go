@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/money"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"github.com/sajalmia381/store-api/src/v1/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPriceProductKeepsStoredPrices(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewProductMemoryRepository()
+	variantPrice := 1000
+	product := model.Product{
+		ID:        primitive.NewObjectID(),
+		Title:     "Priced Twice",
+		Slug:      "priced-twice-" + primitive.NewObjectID().Hex(),
+		Price:     2000,
+		Active:    true,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		Variants:  []model.ProductVariant{{ID: primitive.NewObjectID(), SKU: "PRICED-" + primitive.NewObjectID().Hex(), Price: &variantPrice, Active: true}},
+		Images:    []model.ProductImage{},
+	}
+	if _, err := repo.Store(ctx, product); err != nil {
+		t.Fatal(err)
+	}
+	converter := money.Converter{From: "USD", To: "JPY", Rate: 1.5}
+	for i := 0; i < 2; i++ {
+		found, err := repo.FindBySlug(ctx, product.Slug)
+		if err != nil {
+			t.Fatal(err)
+		}
+		PriceProduct(converter, &found)
+		if *found.Variants[0].Price != 15 {
+			t.Fatalf("read %d: variant price is %d, want 15", i, *found.Variants[0].Price)
+		}
+		products, _, err := repo.FindAll(ctx, dtos.ProductQueryParams{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		PriceProducts(converter, products)
+	}
+	stored, err := repo.FindBySlug(ctx, product.Slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Price != 2000 || *stored.Variants[0].Price != 1000 {
+		t.Fatalf("stored prices changed to %d and %d", stored.Price, *stored.Variants[0].Price)
+	}
+}
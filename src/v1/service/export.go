@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/sajalmia381/store-api/src/config"
+	"github.com/sajalmia381/store-api/src/spreadsheet"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"github.com/sajalmia381/store-api/src/v1/repository"
+)
+
+// ExportService writes the lists of products, categories and users to a
+// spreadsheet, a header row and then a row per element as it is read. The
+// lists are filtered and ordered like the list endpoints, but not paged.
+type ExportService interface {
+	Products(ctx context.Context, queryParams dtos.ProductQueryParams, writer spreadsheet.Writer) error
+	Categories(ctx context.Context, queryParams dtos.CategoryQueryParams, writer spreadsheet.Writer) error
+	Users(ctx context.Context, queryParams dtos.UserQuery, writer spreadsheet.Writer) error
+}
+
+type exportService struct {
+	productRepo  repository.ProductRepository
+	categoryRepo repository.CategoryRepository
+	userRepo     repository.UserRepository
+}
+
+func (s exportService) Products(ctx context.Context, queryParams dtos.ProductQueryParams, writer spreadsheet.Writer) error {
+	err := writer.WriteRow("id", "slug", "title", "price", "currency", "description", "category", "active", "stock", "available",
+		"rating", "reviewCount", "attributes", "image", "createdBy", "createdAt", "updatedAt")
+	if err != nil {
+		return err
+	}
+	return s.productRepo.Each(ctx, queryParams, func(product dtos.ProductResponseDto) error {
+		// the category by slug, as the import reads it
+		category, createdBy := "", ""
+		if product.Category != nil {
+			category = product.Category.Slug
+		}
+		if product.CreatedBy != nil {
+			createdBy = product.CreatedBy.Email
+		}
+		// the attributes as a json object, as the import reads them
+		attributes := ""
+		if len(product.Attributes) > 0 {
+			data, err := json.Marshal(product.Attributes)
+			if err != nil {
+				return err
+			}
+			attributes = string(data)
+		}
+		return writer.WriteRow(product.ID.Hex(), product.Slug, product.Title, product.Price, config.BaseCurrency, product.Description, category,
+			product.Active, product.Stock, product.Available, product.Rating, product.ReviewCount, attributes, product.Image, createdBy,
+			product.CreatedAt, product.UpdatedAt)
+	})
+}
+
+func (s exportService) Categories(ctx context.Context, queryParams dtos.CategoryQueryParams, writer spreadsheet.Writer) error {
+	if err := writer.WriteRow("id", "name", "slug", "parent", "description", "products"); err != nil {
+		return err
+	}
+	return s.categoryRepo.Each(ctx, queryParams, func(category model.Category) error {
+		parent := ""
+		if category.Parent != nil {
+			parent = category.Parent.Hex()
+		}
+		return writer.WriteRow(category.ID.Hex(), category.Name, category.Slug, parent, category.Description, len(category.Products))
+	})
+}
+
+// Users leaves out the passwords.
+func (s exportService) Users(ctx context.Context, queryParams dtos.UserQuery, writer spreadsheet.Writer) error {
+	err := writer.WriteRow("id", "name", "email", "number", "role", "status", "lastLoginAt", "createdAt", "updatedAt")
+	if err != nil {
+		return err
+	}
+	return s.userRepo.Each(ctx, queryParams, func(user model.User) error {
+		return writer.WriteRow(user.ID.Hex(), user.Name, user.Email, user.Number, string(user.Role), user.Status,
+			user.LastLoginAt, user.CreatedAt, user.UpdatedAt)
+	})
+}
+
+func NewExportService(productRepo repository.ProductRepository, categoryRepo repository.CategoryRepository, userRepo repository.UserRepository) ExportService {
+	return &exportService{
+		productRepo:  productRepo,
+		categoryRepo: categoryRepo,
+		userRepo:     userRepo,
+	}
+}
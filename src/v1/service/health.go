@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+)
+
+type HealthService interface {
+	// Check is UP while the database answers a ping.
+	Check(ctx context.Context) dtos.HealthDto
+}
+
+type healthService struct {
+	checker db.HealthChecker
+}
+
+func (s healthService) Check(ctx context.Context) dtos.HealthDto {
+	health := dtos.HealthDto{
+		Status:   enums.HEALTH_UP,
+		Database: s.checker.Health(ctx),
+	}
+	if !health.Database.Reachable {
+		health.Status = enums.HEALTH_DOWN
+	}
+	return health
+}
+
+func NewHealthService(checker db.HealthChecker) HealthService {
+	return &healthService{
+		checker: checker,
+	}
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/imaging"
+	"github.com/sajalmia381/store-api/src/storage"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ImageService interface {
+	// Store checks the upload and writes it with its thumbnails, Check only
+	// checks it for the fake writes.
+	Store(ctx context.Context, upload dtos.ProductImageUpload) (model.ProductImage, error)
+	Check(upload dtos.ProductImageUpload) (model.ProductImage, error)
+	// Delete removes the files of the images, failures are only logged.
+	Delete(ctx context.Context, images ...model.ProductImage)
+	// Open returns the file at key of the image urls with its content type.
+	Open(ctx context.Context, key string) (io.ReadCloser, string, error)
+}
+
+// imageUrlPrefix is where the api serves the image storage.
+const imageUrlPrefix = "/v1/images/"
+
+// maxImagePixels refuses images that would take too much memory to decode.
+const maxImagePixels = 40000000
+
+// imageSizes are the names and the largest side of the thumbnails.
+var imageSizes = []struct {
+	Name string
+	Size int
+}{
+	{"small", 160},
+	{"medium", 480},
+	{"large", 1024},
+}
+
+type imageService struct {
+	storage storage.Storage
+	maxSize int
+}
+
+func (s imageService) Store(ctx context.Context, upload dtos.ProductImageUpload) (model.ProductImage, error) {
+	image, files, err := s.prepare(upload)
+	if err != nil {
+		return image, err
+	}
+	for key, content := range files {
+		if err := s.storage.Put(ctx, key, content); err != nil {
+			s.Delete(ctx, image)
+			return image, domain_error.Unavailable(err)
+		}
+	}
+	return image, nil
+}
+
+func (s imageService) Check(upload dtos.ProductImageUpload) (model.ProductImage, error) {
+	image, _, err := s.prepare(upload)
+	return image, err
+}
+
+// prepare decodes the upload and encodes its thumbnails, it returns the image
+// and its files by key. The original is kept as it was uploaded.
+func (s imageService) prepare(upload dtos.ProductImageUpload) (model.ProductImage, map[string][]byte, error) {
+	var image model.ProductImage
+	if len(upload.Content) == 0 {
+		return image, nil, domain_error.Validation(upload.Name + " is empty")
+	}
+	if len(upload.Content) > s.maxSize {
+		return image, nil, domain_error.Validation(fmt.Sprintf("%s is larger than %d bytes", upload.Name, s.maxSize))
+	}
+	decoded, format, err := imaging.Decode(upload.Content, maxImagePixels)
+	if err != nil {
+		return image, nil, domain_error.Validation(upload.Name + ": " + err.Error())
+	}
+	image = model.ProductImage{
+		ID:          primitive.NewObjectID(),
+		ContentType: imaging.ContentType(format),
+		Width:       decoded.Bounds().Dx(),
+		Height:      decoded.Bounds().Dy(),
+		Size:        int64(len(upload.Content)),
+		Thumbnails:  map[string]string{},
+		CreatedAt:   time.Now().UTC(),
+	}
+	key := image.ID.Hex() + "/original." + imaging.Extension(format)
+	files := map[string][]byte{key: upload.Content}
+	image.URL = imageUrlPrefix + key
+	thumbnailFormat := imaging.ThumbnailFormat(format)
+	for _, size := range imageSizes {
+		content, err := imaging.Encode(imaging.Fit(decoded, size.Size), thumbnailFormat)
+		if err != nil {
+			return image, nil, err
+		}
+		key := image.ID.Hex() + "/" + size.Name + "." + imaging.Extension(thumbnailFormat)
+		files[key] = content
+		image.Thumbnails[size.Name] = imageUrlPrefix + key
+	}
+	return image, files, nil
+}
+
+func (s imageService) Delete(ctx context.Context, images ...model.ProductImage) {
+	for _, image := range images {
+		for _, key := range imageKeys(image) {
+			if err := s.storage.Delete(ctx, key); err != nil {
+				log.Println("[ERROR] Failed to delete image file", key+":", err.Error())
+			}
+		}
+	}
+}
+
+func (s imageService) Open(ctx context.Context, key string) (io.ReadCloser, string, error) {
+	file, err := s.storage.Open(ctx, key)
+	if errors.Is(err, storage.ErrNotExist) || errors.Is(err, storage.ErrInvalidKey) {
+		return nil, "", domain_error.NotFound("image is not found")
+	}
+	if err != nil {
+		return nil, "", domain_error.Unavailable(err)
+	}
+	return file, mime.TypeByExtension(path.Ext(key)), nil
+}
+
+// imageKeys are the storage keys of the original and the thumbnails of the
+// image, the original first.
+func imageKeys(image model.ProductImage) []string {
+	keys := []string{strings.TrimPrefix(image.URL, imageUrlPrefix)}
+	for _, url := range image.Thumbnails {
+		keys = append(keys, strings.TrimPrefix(url, imageUrlPrefix))
+	}
+	sort.Strings(keys[1:])
+	return keys
+}
+
+func NewImageService(storage storage.Storage, maxSize int) ImageService {
+	return &imageService{
+		storage: storage,
+		maxSize: maxSize,
+	}
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	"github.com/sajalmia381/store-api/src/v1/db"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+)
+
+type MigrationService interface {
+	FindAll(ctx context.Context) ([]dtos.MigrationStatusDto, error)
+	Migrate(ctx context.Context) ([]dtos.MigrationStatusDto, error)
+}
+
+type migrationService struct {
+	migrator db.Migrator
+	cache    *CatalogCache
+}
+
+func (s migrationService) FindAll(ctx context.Context) ([]dtos.MigrationStatusDto, error) {
+	statuses, err := s.migrator.MigrationStatus(ctx)
+	return statuses, err
+}
+
+func (s migrationService) Migrate(ctx context.Context) ([]dtos.MigrationStatusDto, error) {
+	statuses, err := s.migrator.Migrate(ctx)
+	s.cache.Invalidate()
+	return statuses, err
+}
+
+func NewMigrationService(migrator db.Migrator, cache *CatalogCache) MigrationService {
+	return &migrationService{
+		migrator: migrator,
+		cache:    cache,
+	}
+}
@@ -1,12 +1,15 @@
 package service
 
 import (
-	"errors"
+	"context"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/domain_error"
 	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/search"
 	"github.com/sajalmia381/store-api/src/utils"
 	"github.com/sajalmia381/store-api/src/v1/dtos"
 	"github.com/sajalmia381/store-api/src/v1/model"
@@ -15,22 +18,65 @@ import (
 )
 
 type ProductService interface {
-	Store(payload dtos.ProductStoreDto) (model.Product, error)
-	FindAll(queryParams dtos.ProductQueryParams) ([]dtos.ProductResponseDto, common.MetaData, error)
-	FindBySlug(slug string) (model.Product, error)
-	UpdateBySlug(slug string, payload dtos.ProductUpdateDto) (model.Product, error)
-	DeleteBySlug(slug string) (model.Product, error)
+	Store(ctx context.Context, payload dtos.ProductStoreDto) (model.Product, error)
+	FindAll(ctx context.Context, queryParams dtos.ProductQueryParams) ([]dtos.ProductResponseDto, common.MetaData, error)
+	FindBySlug(ctx context.Context, slug string) (model.Product, error)
+	// UpdateBySlug and DeleteBySlug write only while the product is at the
+	// given version, nil writes unconditionally.
+	UpdateBySlug(ctx context.Context, slug string, payload dtos.ProductUpdateDto, version *int64) (model.Product, error)
+	DeleteBySlug(ctx context.Context, slug string, version *int64) (model.Product, error)
+	// Trash
+	FindTrash(ctx context.Context) ([]model.Product, error)
+	RestoreBySlug(ctx context.Context, slug string) (model.Product, error)
+	PurgeBySlug(ctx context.Context, slug string) (model.Product, error)
+	// History lists the changes of a live or trashed product.
+	History(ctx context.Context, slug string, queryParams dtos.AuditQueryParams) ([]model.AuditLog, common.MetaData, error)
+	// Variants are written like the product, only while it is at the given
+	// version. They return the product written and the variant.
+	StoreVariant(ctx context.Context, slug string, payload dtos.ProductVariantDto, version *int64) (model.Product, model.ProductVariant, error)
+	UpdateVariant(ctx context.Context, slug string, id string, payload dtos.ProductVariantDto, version *int64) (model.Product, model.ProductVariant, error)
+	DeleteVariant(ctx context.Context, slug string, id string, version *int64) (model.Product, model.ProductVariant, error)
+	// AdjustStock adds the adjustment to the stock of the product, like the
+	// other writes only while it is at the given version, and keeps it in
+	// the stock history.
+	AdjustStock(ctx context.Context, slug string, payload dtos.StockAdjustmentDto, version *int64) (model.Product, model.StockAdjustment, error)
+	// StockHistory lists the stock adjustments of a live product.
+	StockHistory(ctx context.Context, slug string, queryParams dtos.StockQueryParams) ([]model.StockAdjustment, common.MetaData, error)
+	// Images are written like the variants. StoreImages appends the uploads
+	// and returns the images stored, OrderImages moves the images listed to
+	// the front.
+	StoreImages(ctx context.Context, slug string, uploads []dtos.ProductImageUpload, version *int64) (model.Product, []model.ProductImage, error)
+	OrderImages(ctx context.Context, slug string, payload dtos.ProductImageOrderDto, version *int64) (model.Product, error)
+	DeleteImage(ctx context.Context, slug string, id string, version *int64) (model.Product, model.ProductImage, error)
 	// Fake
-	FakeStore(payload dtos.ProductStoreDto) (model.Product, error)
-	FakeUpdateBySlug(slug string, payload dtos.ProductUpdateDto) (model.Product, error)
+	FakeAdjustStock(ctx context.Context, slug string, payload dtos.StockAdjustmentDto, version *int64) (model.Product, model.StockAdjustment, error)
+	FakeStore(ctx context.Context, payload dtos.ProductStoreDto) (model.Product, error)
+	FakeUpdateBySlug(ctx context.Context, slug string, payload dtos.ProductUpdateDto, version *int64) (model.Product, error)
+	FakeDeleteBySlug(ctx context.Context, slug string, version *int64) (model.Product, error)
+	FakeStoreVariant(ctx context.Context, slug string, payload dtos.ProductVariantDto, version *int64) (model.Product, model.ProductVariant, error)
+	FakeUpdateVariant(ctx context.Context, slug string, id string, payload dtos.ProductVariantDto, version *int64) (model.Product, model.ProductVariant, error)
+	FakeDeleteVariant(ctx context.Context, slug string, id string, version *int64) (model.Product, model.ProductVariant, error)
+	FakeStoreImages(ctx context.Context, slug string, uploads []dtos.ProductImageUpload, version *int64) (model.Product, []model.ProductImage, error)
+	FakeOrderImages(ctx context.Context, slug string, payload dtos.ProductImageOrderDto, version *int64) (model.Product, error)
+	FakeDeleteImage(ctx context.Context, slug string, id string, version *int64) (model.Product, model.ProductImage, error)
 }
 
 type productService struct {
 	repo         repository.ProductRepository
 	categoryRepo repository.CategoryRepository
+	stockRepo    repository.StockRepository
+	images       ImageService
+	audit        AuditService
+	cache        *CatalogCache
 }
 
-func (p productService) Store(payload dtos.ProductStoreDto) (model.Product, error) {
+// productPage is the cached result of FindAll.
+type productPage struct {
+	Products []dtos.ProductResponseDto `json:"products"`
+	MetaData common.MetaData           `json:"metaData"`
+}
+
+func (p productService) Store(ctx context.Context, payload dtos.ProductStoreDto) (model.Product, error) {
 	product := model.Product{
 		Title:       payload.Title,
 		Description: *payload.Description,
@@ -38,40 +84,79 @@ func (p productService) Store(payload dtos.ProductStoreDto) (model.Product, erro
 	}
 	catId, err := primitive.ObjectIDFromHex(payload.Category)
 	if err != nil {
-		return product, errors.New("category id is not valid")
+		return product, domain_error.Validation("category id is not valid")
 	}
 	product.Category = &catId
+	if product.Attributes, err = p.checkAttributes(ctx, product.Category, payload.Attributes); err != nil {
+		return product, err
+	}
 	// No dep
 	product.ID = primitive.NewObjectID()
 	product.CreatedAt = time.Now().UTC()
 	product.UpdatedAt = time.Now().UTC()
 	product.Active = true
-	product, err = p.repo.Store(product)
+	product.Variants = []model.ProductVariant{}
+	product.Images = []model.ProductImage{}
+	product, err = p.repo.Store(ctx, product)
+	p.cache.Invalidate()
 	if err != nil {
 		return product, err
 	}
-	if product.Category.Hex() != "" {
-		go func() {
-			err := p.categoryRepo.PushProductToCategory(*product.Category, product.ID)
-			if err != nil {
-				log.Println("[ERROR] add product in category:", err.Error())
-			}
-		}()
-	}
+	p.audit.Record(ctx, enums.AUDIT_PRODUCT, enums.AUDIT_CREATE, product.ID, nil, product)
 	return product, nil
 }
 
-func (p productService) FindAll(queryParams dtos.ProductQueryParams) ([]dtos.ProductResponseDto, common.MetaData, error) {
-	products, metaData, err := p.repo.FindAll(queryParams)
-	return products, metaData, err
+func (p productService) FindAll(ctx context.Context, queryParams dtos.ProductQueryParams) ([]dtos.ProductResponseDto, common.MetaData, error) {
+	var page productPage
+	err := p.cache.load(queryKey("products:", queryParams), &page, func() (err error) {
+		page.Products, page.MetaData, err = p.repo.FindAll(ctx, queryParams)
+		if err == nil && queryParams.Highlight && queryParams.Search != "" {
+			highlightProducts(page.Products, queryParams)
+		}
+		return err
+	})
+	return page.Products, page.MetaData, err
+}
+
+// highlightSize is the length of the description snippets.
+const highlightSize = 160
+
+// highlightProducts marks the matches of the search, the words of a text
+// search or the substring otherwise.
+func highlightProducts(products []dtos.ProductResponseDto, queryParams dtos.ProductQueryParams) {
+	terms := search.Terms(queryParams.Search)
+	matches := func(text string) [][]int {
+		if queryParams.IsTextSearch() {
+			return search.Words(text, terms)
+		}
+		return search.Substrings(text, queryParams.Search)
+	}
+	for i := range products {
+		description := ""
+		if products[i].Description != nil {
+			description = *products[i].Description
+		}
+		products[i].Highlight = &dtos.ProductHighlight{
+			Title:       search.Mark(products[i].Title, matches(products[i].Title)),
+			Description: search.Snippet(description, matches(description), highlightSize),
+		}
+	}
 }
 
-func (p productService) FindBySlug(slug string) (model.Product, error) {
-	product, err := p.repo.FindBySlug(slug)
+func (p productService) FindBySlug(ctx context.Context, slug string) (model.Product, error) {
+	var product model.Product
+	err := p.cache.load("product:"+slug, &product, func() (err error) {
+		product, err = p.repo.FindBySlug(ctx, slug)
+		return err
+	})
 	return product, err
 }
 
-func (p productService) UpdateBySlug(slug string, formData dtos.ProductUpdateDto) (model.Product, error) {
+func (p productService) UpdateBySlug(ctx context.Context, slug string, formData dtos.ProductUpdateDto, version *int64) (model.Product, error) {
+	before, err := p.repo.FindBySlug(ctx, slug)
+	if err != nil {
+		return before, err
+	}
 	payload := primitive.M{}
 
 	if formData.Title != "" {
@@ -87,44 +172,276 @@ func (p productService) UpdateBySlug(slug string, formData dtos.ProductUpdateDto
 		payload["category"] = formData.Category
 	}
 	if formData.UpdateSlug && formData.Title != "" {
-		payload["slug"] = utils.GenerateUniqueSlug(formData.Title, string(enums.PRODUCT_COLLECTION_NAME), slug)
-	}
-
-	product, err := p.repo.UpdateBySlug(slug, payload)
-	isCategoryChange := formData.Category.Hex() != "" && formData.Category.Hex() != product.Category.Hex()
-	log.Println(isCategoryChange)
-	if err == nil {
-		log.Println(formData.Category, formData.Category)
-		log.Println(formData.Category.Hex() != "" && formData.Category.Hex() != product.Category.Hex())
-		// TODO: Fix product change to push category
-		if isCategoryChange {
-			go func() {
-				err := p.categoryRepo.ChangeProductInCategory(*product.Category, *formData.Category, product.ID)
-				if err != nil {
-					log.Println("[ERROR update product category change]:", err.Error())
-				}
-			}()
+		payload["slug"] = utils.GenerateUniqueSlug(ctx, formData.Title, p.repo.IsSlugExists, slug)
+	}
+	if formData.Attributes != nil || formData.Category != nil {
+		if payload["attributes"], err = p.updatedAttributes(ctx, before, formData); err != nil {
+			return before, err
 		}
 	}
-	return product, err
+
+	product, err := p.repo.UpdateBySlug(ctx, slug, payload, version)
+	p.cache.Invalidate()
+	if err != nil {
+		return product, err
+	}
+	p.audit.Record(ctx, enums.AUDIT_PRODUCT, enums.AUDIT_UPDATE, product.ID, before, product)
+	return product, nil
+}
+
+// checkAttributes checks the attribute values against the schema of the
+// category.
+func (p productService) checkAttributes(ctx context.Context, category *primitive.ObjectID, values map[string]interface{}) (map[string]interface{}, error) {
+	schema, err := findCategorySchema(ctx, p.categoryRepo, category)
+	if err != nil {
+		return nil, err
+	}
+	return checkAttributes(schema, values)
+}
+
+// updatedAttributes are the attributes of the product after the update, the
+// ones of the payload or else the ones it has, checked against the schema of
+// its category after the update.
+func (p productService) updatedAttributes(ctx context.Context, product model.Product, formData dtos.ProductUpdateDto) (map[string]interface{}, error) {
+	category, values := product.Category, product.Attributes
+	if formData.Category != nil {
+		category = formData.Category
+	}
+	if formData.Attributes != nil {
+		values = formData.Attributes
+	}
+	return p.checkAttributes(ctx, category, values)
+}
+
+func (p productService) DeleteBySlug(ctx context.Context, slug string, version *int64) (model.Product, error) {
+	before, err := p.repo.FindBySlug(ctx, slug)
+	if err != nil {
+		return before, err
+	}
+	product, err := p.repo.DeleteBySlug(ctx, slug, version)
+	p.cache.Invalidate()
+	if err != nil {
+		return product, err
+	}
+	p.audit.Record(ctx, enums.AUDIT_PRODUCT, enums.AUDIT_DELETE, product.ID, before, product)
+	return product, nil
+}
+
+func (p productService) FindTrash(ctx context.Context) ([]model.Product, error) {
+	products, err := p.repo.FindTrash(ctx)
+	return products, err
+}
+
+func (p productService) RestoreBySlug(ctx context.Context, slug string) (model.Product, error) {
+	before, err := p.repo.FindTrashedBySlug(ctx, slug)
+	if err != nil {
+		return before, err
+	}
+	product, err := p.repo.RestoreBySlug(ctx, slug)
+	p.cache.Invalidate()
+	if err != nil {
+		return product, err
+	}
+	p.audit.Record(ctx, enums.AUDIT_PRODUCT, enums.AUDIT_RESTORE, product.ID, before, product)
+	return product, nil
 }
 
-func (p productService) DeleteBySlug(slug string) (model.Product, error) {
-	product, err := p.repo.DeleteBySlug(slug)
-	if err == nil {
-		if product.Category.Hex() != "" {
-			go func() {
-				err := p.categoryRepo.RemoveProductFromCategory(*product.Category, product.ID)
-				if err != nil {
-					log.Println("[ERROR] delete product from category:", err.Error())
-				}
-			}()
+func (p productService) PurgeBySlug(ctx context.Context, slug string) (model.Product, error) {
+	product, err := p.repo.PurgeBySlug(ctx, slug)
+	p.cache.Invalidate()
+	if err != nil {
+		return product, err
+	}
+	p.images.Delete(ctx, product.Images...)
+	p.audit.Record(ctx, enums.AUDIT_PRODUCT, enums.AUDIT_PURGE, product.ID, product, nil)
+	return product, nil
+}
+
+func (p productService) History(ctx context.Context, slug string, queryParams dtos.AuditQueryParams) ([]model.AuditLog, common.MetaData, error) {
+	product, err := p.repo.FindBySlug(ctx, slug)
+	if domain_error.Is(err, domain_error.NOT_FOUND) {
+		product, err = p.repo.FindTrashedBySlug(ctx, slug)
+		if domain_error.Is(err, domain_error.NOT_FOUND) {
+			err = domain_error.NotFound("product is not found")
 		}
 	}
-	return product, err
+	if err != nil {
+		return []model.AuditLog{}, common.MetaData{}, err
+	}
+	queryParams.Entity = enums.AUDIT_PRODUCT
+	queryParams.EntityId = product.ID.Hex()
+	return p.audit.FindAll(ctx, queryParams)
+}
+
+func (p productService) StoreVariant(ctx context.Context, slug string, payload dtos.ProductVariantDto, version *int64) (model.Product, model.ProductVariant, error) {
+	return p.storeVariant(ctx, slug, payload, version, true)
+}
+
+func (p productService) UpdateVariant(ctx context.Context, slug string, id string, payload dtos.ProductVariantDto, version *int64) (model.Product, model.ProductVariant, error) {
+	return p.updateVariant(ctx, slug, id, payload, version, true)
+}
+
+func (p productService) DeleteVariant(ctx context.Context, slug string, id string, version *int64) (model.Product, model.ProductVariant, error) {
+	return p.deleteVariant(ctx, slug, id, version, true)
+}
+
+func (p productService) storeVariant(ctx context.Context, slug string, payload dtos.ProductVariantDto, version *int64, write bool) (model.Product, model.ProductVariant, error) {
+	now := time.Now().UTC()
+	variant := model.ProductVariant{ID: primitive.NewObjectID(), CreatedAt: now}
+	product, err := p.changeVariants(ctx, slug, version, write, func(variants []model.ProductVariant) ([]model.ProductVariant, error) {
+		variant = newVariant(variant, payload, now)
+		return append(variants, variant), nil
+	})
+	return product, variant, err
+}
+
+// updateVariant replaces the variant, fields missing from the payload are
+// cleared.
+func (p productService) updateVariant(ctx context.Context, slug string, id string, payload dtos.ProductVariantDto, version *int64, write bool) (model.Product, model.ProductVariant, error) {
+	var variant model.ProductVariant
+	product, err := p.changeVariants(ctx, slug, version, write, func(variants []model.ProductVariant) ([]model.ProductVariant, error) {
+		i, err := findVariant(variants, id)
+		if err != nil {
+			return variants, err
+		}
+		variant = newVariant(variants[i], payload, time.Now().UTC())
+		variants[i] = variant
+		return variants, nil
+	})
+	return product, variant, err
 }
 
-func (p productService) FakeStore(payload dtos.ProductStoreDto) (model.Product, error) {
+func (p productService) deleteVariant(ctx context.Context, slug string, id string, version *int64, write bool) (model.Product, model.ProductVariant, error) {
+	var variant model.ProductVariant
+	product, err := p.changeVariants(ctx, slug, version, write, func(variants []model.ProductVariant) ([]model.ProductVariant, error) {
+		i, err := findVariant(variants, id)
+		if err != nil {
+			return variants, err
+		}
+		variant = variants[i]
+		return append(variants[:i], variants[i+1:]...), nil
+	})
+	return product, variant, err
+}
+
+// changeVariants writes the variants change makes out of a copy of the
+// product variants. The fake writes check them and change nothing.
+func (p productService) changeVariants(ctx context.Context, slug string, version *int64, write bool, change func(variants []model.ProductVariant) ([]model.ProductVariant, error)) (model.Product, error) {
+	before, err := p.findAtVersion(ctx, slug, version)
+	if err != nil {
+		return before, err
+	}
+	variants, err := change(append([]model.ProductVariant{}, before.Variants...))
+	if err != nil {
+		return before, err
+	}
+	skus := map[string]bool{}
+	for _, variant := range variants {
+		if skus[variant.SKU] || p.repo.IsSkuExists(ctx, variant.SKU, before.ID) {
+			return before, domain_error.Conflict("sku is already exists")
+		}
+		skus[variant.SKU] = true
+	}
+	if !write {
+		product := before
+		product.Variants = variants
+		product.UpdatedAt = time.Now().UTC()
+		product.Version++
+		return product, nil
+	}
+	product, err := p.repo.UpdateBySlug(ctx, slug, primitive.M{"variants": variants}, version)
+	p.cache.Invalidate()
+	if err != nil {
+		return product, err
+	}
+	p.audit.Record(ctx, enums.AUDIT_PRODUCT, enums.AUDIT_UPDATE, product.ID, before, product)
+	return product, nil
+}
+
+// newVariant sets the fields of the payload on the variant, a variant is
+// active unless told otherwise.
+func newVariant(variant model.ProductVariant, payload dtos.ProductVariantDto, now time.Time) model.ProductVariant {
+	variant.SKU = strings.TrimSpace(payload.SKU)
+	variant.Price = payload.Price
+	variant.Attributes = map[string]string{}
+	for name, value := range payload.Attributes {
+		variant.Attributes[strings.TrimSpace(name)] = value
+	}
+	variant.Active = payload.Active == nil || *payload.Active
+	variant.UpdatedAt = now
+	return variant
+}
+
+func findVariant(variants []model.ProductVariant, id string) (int, error) {
+	_id, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return -1, domain_error.Validation("variant id is not valid")
+	}
+	for i, variant := range variants {
+		if variant.ID == _id {
+			return i, nil
+		}
+	}
+	return -1, domain_error.NotFound("variant is not found")
+}
+
+func (p productService) AdjustStock(ctx context.Context, slug string, payload dtos.StockAdjustmentDto, version *int64) (model.Product, model.StockAdjustment, error) {
+	var adjustment model.StockAdjustment
+	before, err := p.repo.FindBySlug(ctx, slug)
+	if err != nil {
+		return before, adjustment, err
+	}
+	product, err := p.repo.AdjustStock(ctx, slug, payload.Delta, version)
+	p.cache.Invalidate()
+	if err != nil {
+		return product, adjustment, err
+	}
+	adjustment = newStockAdjustment(ctx, product, payload)
+	if err := p.stockRepo.Store(ctx, adjustment); err != nil {
+		log.Println("[ERROR] Stock adjustment of", product.ID.Hex()+":", err.Error())
+	}
+	p.audit.Record(ctx, enums.AUDIT_PRODUCT, enums.AUDIT_UPDATE, product.ID, before, product)
+	return product, adjustment, nil
+}
+
+func (p productService) StockHistory(ctx context.Context, slug string, queryParams dtos.StockQueryParams) ([]model.StockAdjustment, common.MetaData, error) {
+	if err := queryParams.Validate(); err != nil {
+		return []model.StockAdjustment{}, common.MetaData{}, err
+	}
+	product, err := p.repo.FindBySlug(ctx, slug)
+	if err != nil {
+		return []model.StockAdjustment{}, common.MetaData{}, err
+	}
+	queryParams.ProductId = product.ID.Hex()
+	return p.stockRepo.FindByProduct(ctx, queryParams)
+}
+
+// newStockAdjustment is the history entry of the adjustment of the product,
+// the requester is its actor like in the audit log.
+func newStockAdjustment(ctx context.Context, product model.Product, payload dtos.StockAdjustmentDto) model.StockAdjustment {
+	adjustment := model.StockAdjustment{
+		ID:        primitive.NewObjectID(),
+		ProductId: product.ID,
+		Delta:     payload.Delta,
+		Reason:    payload.Reason,
+		Note:      strings.TrimSpace(payload.Note),
+		CreatedAt: time.Now().UTC(),
+	}
+	if product.Stock != nil {
+		adjustment.Stock = *product.Stock
+	}
+	if requester, ok := utils.GetRequester(ctx); ok {
+		adjustment.Actor = &model.AuditActor{
+			ID:    requester.ID,
+			Name:  requester.Name,
+			Email: requester.Email,
+			Role:  requester.Role,
+		}
+	}
+	return adjustment
+}
+
+func (p productService) FakeStore(ctx context.Context, payload dtos.ProductStoreDto) (model.Product, error) {
 	product := model.Product{
 		Title:       payload.Title,
 		Description: *payload.Description,
@@ -132,14 +449,19 @@ func (p productService) FakeStore(payload dtos.ProductStoreDto) (model.Product,
 	}
 	catId, err := primitive.ObjectIDFromHex(payload.Category)
 	if err != nil {
-		return product, errors.New("category id is not valid")
+		return product, domain_error.Validation("category id is not valid")
 	}
 	product.Category = &catId
+	if product.Attributes, err = p.checkAttributes(ctx, product.Category, payload.Attributes); err != nil {
+		return product, err
+	}
 	// No dep
 	product.ID = primitive.NewObjectID()
 	product.CreatedAt = time.Now().UTC()
 	product.UpdatedAt = time.Now().UTC()
 	product.Active = true
+	product.Variants = []model.ProductVariant{}
+	product.Images = []model.ProductImage{}
 	product.Slug = utils.GenerateFakeUniqueSlug(product.Title, false)
 	if err != nil {
 		return product, err
@@ -147,11 +469,12 @@ func (p productService) FakeStore(payload dtos.ProductStoreDto) (model.Product,
 	return product, nil
 }
 
-func (p productService) FakeUpdateBySlug(slug string, payload dtos.ProductUpdateDto) (model.Product, error) {
-	product, err := p.FindBySlug(slug)
+func (p productService) FakeUpdateBySlug(ctx context.Context, slug string, payload dtos.ProductUpdateDto, version *int64) (model.Product, error) {
+	product, err := p.findAtVersion(ctx, slug, version)
 	if err != nil {
 		return product, err
 	}
+	product.Version++
 	if payload.Title != "" {
 		product.Title = payload.Title
 	}
@@ -167,12 +490,72 @@ func (p productService) FakeUpdateBySlug(slug string, payload dtos.ProductUpdate
 	if payload.UpdateSlug && payload.Title != "" {
 		product.Slug = utils.GenerateFakeUniqueSlug(payload.Title, false)
 	}
+	if payload.Attributes != nil || payload.Category != nil {
+		if product.Attributes, err = p.updatedAttributes(ctx, product, payload); err != nil {
+			return product, err
+		}
+	}
 	return product, err
 }
 
-func NewProductService(repo repository.ProductRepository, categoryRepo repository.CategoryRepository) ProductService {
+func (p productService) FakeDeleteBySlug(ctx context.Context, slug string, version *int64) (model.Product, error) {
+	return p.findAtVersion(ctx, slug, version)
+}
+
+func (p productService) FakeStoreVariant(ctx context.Context, slug string, payload dtos.ProductVariantDto, version *int64) (model.Product, model.ProductVariant, error) {
+	return p.storeVariant(ctx, slug, payload, version, false)
+}
+
+func (p productService) FakeUpdateVariant(ctx context.Context, slug string, id string, payload dtos.ProductVariantDto, version *int64) (model.Product, model.ProductVariant, error) {
+	return p.updateVariant(ctx, slug, id, payload, version, false)
+}
+
+func (p productService) FakeDeleteVariant(ctx context.Context, slug string, id string, version *int64) (model.Product, model.ProductVariant, error) {
+	return p.deleteVariant(ctx, slug, id, version, false)
+}
+
+// FakeAdjustStock adds the adjustment to the stock of the product as read, the
+// reservations of the carts are left as they are.
+func (p productService) FakeAdjustStock(ctx context.Context, slug string, payload dtos.StockAdjustmentDto, version *int64) (model.Product, model.StockAdjustment, error) {
+	var adjustment model.StockAdjustment
+	product, err := p.findAtVersion(ctx, slug, version)
+	if err != nil {
+		return product, adjustment, err
+	}
+	stock, available := payload.Delta, payload.Delta
+	if product.Stock != nil && product.Available != nil {
+		stock, available = *product.Stock+payload.Delta, *product.Available+payload.Delta
+	}
+	if available < 0 {
+		return product, adjustment, domain_error.Conflict("stock can not go below what carts reserved")
+	}
+	product.Stock = &stock
+	product.Available = &available
+	product.UpdatedAt = time.Now().UTC()
+	product.Version++
+	return product, newStockAdjustment(ctx, product, payload), nil
+}
+
+// findAtVersion checks the product like a conditional write does, for the fake
+// writes that change nothing.
+func (p productService) findAtVersion(ctx context.Context, slug string, version *int64) (model.Product, error) {
+	product, err := p.repo.FindBySlug(ctx, slug)
+	if err != nil {
+		return product, err
+	}
+	if version != nil && *version != product.Version {
+		return product, domain_error.StaleVersion("product", product.Version)
+	}
+	return product, nil
+}
+
+func NewProductService(repo repository.ProductRepository, categoryRepo repository.CategoryRepository, stockRepo repository.StockRepository, images ImageService, audit AuditService, cache *CatalogCache) ProductService {
 	return &productService{
 		repo:         repo,
 		categoryRepo: categoryRepo,
+		stockRepo:    stockRepo,
+		images:       images,
+		audit:        audit,
+		cache:        cache,
 	}
 }
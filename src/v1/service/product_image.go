@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxProductImages is the most images a product can have.
+const maxProductImages = 10
+
+func (p productService) StoreImages(ctx context.Context, slug string, uploads []dtos.ProductImageUpload, version *int64) (model.Product, []model.ProductImage, error) {
+	return p.storeImages(ctx, slug, uploads, version, true)
+}
+
+func (p productService) OrderImages(ctx context.Context, slug string, payload dtos.ProductImageOrderDto, version *int64) (model.Product, error) {
+	return p.orderImages(ctx, slug, payload, version, true)
+}
+
+func (p productService) DeleteImage(ctx context.Context, slug string, id string, version *int64) (model.Product, model.ProductImage, error) {
+	return p.deleteImage(ctx, slug, id, version, true)
+}
+
+// storeImages appends the uploads to the images, the files of a failed write
+// are deleted again.
+func (p productService) storeImages(ctx context.Context, slug string, uploads []dtos.ProductImageUpload, version *int64, write bool) (model.Product, []model.ProductImage, error) {
+	if len(uploads) == 0 {
+		return model.Product{}, nil, domain_error.Validation("images are required")
+	}
+	stored := []model.ProductImage{}
+	product, err := p.changeImages(ctx, slug, version, write, func(images []model.ProductImage) ([]model.ProductImage, error) {
+		if len(images)+len(uploads) > maxProductImages {
+			return images, domain_error.Validation(fmt.Sprintf("a product can have at most %d images", maxProductImages))
+		}
+		for _, upload := range uploads {
+			var (
+				image model.ProductImage
+				err   error
+			)
+			if write {
+				image, err = p.images.Store(ctx, upload)
+			} else {
+				image, err = p.images.Check(upload)
+			}
+			if err != nil {
+				return images, err
+			}
+			stored = append(stored, image)
+		}
+		return append(images, stored...), nil
+	})
+	if err != nil && write {
+		p.images.Delete(ctx, stored...)
+	}
+	return product, stored, err
+}
+
+func (p productService) orderImages(ctx context.Context, slug string, payload dtos.ProductImageOrderDto, version *int64, write bool) (model.Product, error) {
+	return p.changeImages(ctx, slug, version, write, func(images []model.ProductImage) ([]model.ProductImage, error) {
+		ordered := []model.ProductImage{}
+		listed := map[primitive.ObjectID]bool{}
+		for _, id := range payload.Images {
+			i, err := findImage(images, id)
+			if err != nil {
+				return images, err
+			}
+			ordered = append(ordered, images[i])
+			listed[images[i].ID] = true
+		}
+		for _, image := range images {
+			if !listed[image.ID] {
+				ordered = append(ordered, image)
+			}
+		}
+		return ordered, nil
+	})
+}
+
+// deleteImage removes the image from the product, its files are deleted once
+// the product is written.
+func (p productService) deleteImage(ctx context.Context, slug string, id string, version *int64, write bool) (model.Product, model.ProductImage, error) {
+	var image model.ProductImage
+	product, err := p.changeImages(ctx, slug, version, write, func(images []model.ProductImage) ([]model.ProductImage, error) {
+		i, err := findImage(images, id)
+		if err != nil {
+			return images, err
+		}
+		image = images[i]
+		return append(images[:i], images[i+1:]...), nil
+	})
+	if err == nil && write {
+		p.images.Delete(ctx, image)
+	}
+	return product, image, err
+}
+
+// changeImages writes the images change makes out of a copy of the product
+// images, together with the primary image. The fake writes check them and
+// change nothing.
+func (p productService) changeImages(ctx context.Context, slug string, version *int64, write bool, change func(images []model.ProductImage) ([]model.ProductImage, error)) (model.Product, error) {
+	before, err := p.findAtVersion(ctx, slug, version)
+	if err != nil {
+		return before, err
+	}
+	images, err := change(append([]model.ProductImage{}, before.Images...))
+	if err != nil {
+		return before, err
+	}
+	image, imageSource := primaryImage(images)
+	if image == "" && before.ImageSource == nil {
+		// an image url that was not uploaded stays until there are images
+		image = before.Image
+	}
+	if !write {
+		product := before
+		product.Images = images
+		product.Image = image
+		product.ImageSource = imageSource
+		product.UpdatedAt = time.Now().UTC()
+		product.Version++
+		return product, nil
+	}
+	payload := primitive.M{"images": images, "image": image, "imageSource": imageSource}
+	product, err := p.repo.UpdateBySlug(ctx, slug, payload, version)
+	p.cache.Invalidate()
+	if err != nil {
+		return product, err
+	}
+	p.audit.Record(ctx, enums.AUDIT_PRODUCT, enums.AUDIT_UPDATE, product.ID, before, product)
+	return product, nil
+}
+
+// primaryImage returns the url and the id of the first image, the Image and
+// ImageSource of a product with the images.
+func primaryImage(images []model.ProductImage) (string, *primitive.ObjectID) {
+	if len(images) == 0 {
+		return "", nil
+	}
+	id := images[0].ID
+	return images[0].URL, &id
+}
+
+func findImage(images []model.ProductImage, id string) (int, error) {
+	_id, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return -1, domain_error.Validation("image id is not valid")
+	}
+	for i, image := range images {
+		if image.ID == _id {
+			return i, nil
+		}
+	}
+	return -1, domain_error.NotFound("image is not found")
+}
+
+func (p productService) FakeStoreImages(ctx context.Context, slug string, uploads []dtos.ProductImageUpload, version *int64) (model.Product, []model.ProductImage, error) {
+	return p.storeImages(ctx, slug, uploads, version, false)
+}
+
+func (p productService) FakeOrderImages(ctx context.Context, slug string, payload dtos.ProductImageOrderDto, version *int64) (model.Product, error) {
+	return p.orderImages(ctx, slug, payload, version, false)
+}
+
+func (p productService) FakeDeleteImage(ctx context.Context, slug string, id string, version *int64) (model.Product, model.ProductImage, error) {
+	return p.deleteImage(ctx, slug, id, version, false)
+}
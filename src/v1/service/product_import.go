@@ -0,0 +1,480 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/utils"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"github.com/sajalmia381/store-api/src/v1/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ProductImportService interface {
+	// Parse reads the rows of a csv or json file. Values that can not be
+	// read are problems of their row, only a malformed file fails.
+	Parse(reader io.Reader, format string) ([]dtos.ProductImportRowDto, error)
+	// Import validates every row, then writes them unless it is a dry run. A
+	// row with errors fails the import before anything is written.
+	Import(ctx context.Context, rows []dtos.ProductImportRowDto, dryRun bool) (dtos.ProductImportReportDto, error)
+	// Start runs Import in the background for a signed in requester, FindJob
+	// follows its progress.
+	Start(ctx context.Context, rows []dtos.ProductImportRowDto, dryRun bool) (dtos.ProductImportJobDto, error)
+	// FindJob returns a job started by the requester, super admins see all.
+	FindJob(ctx context.Context, id string) (dtos.ProductImportJobDto, error)
+}
+
+// maxImportRows bounds the rows of a file, they are all held in memory.
+const maxImportRows = 10000
+
+// importColumns are the csv columns, named like the json fields.
+var importColumns = map[string]bool{"slug": true, "title": true, "price": true, "description": true, "category": true, "active": true, "attributes": true}
+
+type productImportService struct {
+	productRepo  repository.ProductRepository
+	categoryRepo repository.CategoryRepository
+	audit        AuditService
+	cache        *CatalogCache
+	jobs         *ImportJobs
+}
+
+// importPlan is what importing a valid row does, product is the product to
+// create or the product before the update.
+type importPlan struct {
+	index   int
+	action  enums.ImportAction
+	product model.Product
+	payload primitive.M
+}
+
+func (s productImportService) Parse(reader io.Reader, format string) ([]dtos.ProductImportRowDto, error) {
+	var (
+		rows []dtos.ProductImportRowDto
+		err  error
+	)
+	switch format {
+	case "csv":
+		rows, err = parseImportCsv(reader)
+	case "json":
+		rows, err = parseImportJson(reader)
+	default:
+		return nil, domain_error.Validation("format must be csv or json")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, domain_error.Validation("file has no rows")
+	}
+	if len(rows) > maxImportRows {
+		return nil, domain_error.Validation(fmt.Sprintf("file has more than %d rows", maxImportRows))
+	}
+	return rows, nil
+}
+
+func parseImportCsv(reader io.Reader) ([]dtos.ProductImportRowDto, error) {
+	records, err := csv.NewReader(reader).ReadAll()
+	if err != nil {
+		return nil, domain_error.Validation("csv is not valid: " + err.Error())
+	}
+	if len(records) == 0 {
+		return nil, domain_error.Validation("csv has no header")
+	}
+	header := records[0]
+	for i, name := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		if !importColumns[header[i]] {
+			return nil, domain_error.Validation(fmt.Sprintf("csv has an unknown column %q", header[i]))
+		}
+		for _, other := range header[:i] {
+			if other == header[i] {
+				return nil, domain_error.Validation(fmt.Sprintf("csv has the column %q twice", header[i]))
+			}
+		}
+	}
+	rows := []dtos.ProductImportRowDto{}
+	for _, record := range records[1:] {
+		values := map[string]string{}
+		for i, name := range header {
+			values[name] = record[i]
+		}
+		row := dtos.ProductImportRowDto{
+			Slug:     strings.TrimSpace(values["slug"]),
+			Title:    values["title"],
+			Category: strings.TrimSpace(values["category"]),
+		}
+		if description, ok := values["description"]; ok {
+			row.Description = &description
+		}
+		if value := strings.TrimSpace(values["price"]); value != "" {
+			price, err := strconv.Atoi(value)
+			if err != nil {
+				row.Problems = append(row.Problems, fmt.Sprintf("price %q is not a number", value))
+			} else {
+				row.Price = &price
+			}
+		}
+		if value := strings.TrimSpace(values["active"]); value != "" {
+			active, err := strconv.ParseBool(value)
+			if err != nil {
+				row.Problems = append(row.Problems, fmt.Sprintf("active %q is not true or false", value))
+			} else {
+				row.Active = &active
+			}
+		}
+		if value := strings.TrimSpace(values["attributes"]); value != "" {
+			if err := json.Unmarshal([]byte(value), &row.Attributes); err != nil {
+				row.Problems = append(row.Problems, "attributes is not a json object")
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// parseImportJson decodes the rows one by one, so a row of the wrong shape
+// only fails itself.
+func parseImportJson(reader io.Reader) ([]dtos.ProductImportRowDto, error) {
+	var objects []json.RawMessage
+	if err := json.NewDecoder(reader).Decode(&objects); err != nil {
+		return nil, domain_error.Validation("json is not an array of rows: " + err.Error())
+	}
+	rows := []dtos.ProductImportRowDto{}
+	for _, object := range objects {
+		var (
+			row    dtos.ProductImportRowDto
+			fields map[string]json.RawMessage
+		)
+		if err := json.Unmarshal(object, &fields); err != nil {
+			rows = append(rows, dtos.ProductImportRowDto{Problems: []string{"row is not an object"}})
+			continue
+		}
+		for name := range fields {
+			if !importColumns[name] {
+				row.Problems = append(row.Problems, fmt.Sprintf("unknown field %q", name))
+			}
+		}
+		// a value of the wrong type is skipped, the others are still read
+		var typeErr *json.UnmarshalTypeError
+		if err := json.Unmarshal(object, &row); errors.As(err, &typeErr) {
+			row.Problems = append(row.Problems, fmt.Sprintf("%s must be %s", typeErr.Field, jsonKind(typeErr.Type)))
+		} else if err != nil {
+			row.Problems = append(row.Problems, err.Error())
+		}
+		sort.Strings(row.Problems)
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func jsonKind(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int:
+		return "a whole number"
+	case reflect.Map:
+		return "an object"
+	}
+	return "a string"
+}
+
+func (s productImportService) Import(ctx context.Context, rows []dtos.ProductImportRowDto, dryRun bool) (dtos.ProductImportReportDto, error) {
+	return s.run(ctx, rows, dryRun, func(validated int, written int) {})
+}
+
+// run calls progress after every row validated and written.
+func (s productImportService) run(ctx context.Context, rows []dtos.ProductImportRowDto, dryRun bool, progress func(validated int, written int)) (dtos.ProductImportReportDto, error) {
+	report := dtos.ProductImportReportDto{DryRun: dryRun, Total: len(rows), Rows: []dtos.ProductImportRowResultDto{}}
+	categories, _, err := s.categoryRepo.FindAll(ctx, dtos.CategoryQueryParams{})
+	if err != nil {
+		return report, err
+	}
+	plans := []importPlan{}
+	slugs := map[string]int{}
+	for i, row := range rows {
+		result := dtos.ProductImportRowResultDto{Row: i + 1, Slug: row.Slug, Errors: row.Errors()}
+		if result.Slug == "" {
+			result.Slug = utils.GenerateSlug(row.Title)
+		}
+		category, found := findImportCategory(categories, row.Category)
+		if row.Category != "" && !found {
+			result.Errors = append(result.Errors, fmt.Sprintf("category %q is not found", row.Category))
+		}
+		if first, ok := slugs[result.Slug]; ok && result.Slug != "" {
+			result.Errors = append(result.Errors, fmt.Sprintf("slug %s is already imported by row %d", result.Slug, first))
+		}
+		slugs[result.Slug] = i + 1
+		if len(result.Errors) == 0 {
+			plan, err := s.plan(ctx, row, result.Slug, category, categorySchema(categories, category))
+			if domain_error.Is(err, domain_error.CONFLICT) || domain_error.Is(err, domain_error.VALIDATION) {
+				result.Errors = append(result.Errors, err.Error())
+			} else if err != nil {
+				return report, err
+			} else {
+				plan.index = i
+				result.Action = plan.action
+				plans = append(plans, plan)
+			}
+		}
+		report.Rows = append(report.Rows, result)
+		progress(i+1, 0)
+	}
+	countImport(&report)
+	if report.Failed > 0 && !dryRun {
+		return report, domain_error.Validation(fmt.Sprintf("%d of %d rows have errors, nothing was imported", report.Failed, report.Total))
+	}
+	if dryRun {
+		return report, nil
+	}
+	defer s.cache.Invalidate()
+	for written, plan := range plans {
+		if err := s.write(ctx, plan); err != nil {
+			report.Rows[plan.index].Errors = []string{err.Error()}
+		}
+		progress(len(rows), written+1)
+	}
+	countImport(&report)
+	return report, nil
+}
+
+// plan finds the product of the slug and works out what the row changes of
+// it, a slug of a trashed product is a conflict. The attributes are checked
+// against the schema of the category.
+func (s productImportService) plan(ctx context.Context, row dtos.ProductImportRowDto, slug string, category primitive.ObjectID, schema []model.CategoryAttribute) (importPlan, error) {
+	title := strings.TrimSpace(row.Title)
+	before, err := s.productRepo.FindBySlug(ctx, slug)
+	if domain_error.Is(err, domain_error.NOT_FOUND) {
+		if _, err := s.productRepo.FindTrashedBySlug(ctx, slug); err == nil {
+			return importPlan{}, domain_error.Conflict("slug " + slug + " is taken by a product in the trash")
+		} else if !domain_error.Is(err, domain_error.NOT_FOUND) {
+			return importPlan{}, err
+		}
+		attributes, err := checkAttributes(schema, row.Attributes)
+		if err != nil {
+			return importPlan{}, err
+		}
+		now := time.Now().UTC()
+		product := model.Product{
+			ID:         primitive.NewObjectID(),
+			Slug:       row.Slug,
+			Title:      title,
+			Price:      *row.Price,
+			Category:   &category,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+			Active:     row.Active == nil || *row.Active,
+			Variants:   []model.ProductVariant{},
+			Images:     []model.ProductImage{},
+			Attributes: attributes,
+		}
+		if row.Description != nil {
+			product.Description = *row.Description
+		}
+		return importPlan{action: enums.IMPORT_CREATE, product: product}, nil
+	}
+	if err != nil {
+		return importPlan{}, err
+	}
+	payload := primitive.M{}
+	if before.Title != title {
+		payload["title"] = title
+	}
+	if before.Price != *row.Price {
+		payload["price"] = *row.Price
+	}
+	if row.Description != nil && before.Description != *row.Description {
+		payload["description"] = *row.Description
+	}
+	if before.Category == nil || *before.Category != category {
+		payload["category"] = &category
+	}
+	if row.Active != nil && before.Active != *row.Active {
+		payload["active"] = *row.Active
+	}
+	values := before.Attributes
+	if row.Attributes != nil {
+		values = row.Attributes
+	}
+	attributes, err := checkAttributes(schema, values)
+	if err != nil {
+		return importPlan{}, err
+	}
+	if (len(attributes) > 0 || len(before.Attributes) > 0) && !reflect.DeepEqual(attributes, before.Attributes) {
+		payload["attributes"] = attributes
+	}
+	if len(payload) == 0 {
+		return importPlan{action: enums.IMPORT_UNCHANGED, product: before}, nil
+	}
+	return importPlan{action: enums.IMPORT_UPDATE, product: before, payload: payload}, nil
+}
+
+// write applies the plan, an update only to the product as it was planned.
+func (s productImportService) write(ctx context.Context, plan importPlan) error {
+	switch plan.action {
+	case enums.IMPORT_CREATE:
+		product, err := s.productRepo.Store(ctx, plan.product)
+		if err != nil {
+			return importError(err)
+		}
+		s.audit.Record(ctx, enums.AUDIT_PRODUCT, enums.AUDIT_CREATE, product.ID, nil, product)
+	case enums.IMPORT_UPDATE:
+		product, err := s.productRepo.UpdateBySlug(ctx, plan.product.Slug, plan.payload, &plan.product.Version)
+		if err != nil {
+			return importError(err)
+		}
+		s.audit.Record(ctx, enums.AUDIT_PRODUCT, enums.AUDIT_UPDATE, product.ID, plan.product, product)
+	}
+	return nil
+}
+
+func importError(err error) error {
+	if domain_error.KindOf(err) == "" {
+		log.Println("[ERROR] Product import:", err.Error())
+	}
+	return err
+}
+
+// findImportCategory finds a live category by slug or id.
+func findImportCategory(categories []model.Category, ref string) (primitive.ObjectID, bool) {
+	for _, category := range categories {
+		if category.Slug == ref || category.ID.Hex() == ref {
+			return category.ID, true
+		}
+	}
+	return primitive.NilObjectID, false
+}
+
+func countImport(report *dtos.ProductImportReportDto) {
+	report.Created, report.Updated, report.Unchanged, report.Failed = 0, 0, 0, 0
+	for _, row := range report.Rows {
+		switch {
+		case len(row.Errors) > 0:
+			report.Failed++
+		case row.Action == enums.IMPORT_CREATE:
+			report.Created++
+		case row.Action == enums.IMPORT_UPDATE:
+			report.Updated++
+		default:
+			report.Unchanged++
+		}
+	}
+}
+
+func (s productImportService) Start(ctx context.Context, rows []dtos.ProductImportRowDto, dryRun bool) (dtos.ProductImportJobDto, error) {
+	var job dtos.ProductImportJobDto
+	// jobs are found by their requester, anonymous ones could be read by anyone
+	requester, ok := utils.GetRequester(ctx)
+	if !ok {
+		return job, domain_error.Forbidden("Only a signed in user can start a background import")
+	}
+	job = dtos.ProductImportJobDto{
+		ID:        primitive.NewObjectID().Hex(),
+		Status:    enums.JOB_RUNNING,
+		DryRun:    dryRun,
+		Total:     len(rows),
+		CreatedBy: requester.Email,
+		CreatedAt: time.Now().UTC(),
+	}
+	// the job outlives the request, it keeps only its requester
+	background := utils.WithRequester(context.Background(), requester)
+	s.jobs.add(job)
+	go func() {
+		report, err := s.run(background, rows, dryRun, func(validated int, written int) {
+			s.jobs.update(job.ID, func(job *dtos.ProductImportJobDto) {
+				job.Validated, job.Written = validated, written
+			})
+		})
+		s.jobs.update(job.ID, func(job *dtos.ProductImportJobDto) {
+			now := time.Now().UTC()
+			job.FinishedAt = &now
+			job.Report = &report
+			job.Status = enums.JOB_DONE
+			if err != nil {
+				job.Status = enums.JOB_FAILED
+				job.Error = err.Error()
+			}
+		})
+	}()
+	return job, nil
+}
+
+func (s productImportService) FindJob(ctx context.Context, id string) (dtos.ProductImportJobDto, error) {
+	job, ok := s.jobs.get(id)
+	requester, signedIn := utils.GetRequester(ctx)
+	if !ok || !signedIn || job.CreatedBy != requester.Email && requester.Role != string(enums.ROLE_SUPER_ADMIN) {
+		return job, domain_error.NotFound("import job is not found")
+	}
+	return job, nil
+}
+
+// importJobRetention is how long a finished job can still be read.
+const importJobRetention = time.Hour
+
+// ImportJobs keeps the import jobs in process, so they are lost on restart
+// and only seen by the instance running them.
+type ImportJobs struct {
+	mu   sync.Mutex
+	jobs map[string]*dtos.ProductImportJobDto
+}
+
+// add drops the jobs finished for longer than importJobRetention first.
+func (j *ImportJobs) add(job dtos.ProductImportJobDto) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for id, other := range j.jobs {
+		if other.FinishedAt != nil && time.Since(*other.FinishedAt) > importJobRetention {
+			delete(j.jobs, id)
+		}
+	}
+	j.jobs[job.ID] = &job
+}
+
+func (j *ImportJobs) get(id string) (dtos.ProductImportJobDto, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	job, ok := j.jobs[id]
+	if !ok {
+		return dtos.ProductImportJobDto{}, false
+	}
+	return *job, true
+}
+
+func (j *ImportJobs) update(id string, change func(job *dtos.ProductImportJobDto)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if job, ok := j.jobs[id]; ok {
+		change(job)
+	}
+}
+
+func NewImportJobs() *ImportJobs {
+	return &ImportJobs{jobs: map[string]*dtos.ProductImportJobDto{}}
+}
+
+func NewProductImportService(productRepo repository.ProductRepository, categoryRepo repository.CategoryRepository, audit AuditService, cache *CatalogCache, jobs *ImportJobs) ProductImportService {
+	return &productImportService{
+		productRepo:  productRepo,
+		categoryRepo: categoryRepo,
+		audit:        audit,
+		cache:        cache,
+		jobs:         jobs,
+	}
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/utils"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+)
+
+func TestParseImportCsv(t *testing.T) {
+	s := productImportService{}
+	rows, err := s.Parse(strings.NewReader("\ufeffslug, title ,price,active,attributes\n"+
+		"red-shirt,Red shirt,1200,true,\"{\"\"size\"\":\"\"m\"\"}\"\n"+
+		"blue-shirt,Blue shirt,cheap,maybe,[1]\n"), "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	if rows[0].Slug != "red-shirt" || rows[0].Price == nil || *rows[0].Price != 1200 || rows[0].Active == nil || !*rows[0].Active {
+		t.Errorf("first row is %+v", rows[0])
+	}
+	if rows[0].Attributes["size"] != "m" || len(rows[0].Problems) != 0 {
+		t.Errorf("first row has attributes %v and problems %v", rows[0].Attributes, rows[0].Problems)
+	}
+	if rows[0].Description != nil {
+		t.Errorf("description of a file without the column is %q, want nil", *rows[0].Description)
+	}
+	want := []string{`price "cheap" is not a number`, `active "maybe" is not true or false`, "attributes is not a json object"}
+	if !reflect.DeepEqual(rows[1].Problems, want) {
+		t.Errorf("problems of the second row are %v, want %v", rows[1].Problems, want)
+	}
+}
+
+func TestParseImportRejectsMalformedFiles(t *testing.T) {
+	s := productImportService{}
+	files := []struct {
+		name   string
+		format string
+		body   string
+	}{
+		{"unknown format", "xml", "<products/>"},
+		{"unknown column", "csv", "slug,colour\nred,red\n"},
+		{"column twice", "csv", "slug,title,slug\na,A,b\n"},
+		{"only a header", "csv", "slug,title,price\n"},
+		{"not an array", "json", `{"slug":"a"}`},
+		{"empty array", "json", `[]`},
+	}
+	for _, file := range files {
+		if _, err := s.Parse(strings.NewReader(file.body), file.format); !domain_error.Is(err, domain_error.VALIDATION) {
+			t.Errorf("%s: got %v, want a validation error", file.name, err)
+		}
+	}
+}
+
+func TestParseImportJson(t *testing.T) {
+	s := productImportService{}
+	rows, err := s.Parse(strings.NewReader(`[
+		{"slug": "red-shirt", "title": "Red shirt", "price": 1200, "colour": "red"},
+		{"title": "Blue shirt", "price": "cheap", "active": true},
+		"blue-shirt"
+	]`), "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3", len(rows))
+	}
+	if rows[0].Price == nil || *rows[0].Price != 1200 || !reflect.DeepEqual(rows[0].Problems, []string{`unknown field "colour"`}) {
+		t.Errorf("first row is %+v", rows[0])
+	}
+	// the other values of a row with a wrong type are still read
+	if rows[1].Title != "Blue shirt" || rows[1].Active == nil || !reflect.DeepEqual(rows[1].Problems, []string{"price must be a whole number"}) {
+		t.Errorf("second row is %+v", rows[1])
+	}
+	if !reflect.DeepEqual(rows[2].Problems, []string{"row is not an object"}) {
+		t.Errorf("problems of the third row are %v", rows[2].Problems)
+	}
+}
+
+func TestImportJobsAreFoundByTheirRequester(t *testing.T) {
+	s := productImportService{jobs: NewImportJobs()}
+	if _, err := s.Start(context.Background(), nil, true); !domain_error.Is(err, domain_error.FORBIDDEN) {
+		t.Errorf("anonymous start got %v, want forbidden", err)
+	}
+	s.jobs.add(dtos.ProductImportJobDto{ID: "job", Status: enums.JOB_DONE, CreatedBy: "owner@example.com"})
+	requesters := []struct {
+		name  string
+		ctx   context.Context
+		found bool
+	}{
+		{"anonymous", context.Background(), false},
+		{"other user", utils.WithRequester(context.Background(), dtos.JwtPayload{Email: "other@example.com", Role: string(enums.ROLE_ADMIN)}), false},
+		{"creator", utils.WithRequester(context.Background(), dtos.JwtPayload{Email: "owner@example.com", Role: string(enums.ROLE_ADMIN)}), true},
+		{"super admin", utils.WithRequester(context.Background(), dtos.JwtPayload{Email: "root@example.com", Role: string(enums.ROLE_SUPER_ADMIN)}), true},
+	}
+	for _, requester := range requesters {
+		job, err := s.FindJob(requester.ctx, "job")
+		if requester.found && (err != nil || job.ID != "job") {
+			t.Errorf("%s got %v, want the job", requester.name, err)
+		}
+		if !requester.found && !domain_error.Is(err, domain_error.NOT_FOUND) {
+			t.Errorf("%s got %v, want not found", requester.name, err)
+		}
+	}
+	if _, err := s.FindJob(requesters[2].ctx, "other"); !domain_error.Is(err, domain_error.NOT_FOUND) {
+		t.Errorf("unknown job got %v, want not found", err)
+	}
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/config"
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/utils"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"github.com/sajalmia381/store-api/src/v1/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReviewService keeps the reviews of the products. With REVIEW_MODERATION new
+// and edited reviews wait for an admin to approve them, otherwise they are
+// approved at once and can be rejected later. Only approved reviews count in
+// the rating of the product.
+type ReviewService interface {
+	// Store reviews a live product as the requester.
+	Store(ctx context.Context, slug string, payload dtos.ReviewStoreDto) (model.Review, error)
+	// FindByProduct lists the reviews of a live product.
+	FindByProduct(ctx context.Context, slug string, queryParams dtos.ReviewQueryParams) ([]model.Review, common.MetaData, error)
+	FindAll(ctx context.Context, queryParams dtos.ReviewQueryParams) ([]model.Review, common.MetaData, error)
+	FindById(ctx context.Context, id string) (model.Review, error)
+	// UpdateById, Moderate and DeleteById write only while the review is at
+	// the given version, nil writes unconditionally.
+	UpdateById(ctx context.Context, id string, payload dtos.ReviewUpdateDto, version *int64) (model.Review, error)
+	Moderate(ctx context.Context, id string, payload dtos.ReviewModerationDto, version *int64) (model.Review, error)
+	DeleteById(ctx context.Context, id string, version *int64) (model.Review, error)
+	// Fake Action
+	FakeModerate(ctx context.Context, id string, payload dtos.ReviewModerationDto, version *int64) (model.Review, error)
+	FakeDeleteById(ctx context.Context, id string, version *int64) (model.Review, error)
+}
+
+type reviewService struct {
+	repo        repository.ReviewRepository
+	productRepo repository.ProductRepository
+	audit       AuditService
+	cache       *CatalogCache
+}
+
+func (s reviewService) Store(ctx context.Context, slug string, payload dtos.ReviewStoreDto) (model.Review, error) {
+	var review model.Review
+	requester, ok := utils.GetRequester(ctx)
+	if !ok {
+		return review, domain_error.Forbidden("Only a signed in user can review a product")
+	}
+	product, err := s.productRepo.FindBySlug(ctx, slug)
+	if err != nil {
+		return review, err
+	}
+	now := time.Now().UTC()
+	review = model.Review{
+		ID:         primitive.NewObjectID(),
+		ProductId:  product.ID,
+		AuthorId:   requester.ID,
+		AuthorName: requester.Name,
+		Rating:     payload.Rating,
+		Title:      strings.TrimSpace(payload.Title),
+		Body:       strings.TrimSpace(payload.Body),
+		Status:     newReviewStatus(),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	review, err = s.repo.Store(ctx, review)
+	s.cache.Invalidate()
+	if err != nil {
+		return review, err
+	}
+	s.audit.Record(ctx, enums.AUDIT_REVIEW, enums.AUDIT_CREATE, review.ID, nil, review)
+	return review, nil
+}
+
+// newReviewStatus is the status of a new or edited review.
+func newReviewStatus() enums.ReviewStatus {
+	if config.ReviewModeration {
+		return enums.REVIEW_PENDING
+	}
+	return enums.REVIEW_APPROVED
+}
+
+func (s reviewService) FindByProduct(ctx context.Context, slug string, queryParams dtos.ReviewQueryParams) ([]model.Review, common.MetaData, error) {
+	product, err := s.productRepo.FindBySlug(ctx, slug)
+	if err != nil {
+		return []model.Review{}, common.MetaData{}, err
+	}
+	queryParams.Product = product.ID.Hex()
+	return s.FindAll(ctx, queryParams)
+}
+
+func (s reviewService) FindAll(ctx context.Context, queryParams dtos.ReviewQueryParams) ([]model.Review, common.MetaData, error) {
+	if err := queryParams.Validate(); err != nil {
+		return []model.Review{}, common.MetaData{}, err
+	}
+	return s.repo.FindAll(ctx, queryParams)
+}
+
+func (s reviewService) FindById(ctx context.Context, id string) (model.Review, error) {
+	return s.repo.FindById(ctx, id)
+}
+
+func (s reviewService) UpdateById(ctx context.Context, id string, formData dtos.ReviewUpdateDto, version *int64) (model.Review, error) {
+	before, err := s.repo.FindById(ctx, id)
+	if err != nil {
+		return before, err
+	}
+	payload := bson.M{
+		"updatedAt": time.Now().UTC(),
+		"status":    newReviewStatus(),
+	}
+	if formData.Rating != 0 {
+		payload["rating"] = formData.Rating
+	}
+	if title := strings.TrimSpace(formData.Title); title != "" {
+		payload["title"] = title
+	}
+	if body := strings.TrimSpace(formData.Body); body != "" {
+		payload["body"] = body
+	}
+	return s.update(ctx, before, payload, version)
+}
+
+func (s reviewService) Moderate(ctx context.Context, id string, formData dtos.ReviewModerationDto, version *int64) (model.Review, error) {
+	before, err := s.repo.FindById(ctx, id)
+	if err != nil {
+		return before, err
+	}
+	payload := bson.M{
+		"updatedAt": time.Now().UTC(),
+		"status":    formData.Status,
+	}
+	return s.update(ctx, before, payload, version)
+}
+
+func (s reviewService) update(ctx context.Context, before model.Review, payload primitive.M, version *int64) (model.Review, error) {
+	review, err := s.repo.UpdateById(ctx, before.ID.Hex(), payload, version)
+	s.cache.Invalidate()
+	if err != nil {
+		return review, err
+	}
+	s.audit.Record(ctx, enums.AUDIT_REVIEW, enums.AUDIT_UPDATE, review.ID, before, review)
+	return review, nil
+}
+
+func (s reviewService) DeleteById(ctx context.Context, id string, version *int64) (model.Review, error) {
+	review, err := s.repo.DeleteById(ctx, id, version)
+	s.cache.Invalidate()
+	if err != nil {
+		return review, err
+	}
+	s.audit.Record(ctx, enums.AUDIT_REVIEW, enums.AUDIT_DELETE, review.ID, review, nil)
+	return review, nil
+}
+
+// Fake
+func (s reviewService) FakeModerate(ctx context.Context, id string, payload dtos.ReviewModerationDto, version *int64) (model.Review, error) {
+	review, err := s.findAtVersion(ctx, id, version)
+	if err != nil {
+		return review, err
+	}
+	review.Status = payload.Status
+	review.UpdatedAt = time.Now().UTC()
+	review.Version++
+	return review, nil
+}
+
+func (s reviewService) FakeDeleteById(ctx context.Context, id string, version *int64) (model.Review, error) {
+	return s.findAtVersion(ctx, id, version)
+}
+
+// findAtVersion checks the review like a conditional write does, for the fake
+// writes that change nothing.
+func (s reviewService) findAtVersion(ctx context.Context, id string, version *int64) (model.Review, error) {
+	review, err := s.repo.FindById(ctx, id)
+	if err != nil {
+		return review, err
+	}
+	if version != nil && *version != review.Version {
+		return review, domain_error.StaleVersion("review", review.Version)
+	}
+	return review, nil
+}
+
+func NewReviewService(repo repository.ReviewRepository, productRepo repository.ProductRepository, audit AuditService, cache *CatalogCache) ReviewService {
+	return &reviewService{
+		repo:        repo,
+		productRepo: productRepo,
+		audit:       audit,
+		cache:       cache,
+	}
+}
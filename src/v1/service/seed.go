@@ -0,0 +1,305 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/enums"
+	"github.com/sajalmia381/store-api/src/fixture"
+	"github.com/sajalmia381/store-api/src/utils"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"github.com/sajalmia381/store-api/src/v1/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type SeedService interface {
+	// Seed stores the catalog fixtures that do not exist yet, so running it
+	// again only adds what is new.
+	Seed(ctx context.Context, catalog fixture.Catalog) (dtos.SeedReportDto, error)
+}
+
+type seedService struct {
+	categoryRepo repository.CategoryRepository
+	productRepo  repository.ProductRepository
+	userRepo     repository.UserRepository
+	cartRepo     repository.CartRepository
+	images       ImageService
+	cache        *CatalogCache
+}
+
+func (s seedService) Seed(ctx context.Context, catalog fixture.Catalog) (dtos.SeedReportDto, error) {
+	var report dtos.SeedReportDto
+	defer s.cache.Invalidate()
+	// Users first, products and carts refer to them
+	for _, user := range catalog.Users {
+		created, err := s.seedUser(ctx, user)
+		if err != nil {
+			return report, err
+		}
+		countSeeded(&report.Users, created)
+	}
+	for _, category := range catalog.Categories {
+		created, err := s.seedCategory(ctx, category)
+		if err != nil {
+			return report, err
+		}
+		countSeeded(&report.Categories, created)
+	}
+	for _, product := range catalog.Products {
+		created, err := s.seedProduct(ctx, catalog, product)
+		if err != nil {
+			return report, err
+		}
+		countSeeded(&report.Products, created)
+	}
+	for _, cart := range catalog.Carts {
+		created, err := s.seedCart(ctx, cart)
+		if err != nil {
+			return report, err
+		}
+		countSeeded(&report.Carts, created)
+	}
+	return report, nil
+}
+
+func (s seedService) seedUser(ctx context.Context, payload fixture.User) (bool, error) {
+	if payload.Email == "" || payload.Password == "" {
+		return false, domain_error.Validation(fmt.Sprintf("user %q: email and password are required", payload.Name))
+	}
+	_, err := s.userRepo.FindByEmail(ctx, payload.Email)
+	if err == nil || !domain_error.Is(err, domain_error.NOT_FOUND) {
+		return false, err
+	}
+	role := enums.Role(payload.Role)
+	if role == "" {
+		role = enums.ROLE_CUSTOMER
+	}
+	if role != enums.ROLE_CUSTOMER && role != enums.ROLE_ADMIN {
+		return false, domain_error.Validation(fmt.Sprintf("user %q: role %q is not allowed", payload.Email, payload.Role))
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(payload.Password), bcrypt.DefaultCost)
+	if err != nil {
+		log.Println("[ERROR] convert string to hash password:", err.Error())
+		return false, err
+	}
+	user := model.User{
+		ID:        primitive.NewObjectID(),
+		Name:      payload.Name,
+		Email:     payload.Email,
+		Password:  string(hashedPassword),
+		Number:    payload.Number,
+		Status:    true,
+		Role:      role,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+	_, err = s.userRepo.Store(ctx, user)
+	if domain_error.Is(err, domain_error.CONFLICT) {
+		// a trashed user keeps its email
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s seedService) seedCategory(ctx context.Context, payload fixture.Category) (bool, error) {
+	if payload.Name == "" {
+		return false, domain_error.Validation("category: name is required")
+	}
+	_, err := s.categoryRepo.FindBySlug(ctx, utils.GenerateSlug(payload.Name))
+	if err == nil || !domain_error.Is(err, domain_error.NOT_FOUND) {
+		return false, err
+	}
+	attributes := []model.CategoryAttribute{}
+	for _, attribute := range payload.Attributes {
+		attributes = append(attributes, model.CategoryAttribute{
+			Name:     attribute.Name,
+			Label:    attribute.Label,
+			Type:     enums.AttributeType(attribute.Type),
+			Required: attribute.Required,
+			Unit:     attribute.Unit,
+			Options:  attribute.Options,
+		})
+	}
+	if err := (&dtos.CategoryStoreDto{Name: payload.Name, Attributes: attributes}).Validate(); err != nil {
+		return false, domain_error.Validation(fmt.Sprintf("category %q: %s", payload.Name, err.Error()))
+	}
+	category := model.Category{
+		ID:          primitive.NewObjectID(),
+		Name:        payload.Name,
+		Description: payload.Description,
+		Products:    []primitive.ObjectID{},
+		Attributes:  newAttributeSchema(attributes),
+	}
+	if payload.Parent != "" {
+		parent, err := s.findCategory(ctx, payload.Parent)
+		if err != nil {
+			return false, err
+		}
+		category.Parent = &parent.ID
+	}
+	_, err = s.categoryRepo.Store(ctx, category)
+	return err == nil, err
+}
+
+func (s seedService) seedProduct(ctx context.Context, catalog fixture.Catalog, payload fixture.Product) (bool, error) {
+	if payload.Title == "" {
+		return false, domain_error.Validation("product: title is required")
+	}
+	_, err := s.productRepo.FindBySlug(ctx, utils.GenerateSlug(payload.Title))
+	if err == nil || !domain_error.Is(err, domain_error.NOT_FOUND) {
+		return false, err
+	}
+	product := model.Product{
+		ID:          primitive.NewObjectID(),
+		CreatedBy:   payload.CreatedBy,
+		Title:       payload.Title,
+		Price:       payload.Price,
+		Image:       payload.Image,
+		Description: payload.Description,
+		CreatedAt:   time.Now().UTC(),
+		UpdatedAt:   time.Now().UTC(),
+		Active:      true,
+		Variants:    []model.ProductVariant{},
+		Images:      []model.ProductImage{},
+	}
+	if payload.Stock != nil {
+		if *payload.Stock < 0 {
+			return false, domain_error.Validation(fmt.Sprintf("product %q: stock must not be negative", payload.Title))
+		}
+		stock, available := *payload.Stock, *payload.Stock
+		product.Stock = &stock
+		product.Available = &available
+	}
+	for _, item := range payload.Variants {
+		if item.SKU == "" {
+			return false, domain_error.Validation(fmt.Sprintf("product %q: variant sku is required", payload.Title))
+		}
+		attributes := item.Attributes
+		if attributes == nil {
+			attributes = map[string]string{}
+		}
+		product.Variants = append(product.Variants, model.ProductVariant{
+			ID:         primitive.NewObjectID(),
+			SKU:        item.SKU,
+			Price:      item.Price,
+			Attributes: attributes,
+			Active:     true,
+			CreatedAt:  product.CreatedAt,
+			UpdatedAt:  product.UpdatedAt,
+		})
+	}
+	if payload.Category != "" {
+		category, err := s.findCategory(ctx, payload.Category)
+		if err != nil {
+			return false, err
+		}
+		product.Category = &category.ID
+	}
+	schema, err := findCategorySchema(ctx, s.categoryRepo, product.Category)
+	if err != nil {
+		return false, err
+	}
+	if product.Attributes, err = checkAttributes(schema, payload.Attributes); err != nil {
+		return false, domain_error.Validation(fmt.Sprintf("product %q: %s", payload.Title, err.Error()))
+	}
+	if len(payload.Images) > maxProductImages {
+		return false, domain_error.Validation(fmt.Sprintf("product %q: a product can have at most %d images", payload.Title, maxProductImages))
+	}
+	for _, name := range payload.Images {
+		content, err := catalog.ReadFile(name)
+		if err != nil {
+			s.images.Delete(ctx, product.Images...)
+			return false, domain_error.Validation(fmt.Sprintf("product %q: %s", payload.Title, err.Error()))
+		}
+		image, err := s.images.Store(ctx, dtos.ProductImageUpload{Name: name, Content: content})
+		if err != nil {
+			s.images.Delete(ctx, product.Images...)
+			return false, err
+		}
+		product.Images = append(product.Images, image)
+	}
+	if len(product.Images) > 0 {
+		product.Image, product.ImageSource = primaryImage(product.Images)
+	}
+	_, err = s.productRepo.Store(ctx, product)
+	if err != nil {
+		s.images.Delete(ctx, product.Images...)
+	}
+	return err == nil, err
+}
+
+// seedCart fills the cart of the user unless it already has products.
+func (s seedService) seedCart(ctx context.Context, payload fixture.Cart) (bool, error) {
+	user, err := s.userRepo.FindByEmail(ctx, payload.User)
+	if domain_error.Is(err, domain_error.NOT_FOUND) {
+		return false, domain_error.Validation(fmt.Sprintf("cart: user %q is not found", payload.User))
+	}
+	if err != nil {
+		return false, err
+	}
+	cart, err := s.cartRepo.FindByUserId(ctx, user.ID)
+	if err == nil && len(cart.Products) > 0 {
+		return false, nil
+	}
+	if err != nil && !domain_error.Is(err, domain_error.NOT_FOUND) {
+		return false, err
+	}
+	products := []model.CartProductSpec{}
+	for _, item := range payload.Products {
+		product, err := s.productRepo.FindBySlug(ctx, item.Product)
+		if domain_error.Is(err, domain_error.NOT_FOUND) {
+			return false, domain_error.Validation(fmt.Sprintf("cart of %q: product %q is not found", payload.User, item.Product))
+		}
+		if err != nil {
+			return false, err
+		}
+		line := model.CartProductSpec{ProductId: product.ID, Quantity: item.Quantity}
+		if item.Variant != "" {
+			for _, variant := range product.Variants {
+				if variant.SKU == item.Variant {
+					id := variant.ID
+					line.VariantId = &id
+					break
+				}
+			}
+			if line.VariantId == nil {
+				return false, domain_error.Validation(fmt.Sprintf("cart of %q: variant %q of %q is not found", payload.User, item.Variant, item.Product))
+			}
+		}
+		products = append(products, line)
+	}
+	_, err = s.cartRepo.UpdateCartByProducts(ctx, user.ID, products)
+	return err == nil, err
+}
+
+func (s seedService) findCategory(ctx context.Context, slug string) (model.Category, error) {
+	category, err := s.categoryRepo.FindBySlug(ctx, slug)
+	if domain_error.Is(err, domain_error.NOT_FOUND) {
+		return category, domain_error.Validation(fmt.Sprintf("category %q is not found", slug))
+	}
+	return category, err
+}
+
+func countSeeded(counter *dtos.SeedCountDto, created bool) {
+	if created {
+		counter.Created++
+	} else {
+		counter.Skipped++
+	}
+}
+
+func NewSeedService(categoryRepo repository.CategoryRepository, productRepo repository.ProductRepository, userRepo repository.UserRepository, cartRepo repository.CartRepository, images ImageService, cache *CatalogCache) SeedService {
+	return &seedService{
+		categoryRepo: categoryRepo,
+		productRepo:  productRepo,
+		userRepo:     userRepo,
+		cartRepo:     cartRepo,
+		images:       images,
+		cache:        cache,
+	}
+}
@@ -1,33 +1,34 @@
 package service
 
 import (
+	"context"
 	"github.com/sajalmia381/store-api/src/v1/model"
 	"github.com/sajalmia381/store-api/src/v1/repository"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type TokenService interface {
-	Store(payload model.Token) (model.Token, error)
-	FindByToken(token string) (model.Token, error)
-	DeleteByToken(token string) (*mongo.DeleteResult, error)
+	Store(ctx context.Context, payload model.Token) (model.Token, error)
+	FindByToken(ctx context.Context, token string) (model.Token, error)
+	DeleteByToken(ctx context.Context, token string) (*mongo.DeleteResult, error)
 }
 
 type tokenService struct {
 	repo repository.TokenRepository
 }
 
-func (s tokenService) Store(payload model.Token) (model.Token, error) {
-	token, err := s.repo.Store(payload)
+func (s tokenService) Store(ctx context.Context, payload model.Token) (model.Token, error) {
+	token, err := s.repo.Store(ctx, payload)
 	return token, err
 }
 
-func (s tokenService) FindByToken(token string) (model.Token, error) {
-	tokenObj, err := s.repo.FindByToken(token)
+func (s tokenService) FindByToken(ctx context.Context, token string) (model.Token, error) {
+	tokenObj, err := s.repo.FindByToken(ctx, token)
 	return tokenObj, err
 }
 
-func (s tokenService) DeleteByToken(token string) (*mongo.DeleteResult, error) {
-	result, err := s.repo.DeleteByToken(token)
+func (s tokenService) DeleteByToken(ctx context.Context, token string) (*mongo.DeleteResult, error) {
+	result, err := s.repo.DeleteByToken(ctx, token)
 	return result, err
 }
 
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/sajalmia381/store-api/src/domain_error"
+	"github.com/sajalmia381/store-api/src/v1/dtos"
+	"github.com/sajalmia381/store-api/src/v1/model"
+	"github.com/sajalmia381/store-api/src/v1/repository"
+)
+
+type TrashService interface {
+	// Purge permanently removes the products, categories and users that are
+	// in the trash for longer than olderThan.
+	Purge(ctx context.Context, olderThan time.Duration) (dtos.TrashPurgeReportDto, error)
+}
+
+type trashService struct {
+	productRepo  repository.ProductRepository
+	categoryRepo repository.CategoryRepository
+	userRepo     repository.UserRepository
+	images       ImageService
+	cache        *CatalogCache
+}
+
+func (s trashService) Purge(ctx context.Context, olderThan time.Duration) (dtos.TrashPurgeReportDto, error) {
+	report := dtos.TrashPurgeReportDto{
+		Before: time.Now().UTC().Add(-olderThan),
+	}
+	if olderThan < 0 {
+		return report, domain_error.Validation("olderThan must not be negative")
+	}
+	defer s.cache.Invalidate()
+	trash, err := s.productRepo.FindTrash(ctx)
+	if err != nil {
+		return report, err
+	}
+	if report.Products, err = s.productRepo.PurgeDeletedBefore(ctx, report.Before); err != nil {
+		return report, err
+	}
+	s.deleteImages(ctx, trash, report.Before)
+	if report.Categories, err = s.categoryRepo.PurgeDeletedBefore(ctx, report.Before); err != nil {
+		return report, err
+	}
+	report.Users, err = s.userRepo.PurgeDeletedBefore(ctx, report.Before)
+	return report, err
+}
+
+// deleteImages deletes the images of the products of the trash that were
+// purged, a product restored meanwhile keeps them.
+func (s trashService) deleteImages(ctx context.Context, trash []model.Product, before time.Time) {
+	for _, product := range trash {
+		if len(product.Images) == 0 || product.DeletedAt == nil || !product.DeletedAt.Before(before) {
+			continue
+		}
+		if _, err := s.productRepo.FindById(ctx, product.ID); !domain_error.Is(err, domain_error.NOT_FOUND) {
+			continue
+		}
+		s.images.Delete(ctx, product.Images...)
+	}
+}
+
+func NewTrashService(productRepo repository.ProductRepository, categoryRepo repository.CategoryRepository, userRepo repository.UserRepository, images ImageService, cache *CatalogCache) TrashService {
+	return &trashService{
+		productRepo:  productRepo,
+		categoryRepo: categoryRepo,
+		userRepo:     userRepo,
+		images:       images,
+		cache:        cache,
+	}
+}
@@ -1,10 +1,12 @@
 package service
 
 import (
-	"errors"
+	"context"
 	"log"
 	"time"
 
+	"github.com/sajalmia381/store-api/src/api/common"
+	"github.com/sajalmia381/store-api/src/domain_error"
 	"github.com/sajalmia381/store-api/src/enums"
 	"github.com/sajalmia381/store-api/src/v1/dtos"
 	"github.com/sajalmia381/store-api/src/v1/model"
@@ -14,24 +16,36 @@ import (
 )
 
 type UserService interface {
-	Store(payload dtos.UserRegisterDTO) (model.User, error)
-	FindAll(filterData dtos.UserQuery) ([]model.User, error)
-	FindById(id string) (model.User, error)
-	FindByEmail(email string) (model.User, error)
-	UpdateById(id string, payload dtos.UserUpdateDto) (model.User, error)
-	DeleteById(id string) error
+	Store(ctx context.Context, payload dtos.UserRegisterDTO) (model.User, error)
+	FindAll(ctx context.Context, filterData dtos.UserQuery) ([]model.User, common.MetaData, error)
+	FindById(ctx context.Context, id string) (model.User, error)
+	FindByEmail(ctx context.Context, email string) (model.User, error)
+	// UpdateById and DeleteById write only while the user is at the given
+	// version, nil writes unconditionally.
+	UpdateById(ctx context.Context, id string, payload dtos.UserUpdateDto, version *int64) (model.User, error)
+	DeleteById(ctx context.Context, id string, version *int64) error
+	// Trash
+	FindTrash(ctx context.Context) ([]model.User, error)
+	RestoreById(ctx context.Context, id string) (model.User, error)
+	PurgeById(ctx context.Context, id string) (model.User, error)
+	// History lists the changes of a live or trashed user.
+	History(ctx context.Context, id string, queryParams dtos.AuditQueryParams) ([]model.AuditLog, common.MetaData, error)
 	// For super admin
-	StoreSuperAdmin(payload dtos.UserRegisterDTO) (model.User, error)
+	StoreSuperAdmin(ctx context.Context, payload dtos.UserRegisterDTO) (model.User, error)
 	// Fake Action
-	FakeStore(payload dtos.UserRegisterDTO) (model.User, error)
-	FakeUpdateById(id string, payload dtos.UserUpdateDto) (model.User, error)
+	FakeStore(ctx context.Context, payload dtos.UserRegisterDTO) (model.User, error)
+	FakeUpdateById(ctx context.Context, id string, payload dtos.UserUpdateDto, version *int64) (model.User, error)
+	FakeDeleteById(ctx context.Context, id string, version *int64) error
 }
 
 type userService struct {
-	repo repository.UserRepository
+	repo  repository.UserRepository
+	audit AuditService
+	// cache is invalidated by the writes, products embed their creator
+	cache *CatalogCache
 }
 
-func (s userService) Store(payload dtos.UserRegisterDTO) (model.User, error) {
+func (s userService) Store(ctx context.Context, payload dtos.UserRegisterDTO) (model.User, error) {
 	user := mergePayloadDataToUser(payload)
 	user.Role = enums.ROLE_CUSTOMER
 	user.Status = true
@@ -45,11 +59,15 @@ func (s userService) Store(payload dtos.UserRegisterDTO) (model.User, error) {
 	user.Password = string(hashedPassword)
 	user.CreatedAt = time.Now().UTC()
 	user.UpdatedAt = time.Now().UTC()
-	newUser, err := s.repo.Store(user)
-	return newUser, err
+	newUser, err := s.repo.Store(ctx, user)
+	if err != nil {
+		return newUser, err
+	}
+	s.audit.Record(ctx, enums.AUDIT_USER, enums.AUDIT_CREATE, newUser.ID, nil, newUser)
+	return newUser, nil
 }
 
-func (s userService) StoreSuperAdmin(payload dtos.UserRegisterDTO) (model.User, error) {
+func (s userService) StoreSuperAdmin(ctx context.Context, payload dtos.UserRegisterDTO) (model.User, error) {
 
 	user := mergePayloadDataToUser(payload)
 	user.Role = enums.ROLE_SUPER_ADMIN
@@ -64,35 +82,39 @@ func (s userService) StoreSuperAdmin(payload dtos.UserRegisterDTO) (model.User,
 	user.Password = string(hashedPassword)
 	user.CreatedAt = time.Now().UTC()
 	user.UpdatedAt = time.Now().UTC()
-	newUser, err := s.repo.Store(user)
-	return newUser, err
+	newUser, err := s.repo.Store(ctx, user)
+	if err != nil {
+		return newUser, err
+	}
+	s.audit.Record(ctx, enums.AUDIT_USER, enums.AUDIT_CREATE, newUser.ID, nil, newUser)
+	return newUser, nil
 }
 
-func (s userService) FindAll(query dtos.UserQuery) ([]model.User, error) {
-	objects, err := s.repo.FindAll(query)
-	return objects, err
+func (s userService) FindAll(ctx context.Context, query dtos.UserQuery) ([]model.User, common.MetaData, error) {
+	objects, metaData, err := s.repo.FindAll(ctx, query)
+	return objects, metaData, err
 }
 
-func (s userService) FindById(id string) (model.User, error) {
+func (s userService) FindById(ctx context.Context, id string) (model.User, error) {
 	var user model.User
 	_id, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return user, errors.New("invalid user id")
+		return user, domain_error.Validation("invalid user id")
 	}
-	user, err = s.repo.FindById(_id)
+	user, err = s.repo.FindById(ctx, _id)
 	return user, err
 }
 
-func (s userService) FindByEmail(email string) (model.User, error) {
-	user, err := s.repo.FindByEmail(email)
+func (s userService) FindByEmail(ctx context.Context, email string) (model.User, error) {
+	user, err := s.repo.FindByEmail(ctx, email)
 	return user, err
 }
 
-func (s userService) UpdateById(id string, formData dtos.UserUpdateDto) (model.User, error) {
+func (s userService) UpdateById(ctx context.Context, id string, formData dtos.UserUpdateDto, version *int64) (model.User, error) {
 	var user model.User
 	_id, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return user, err
+		return user, domain_error.Validation("invalid user id")
 	}
 	payload := primitive.M{}
 	if formData.Name == "" {
@@ -103,7 +125,7 @@ func (s userService) UpdateById(id string, formData dtos.UserUpdateDto) (model.U
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(formData.Password), bcrypt.DefaultCost)
 		if err != nil {
 			log.Println("[ERROR] convert string to hash password:", err.Error())
-			panic(err)
+			return user, err
 		}
 		payload["password"] = string(hashedPassword)
 	}
@@ -113,25 +135,105 @@ func (s userService) UpdateById(id string, formData dtos.UserUpdateDto) (model.U
 	if formData.Status == nil {
 		payload["status"] = &user.Status
 	}
-	newUser, err := s.repo.UpdateById(_id, payload)
-	return newUser, err
+	before, err := s.repo.FindById(ctx, _id)
+	if err != nil {
+		return before, err
+	}
+	newUser, err := s.repo.UpdateById(ctx, _id, payload, version)
+	s.cache.Invalidate()
+	if err != nil {
+		return newUser, err
+	}
+	s.audit.Record(ctx, enums.AUDIT_USER, enums.AUDIT_UPDATE, newUser.ID, before, newUser)
+	return newUser, nil
 }
 
-func (s userService) DeleteById(id string) error {
+func (s userService) DeleteById(ctx context.Context, id string, version *int64) error {
 	_id, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return errors.New("invalid user id")
+		return domain_error.Validation("invalid user id")
 	}
-	_, err = s.repo.DeleteById(_id)
-	return err
+	before, err := s.repo.FindById(ctx, _id)
+	if err != nil {
+		return err
+	}
+	_, err = s.repo.DeleteById(ctx, _id, version)
+	s.cache.Invalidate()
+	if err != nil {
+		return err
+	}
+	if after, err := s.repo.FindTrashedById(ctx, _id); err == nil {
+		s.audit.Record(ctx, enums.AUDIT_USER, enums.AUDIT_DELETE, _id, before, after)
+	}
+	return nil
+}
+
+func (s userService) FindTrash(ctx context.Context) ([]model.User, error) {
+	users, err := s.repo.FindTrash(ctx)
+	return users, err
+}
+
+func (s userService) RestoreById(ctx context.Context, id string) (model.User, error) {
+	_id, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return model.User{}, domain_error.Validation("invalid user id")
+	}
+	before, err := s.repo.FindTrashedById(ctx, _id)
+	if err != nil {
+		return before, err
+	}
+	user, err := s.repo.RestoreById(ctx, _id)
+	s.cache.Invalidate()
+	if err != nil {
+		return user, err
+	}
+	s.audit.Record(ctx, enums.AUDIT_USER, enums.AUDIT_RESTORE, _id, before, user)
+	return user, nil
+}
+
+func (s userService) PurgeById(ctx context.Context, id string) (model.User, error) {
+	_id, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return model.User{}, domain_error.Validation("invalid user id")
+	}
+	user, err := s.repo.PurgeById(ctx, _id)
+	s.cache.Invalidate()
+	if err != nil {
+		return user, err
+	}
+	s.audit.Record(ctx, enums.AUDIT_USER, enums.AUDIT_PURGE, _id, user, nil)
+	return user, nil
+}
+
+func (s userService) History(ctx context.Context, id string, queryParams dtos.AuditQueryParams) ([]model.AuditLog, common.MetaData, error) {
+	_id, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return []model.AuditLog{}, common.MetaData{}, domain_error.Validation("invalid user id")
+	}
+	_, err = s.repo.FindById(ctx, _id)
+	if domain_error.Is(err, domain_error.NOT_FOUND) {
+		_, err = s.repo.FindTrashedById(ctx, _id)
+		if domain_error.Is(err, domain_error.NOT_FOUND) {
+			err = domain_error.NotFound("user is not found")
+		}
+	}
+	if err != nil {
+		return []model.AuditLog{}, common.MetaData{}, err
+	}
+	queryParams.Entity = enums.AUDIT_USER
+	queryParams.EntityId = _id.Hex()
+	return s.audit.FindAll(ctx, queryParams)
 }
 
 // None Super Admin
-func (s userService) FakeStore(payload dtos.UserRegisterDTO) (model.User, error) {
+func (s userService) FakeStore(ctx context.Context, payload dtos.UserRegisterDTO) (model.User, error) {
 	var user model.User
-	_, err := s.repo.FindByEmail(payload.Email)
+	_, err := s.repo.FindByEmail(ctx, payload.Email)
 	if err == nil {
-		return user, errors.New("user is exists! try with another email")
+		return user, domain_error.Conflict("user is exists! try with another email")
+	}
+	if !domain_error.Is(err, domain_error.NOT_FOUND) {
+		return user, err
 	}
 	user = mergePayloadDataToUser(payload)
 	user.Role = enums.Role("ROLE_CUSTOMER")
@@ -148,12 +250,13 @@ func (s userService) FakeStore(payload dtos.UserRegisterDTO) (model.User, error)
 	return user, err
 }
 
-func (s userService) FakeUpdateById(id string, payload dtos.UserUpdateDto) (model.User, error) {
-	user, err := s.FindById(id)
+func (s userService) FakeUpdateById(ctx context.Context, id string, payload dtos.UserUpdateDto, version *int64) (model.User, error) {
+	user, err := s.findAtVersion(ctx, id, version)
 	if err != nil {
 		return user, err
 	}
 	user.UpdatedAt = time.Now().UTC()
+	user.Version++
 	if payload.Name != "" {
 		user.Name = payload.Name
 	}
@@ -161,7 +264,7 @@ func (s userService) FakeUpdateById(id string, payload dtos.UserUpdateDto) (mode
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(payload.Password), bcrypt.DefaultCost)
 		if err != nil {
 			log.Println("[ERROR] convert string to hash password:", err.Error())
-			panic(err)
+			return user, err
 		}
 		user.Password = string(hashedPassword)
 	}
@@ -174,9 +277,29 @@ func (s userService) FakeUpdateById(id string, payload dtos.UserUpdateDto) (mode
 	return user, nil
 }
 
-func NewUserService(userRepo repository.UserRepository) UserService {
+func (s userService) FakeDeleteById(ctx context.Context, id string, version *int64) error {
+	_, err := s.findAtVersion(ctx, id, version)
+	return err
+}
+
+// findAtVersion checks the user like a conditional write does, for the fake
+// writes that change nothing.
+func (s userService) findAtVersion(ctx context.Context, id string, version *int64) (model.User, error) {
+	user, err := s.FindById(ctx, id)
+	if err != nil {
+		return user, err
+	}
+	if version != nil && *version != user.Version {
+		return user, domain_error.StaleVersion("user", user.Version)
+	}
+	return user, nil
+}
+
+func NewUserService(userRepo repository.UserRepository, audit AuditService, cache *CatalogCache) UserService {
 	return &userService{
-		repo: userRepo,
+		repo:  userRepo,
+		audit: audit,
+		cache: cache,
 	}
 }
 